@@ -1,17 +1,21 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	. "github.com/faelmori/getl/etypes"
+	etlkafka "github.com/faelmori/getl/kafka"
 	. "github.com/faelmori/getl/sql"
 	. "github.com/faelmori/getl/utils"
 	"github.com/faelmori/logz"
 	"github.com/segmentio/kafka-go"
 	"github.com/spf13/cobra"
 	"os"
+	"strings"
+	"time"
 )
 
 // VacuumCmd cria um comando Cobra para executar a limpeza de registros de uma tabela.
@@ -59,7 +63,7 @@ func ExtractCmd() *cobra.Command {
 			var fieldsErr error
 
 			// Extrai os dados com os tipos de coluna para contingência caso o tipo de coluna não seja informado
-			data, _, fieldsErr = ExtractDataWithTypes(nil, sourceConfig)
+			data, _, fieldsErr = ExtractDataWithTypesContext(cmd.Context(), nil, sourceConfig)
 			if fieldsErr != nil {
 				return fmt.Errorf("falha ao extrair dados do destino: %w", fieldsErr)
 			}
@@ -119,7 +123,7 @@ func LoadCmd() *cobra.Command {
 			}
 
 			// Carregar os dados no banco de destino
-			if loadDataErr := LoadData(nil, destinationConfig); loadDataErr != nil {
+			if loadDataErr := LoadDataContext(cmd.Context(), nil, destinationConfig); loadDataErr != nil {
 				return fmt.Errorf("falha ao carregar os dados no destino: %w", loadDataErr)
 			}
 
@@ -140,18 +144,28 @@ func SyncCmd() *cobra.Command {
 	var fileConfigPath, fileOutputPath, outputFormat string
 	var needCheck bool
 	var checkMethod string
+	var pipelineNames []string
+	var dryRun, explain bool
+	var sampleSize int
+	var resumeRunID string
 
 	sCmd := &cobra.Command{
 		Use:     "sync",
 		Aliases: []string{"s", "etl", "integrate"},
 		Short:   "Executa as etapas extract, transform e load em sequência",
-		Long:    "Este comando executa as etapas de extração, transformação e carregamento de dados em sequência.",
+		Long:    "Este comando executa as etapas de extração, transformação e carregamento de dados em sequência. Em arquivos com várias pipelines, todas são executadas, a menos que --pipeline seja informado.",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if validateArgsErr := ValidateArgs(fileConfigPath); validateArgsErr != nil {
 				logz.Error(fmt.Sprintf("falha ao validar argumentos: %v", validateArgsErr), map[string]interface{}{})
 				return validateArgsErr
 			}
-			return ExecuteETL(fileConfigPath, fileOutputPath, outputFormat, needCheck, checkMethod)
+			if dryRun || explain {
+				return DryRunETLPipelines(cmd.OutOrStdout(), fileConfigPath, pipelineNames, sampleSize, explain)
+			}
+			if resumeRunID != "" {
+				return ResumeETLPipelinesContext(cmd.Context(), resumeRunID, fileConfigPath, pipelineNames, fileOutputPath, outputFormat, needCheck, checkMethod)
+			}
+			return ExecuteETLPipelinesContext(cmd.Context(), fileConfigPath, pipelineNames, fileOutputPath, outputFormat, needCheck, checkMethod)
 		},
 	}
 
@@ -160,6 +174,11 @@ func SyncCmd() *cobra.Command {
 	sCmd.Flags().StringVarP(&outputFormat, "format", "F", "json", "Formato de saída dos dados")
 	sCmd.Flags().BoolVarP(&needCheck, "check", "c", false, "Indica se é necessário realizar a verificação dos dados")
 	sCmd.Flags().StringVarP(&checkMethod, "method", "m", "", "Método de verificação dos dados")
+	sCmd.Flags().StringSliceVarP(&pipelineNames, "pipeline", "p", []string{}, "Nome da pipeline a executar (pode ser repetido); sem ele, executa todas")
+	sCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Mostra a configuração, a consulta, o DDL, os tipos e uma amostra sem gravar no destino")
+	sCmd.Flags().BoolVar(&explain, "explain", false, "Executa o EXPLAIN da consulta de extração na origem (implica --dry-run)")
+	sCmd.Flags().IntVar(&sampleSize, "sample", 5, "Número de linhas transformadas exibidas no --dry-run")
+	sCmd.Flags().StringVar(&resumeRunID, "resume", "", "Retoma a execução informada a partir do último lote confirmado (pipelines com checkpoint)")
 
 	_ = sCmd.MarkFlagRequired("file")
 
@@ -169,66 +188,213 @@ func SyncCmd() *cobra.Command {
 // produceCmd cria um comando Cobra para produzir mensagens no Kafka.
 // Retorna um ponteiro para o comando Cobra configurado.
 func ProduceCmd() *cobra.Command {
-	var kafkaURL, topic, message string
+	var kafkaURL, topic, message, key, inputPath, keySeparator string
+	var headerValues []string
+	var client kafkaClientFlags
 
 	cmd := &cobra.Command{
 		Use:   "produce",
 		Short: "Produz mensagens no Kafka",
+		Long:  "Este comando produz no tópico a mensagem de --message, com a chave --key, ou as mensagens do arquivo --input, uma por linha (- lê da entrada padrão). Com --key-separator, cada linha do arquivo traz a chave antes do separador e a mensagem depois dele. Os cabeçalhos --header (nome=valor) são acrescentados a todas as mensagens. --kafka-url aceita uma lista de brokers separados por vírgulas, e --tls*, --sasl-* e --client-id configuram a conexão.",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			writer := &kafka.Writer{
-				Addr:  kafka.TCP(kafkaURL),
-				Topic: topic,
+			if (message == "") == (inputPath == "") {
+				return fmt.Errorf("informe --message ou --input")
+			}
+			headers := make([]kafka.Header, 0, len(headerValues))
+			for _, header := range headerValues {
+				name, value, found := strings.Cut(header, "=")
+				if !found || name == "" {
+					return fmt.Errorf("cabeçalho inválido: %q (use nome=valor)", header)
+				}
+				headers = append(headers, kafka.Header{Key: name, Value: []byte(value)})
+			}
+
+			var messages []kafka.Message
+			if inputPath == "" {
+				messages = append(messages, kafka.Message{Value: []byte(message)})
+				if key != "" {
+					messages[0].Key = []byte(key)
+				}
+			} else {
+				var readErr error
+				if messages, readErr = readMessagesFile(inputPath, keySeparator); readErr != nil {
+					return readErr
+				}
 			}
+			for i := range messages {
+				messages[i].Headers = headers
+			}
+
+			config := Config{KafkaURL: kafkaURL}
+			if applyErr := client.apply(cmd, &config.Kafka); applyErr != nil {
+				return applyErr
+			}
+			writer, writerErr := etlkafka.NewKafkaWriter(config, topic)
+			if writerErr != nil {
+				return writerErr
+			}
+			writer.Balancer = &kafka.Hash{}
 			defer func(writer *kafka.Writer) {
 				_ = writer.Close()
 			}(writer)
 
-			err := writer.WriteMessages(context.Background(), kafka.Message{
-				Value: []byte(message),
-			})
-			if err != nil {
-				return fmt.Errorf("falha ao produzir mensagem: %w", err)
+			// Cada escrita envia um lote; o Writer aguarda a confirmação de cada uma.
+			const chunkSize = 100
+			for start := 0; start < len(messages); start += chunkSize {
+				chunk := messages[start:min(start+chunkSize, len(messages))]
+				if err := writer.WriteMessages(cmd.Context(), chunk...); err != nil {
+					return fmt.Errorf("falha ao produzir mensagens após %d de %d: %w", start, len(messages), err)
+				}
 			}
 
-			logz.Info("Mensagem produzida com sucesso", map[string]interface{}{})
+			logz.Info(fmt.Sprintf("%d mensagem(ns) produzida(s) com sucesso", len(messages)), map[string]interface{}{})
 			return nil
 		},
 	}
 
-	cmd.Flags().StringVarP(&kafkaURL, "kafka-url", "k", "localhost:9092", "URL do Kafka")
+	cmd.Flags().StringVarP(&kafkaURL, "kafka-url", "k", "localhost:9092", "Brokers do Kafka, separados por vírgulas")
 	cmd.Flags().StringVarP(&topic, "topic", "t", "", "Tópico do Kafka")
 	cmd.Flags().StringVarP(&message, "message", "m", "", "Mensagem a ser produzida")
+	cmd.Flags().StringVar(&key, "key", "", "Chave da mensagem de --message")
+	cmd.Flags().StringArrayVarP(&headerValues, "header", "H", []string{}, "Cabeçalho nome=valor das mensagens (pode ser repetido)")
+	cmd.Flags().StringVarP(&inputPath, "input", "i", "", "Arquivo com uma mensagem por linha (- para a entrada padrão)")
+	cmd.Flags().StringVar(&keySeparator, "key-separator", "", "Com --input, separa a chave da mensagem em cada linha")
+	client.register(cmd, false)
 	_ = cmd.MarkFlagRequired("topic")
-	_ = cmd.MarkFlagRequired("message")
 
 	return cmd
 }
 
+// readMessagesFile lê as mensagens de um arquivo, uma por linha, ignorando as linhas vazias. Com
+// keySeparator, a chave de cada mensagem é o texto antes da primeira ocorrência dele.
+func readMessagesFile(path, keySeparator string) ([]kafka.Message, error) {
+	input := os.Stdin
+	if path != "-" {
+		file, openErr := os.Open(path)
+		if openErr != nil {
+			return nil, fmt.Errorf("falha ao abrir o arquivo de mensagens: %w", openErr)
+		}
+		defer func(file *os.File) {
+			_ = file.Close()
+		}(file)
+		input = file
+	}
+
+	var messages []kafka.Message
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		if keySeparator == "" {
+			messages = append(messages, kafka.Message{Value: []byte(text)})
+			continue
+		}
+		messageKey, value, found := strings.Cut(text, keySeparator)
+		if !found {
+			return nil, fmt.Errorf("linha %d do arquivo de mensagens sem o separador da chave %q", line, keySeparator)
+		}
+		messages = append(messages, kafka.Message{Key: []byte(messageKey), Value: []byte(value)})
+	}
+	if scanErr := scanner.Err(); scanErr != nil {
+		return nil, fmt.Errorf("falha ao ler o arquivo de mensagens: %w", scanErr)
+	}
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("nenhuma mensagem em %s", path)
+	}
+	return messages, nil
+}
+
 // consumeCmd cria um comando Cobra para consumir mensagens do Kafka.
 // Retorna um ponteiro para o comando Cobra configurado.
 func ConsumeCmd() *cobra.Command {
-	var kafkaURL, topic, groupID string
+	var kafkaURL, topic, groupID, fileConfigPath, from string
+	var pipelineNames []string
+	var client kafkaClientFlags
 
 	cmd := &cobra.Command{
 		Use:   "consume",
 		Short: "Consome mensagens do Kafka",
+		Long:  "Este comando consome mensagens do Kafka. Com --file, grava as mensagens do kafkaTopic de cada pipeline no seu destino, em lotes de kafka.consumer.batchSize transformados e gravados em uma transação, e só confirma os offsets após o commit; --kafka-url, --topic e --group-id substituem os da pipeline. Com kafka.consumer.deadLetterTopic, as mensagens que não podem ser decodificadas ou gravadas são tentadas de novo pelo tópico de retentativa e depois publicadas no dead-letter, sem interromper o consumo. Sem --file, apenas registra as mensagens de --topic. --kafka-url aceita uma lista de brokers separados por vírgulas; --tls*, --sasl-*, --client-id e as opções de leitura (--start-offset, --min-bytes, --max-bytes, --commit-interval e --isolation-level) substituem as de kafka da pipeline. Com --from, os offsets do grupo são redefinidos antes do consumo para o início (earliest) ou o fim (latest) de cada partição, para a primeira mensagem a partir de um instante RFC 3339 ou para os offsets partição:offset informados; o grupo não pode ter outros consumidores ativos.",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			reader := kafka.NewReader(kafka.ReaderConfig{
-				Brokers: []string{kafkaURL},
-				Topic:   topic,
-				GroupID: groupID,
-			})
+			var position *StartPosition
+			if cmd.Flags().Changed("from") {
+				parsed, parseErr := ParseStartPosition(from)
+				if parseErr != nil {
+					return fmt.Errorf("--from: %w", parseErr)
+				}
+				position = &parsed
+			}
+			if fileConfigPath != "" {
+				pipelines, loadConfigErr := LoadPipelinesFile(fileConfigPath)
+				if loadConfigErr != nil {
+					return loadConfigErr
+				}
+				selected, selectErr := SelectPipelines(pipelines, pipelineNames)
+				if selectErr != nil {
+					return selectErr
+				}
+				for i := range selected {
+					if cmd.Flags().Changed("kafka-url") {
+						selected[i].KafkaURL = kafkaURL
+						selected[i].Kafka.Brokers = nil
+					}
+					if cmd.Flags().Changed("topic") {
+						selected[i].KafkaTopic = topic
+					}
+					if cmd.Flags().Changed("group-id") {
+						selected[i].KafkaGroupID = groupID
+					}
+					if applyErr := client.apply(cmd, &selected[i].Kafka); applyErr != nil {
+						return applyErr
+					}
+					if position != nil {
+						if resetErr := resetConsumerOffsets(cmd, selected[i].Config, *position); resetErr != nil {
+							return fmt.Errorf("pipeline %s: %w", selected[i].Name, resetErr)
+						}
+					}
+				}
+				return etlkafka.ConsumePipelines(cmd.Context(), selected)
+			}
+			if topic == "" || groupID == "" {
+				return fmt.Errorf("informe --file ou --topic e --group-id")
+			}
+
+			config := Config{KafkaURL: kafkaURL, KafkaTopic: topic, KafkaGroupID: groupID}
+			if applyErr := client.apply(cmd, &config.Kafka); applyErr != nil {
+				return applyErr
+			}
+			if position != nil {
+				if resetErr := resetConsumerOffsets(cmd, config, *position); resetErr != nil {
+					return resetErr
+				}
+			}
+			reader, readerErr := etlkafka.NewKafkaReader(config, topic, groupID)
+			if readerErr != nil {
+				return readerErr
+			}
 			defer reader.Close()
 
+			consumed, skipped := 0, 0
 			for {
-				msg, err := reader.ReadMessage(context.Background())
+				msg, err := reader.ReadMessage(cmd.Context())
 				if err != nil {
+					if cmd.Context().Err() != nil {
+						logz.Info(fmt.Sprintf("Consumo encerrado após %d mensagem(ns), %d inválida(s)", consumed, skipped), map[string]interface{}{})
+						return nil
+					}
 					return fmt.Errorf("falha ao consumir mensagem: %w", err)
 				}
+				consumed++
 
 				var data map[string]interface{}
 				if err := json.Unmarshal(msg.Value, &data); err != nil {
-					return fmt.Errorf("falha ao deserializar mensagem: %w", err)
+					// Uma mensagem inválida não encerra o consumo.
+					skipped++
+					logz.Error(fmt.Sprintf("mensagem %d da partição %d não é um objeto JSON: %v", msg.Offset, msg.Partition, err), map[string]interface{}{})
+					continue
 				}
 
 				logz.Info(fmt.Sprintf("Mensagem consumida: %v", data), map[string]interface{}{})
@@ -236,11 +402,330 @@ func ConsumeCmd() *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVarP(&kafkaURL, "kafka-url", "k", "localhost:9092", "URL do Kafka")
+	cmd.Flags().StringVarP(&kafkaURL, "kafka-url", "k", "localhost:9092", "Brokers do Kafka, separados por vírgulas")
 	cmd.Flags().StringVarP(&topic, "topic", "t", "", "Tópico do Kafka")
 	cmd.Flags().StringVarP(&groupID, "group-id", "g", "", "ID do grupo do Kafka")
-	_ = cmd.MarkFlagRequired("topic")
-	_ = cmd.MarkFlagRequired("group-id")
+	cmd.Flags().StringVarP(&fileConfigPath, "file", "f", "", "Arquivo de configuração: grava as mensagens no destino de cada pipeline")
+	cmd.Flags().StringSliceVarP(&pipelineNames, "pipeline", "p", []string{}, "Com --file, nome da pipeline (pode ser repetido); sem ele, consome todas")
+	cmd.Flags().StringVar(&from, "from", "", "Redefine os offsets do grupo antes do consumo: earliest, latest, um instante RFC 3339 ou partição:offset,...")
+	client.register(cmd, true)
+
+	return cmd
+}
+
+// resetConsumerOffsets redefine os offsets do kafkaGroupID no kafkaTopic de config para position e
+// registra as novas posições.
+func resetConsumerOffsets(cmd *cobra.Command, config Config, position StartPosition) error {
+	if config.KafkaTopic == "" || config.KafkaGroupID == "" {
+		return fmt.Errorf("--from exige kafkaTopic e kafkaGroupID")
+	}
+	offsets, resetErr := etlkafka.ResetPipelineOffsets(cmd.Context(), config, position)
+	if resetErr != nil {
+		return resetErr
+	}
+	for _, offset := range offsets {
+		logz.Info(fmt.Sprintf("grupo %s: partição %d de %s a partir do offset %d (%s)", config.KafkaGroupID, offset.Partition, offset.Topic, offset.Committed, position), map[string]interface{}{})
+	}
+	return nil
+}
+
+// StreamCmd cria o comando Cobra que transforma as mensagens de um tópico do Kafka e as publica em
+// outro, em transações. Retorna um ponteiro para o comando Cobra configurado.
+func StreamCmd() *cobra.Command {
+	var kafkaURL, topic, groupID, outputTopic, fileConfigPath string
+	var pipelineNames []string
+	var client kafkaClientFlags
+
+	cmd := &cobra.Command{
+		Use:   "stream",
+		Short: "Transforma as mensagens de um tópico do Kafka e as publica em outro",
+		Long:  "Este comando consome o kafkaTopic de cada pipeline de --file, aplica as transformations às linhas JSON e as publica em kafka.stream.outputTopic. Cada lote de kafka.consumer.batchSize mensagens é publicado em uma transação do Kafka que também confirma os offsets do kafkaGroupID, de modo que uma falha não perde nem duplica linhas para os consumidores com isolationLevel read_committed. Com kafka.stream.dedup.key, as linhas iguais à última publicada com a mesma chave são descartadas, com o estado em um arquivo SQLite local gravado após cada transação, fora dela. Execute uma instância por kafkaGroupID. --kafka-url, --topic, --group-id e --output-topic substituem os da pipeline; --kafka-url aceita uma lista de brokers separados por vírgulas, e --tls*, --sasl-*, --client-id e as opções de leitura substituem as de kafka da pipeline.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			pipelines, loadConfigErr := LoadPipelinesFile(fileConfigPath)
+			if loadConfigErr != nil {
+				return loadConfigErr
+			}
+			selected, selectErr := SelectPipelines(pipelines, pipelineNames)
+			if selectErr != nil {
+				return selectErr
+			}
+			for i := range selected {
+				if cmd.Flags().Changed("kafka-url") {
+					selected[i].KafkaURL = kafkaURL
+					selected[i].Kafka.Brokers = nil
+				}
+				if cmd.Flags().Changed("topic") {
+					selected[i].KafkaTopic = topic
+				}
+				if cmd.Flags().Changed("group-id") {
+					selected[i].KafkaGroupID = groupID
+				}
+				if cmd.Flags().Changed("output-topic") {
+					selected[i].Kafka.Stream.OutputTopic = outputTopic
+				}
+				if applyErr := client.apply(cmd, &selected[i].Kafka); applyErr != nil {
+					return applyErr
+				}
+			}
+			return etlkafka.StreamPipelines(cmd.Context(), selected)
+		},
+	}
+
+	cmd.Flags().StringVarP(&kafkaURL, "kafka-url", "k", "localhost:9092", "Brokers do Kafka, separados por vírgulas")
+	cmd.Flags().StringVarP(&topic, "topic", "t", "", "Tópico de entrada")
+	cmd.Flags().StringVarP(&groupID, "group-id", "g", "", "ID do grupo do Kafka")
+	cmd.Flags().StringVarP(&outputTopic, "output-topic", "o", "", "Tópico de saída")
+	cmd.Flags().StringVarP(&fileConfigPath, "file", "f", "", "Arquivo de configuração")
+	cmd.Flags().StringSliceVarP(&pipelineNames, "pipeline", "p", []string{}, "Nome da pipeline (pode ser repetido); sem ele, executa todas")
+	client.register(cmd, true)
+	_ = cmd.MarkFlagRequired("file")
+
+	return cmd
+}
+
+// KafkaCmd cria o comando Cobra que agrupa as ferramentas administrativas do Kafka.
+// Retorna um ponteiro para o comando Cobra configurado.
+func KafkaCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "kafka",
+		Short: "Ferramentas administrativas do Kafka",
+	}
+	cmd.AddCommand(kafkaOffsetsCmd())
+	return cmd
+}
+
+// kafkaOffsetsCmd cria o comando Cobra que mostra e redefine os offsets dos grupos de consumo.
+func kafkaOffsetsCmd() *cobra.Command {
+	var kafkaURL, topic, groupID, fileConfigPath, resetTo string
+	var pipelineNames []string
+	var dryRun bool
+	var client kafkaClientFlags
+
+	cmd := &cobra.Command{
+		Use:   "offsets",
+		Short: "Mostra e redefine os offsets dos grupos de consumo",
+		Long:  "Este comando mostra, para cada partição, o offset confirmado pelo grupo, o fim da partição e o atraso (as mensagens ainda não lidas). Com --file, usa o kafkaGroupID de cada pipeline, no kafkaTopic e nos tópicos de kafka.consumer.routes; sem ele, --topic e --group-id. Com --reset-to, redefine os offsets para o início (earliest) ou o fim (latest) de cada partição, para a primeira mensagem a partir de um instante RFC 3339 ou para os offsets partição:offset informados, e mostra as novas posições; o grupo não pode ter consumidores ativos. --dry-run mostra as posições sem redefini-las.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var position *StartPosition
+			if resetTo != "" {
+				parsed, parseErr := ParseStartPosition(resetTo)
+				if parseErr != nil {
+					return fmt.Errorf("--reset-to: %w", parseErr)
+				}
+				position = &parsed
+			} else if dryRun {
+				return fmt.Errorf("--dry-run exige --reset-to")
+			}
+
+			var configs []Config
+			if fileConfigPath != "" {
+				pipelines, loadConfigErr := LoadPipelinesFile(fileConfigPath)
+				if loadConfigErr != nil {
+					return loadConfigErr
+				}
+				selected, selectErr := SelectPipelines(pipelines, pipelineNames)
+				if selectErr != nil {
+					return selectErr
+				}
+				for _, pipeline := range selected {
+					if pipeline.KafkaTopic != "" && pipeline.KafkaGroupID != "" {
+						configs = append(configs, pipeline.Config)
+					}
+				}
+				if len(configs) == 0 {
+					return fmt.Errorf("nenhuma pipeline selecionada tem kafkaTopic e kafkaGroupID")
+				}
+			} else {
+				if topic == "" || groupID == "" {
+					return fmt.Errorf("informe --file ou --topic e --group-id")
+				}
+				configs = append(configs, Config{KafkaURL: kafkaURL, KafkaTopic: topic, KafkaGroupID: groupID})
+			}
+
+			for _, config := range configs {
+				if cmd.Flags().Changed("kafka-url") {
+					config.KafkaURL = kafkaURL
+					config.Kafka.Brokers = nil
+				}
+				if applyErr := client.apply(cmd, &config.Kafka); applyErr != nil {
+					return applyErr
+				}
+				kafkaClient, clientErr := etlkafka.NewKafkaClient(config)
+				if clientErr != nil {
+					return clientErr
+				}
+				var offsets []etlkafka.PartitionOffset
+				for _, topic := range ConsumerTopics(config) {
+					var topicOffsets []etlkafka.PartitionOffset
+					var offsetsErr error
+					if position == nil {
+						topicOffsets, offsetsErr = etlkafka.GroupOffsets(cmd.Context(), kafkaClient, config.KafkaGroupID, topic)
+					} else {
+						topicOffsets, offsetsErr = etlkafka.ResetGroupOffsets(cmd.Context(), kafkaClient, config.KafkaGroupID, topic, *position, dryRun)
+					}
+					if offsetsErr != nil {
+						return fmt.Errorf("grupo %s em %s: %w", config.KafkaGroupID, topic, offsetsErr)
+					}
+					offsets = append(offsets, topicOffsets...)
+				}
+				if printErr := etlkafka.PrintPartitionOffsets(cmd.OutOrStdout(), config.KafkaGroupID, offsets); printErr != nil {
+					return printErr
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&kafkaURL, "kafka-url", "k", "localhost:9092", "Brokers do Kafka, separados por vírgulas")
+	cmd.Flags().StringVarP(&topic, "topic", "t", "", "Tópico do Kafka")
+	cmd.Flags().StringVarP(&groupID, "group-id", "g", "", "ID do grupo do Kafka")
+	cmd.Flags().StringVarP(&fileConfigPath, "file", "f", "", "Arquivo de configuração: usa o kafkaTopic e o kafkaGroupID de cada pipeline")
+	cmd.Flags().StringSliceVarP(&pipelineNames, "pipeline", "p", []string{}, "Com --file, nome da pipeline (pode ser repetido); sem ele, todas com kafkaTopic e kafkaGroupID")
+	cmd.Flags().StringVar(&resetTo, "reset-to", "", "Redefine os offsets: earliest, latest, um instante RFC 3339 ou partição:offset,...")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Com --reset-to, mostra as novas posições sem redefini-las")
+	client.register(cmd, false)
+
+	return cmd
+}
+
+// kafkaClientFlags são as opções de conexão com o Kafka, e de leitura no consume, dos comandos
+// produce e consume. Com --file, as informadas substituem as de kafka de cada pipeline.
+type kafkaClientFlags struct {
+	clientID, caFile, certFile, keyFile, serverName string
+	tls, insecure                                   bool
+	saslMechanism, saslUsername, saslPassword       string
+	startOffset, commitInterval, isolationLevel     string
+	minBytes, maxBytes                              int
+}
+
+// register declara as flags no comando; reader acrescenta as de kafka.reader.
+func (f *kafkaClientFlags) register(cmd *cobra.Command, reader bool) {
+	cmd.Flags().StringVar(&f.clientID, "client-id", "", "Client ID das conexões com o Kafka")
+	cmd.Flags().BoolVar(&f.tls, "tls", false, "Conecta aos brokers com TLS")
+	cmd.Flags().StringVar(&f.caFile, "tls-ca", "", "Certificado PEM da autoridade dos brokers (implica --tls)")
+	cmd.Flags().StringVar(&f.certFile, "tls-cert", "", "Certificado PEM do cliente (implica --tls)")
+	cmd.Flags().StringVar(&f.keyFile, "tls-key", "", "Chave PEM do certificado do cliente")
+	cmd.Flags().StringVar(&f.serverName, "tls-server-name", "", "Nome verificado no certificado dos brokers")
+	cmd.Flags().BoolVar(&f.insecure, "tls-insecure", false, "Aceita qualquer certificado dos brokers (só para testes)")
+	cmd.Flags().StringVar(&f.saslMechanism, "sasl-mechanism", "", "Mecanismo SASL: "+strings.Join(SupportedSASLMechanisms, ", "))
+	cmd.Flags().StringVar(&f.saslUsername, "sasl-username", "", "Usuário SASL")
+	cmd.Flags().StringVar(&f.saslPassword, "sasl-password", "", "Senha SASL; aceita ${VAR} e ${file:/caminho}")
+	if reader {
+		cmd.Flags().StringVar(&f.startOffset, "start-offset", "", "Início de um grupo sem offsets confirmados: "+strings.Join(SupportedStartOffsets, ", "))
+		cmd.Flags().IntVar(&f.minBytes, "min-bytes", 0, "Mínimo de bytes de cada busca nos brokers")
+		cmd.Flags().IntVar(&f.maxBytes, "max-bytes", 0, "Máximo de bytes de cada busca nos brokers")
+		cmd.Flags().StringVar(&f.commitInterval, "commit-interval", "", "Confirma os offsets periodicamente (e.g. 1s) em vez de a cada lote")
+		cmd.Flags().StringVar(&f.isolationLevel, "isolation-level", "", "Nível de isolamento: "+strings.Join(SupportedIsolationLevels, ", "))
+	}
+}
+
+// apply copia para options as flags informadas na linha de comando.
+func (f *kafkaClientFlags) apply(cmd *cobra.Command, options *KafkaOptions) error {
+	changed := cmd.Flags().Changed
+	if changed("client-id") {
+		options.ClientID = f.clientID
+	}
+	if changed("tls") {
+		options.TLS.Enabled = f.tls
+	}
+	if changed("tls-ca") {
+		options.TLS.CAFile = f.caFile
+	}
+	if changed("tls-cert") {
+		options.TLS.CertFile = f.certFile
+	}
+	if changed("tls-key") {
+		options.TLS.KeyFile = f.keyFile
+	}
+	if changed("tls-server-name") {
+		options.TLS.ServerName = f.serverName
+	}
+	if changed("tls-insecure") {
+		options.TLS.InsecureSkipVerify = f.insecure
+	}
+	if changed("sasl-mechanism") {
+		options.SASL.Mechanism = f.saslMechanism
+	}
+	if changed("sasl-username") {
+		options.SASL.Username = f.saslUsername
+	}
+	if changed("sasl-password") {
+		password, interpolateErr := InterpolateConfigString(f.saslPassword)
+		if interpolateErr != nil {
+			return fmt.Errorf("--sasl-password: %w", interpolateErr)
+		}
+		options.SASL.Password = password
+	}
+	if changed("start-offset") {
+		options.Reader.StartOffset = f.startOffset
+	}
+	if changed("min-bytes") {
+		options.Reader.MinBytes = f.minBytes
+	}
+	if changed("max-bytes") {
+		options.Reader.MaxBytes = f.maxBytes
+	}
+	if changed("commit-interval") {
+		options.Reader.CommitInterval = f.commitInterval
+	}
+	if changed("isolation-level") {
+		options.Reader.IsolationLevel = f.isolationLevel
+	}
+	return nil
+}
+
+// DLQCmd cria um comando Cobra para reinjetar as mensagens do dead-letter do consumo.
+// Retorna um ponteiro para o comando Cobra configurado.
+func DLQCmd() *cobra.Command {
+	var fileConfigPath, topic string
+	var pipelineNames []string
+	var maxMessages int
+	var idle time.Duration
+
+	cmd := &cobra.Command{
+		Use:       "dlq <replay>",
+		Short:     "Reinjeta as mensagens do dead-letter do consumo",
+		Long:      "Este comando gerencia o dead-letter (kafka.consumer.deadLetterTopic) das pipelines. replay publica de novo cada mensagem no seu tópico de origem (cabeçalho getl-original-topic), ou em --to, com a chave e o valor originais e sem os cabeçalhos de erro, e confirma o offset no grupo <kafkaGroupID>-replay: as mensagens já reinjetadas não são lidas de novo. Encerra após --idle sem novas mensagens ou após --max mensagens.",
+		Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		ValidArgs: []string{"replay"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if validateArgsErr := ValidateArgs(fileConfigPath); validateArgsErr != nil {
+				logz.Error(fmt.Sprintf("falha ao validar argumentos: %v", validateArgsErr), map[string]interface{}{})
+				return validateArgsErr
+			}
+			pipelines, loadConfigErr := LoadPipelinesFile(fileConfigPath)
+			if loadConfigErr != nil {
+				return loadConfigErr
+			}
+			selected, selectErr := SelectPipelines(pipelines, pipelineNames)
+			if selectErr != nil {
+				return selectErr
+			}
+			replayed := 0
+			for _, pipeline := range selected {
+				if pipeline.Kafka.Consumer.DeadLetterTopic == "" {
+					continue
+				}
+				count, replayErr := etlkafka.ReplayDeadLetters(cmd.Context(), pipeline.Config, etlkafka.ReplayOptions{Topic: topic, Max: maxMessages, Idle: idle})
+				if replayErr != nil {
+					return fmt.Errorf("pipeline %s: %w", pipeline.Name, replayErr)
+				}
+				_, _ = fmt.Fprintf(cmd.OutOrStdout(), "%s: %d mensagem(ns) reinjetada(s) de %s\n", pipeline.Name, count, pipeline.Kafka.Consumer.DeadLetterTopic)
+				replayed++
+			}
+			if replayed == 0 {
+				return fmt.Errorf("nenhuma pipeline selecionada tem kafka.consumer.deadLetterTopic")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&fileConfigPath, "file", "f", "", "Arquivo de configuração")
+	cmd.Flags().StringSliceVarP(&pipelineNames, "pipeline", "p", []string{}, "Nome da pipeline (pode ser repetido); sem ele, todas com dead-letter")
+	cmd.Flags().StringVar(&topic, "to", "", "Tópico de destino das mensagens (padrão: o tópico de origem de cada uma)")
+	cmd.Flags().IntVar(&maxMessages, "max", 0, "Máximo de mensagens reinjetadas por pipeline (0: todas)")
+	cmd.Flags().DurationVar(&idle, "idle", 5*time.Second, "Encerra após esse tempo sem novas mensagens no dead-letter (0: sem limite)")
+	_ = cmd.MarkFlagRequired("file")
 
 	return cmd
 }
@@ -273,6 +758,304 @@ func DataTableCmd() *cobra.Command {
 	return cmd
 }
 
+// ValidateCmd cria um comando Cobra para validar um arquivo de configuração contra o schema da Config.
+// Retorna um ponteiro para o comando Cobra configurado.
+func ValidateCmd() *cobra.Command {
+	var fileConfigPath string
+	var printSchema string
+
+	cmd := &cobra.Command{
+		Use:     "validate",
+		Aliases: []string{"lint", "check-config"},
+		Short:   "Valida um arquivo de configuração",
+		Long:    "Este comando valida o arquivo de configuração contra o JSON Schema da Config, reportando campos obrigatórios ausentes, chaves desconhecidas, operações e joins inválidos e syncInterval mal formatado, com linha e coluna.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if printSchema != "" {
+				var schema []byte
+				var schemaErr error
+				switch printSchema {
+				case "config":
+					schema, schemaErr = MarshalConfigSchema()
+				case "pipelines":
+					schema, schemaErr = MarshalConfigFileSchema()
+				default:
+					return fmt.Errorf("schema desconhecido: %s (use config ou pipelines)", printSchema)
+				}
+				if schemaErr != nil {
+					return schemaErr
+				}
+				_, _ = cmd.OutOrStdout().Write(schema)
+				return nil
+			}
+
+			if validateArgsErr := ValidateArgs(fileConfigPath); validateArgsErr != nil {
+				logz.Error(fmt.Sprintf("falha ao validar argumentos: %v", validateArgsErr), map[string]interface{}{})
+				return validateArgsErr
+			}
+
+			fileData, fileDataErr := os.ReadFile(fileConfigPath)
+			if fileDataErr != nil {
+				return fmt.Errorf("falha ao ler o arquivo de configuração: %w", fileDataErr)
+			}
+
+			validationErrs := ValidateConfigData(fileData, ConfigFormatFromPath(fileConfigPath))
+			for _, validationErr := range validationErrs {
+				_, _ = fmt.Fprintf(cmd.OutOrStdout(), "%s: %s\n", fileConfigPath, validationErr.Error())
+			}
+			if len(validationErrs) > 0 {
+				return fmt.Errorf("%d erro(s) de validação em %s", len(validationErrs), fileConfigPath)
+			}
+
+			logz.Info("Configuração válida: "+fileConfigPath, map[string]interface{}{})
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&fileConfigPath, "file", "f", "", "Caminho para o arquivo de configuração")
+	cmd.Flags().StringVar(&printSchema, "print-schema", "", "Imprime o JSON Schema (config ou pipelines) e sai")
+	cmd.Flags().Lookup("print-schema").NoOptDefVal = "config"
+
+	return cmd
+}
+
+// PingCmd cria um comando Cobra para testar as conexões configuradas.
+// Retorna um ponteiro para o comando Cobra configurado.
+func PingCmd() *cobra.Command {
+	var fileConfigPath string
+	var pipelineNames []string
+
+	cmd := &cobra.Command{
+		Use:   "ping",
+		Short: "Testa as conexões de origem, destino e Kafka configuradas",
+		Long:  "Este comando abre cada conexão do arquivo de configuração (uma vez por driver e DSN), com as novas tentativas de pool.retries, e mostra a latência ou o erro de cada uma.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if validateArgsErr := ValidateArgs(fileConfigPath); validateArgsErr != nil {
+				logz.Error(fmt.Sprintf("falha ao validar argumentos: %v", validateArgsErr), map[string]interface{}{})
+				return validateArgsErr
+			}
+
+			pipelines, loadConfigErr := LoadPipelinesFile(fileConfigPath)
+			if loadConfigErr != nil {
+				return loadConfigErr
+			}
+			selected, selectErr := SelectPipelines(pipelines, pipelineNames)
+			if selectErr != nil {
+				return selectErr
+			}
+
+			results := PingConnections(cmd.Context(), selected)
+			results = append(results, pingKafkaBrokers(cmd.Context(), selected)...)
+			failed, printErr := PrintPingResults(cmd.OutOrStdout(), results)
+			if printErr != nil {
+				return printErr
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d de %d conexão(ões) com falha", failed, len(results))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&fileConfigPath, "file", "f", "", "Caminho para o arquivo de configuração")
+	cmd.Flags().StringSliceVarP(&pipelineNames, "pipeline", "p", []string{}, "Nome da pipeline a testar (pode ser repetido); sem ele, testa todas")
+	_ = cmd.MarkFlagRequired("file")
+
+	return cmd
+}
+
+// TriggersCmd cria um comando Cobra para gerenciar os triggers configurados no banco de destino.
+// Retorna um ponteiro para o comando Cobra configurado.
+func TriggersCmd() *cobra.Command {
+	var fileConfigPath string
+	var pipelineNames []string
+	var replace bool
+
+	cmd := &cobra.Command{
+		Use:       "triggers <apply|drop|list>",
+		Short:     "Aplica, remove ou lista os triggers configurados no banco de destino",
+		Long:      "Este comando gerencia os triggers de cada pipeline no banco de destino. apply cria os triggers ausentes (com --replace, recria os existentes), drop remove os existentes e list mostra se cada um está aplicado.",
+		Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		ValidArgs: []string{"apply", "drop", "list"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if validateArgsErr := ValidateArgs(fileConfigPath); validateArgsErr != nil {
+				logz.Error(fmt.Sprintf("falha ao validar argumentos: %v", validateArgsErr), map[string]interface{}{})
+				return validateArgsErr
+			}
+
+			pipelines, loadConfigErr := LoadPipelinesFile(fileConfigPath)
+			if loadConfigErr != nil {
+				return loadConfigErr
+			}
+			selected, selectErr := SelectPipelines(pipelines, pipelineNames)
+			if selectErr != nil {
+				return selectErr
+			}
+
+			var statuses []TriggerStatus
+			for _, pipeline := range selected {
+				if len(pipeline.Triggers) == 0 {
+					continue
+				}
+				db, dbErr := OpenDestination(cmd.Context(), pipeline.Config)
+				if dbErr != nil {
+					return fmt.Errorf("pipeline %s: %w", pipeline.Name, dbErr)
+				}
+
+				switch args[0] {
+				case "apply":
+					applied, applyErr := ApplyTriggers(cmd.Context(), db, pipeline.Config, replace)
+					if applyErr != nil {
+						return fmt.Errorf("pipeline %s: %w", pipeline.Name, applyErr)
+					}
+					_, _ = fmt.Fprintf(cmd.OutOrStdout(), "%s: %d trigger(s) aplicado(s), %d já existente(s)\n", pipeline.Name, applied, len(pipeline.Triggers)-applied)
+				case "drop":
+					dropped, dropErr := DropTriggers(cmd.Context(), db, pipeline.Config)
+					if dropErr != nil {
+						return fmt.Errorf("pipeline %s: %w", pipeline.Name, dropErr)
+					}
+					_, _ = fmt.Fprintf(cmd.OutOrStdout(), "%s: %d trigger(s) removido(s)\n", pipeline.Name, dropped)
+				case "list":
+					pipelineStatuses, statusErr := TriggerStatuses(cmd.Context(), db, pipeline.Name, pipeline.Config)
+					if statusErr != nil {
+						return fmt.Errorf("pipeline %s: %w", pipeline.Name, statusErr)
+					}
+					statuses = append(statuses, pipelineStatuses...)
+				}
+			}
+
+			if args[0] == "list" {
+				return PrintTriggerStatuses(cmd.OutOrStdout(), statuses)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&fileConfigPath, "file", "f", "", "Caminho para o arquivo de configuração")
+	cmd.Flags().StringSliceVarP(&pipelineNames, "pipeline", "p", []string{}, "Nome da pipeline (pode ser repetido); sem ele, considera todas")
+	cmd.Flags().BoolVar(&replace, "replace", false, "Em apply, recria os triggers que já existem para aplicar mudanças de evento ou statement")
+	_ = cmd.MarkFlagRequired("file")
+
+	return cmd
+}
+
+// CDCCmd cria um comando Cobra para a captura de alterações das tabelas de origem.
+// Retorna um ponteiro para o comando Cobra configurado.
+func CDCCmd() *cobra.Command {
+	var fileConfigPath string
+	var pipelineNames []string
+	var interval string
+	var once bool
+
+	cmd := &cobra.Command{
+		Use:       "cdc <install|uninstall|status|run>",
+		Short:     "Captura as alterações da origem e as aplica no destino ou publica no Kafka",
+		Long:      "Este comando gerencia a captura de alterações das pipelines com cdc configurado. No modo triggers, install cria o changelog e os triggers na tabela de origem, uninstall remove os triggers e run aplica no destino, a cada syncInterval, as inclusões, atualizações e exclusões registradas no changelog, removendo-as em seguida. Nos modos pgoutput e wal2json (replicação lógica do PostgreSQL), install cria a publicação e o slot, uninstall os remove e run recebe as alterações continuamente, registrando o LSN aplicado para retomar de onde parou. No modo binlog (MySQL e MariaDB), install verifica a configuração do binlog e registra a posição corrente, uninstall apaga a posição e run lê o binlog como réplica a partir dela, pelo arquivo e posição ou, com cdc.gtid, pelos GTIDs. status mostra o que está instalado e as alterações pendentes. Com cdc.sink kafka, as alterações são publicadas em kafkaTopic.",
+		Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		ValidArgs: []string{"install", "uninstall", "status", "run"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if validateArgsErr := ValidateArgs(fileConfigPath); validateArgsErr != nil {
+				logz.Error(fmt.Sprintf("falha ao validar argumentos: %v", validateArgsErr), map[string]interface{}{})
+				return validateArgsErr
+			}
+
+			pipelines, loadConfigErr := LoadPipelinesFile(fileConfigPath)
+			if loadConfigErr != nil {
+				return loadConfigErr
+			}
+			selected, selectErr := SelectPipelines(pipelines, pipelineNames)
+			if selectErr != nil {
+				return selectErr
+			}
+			var captured []Pipeline
+			for _, pipeline := range selected {
+				if pipeline.CDC.Enabled() {
+					captured = append(captured, pipeline)
+				}
+			}
+			if len(captured) == 0 {
+				return fmt.Errorf("nenhuma pipeline selecionada tem cdc configurado")
+			}
+
+			switch args[0] {
+			case "install":
+				for _, pipeline := range captured {
+					if installErr := InstallChangeCapture(cmd.Context(), pipeline.Config); installErr != nil {
+						return fmt.Errorf("pipeline %s: %w", pipeline.Name, installErr)
+					}
+					_, _ = fmt.Fprintf(cmd.OutOrStdout(), "%s: captura (%s) instalada em %s\n", pipeline.Name, pipeline.CDC.Mode, pipeline.SourceTable)
+				}
+			case "uninstall":
+				for _, pipeline := range captured {
+					if uninstallErr := UninstallChangeCapture(cmd.Context(), pipeline.Config); uninstallErr != nil {
+						return fmt.Errorf("pipeline %s: %w", pipeline.Name, uninstallErr)
+					}
+					_, _ = fmt.Fprintf(cmd.OutOrStdout(), "%s: captura (%s) removida de %s\n", pipeline.Name, pipeline.CDC.Mode, pipeline.SourceTable)
+				}
+			case "status":
+				statuses, statusErr := ChangeCaptureStatuses(cmd.Context(), captured)
+				if statusErr != nil {
+					return statusErr
+				}
+				return PrintChangeCaptureStatuses(cmd.OutOrStdout(), statuses)
+			case "run":
+				var every time.Duration
+				if interval != "" {
+					var intervalErr error
+					if every, intervalErr = ParseSyncInterval(interval); intervalErr != nil {
+						return intervalErr
+					}
+				}
+				return CaptureChanges(cmd.Context(), captured, every, once, etlkafka.NewChangeSink)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&fileConfigPath, "file", "f", "", "Caminho para o arquivo de configuração")
+	cmd.Flags().StringSliceVarP(&pipelineNames, "pipeline", "p", []string{}, "Nome da pipeline (pode ser repetido); sem ele, considera todas com cdc")
+	cmd.Flags().StringVar(&interval, "interval", "", "Em run, intervalo entre os ciclos (na replicação lógica, espera antes de reconectar); padrão: syncInterval da pipeline ou 10s")
+	cmd.Flags().BoolVar(&once, "once", false, "Em run, aplica as alterações pendentes uma vez e termina")
+	_ = cmd.MarkFlagRequired("file")
+
+	return cmd
+}
+
+// pingKafkaBrokers testa os brokers Kafka das pipelines, uma vez por URL, pedindo a lista de brokers do cluster.
+func pingKafkaBrokers(ctx context.Context, pipelines []Pipeline) []PingResult {
+	var results []PingResult
+	seen := map[string]bool{}
+	for _, pipeline := range pipelines {
+		brokers := KafkaBrokers(pipeline.Config)
+		target := strings.Join(brokers, ",")
+		if len(brokers) == 0 || seen[target] {
+			continue
+		}
+		seen[target] = true
+
+		result := PingResult{Name: pipeline.Name + ".kafka", Driver: "kafka", Target: target}
+		started := time.Now()
+		var conn *kafka.Conn
+		dialer, dialErr := etlkafka.NewKafkaDialer(pipeline.Config)
+		if dialErr == nil {
+			// Um broker basta para obter a lista dos demais; os seguintes são tentados se ele falhar.
+			for _, broker := range brokers {
+				if conn, dialErr = dialer.DialContext(ctx, "tcp", broker); dialErr == nil {
+					break
+				}
+			}
+		}
+		if dialErr == nil {
+			_, result.Err = conn.Brokers()
+			_ = conn.Close()
+		} else {
+			result.Err = dialErr
+		}
+		result.Latency = time.Since(started)
+		results = append(results, result)
+	}
+	return results
+}
+
 // validateArgs valida os argumentos passados para o comando.
 // fileConfigPath: caminho para o arquivo de configuração das transformações.
 // Retorna um erro se o caminho do arquivo de configuração estiver vazio.
@@ -1,6 +1,7 @@
 package main
 
 import (
+	. "github.com/faelmori/getl/sql"
 	"github.com/faelmori/getl/version"
 	"github.com/spf13/cobra"
 	"os"
@@ -54,16 +55,23 @@ func (m *GETl) Module() string {
 // Execute executa o comando especificado para o módulo.
 // commandArgs: um slice de strings contendo os argumentos do comando.
 // Retorna um erro, se houver.
+// SIGINT e SIGTERM cancelam o contexto dos comandos (veja newShutdownContext).
 func (m *GETl) Execute(args []string) error {
+	ctx, stop := newShutdownContext()
+	defer stop()
+	defer func() {
+		_ = CloseConnections()
+	}()
+
 	cmdEtl := m.Command()
 	if args != nil {
 		parseFlagsErr := cmdEtl.ParseFlags(args)
 		if parseFlagsErr != nil {
 			return parseFlagsErr
 		}
-		return cmdEtl.Execute()
+		return cmdEtl.ExecuteContext(ctx)
 	} else {
-		return cmdEtl.Execute()
+		return cmdEtl.ExecuteContext(ctx)
 	}
 }
 
@@ -90,11 +98,19 @@ func (m *GETl) Command() *cobra.Command {
 				"Sync manager for almost any database, any environment and any data source.",
 			}, true),
 	}
+	cmd.AddCommand(InitCmd())
+	cmd.AddCommand(ValidateCmd())
+	cmd.AddCommand(PingCmd())
+	cmd.AddCommand(TriggersCmd())
+	cmd.AddCommand(CDCCmd())
 	cmd.AddCommand(SyncCmd())
 	cmd.AddCommand(ExtractCmd())
 	cmd.AddCommand(LoadCmd())
 	cmd.AddCommand(ProduceCmd())
 	cmd.AddCommand(ConsumeCmd())
+	cmd.AddCommand(StreamCmd())
+	cmd.AddCommand(DLQCmd())
+	cmd.AddCommand(KafkaCmd())
 	cmd.AddCommand(DataTableCmd())
 	cmd.AddCommand(VacuumCmd())
 	cmd.AddCommand(version.CliCommand())
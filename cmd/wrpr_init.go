@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	. "github.com/faelmori/getl/etypes"
+	. "github.com/faelmori/getl/sql"
+	. "github.com/faelmori/getl/utils"
+	"github.com/faelmori/logz"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+	"strings"
+)
+
+// InitCmd cria um comando Cobra para gerar um arquivo de configuração.
+// Sem --non-interactive, abre um assistente que pergunta origem, destino e tabelas.
+// Retorna um ponteiro para o comando Cobra configurado.
+func InitCmd() *cobra.Command {
+	var outputPath, format string
+	var nonInteractive, introspect, force bool
+	config := Config{}
+
+	cmd := &cobra.Command{
+		Use:     "init",
+		Aliases: []string{"new", "scaffold"},
+		Short:   "Gera um arquivo de configuração de sincronização",
+		Long:    "Este comando gera um arquivo de configuração válido em JSON, YAML ou TOML, de forma interativa ou a partir das flags informadas.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !force {
+				if _, statErr := os.Stat(outputPath); statErr == nil {
+					return fmt.Errorf("o arquivo %s já existe, use --force para sobrescrever", outputPath)
+				}
+			}
+
+			if !nonInteractive {
+				wizardErr := runInitWizard(cmd.InOrStdin(), cmd.OutOrStdout(), &config, &introspect)
+				if wizardErr != nil {
+					return wizardErr
+				}
+			}
+
+			if config.DestinationTable == "" {
+				config.DestinationTable = config.SourceTable
+			}
+			if validateErr := validateInitConfig(config); validateErr != nil {
+				return validateErr
+			}
+
+			if introspect {
+				db, dbErr := OpenSource(cmd.Context(), config)
+				if dbErr != nil {
+					logz.Error(fmt.Sprintf("falha ao inspecionar a origem: %v", dbErr), map[string]interface{}{})
+					return dbErr
+				}
+				transformations, introspectErr := IntrospectTransformations(db, config.SourceTable)
+				if introspectErr != nil {
+					logz.Error(fmt.Sprintf("falha ao inspecionar a origem: %v", introspectErr), map[string]interface{}{})
+					return introspectErr
+				}
+				config.Transformations = transformations
+			}
+			if config.Transformations == nil {
+				config.Transformations = []Transformation{}
+			}
+			if config.Joins == nil {
+				config.Joins = []Join{}
+			}
+			if config.Triggers == nil {
+				config.Triggers = []Trigger{}
+			}
+
+			if writeErr := WriteConfigFile(outputPath, config, format); writeErr != nil {
+				return writeErr
+			}
+
+			logz.Info("Arquivo de configuração gerado em "+outputPath, map[string]interface{}{})
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputPath, "output", "o", "getl.json", "Caminho do arquivo de configuração gerado")
+	cmd.Flags().StringVarP(&format, "format", "F", "", "Formato do arquivo (json, yaml, toml). Padrão: deduzido pela extensão")
+	cmd.Flags().BoolVarP(&nonInteractive, "non-interactive", "y", false, "Não perguntar, usar apenas as flags informadas")
+	cmd.Flags().BoolVarP(&introspect, "introspect", "i", false, "Inspecionar a tabela de origem para preencher as transformações")
+	cmd.Flags().BoolVar(&force, "force", false, "Sobrescrever o arquivo se ele já existir")
+	cmd.Flags().StringVar(&config.SourceType, "source-type", "", "Driver do banco de origem ("+strings.Join(SupportedDrivers, ", ")+")")
+	cmd.Flags().StringVar(&config.SourceConnectionString, "source-conn", "", "String de conexão do banco de origem")
+	cmd.Flags().StringVar(&config.SourceTable, "source-table", "", "Tabela de origem")
+	cmd.Flags().StringVar(&config.DestinationType, "dest-type", "", "Driver do banco de destino ("+strings.Join(SupportedDrivers, ", ")+")")
+	cmd.Flags().StringVar(&config.DestinationConnectionString, "dest-conn", "", "String de conexão do banco de destino")
+	cmd.Flags().StringVar(&config.DestinationTable, "dest-table", "", "Tabela de destino. Padrão: a tabela de origem")
+	cmd.Flags().StringVar(&config.UpdateKey, "update-key", "", "Coluna usada como chave de atualização (upsert)")
+
+	return cmd
+}
+
+// runInitWizard pergunta, um a um, os campos da configuração, usando os valores já
+// informados por flag como padrão. Os campos obrigatórios são perguntados de novo enquanto a
+// resposta for vazia.
+func runInitWizard(in io.Reader, out io.Writer, config *Config, introspect *bool) error {
+	reader := bufio.NewReader(in)
+	template := NewConfigTemplate()
+
+	prompts := []struct {
+		label    string
+		target   *string
+		fallback string
+		driver   bool
+		required bool
+	}{
+		{"Driver do banco de origem", &config.SourceType, template.SourceType, true, true},
+		{"String de conexão da origem", &config.SourceConnectionString, template.SourceConnectionString, false, true},
+		{"Tabela de origem", &config.SourceTable, "", false, true},
+		{"Driver do banco de destino", &config.DestinationType, template.DestinationType, true, true},
+		{"String de conexão do destino", &config.DestinationConnectionString, template.DestinationConnectionString, false, true},
+		{"Tabela de destino", &config.DestinationTable, "", false, true},
+		{"Chave de atualização (opcional)", &config.UpdateKey, "", false, false},
+	}
+
+	for _, p := range prompts {
+		fallback := *p.target
+		if fallback == "" {
+			fallback = p.fallback
+		}
+		if p.target == &config.DestinationTable && fallback == "" {
+			fallback = config.SourceTable
+		}
+		for {
+			value, promptErr := promptValue(reader, out, p.label, fallback)
+			if promptErr != nil && !errors.Is(promptErr, io.EOF) {
+				return promptErr
+			}
+			if p.required && value == "" {
+				// No fim da entrada, perguntar de novo não traria a resposta.
+				if promptErr != nil {
+					return fmt.Errorf("campo obrigatório não informado: %s", p.label)
+				}
+				_, _ = fmt.Fprintln(out, "Resposta obrigatória, informe um valor")
+				continue
+			}
+			if p.driver && !IsSupportedDriver(value) {
+				_, _ = fmt.Fprintf(out, "Driver inválido, use um de: %s\n", strings.Join(SupportedDrivers, ", "))
+				fallback = p.fallback
+				continue
+			}
+			*p.target = value
+			break
+		}
+	}
+
+	defaultAnswer := "n"
+	if *introspect {
+		defaultAnswer = "s"
+	}
+	answer, promptErr := promptValue(reader, out, "Inspecionar a origem para preencher as transformações? (s/n)", defaultAnswer)
+	if promptErr != nil && !errors.Is(promptErr, io.EOF) {
+		return promptErr
+	}
+	*introspect = strings.HasPrefix(strings.ToLower(answer), "s") || strings.HasPrefix(strings.ToLower(answer), "y")
+
+	return nil
+}
+
+// promptValue escreve a pergunta em out e lê uma linha de reader.
+// Retorna fallback quando a resposta é vazia e, no fim da entrada sem resposta, também io.EOF.
+func promptValue(reader *bufio.Reader, out io.Writer, label, fallback string) (string, error) {
+	if fallback != "" {
+		_, _ = fmt.Fprintf(out, "%s [%s]: ", label, fallback)
+	} else {
+		_, _ = fmt.Fprintf(out, "%s: ", label)
+	}
+
+	line, readErr := reader.ReadString('\n')
+	if readErr != nil && !errors.Is(readErr, io.EOF) {
+		return "", fmt.Errorf("falha ao ler a resposta: %w", readErr)
+	}
+
+	line = strings.TrimSpace(line)
+	if line == "" {
+		if readErr != nil {
+			return fallback, io.EOF
+		}
+		return fallback, nil
+	}
+	return line, nil
+}
+
+// validateInitConfig garante que a configuração gerada tem os campos obrigatórios
+// e drivers suportados antes de gravar o arquivo.
+func validateInitConfig(config Config) error {
+	required := []struct{ flag, value string }{
+		{"source-type", config.SourceType},
+		{"source-conn", config.SourceConnectionString},
+		{"source-table", config.SourceTable},
+		{"dest-type", config.DestinationType},
+		{"dest-conn", config.DestinationConnectionString},
+	}
+	for _, field := range required {
+		if field.value == "" {
+			return fmt.Errorf("campo obrigatório não informado: --%s", field.flag)
+		}
+	}
+	if !IsSupportedDriver(config.SourceType) {
+		return fmt.Errorf("driver de origem não suportado: %s", config.SourceType)
+	}
+	if !IsSupportedDriver(config.DestinationType) {
+		return fmt.Errorf("driver de destino não suportado: %s", config.DestinationType)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/faelmori/logz"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// newShutdownContext retorna um contexto cancelado no primeiro SIGINT ou SIGTERM, para que o comando
+// em execução conclua ou reverta a etapa atual e informe o progresso. Um segundo sinal encerra o
+// processo imediatamente. A função retornada libera o tratamento dos sinais.
+func newShutdownContext() (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case sig := <-signals:
+			logz.Warn(fmt.Sprintf("sinal %s recebido, encerrando a etapa atual (envie novamente para forçar a saída)", sig), map[string]interface{}{})
+			cancel()
+		case <-ctx.Done():
+			return
+		}
+		if sig, ok := <-signals; ok {
+			logz.Error(fmt.Sprintf("sinal %s recebido novamente, saindo sem concluir", sig), map[string]interface{}{})
+			os.Exit(130)
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel()
+	}
+}
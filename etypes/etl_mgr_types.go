@@ -3,6 +3,8 @@ package etypes
 import (
 	"fmt"
 	"github.com/faelmori/logz"
+	"slices"
+	"time"
 )
 
 const batchSize = 1000
@@ -56,49 +58,519 @@ type Field map[string]string
 type Fields map[string][]Field
 type Data map[string]interface{}
 type Config struct {
-	SourceType                  string           `json:"sourceType"`
-	SourceConnectionString      string           `json:"sourceConnectionString"`
-	SourceTable                 string           `json:"sourceTable"`
-	DestinationType             string           `json:"destinationType"`
-	DestinationConnectionString string           `json:"destinationConnectionString"`
-	DestinationTable            string           `json:"destinationTable"`
-	SQLQuery                    string           `json:"sqlQuery"`
-	OutputPath                  string           `json:"outputPath"`
-	OutputFormat                string           `json:"outputFormat"`
-	Transformations             []Transformation `json:"transformations"`
-	NeedCheck                   bool             `json:"needCheck"`
-	CheckMethod                 string           `json:"checkMethod"`
-	Joins                       []Join           `json:"joins"`
-	Where                       string           `json:"where"`
-	OrderBy                     string           `json:"orderBy"`
-	Triggers                    []Trigger        `json:"triggers"`
-	LogTable                    string           `json:"logTable"`
-	SyncInterval                string           `json:"syncInterval"`
-	KafkaURL                    string           `json:"kafkaURL"`
-	KafkaTopic                  string           `json:"kafkaTopic"`
-	KafkaGroupID                string           `json:"kafkaGroupID"`
-	PrimaryKey                  string           `json:"primaryKey"`
-	UpdateKey                   string           `json:"updateKey"`
+	SourceType                  string            `json:"sourceType" yaml:"sourceType" toml:"sourceType"`
+	SourceConnectionString      string            `json:"sourceConnectionString" yaml:"sourceConnectionString" toml:"sourceConnectionString"`
+	SourceTable                 string            `json:"sourceTable" yaml:"sourceTable" toml:"sourceTable"`
+	DestinationType             string            `json:"destinationType" yaml:"destinationType" toml:"destinationType"`
+	DestinationConnectionString string            `json:"destinationConnectionString" yaml:"destinationConnectionString" toml:"destinationConnectionString"`
+	DestinationTable            string            `json:"destinationTable" yaml:"destinationTable" toml:"destinationTable"`
+	SQLQuery                    string            `json:"sqlQuery" yaml:"sqlQuery" toml:"sqlQuery"`
+	OutputPath                  string            `json:"outputPath" yaml:"outputPath" toml:"outputPath"`
+	OutputFormat                string            `json:"outputFormat" yaml:"outputFormat" toml:"outputFormat"`
+	Transformations             []Transformation  `json:"transformations" yaml:"transformations" toml:"transformations"`
+	NeedCheck                   bool              `json:"needCheck" yaml:"needCheck" toml:"needCheck"`
+	CheckMethod                 string            `json:"checkMethod" yaml:"checkMethod" toml:"checkMethod"`
+	Joins                       []Join            `json:"joins" yaml:"joins" toml:"joins"`
+	Where                       string            `json:"where" yaml:"where" toml:"where"`
+	OrderBy                     string            `json:"orderBy" yaml:"orderBy" toml:"orderBy"`
+	Triggers                    []Trigger         `json:"triggers" yaml:"triggers" toml:"triggers"`
+	LogTable                    string            `json:"logTable" yaml:"logTable" toml:"logTable"`
+	SyncInterval                string            `json:"syncInterval" yaml:"syncInterval" toml:"syncInterval"`
+	KafkaURL                    string            `json:"kafkaURL" yaml:"kafkaURL" toml:"kafkaURL"`
+	KafkaTopic                  string            `json:"kafkaTopic" yaml:"kafkaTopic" toml:"kafkaTopic"`
+	KafkaGroupID                string            `json:"kafkaGroupID" yaml:"kafkaGroupID" toml:"kafkaGroupID"`
+	PrimaryKey                  string            `json:"primaryKey" yaml:"primaryKey" toml:"primaryKey"`
+	UpdateKey                   string            `json:"updateKey" yaml:"updateKey" toml:"updateKey"`
+	Timeouts                    Timeouts          `json:"timeouts" yaml:"timeouts" toml:"timeouts"`
+	Partitioning                Partitioning      `json:"partitioning" yaml:"partitioning" toml:"partitioning"`
+	Checkpoint                  Checkpointing     `json:"checkpoint" yaml:"checkpoint" toml:"checkpoint"`
+	Pool                        Pool              `json:"pool" yaml:"pool" toml:"pool"`
+	CDC                         ChangeCapture     `json:"cdc" yaml:"cdc" toml:"cdc"`
+	Deletes                     DeletePropagation `json:"deletes" yaml:"deletes" toml:"deletes"`
+	// LoadMode define como as linhas são gravadas no destino: "insert" (padrão) insere ou, com
+	// UpdateKey, atualiza a linha existente; "scd2" mantém o histórico das versões (SCD2).
+	LoadMode string                  `json:"loadMode" yaml:"loadMode" toml:"loadMode"`
+	SCD2     SlowlyChangingDimension `json:"scd2" yaml:"scd2" toml:"scd2"`
+	// Kafka reúne as opções do cliente Kafka de kafkaURL e kafkaTopic.
+	Kafka KafkaOptions `json:"kafka" yaml:"kafka" toml:"kafka"`
+	// Redis reúne as opções da origem e do destino com sourceType ou destinationType redis.
+	Redis RedisOptions `json:"redis" yaml:"redis" toml:"redis"`
 }
+
+// Timeouts define o tempo máximo de cada etapa, como duração Go ("30s", "5m").
+// Etapas sem valor não têm limite além do cancelamento do contexto.
+type Timeouts struct {
+	// Extract limita a consulta e a leitura das linhas na origem.
+	Extract string `json:"extract" yaml:"extract" toml:"extract"`
+	// Load limita a criação da tabela e a transação de carga no destino.
+	Load string `json:"load" yaml:"load" toml:"load"`
+	// Consume limita a gravação de cada lote de mensagens consumido do Kafka.
+	Consume string `json:"consume" yaml:"consume" toml:"consume"`
+}
+
+// Partitioning divide a extração em consultas limitadas por faixas de Column, executadas em paralelo.
+// As faixas são explícitas (Ranges) ou calculadas a partir de MIN e MAX da coluna (Count), e neste
+// caso uma partição extra traz as linhas com Column nula.
+type Partitioning struct {
+	Column string           `json:"column" yaml:"column" toml:"column"`
+	Count  int              `json:"count" yaml:"count" toml:"count"`
+	Ranges []PartitionRange `json:"ranges" yaml:"ranges" toml:"ranges"`
+	// Workers limita as partições extraídas ao mesmo tempo (padrão: 4).
+	Workers int `json:"workers" yaml:"workers" toml:"workers"`
+	// Retries é o número de novas tentativas de uma partição que falhou (padrão: 2; negativo desativa).
+	Retries int `json:"retries" yaml:"retries" toml:"retries"`
+}
+
+// Enabled indica se a extração deve ser particionada.
+func (p Partitioning) Enabled() bool { return p.Column != "" }
+
+// PartitionRange é a faixa [From, To) de uma partição. Um limite nulo deixa a faixa aberta naquele lado.
+type PartitionRange struct {
+	From interface{} `json:"from" yaml:"from" toml:"from"`
+	To   interface{} `json:"to" yaml:"to" toml:"to"`
+}
+
+// Pool configura os pools de conexão da origem e do destino, compartilhados por driver e DSN,
+// e as novas tentativas ao conectar. As opções valem para o pool criado na primeira conexão ao DSN.
+type Pool struct {
+	MaxOpenConns int `json:"maxOpenConns" yaml:"maxOpenConns" toml:"maxOpenConns"`
+	MaxIdleConns int `json:"maxIdleConns" yaml:"maxIdleConns" toml:"maxIdleConns"`
+	// ConnMaxLifetime e ConnMaxIdleTime são durações Go ("30m", "5m").
+	ConnMaxLifetime string `json:"connMaxLifetime" yaml:"connMaxLifetime" toml:"connMaxLifetime"`
+	ConnMaxIdleTime string `json:"connMaxIdleTime" yaml:"connMaxIdleTime" toml:"connMaxIdleTime"`
+	// Retries é o número de novas tentativas após um erro transitório ao conectar, ao extrair ou ao
+	// gravar uma transação de carga (padrão: 3; negativo desativa).
+	Retries int `json:"retries" yaml:"retries" toml:"retries"`
+	// RetryBackoff é a espera antes da primeira nova tentativa, dobrada a cada tentativa (padrão: 500ms).
+	RetryBackoff string `json:"retryBackoff" yaml:"retryBackoff" toml:"retryBackoff"`
+}
+
+// Checkpointing faz a carga ser confirmada em lotes de BatchSize linhas. Cada lote grava, na mesma
+// transação, um checkpoint na tabela etl_checkpoints do destino, usado por getl sync --resume.
+type Checkpointing struct {
+	BatchSize int `json:"batchSize" yaml:"batchSize" toml:"batchSize"`
+	// Key é a coluna de origem que ordena a extração; ao retomar, só as linhas com Key maior que a
+	// última confirmada são lidas. Sem Key, vale PrimaryKey.
+	Key string `json:"key" yaml:"key" toml:"key"`
+}
+
+// Enabled indica se a carga deve ser feita em lotes com checkpoint.
+func (c Checkpointing) Enabled() bool { return c.BatchSize > 0 }
+
+// ChangeCapture captura as alterações da tabela de origem para aplicá-las no destino com getl cdc run.
+// No modo "triggers", triggers na origem gravam a operação, a chave primária (PrimaryKey) e a imagem
+// da linha em JSON na tabela Changelog, que é consumida e esvaziada a cada ciclo. Nos modos "pgoutput"
+// e "wal2json", as alterações são lidas do WAL do PostgreSQL por um slot de replicação lógica. No modo
+// "binlog", o getl se conecta ao MySQL/MariaDB como réplica e lê os eventos de linha do binlog.
+type ChangeCapture struct {
+	Mode string `json:"mode" yaml:"mode" toml:"mode"`
+	// Changelog é a tabela de alterações na origem (padrão: getl_changelog), compartilhada entre tabelas.
+	Changelog string `json:"changelog" yaml:"changelog" toml:"changelog"`
+	// BatchSize limita as alterações aplicadas em cada transação no destino (padrão: 1000).
+	BatchSize int `json:"batchSize" yaml:"batchSize" toml:"batchSize"`
+	// Slot é o slot de replicação lógica (padrão: getl_<sourceTable>). No modo binlog, é o nome com que
+	// a posição lida é registrada no destino.
+	Slot string `json:"slot" yaml:"slot" toml:"slot"`
+	// Publication é a publicação lida pelo pgoutput (padrão: o nome do slot), criada para SourceTable.
+	Publication string `json:"publication" yaml:"publication" toml:"publication"`
+	// Sink define para onde vão as alterações: "destination" (padrão) ou "kafka", que as publica em KafkaTopic.
+	Sink string `json:"sink" yaml:"sink" toml:"sink"`
+	// ServerID identifica o getl como réplica no modo binlog; deve ser único entre as réplicas do
+	// servidor (padrão: derivado do Slot).
+	ServerID uint32 `json:"serverId" yaml:"serverId" toml:"serverId"`
+	// GTID registra, no modo binlog, o conjunto de GTIDs aplicados em vez do arquivo e da posição do
+	// binlog; exige gtid_mode=ON no MySQL e não é suportado no MariaDB.
+	GTID bool `json:"gtid" yaml:"gtid" toml:"gtid"`
+}
+
+// Enabled indica se a captura de alterações está configurada.
+func (c ChangeCapture) Enabled() bool { return c.Mode != "" }
+
+// Logical indica se a captura lê o WAL do PostgreSQL por replicação lógica.
+func (c ChangeCapture) Logical() bool { return c.Mode == "pgoutput" || c.Mode == "wal2json" }
+
+// Streaming indica se a captura lê o log da origem (WAL ou binlog), registrando a posição lida.
+func (c ChangeCapture) Streaming() bool { return c.Logical() || c.Mode == "binlog" }
+
+// ChangeEvent é uma alteração de linha capturada na origem. Before é a imagem anterior, presente nas
+// exclusões e, quando a origem a fornece, nas atualizações; After é a nova imagem das inclusões e
+// atualizações. Position é a posição da origem após a transação da alteração (e.g. o LSN).
+type ChangeEvent struct {
+	Operation string    `json:"op"`
+	Table     string    `json:"table"`
+	Position  string    `json:"position,omitempty"`
+	Timestamp time.Time `json:"ts,omitempty"`
+	Before    Data      `json:"before,omitempty"`
+	After     Data      `json:"after,omitempty"`
+}
+
+// DebeziumEnvelope é uma alteração de linha no envelope das mensagens do Debezium. Op é "c"
+// (inclusão), "u" (atualização), "d" (exclusão) ou "r" (leitura da carga inicial); TsMs é o horário,
+// em milissegundos, em que a mensagem foi produzida.
+type DebeziumEnvelope struct {
+	Before Data           `json:"before"`
+	After  Data           `json:"after"`
+	Source DebeziumSource `json:"source"`
+	Op     string         `json:"op"`
+	TsMs   int64          `json:"ts_ms"`
+}
+
+// DebeziumSource descreve a origem da alteração. TsMs é o horário da alteração na origem, Snapshot
+// é "true" nas linhas da carga inicial e Position é a posição da origem (LSN, arquivo e posição do
+// binlog ou GTIDs) após a transação, quando capturada do log.
+type DebeziumSource struct {
+	Connector string `json:"connector"`
+	TsMs      int64  `json:"ts_ms"`
+	Snapshot  string `json:"snapshot"`
+	DB        string `json:"db"`
+	Table     string `json:"table"`
+	Position  string `json:"position,omitempty"`
+}
+
+// DeletePropagation leva ao destino as linhas removidas da origem. Com Detect "keys", cada sync
+// compara as chaves de PrimaryKey extraídas com as do destino; com "cdc", as exclusões vêm de getl
+// cdc run. Com Mode "hard" (padrão), a linha é removida do destino; com "soft", ela é mantida e
+// marcada em Column.
+type DeletePropagation struct {
+	Detect string `json:"detect" yaml:"detect" toml:"detect"`
+	Mode   string `json:"mode" yaml:"mode" toml:"mode"`
+	// Column é a coluna de destino marcada na exclusão lógica (padrão: deleted_at), criada se preciso.
+	// Recebe o horário da exclusão e fica nula nas linhas presentes na origem.
+	Column string `json:"column" yaml:"column" toml:"column"`
+	// Flag faz Column ser um booleano: verdadeiro nas linhas excluídas e falso nas demais.
+	Flag bool `json:"flag" yaml:"flag" toml:"flag"`
+	// MaxPercent interrompe a pipeline, sem alterar o destino, se a comparação de chaves excluiria
+	// mais que esse percentual das linhas do destino (padrão: 10; 100 desativa).
+	MaxPercent int `json:"maxPercent" yaml:"maxPercent" toml:"maxPercent"`
+}
+
+// Enabled indica se a propagação de exclusões está configurada.
+func (d DeletePropagation) Enabled() bool { return d.Detect != "" }
+
+// Soft indica se as exclusões marcam a linha em Column em vez de removê-la.
+func (d DeletePropagation) Soft() bool { return d.Mode == "soft" }
+
+// SlowlyChangingDimension configura a carga com LoadMode "scd2". Cada linha do destino é uma versão
+// da linha de origem identificada por PrimaryKey; quando alguma das TrackedColumns muda, a versão
+// corrente é fechada (ValidTo e CurrentFlag falso) e uma nova é inserida. As colunas de versão são
+// criadas com a tabela de destino.
+type SlowlyChangingDimension struct {
+	// TrackedColumns são as colunas de destino comparadas (padrão: todas, exceto a chave).
+	TrackedColumns []string `json:"trackedColumns" yaml:"trackedColumns" toml:"trackedColumns"`
+	// SurrogateKey é a chave gerada pelo banco para cada versão (padrão: sk).
+	SurrogateKey string `json:"surrogateKey" yaml:"surrogateKey" toml:"surrogateKey"`
+	// ValidFrom e ValidTo delimitam a vigência da versão (padrão: valid_from e valid_to); ValidTo é
+	// nulo na versão corrente.
+	ValidFrom string `json:"validFrom" yaml:"validFrom" toml:"validFrom"`
+	ValidTo   string `json:"validTo" yaml:"validTo" toml:"validTo"`
+	// CurrentFlag indica a versão corrente de cada chave (padrão: is_current).
+	CurrentFlag string `json:"currentFlag" yaml:"currentFlag" toml:"currentFlag"`
+}
+
+// KafkaOptions reúne as opções do cliente Kafka da pipeline.
+type KafkaOptions struct {
+	// Format define o valor das mensagens publicadas: "json" (padrão), a linha ou a ChangeEvent,
+	// "debezium", o envelope DebeziumEnvelope, ou "avro" e "protobuf", a linha serializada com o
+	// schema registrado em SchemaRegistry. O consumo aceita todos os formatos.
+	Format string `json:"format" yaml:"format" toml:"format"`
+	// SchemaRegistry é o schema registry dos formatos avro e protobuf.
+	SchemaRegistry SchemaRegistry `json:"schemaRegistry" yaml:"schemaRegistry" toml:"schemaRegistry"`
+	// Brokers lista os brokers do cluster (host:porta); sem ele, os de kafkaURL, separados por vírgulas.
+	Brokers []string `json:"brokers" yaml:"brokers" toml:"brokers"`
+	// ClientID identifica o getl nos logs e nas quotas do cluster (padrão: o do kafka-go).
+	ClientID string        `json:"clientID" yaml:"clientID" toml:"clientID"`
+	TLS      KafkaTLS      `json:"tls" yaml:"tls" toml:"tls"`
+	SASL     KafkaSASL     `json:"sasl" yaml:"sasl" toml:"sasl"`
+	Reader   KafkaReader   `json:"reader" yaml:"reader" toml:"reader"`
+	Consumer KafkaConsumer `json:"consumer" yaml:"consumer" toml:"consumer"`
+	Producer KafkaProducer `json:"producer" yaml:"producer" toml:"producer"`
+	Stream   KafkaStream   `json:"stream" yaml:"stream" toml:"stream"`
+}
+
+// KafkaStream configura getl stream, que consome kafkaTopic, aplica as transformations e publica as
+// linhas em OutputTopic em transações do Kafka, confirmando os offsets de kafkaGroupID na mesma transação.
+type KafkaStream struct {
+	// OutputTopic recebe as linhas transformadas em JSON, com a chave e os cabeçalhos de kafka.producer.
+	OutputTopic string `json:"outputTopic" yaml:"outputTopic" toml:"outputTopic"`
+	// TransactionalID identifica o produtor transacional (padrão: "getl-stream-<kafkaGroupID>"). Uma
+	// nova instância com o mesmo id interrompe a anterior; instâncias em paralelo precisam de ids distintos.
+	TransactionalID string `json:"transactionalID" yaml:"transactionalID" toml:"transactionalID"`
+	// TransactionTimeout é o tempo máximo de cada transação, como duração Go (padrão: 1m).
+	TransactionTimeout string     `json:"transactionTimeout" yaml:"transactionTimeout" toml:"transactionTimeout"`
+	Dedup              KafkaDedup `json:"dedup" yaml:"dedup" toml:"dedup"`
+}
+
+// KafkaDedup descarta as linhas que repetem a última publicada com a mesma chave, lembrada em um
+// arquivo SQLite local.
+type KafkaDedup struct {
+	// Key é a coluna ou o modelo com colunas entre chaves (e.g. "{region}-{id}") da chave de
+	// deduplicação, lido na linha transformada; sem ele, não há deduplicação.
+	Key string `json:"key" yaml:"key" toml:"key"`
+	// Path é o arquivo do estado (padrão: "getl-stream-<kafkaGroupID>.db").
+	Path string `json:"path" yaml:"path" toml:"path"`
+	// TTL é por quanto tempo cada chave é lembrada, como duração Go (padrão: sem expiração).
+	TTL string `json:"ttl" yaml:"ttl" toml:"ttl"`
+}
+
+// KafkaTLS configura a conexão TLS com os brokers, usada com Enabled ou com qualquer um dos arquivos.
+type KafkaTLS struct {
+	Enabled bool `json:"enabled" yaml:"enabled" toml:"enabled"`
+	// CAFile é o certificado PEM da autoridade que assina os dos brokers (padrão: as do sistema).
+	CAFile string `json:"caFile" yaml:"caFile" toml:"caFile"`
+	// CertFile e KeyFile são o certificado e a chave PEM do cliente, para a autenticação mútua.
+	CertFile string `json:"certFile" yaml:"certFile" toml:"certFile"`
+	KeyFile  string `json:"keyFile" yaml:"keyFile" toml:"keyFile"`
+	// ServerName substitui o nome verificado no certificado dos brokers.
+	ServerName string `json:"serverName" yaml:"serverName" toml:"serverName"`
+	// InsecureSkipVerify aceita qualquer certificado dos brokers; só para testes.
+	InsecureSkipVerify bool `json:"insecureSkipVerify" yaml:"insecureSkipVerify" toml:"insecureSkipVerify"`
+}
+
+// KafkaSASL configura a autenticação SASL com os brokers.
+type KafkaSASL struct {
+	// Mechanism é "plain", "scram-sha-256" ou "scram-sha-512" (padrão: sem autenticação).
+	Mechanism string `json:"mechanism" yaml:"mechanism" toml:"mechanism"`
+	Username  string `json:"username" yaml:"username" toml:"username"`
+	Password  string `json:"password" yaml:"password" toml:"password"`
+}
+
+// KafkaReader configura a leitura dos tópicos consumidos (getl consume, retentativas e dead-letter).
+type KafkaReader struct {
+	// StartOffset é onde um grupo sem offsets confirmados começa: "earliest" (padrão) ou "latest".
+	StartOffset string `json:"startOffset" yaml:"startOffset" toml:"startOffset"`
+	// MinBytes e MaxBytes limitam o tamanho de cada busca nos brokers (padrão: 1 e 1MB).
+	MinBytes int `json:"minBytes" yaml:"minBytes" toml:"minBytes"`
+	MaxBytes int `json:"maxBytes" yaml:"maxBytes" toml:"maxBytes"`
+	// CommitInterval envia as confirmações de offsets periodicamente, como duração Go, em vez de a
+	// cada lote (padrão). As confirmações pendentes se perdem se o processo for interrompido, e os
+	// lotes são lidos de novo.
+	CommitInterval string `json:"commitInterval" yaml:"commitInterval" toml:"commitInterval"`
+	// IsolationLevel é "read_uncommitted" (padrão) ou "read_committed", que ignora as mensagens de
+	// transações abortadas ou em aberto.
+	IsolationLevel string `json:"isolationLevel" yaml:"isolationLevel" toml:"isolationLevel"`
+}
+
+// SchemaRegistry configura o acesso a um schema registry compatível com a API do Confluent. Antes
+// de publicar, o schema deduzido das colunas de origem é verificado contra a última versão do
+// Subject e registrado; cada mensagem leva o id do schema, usado pelo consumo para decodificá-la.
+type SchemaRegistry struct {
+	// URL é o endereço da API, e.g. http://localhost:8081.
+	URL      string `json:"url" yaml:"url" toml:"url"`
+	Username string `json:"username" yaml:"username" toml:"username"`
+	Password string `json:"password" yaml:"password" toml:"password"`
+	// Subject é o subject do schema das mensagens (padrão: "<kafkaTopic>-value").
+	Subject string `json:"subject" yaml:"subject" toml:"subject"`
+}
+
+// KafkaProducer configura a publicação das linhas em KafkaTopic (RunETL e cdc.sink kafka).
+type KafkaProducer struct {
+	// Key define a chave das mensagens: o nome de uma coluna ou um modelo com colunas entre chaves,
+	// e.g. "{region}-{id}" (padrão: PrimaryKey). Mensagens com a mesma chave vão para a mesma partição.
+	Key string `json:"key" yaml:"key" toml:"key"`
+	// Partitioner escolhe a partição de cada mensagem: "hash" (padrão com chave), "murmur2" (o
+	// particionador do cliente Java), "crc32" (o do librdkafka), "roundrobin" ou "leastbytes" (padrão sem chave).
+	Partitioner string `json:"partitioner" yaml:"partitioner" toml:"partitioner"`
+	// BatchSize é o máximo de mensagens de cada escrita (padrão: 100).
+	BatchSize int `json:"batchSize" yaml:"batchSize" toml:"batchSize"`
+	// Linger é quanto um lote incompleto espera antes de ser enviado, como duração Go (padrão: 1s).
+	Linger string `json:"linger" yaml:"linger" toml:"linger"`
+	// Compression comprime os lotes: "gzip", "snappy", "lz4" ou "zstd" (padrão: sem compressão).
+	Compression string `json:"compression" yaml:"compression" toml:"compression"`
+	// RequiredAcks define as confirmações esperadas: "none" (padrão), "one" (só o líder) ou "all" (todas as réplicas em sincronia).
+	RequiredAcks string `json:"requiredAcks" yaml:"requiredAcks" toml:"requiredAcks"`
+	// Headers são acrescentados a todas as mensagens, além de getl-source-table e getl-run-id.
+	Headers map[string]string `json:"headers" yaml:"headers" toml:"headers"`
+}
+
+// KafkaConsumer configura a carga das mensagens de KafkaTopic no destino (getl consume -f). As
+// mensagens são gravadas em lotes, cada um em uma transação, e os offsets só são confirmados após
+// o commit do lote.
+type KafkaConsumer struct {
+	// BatchSize é o máximo de mensagens de cada lote (padrão: 100).
+	BatchSize int `json:"batchSize" yaml:"batchSize" toml:"batchSize"`
+	// FlushInterval é quanto um lote incompleto espera por mais mensagens, como duração Go (padrão: 1s).
+	FlushInterval string `json:"flushInterval" yaml:"flushInterval" toml:"flushInterval"`
+	// DeadLetterTopic ativa o tratamento de erros por mensagem: as mensagens que não podem ser
+	// decodificadas ou gravadas são publicadas em Retry.Topic e, esgotadas as tentativas, neste
+	// tópico, com o erro nos cabeçalhos, sem interromper o consumo. Sem ele, as mensagens inválidas
+	// são descartadas e uma falha de gravação encerra o consumo.
+	DeadLetterTopic string     `json:"deadLetterTopic" yaml:"deadLetterTopic" toml:"deadLetterTopic"`
+	Retry           KafkaRetry `json:"retry" yaml:"retry" toml:"retry"`
+	// Routes distribui as mensagens entre várias tabelas de destino: cada mensagem vai para a tabela
+	// da primeira rota que a atende e, sem nenhuma, para destinationTable. Os tópicos das rotas são
+	// consumidos com kafkaTopic, no mesmo kafkaGroupID.
+	Routes []KafkaRoute `json:"routes" yaml:"routes" toml:"routes"`
+}
+
+// KafkaRoute envia para Table as mensagens que atendem a todas as condições informadas.
+type KafkaRoute struct {
+	// Topic é o tópico da mensagem.
+	Topic string `json:"topic" yaml:"topic" toml:"topic"`
+	// Headers exige os cabeçalhos da mensagem com esses valores.
+	Headers map[string]string `json:"headers" yaml:"headers" toml:"headers"`
+	// Fields exige os campos da linha decodificada com esses valores, comparados como texto.
+	Fields map[string]string `json:"fields" yaml:"fields" toml:"fields"`
+	Table  string            `json:"table" yaml:"table" toml:"table"`
+	// PrimaryKey é a chave das linhas na mensagem (padrão: primaryKey).
+	PrimaryKey string `json:"primaryKey" yaml:"primaryKey" toml:"primaryKey"`
+	// Transformations gera as colunas de Table; sem elas, as colunas são os campos da mensagem.
+	Transformations []Transformation `json:"transformations" yaml:"transformations" toml:"transformations"`
+}
+
+// KafkaRetry configura as novas tentativas das mensagens que falharam no consumo com DeadLetterTopic.
+type KafkaRetry struct {
+	// Topic recebe as mensagens a tentar de novo, consumido com o mesmo kafkaGroupID (padrão: "<kafkaTopic>-retry").
+	Topic string `json:"topic" yaml:"topic" toml:"topic"`
+	// Attempts é o máximo de novas tentativas de cada mensagem antes do dead-letter (padrão: 3).
+	Attempts int `json:"attempts" yaml:"attempts" toml:"attempts"`
+	// Backoff é a espera antes da primeira nova tentativa, dobrada nas seguintes, como duração Go (padrão: 5s).
+	Backoff string `json:"backoff" yaml:"backoff" toml:"backoff"`
+}
+
+// RedisOptions configura a origem e o destino Redis, cujas connection strings são URLs
+// redis://[usuário:senha@]host:porta[/db] (rediss:// com TLS).
+type RedisOptions struct {
+	Source RedisSource `json:"source" yaml:"source" toml:"source"`
+	Sink   RedisSink   `json:"sink" yaml:"sink" toml:"sink"`
+	// BatchSize é o número de chaves ou entradas de cada leitura e de linhas de cada transação
+	// MULTI/EXEC no destino (padrão: 100).
+	BatchSize int `json:"batchSize" yaml:"batchSize" toml:"batchSize"`
+}
+
+// RedisSource lê as linhas dos hashes cujas chaves atendem Pattern ou das entradas de Stream,
+// consumido por um grupo; informe um dos dois.
+type RedisSource struct {
+	// Pattern seleciona as chaves com SCAN MATCH, e.g. "items:*"; cada hash é uma linha.
+	Pattern string `json:"pattern" yaml:"pattern" toml:"pattern"`
+	// Stream é lido com XREADGROUP pelo grupo Group, criado se preciso, e as entradas são confirmadas
+	// com XACK após a carga; as pendentes de Consumer são lidas de novo na execução seguinte.
+	Stream string `json:"stream" yaml:"stream" toml:"stream"`
+	// Group é o grupo de consumo do stream (padrão: "getl").
+	Group string `json:"group" yaml:"group" toml:"group"`
+	// Consumer é o consumidor do grupo (padrão: "getl").
+	Consumer string `json:"consumer" yaml:"consumer" toml:"consumer"`
+	// KeyField é a coluna que recebe a chave do hash ou o id da entrada do stream; sem ela, não são lidos.
+	KeyField string `json:"keyField" yaml:"keyField" toml:"keyField"`
+}
+
+// RedisSink grava as linhas transformadas como hashes, entradas de um stream ou elementos de uma lista.
+type RedisSink struct {
+	// Type é hash (padrão), stream ou list.
+	Type string `json:"type" yaml:"type" toml:"type"`
+	// Key é o modelo da chave de cada linha, com colunas entre chaves (e.g. "items:{id}"); sem colunas,
+	// é o nome da chave. Com hash, o padrão é "<destinationTable>:{<primaryKey>}"; com stream e list,
+	// destinationTable.
+	Key string `json:"key" yaml:"key" toml:"key"`
+	// TTL é a expiração dos hashes gravados, como duração Go (padrão: sem expiração).
+	TTL string `json:"ttl" yaml:"ttl" toml:"ttl"`
+	// MaxLen limita o stream a aproximadamente esse número de entradas (padrão: sem limite).
+	MaxLen int64 `json:"maxLen" yaml:"maxLen" toml:"maxLen"`
+}
+
 type Transformation struct {
-	SourceField      string `json:"sourceField"`
-	DestinationField string `json:"destinationField"`
-	Operation        string `json:"operation"`
-	SPath            string `json:"sPath"`
-	DPath            string `json:"dPath"`
-	Type             string `json:"type"`
+	SourceField      string `json:"sourceField" yaml:"sourceField" toml:"sourceField"`
+	DestinationField string `json:"destinationField" yaml:"destinationField" toml:"destinationField"`
+	Operation        string `json:"operation" yaml:"operation" toml:"operation"`
+	SPath            string `json:"sPath" yaml:"sPath" toml:"sPath"`
+	DPath            string `json:"dPath" yaml:"dPath" toml:"dPath"`
+	Type             string `json:"type" yaml:"type" toml:"type"`
 }
 type Join struct {
-	Table     string `json:"table"`
-	Condition string `json:"condition"`
-	JoinType  string `json:"joinType"`
+	Table     string `json:"table" yaml:"table" toml:"table"`
+	Condition string `json:"condition" yaml:"condition" toml:"condition"`
+	JoinType  string `json:"joinType" yaml:"joinType" toml:"joinType"`
 }
+
+// Trigger é um trigger mantido no banco de destino. Sem Table, o trigger é criado na tabela de destino.
 type Trigger struct {
-	Name      string `json:"name"`
-	Table     string `json:"table"`
-	Event     string `json:"event"`
-	Statement string `json:"statement"`
+	Name      string `json:"name" yaml:"name" toml:"name"`
+	Table     string `json:"table" yaml:"table" toml:"table"`
+	Event     string `json:"event" yaml:"event" toml:"event"`
+	Statement string `json:"statement" yaml:"statement" toml:"statement"`
+}
+
+// Connection é uma conexão nomeada, compartilhada entre os pipelines de um mesmo arquivo.
+type Connection struct {
+	Type             string `json:"type" yaml:"type" toml:"type"`
+	ConnectionString string `json:"connectionString" yaml:"connectionString" toml:"connectionString"`
+}
+
+// Pipeline é uma Config nomeada dentro de um arquivo com vários pipelines.
+// Source e Destination referenciam conexões de ConfigFile.Connections pelo nome e,
+// quando informados, preenchem o tipo e a string de conexão da origem e do destino.
+type Pipeline struct {
+	Name        string `json:"name" yaml:"name" toml:"name"`
+	Source      string `json:"source" yaml:"source" toml:"source"`
+	Destination string `json:"destination" yaml:"destination" toml:"destination"`
+	Config      `yaml:",inline"`
 }
+
+// ConfigFile é o formato de arquivo com várias pipelines e conexões compartilhadas.
+type ConfigFile struct {
+	Connections map[string]Connection `json:"connections" yaml:"connections" toml:"connections"`
+	Pipelines   []Pipeline            `json:"pipelines" yaml:"pipelines" toml:"pipelines"`
+}
+
+// SupportedDrivers lista os drivers database/sql registrados pelo getl e aceitos
+// em sourceType e destinationType.
+var SupportedDrivers = []string{"sqlite3", "postgres", "mysql", "godror", "sqlserver", "mssql"}
+
+// IsSupportedDriver verifica se o driver informado está em SupportedDrivers.
+func IsSupportedDriver(driver string) bool {
+	return slices.Contains(SupportedDrivers, driver)
+}
+
+// RedisType é o sourceType e o destinationType das pipelines que leem ou gravam no Redis.
+const RedisType = "redis"
+
+// SupportedOperations lista as operações aceitas em Transformation.Operation.
+var SupportedOperations = []string{"copy", "none", "uppercase", "base64", "toInt"}
+
+// SupportedJoinTypes lista os tipos aceitos em Join.JoinType (sem diferenciar maiúsculas).
+var SupportedJoinTypes = []string{"INNER", "LEFT", "RIGHT"}
+
+// TriggerTimings e TriggerOperations compõem Trigger.Event, no formato "<momento> <operação>[ OR <operação>...]",
+// e.g. "AFTER INSERT" ou "BEFORE INSERT OR UPDATE" (sem diferenciar maiúsculas).
+var TriggerTimings = []string{"BEFORE", "AFTER", "INSTEAD OF"}
+var TriggerOperations = []string{"INSERT", "UPDATE", "DELETE"}
+
+// SupportedCaptureModes lista os modos aceitos em ChangeCapture.Mode.
+var SupportedCaptureModes = []string{"triggers", "pgoutput", "wal2json", "binlog"}
+
+// SupportedChangeSinks lista os destinos aceitos em ChangeCapture.Sink.
+var SupportedChangeSinks = []string{"destination", "kafka"}
+
+// SupportedKafkaFormats lista os formatos aceitos em KafkaOptions.Format.
+var SupportedKafkaFormats = []string{"json", "debezium", "avro", "protobuf"}
+
+// SupportedPartitioners lista os particionadores aceitos em KafkaProducer.Partitioner.
+var SupportedPartitioners = []string{"hash", "murmur2", "crc32", "roundrobin", "leastbytes"}
+
+// SupportedCompressions lista as compressões aceitas em KafkaProducer.Compression.
+var SupportedCompressions = []string{"gzip", "snappy", "lz4", "zstd"}
+
+// SupportedRequiredAcks lista os valores aceitos em KafkaProducer.RequiredAcks.
+var SupportedRequiredAcks = []string{"none", "one", "all"}
+
+// SupportedSASLMechanisms lista os mecanismos aceitos em KafkaSASL.Mechanism.
+var SupportedSASLMechanisms = []string{"plain", "scram-sha-256", "scram-sha-512"}
+
+// SupportedStartOffsets lista os valores aceitos em KafkaReader.StartOffset.
+var SupportedStartOffsets = []string{"earliest", "latest"}
+
+// SupportedIsolationLevels lista os valores aceitos em KafkaReader.IsolationLevel.
+var SupportedIsolationLevels = []string{"read_uncommitted", "read_committed"}
+
+// SupportedRedisSinkTypes lista os tipos aceitos em RedisSink.Type.
+var SupportedRedisSinkTypes = []string{"hash", "stream", "list"}
+
+// SupportedLoadModes lista os modos aceitos em Config.LoadMode.
+var SupportedLoadModes = []string{"insert", "scd2"}
+
+// SupportedDeleteDetections e SupportedDeleteModes listam os valores aceitos em DeletePropagation.
+var SupportedDeleteDetections = []string{"keys", "cdc"}
+var SupportedDeleteModes = []string{"hard", "soft"}
+
 type VendorSqlTypeMap struct {
 	sourceType string
 	targetType string
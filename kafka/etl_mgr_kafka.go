@@ -2,14 +2,17 @@ package kafka
 
 import (
 	"context"
-	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	. "github.com/faelmori/getl/etypes"
+	"github.com/faelmori/getl/meta"
 	s "github.com/faelmori/getl/sql"
+	. "github.com/faelmori/getl/utils"
 	"github.com/faelmori/logz"
 	"github.com/segmentio/kafka-go"
 	"sync"
+	"time"
 )
 
 type IKafka interface {
@@ -35,7 +38,9 @@ type IKafka interface {
 
 	Close()
 	SyncData()
+	SyncDataContext(ctx context.Context) error
 	RunETL() error
+	RunETLContext(ctx context.Context) error
 }
 type Kafka struct {
 	// mu is the mutex for the Kafka struct
@@ -109,29 +114,59 @@ func NewKafkaWithConfigAndSQL(kafkaURL, topic, groupID, sourceType, sourceConnec
 	}
 }
 
+// GetKafkaReader retorna o Reader do tópico, criado na primeira chamada com as opções de KafkaConfig.
+// Retorna nil, registrando o erro, se as opções forem inválidas.
 func (k *Kafka) GetKafkaReader() *kafka.Reader {
+	reader, readerErr := k.kafkaReader()
+	if readerErr != nil {
+		logz.Error("erro ao criar o leitor Kafka: "+readerErr.Error(), map[string]interface{}{})
+	}
+	return reader
+}
+
+// GetKafkaWriter retorna o Writer do tópico, criado na primeira chamada com as opções de KafkaConfig.
+// Retorna nil, registrando o erro, se as opções forem inválidas.
+func (k *Kafka) GetKafkaWriter() *kafka.Writer {
+	writer, writerErr := k.kafkaWriter()
+	if writerErr != nil {
+		logz.Error("erro ao criar o escritor Kafka: "+writerErr.Error(), map[string]interface{}{})
+	}
+	return writer
+}
+
+func (k *Kafka) kafkaReader() (*kafka.Reader, error) {
 	k.mu.Lock()
 	defer k.mu.Unlock()
 	if k.Reader == nil {
-		k.Reader = kafka.NewReader(kafka.ReaderConfig{
-			Brokers: []string{k.KafkaURL},
-			Topic:   k.Topic,
-			GroupID: k.GroupID,
-		})
+		reader, readerErr := NewKafkaReader(k.clientConfig(), k.Topic, k.GroupID)
+		if readerErr != nil {
+			return nil, readerErr
+		}
+		k.Reader = reader
 	}
-	return k.Reader
+	return k.Reader, nil
 }
-func (k *Kafka) GetKafkaWriter() *kafka.Writer {
+
+func (k *Kafka) kafkaWriter() (*kafka.Writer, error) {
 	k.mu.Lock()
 	defer k.mu.Unlock()
 	if k.Writer == nil {
-		k.Writer = &kafka.Writer{
-			Addr:     kafka.TCP(k.KafkaURL),
-			Topic:    k.Topic,
-			Balancer: &kafka.LeastBytes{},
+		writer, writerErr := NewKafkaWriter(k.clientConfig(), k.Topic)
+		if writerErr != nil {
+			return nil, writerErr
 		}
+		k.Writer = writer
 	}
-	return k.Writer
+	return k.Writer, nil
+}
+
+// clientConfig retorna KafkaConfig com o KafkaURL do Kafka, usado com as opções de segurança dela.
+func (k *Kafka) clientConfig() Config {
+	config := k.KafkaConfig
+	if k.KafkaURL != "" {
+		config.KafkaURL = k.KafkaURL
+	}
+	return config
 }
 func (k *Kafka) GetConfig() Config                         { return k.KafkaConfig }
 func (k *Kafka) GetSourceType() string                     { return k.SourceType }
@@ -168,80 +203,36 @@ func (k *Kafka) Close() {
 	}
 }
 func (k *Kafka) SyncData() {
-	db, openErr := sql.Open(k.DestinationType, k.DestinationConnectionString)
-	if openErr != nil {
-		logz.Error("erro ao conectar ao banco de dados de destino: "+openErr.Error(), map[string]interface{}{})
-		return
-	}
-	defer db.Close()
-
-	kafkaReader := k.GetKafkaReader()
-	for {
-		msg, kafkaReaderErr := kafkaReader.ReadMessage(context.Background())
-		if kafkaReaderErr != nil {
-			logz.Error("erro ao ler mensagem do Kafka: "+kafkaReaderErr.Error(), map[string]interface{}{})
-			continue
-		}
-
-		var row = make(map[string]interface{})
-		if unmarshalErr := json.Unmarshal(msg.Value, &row); unmarshalErr != nil {
-			logz.Error("erro ao decodificar mensagem do Kafka: "+unmarshalErr.Error(), map[string]interface{}{})
-			continue
-		}
-
-		if loadDataErr := s.LoadData(db, k.KafkaConfig); loadDataErr != nil {
-			logz.Error("erro ao carregar dados no banco de destino: "+loadDataErr.Error(), map[string]interface{}{})
-		}
+	if syncErr := k.SyncDataContext(context.Background()); syncErr != nil {
+		logz.Error("erro ao sincronizar dados do Kafka: "+syncErr.Error(), map[string]interface{}{})
 	}
 }
-func (k *Kafka) RunETL() error {
-	db, dbErr := sql.Open(k.SourceType, k.SourceConnectionString)
-	if dbErr != nil {
-		return fmt.Errorf("falha ao conectar ao banco de dados de origem: %w", dbErr)
-	}
-	defer db.Close()
 
-	rows, rowsErr := db.Query(k.KafkaConfig.SQLQuery)
-	if rowsErr != nil {
-		return fmt.Errorf("falha ao executar a consulta SQL: %w", rowsErr)
+// SyncDataContext consome o tópico até ctx ser cancelado, carregando os dados no destino do Kafka.
+func (k *Kafka) SyncDataContext(ctx context.Context) error {
+	config := k.KafkaConfig
+	config.DestinationType = k.DestinationType
+	config.DestinationConnectionString = k.DestinationConnectionString
+	reader, readerErr := k.kafkaReader()
+	if readerErr != nil {
+		return readerErr
 	}
-	defer rows.Close()
-
-	columns, columnsErr := rows.Columns()
-	if columnsErr != nil {
-		return fmt.Errorf("falha ao obter colunas: %w", columnsErr)
-	}
-
-	for rows.Next() {
-		values := make([]interface{}, len(columns))
-		valuePtrs := make([]interface{}, len(columns))
-		for i := range values {
-			valuePtrs[i] = &values[i]
-		}
-
-		if err := rows.Scan(valuePtrs...); err != nil {
-			return fmt.Errorf("falha ao escanear linha: %w", err)
-		}
-
-		rowMap := make(map[string]interface{})
-		for i, col := range columns {
-			rowMap[col] = values[i]
-		}
-
-		message, err := json.Marshal(rowMap)
-		if err != nil {
-			return fmt.Errorf("falha ao serializar linha: %w", err)
-		}
+	return SyncDataContext(ctx, config, reader)
+}
+func (k *Kafka) RunETL() error {
+	return k.RunETLContext(context.Background())
+}
 
-		err = k.GetKafkaWriter().WriteMessages(context.Background(), kafka.Message{
-			Value: message,
-		})
-		if err != nil {
-			return fmt.Errorf("falha ao escrever mensagem no Kafka: %w", err)
-		}
+// RunETLContext publica no tópico as linhas da consulta de origem, parando quando ctx é cancelado.
+func (k *Kafka) RunETLContext(ctx context.Context) error {
+	config := k.KafkaConfig
+	config.SourceType = k.SourceType
+	config.SourceConnectionString = k.SourceConnectionString
+	writer, writerErr := k.kafkaWriter()
+	if writerErr != nil {
+		return writerErr
 	}
-
-	return nil
+	return RunETLContext(ctx, config, writer)
 }
 
 func CreateKafkaConfig(kafkaURL, topic, groupID, sourceType, sourceConnectionString, destinationType, destinationConnectionString string) Config {
@@ -290,16 +281,21 @@ func CreateKafkaConfigWithSQLAndTopicAndGroupID(kafkaURL, topic, groupID, source
 	}
 }
 
+// CreateKafkaWriter cria o Writer de topic nos brokers de kafkaURL, separados por vírgulas, sem TLS
+// nem autenticação; NewKafkaWriter usa as opções de segurança da Config.
 func CreateKafkaWriter(kafkaURL, topic string) *kafka.Writer {
 	return &kafka.Writer{
-		Addr:     kafka.TCP(kafkaURL),
+		Addr:     kafka.TCP(SplitKafkaBrokers(kafkaURL)...),
 		Topic:    topic,
 		Balancer: &kafka.LeastBytes{},
 	}
 }
+
+// CreateKafkaReader cria o Reader de topic nos brokers de kafkaURL, separados por vírgulas, sem TLS
+// nem autenticação; NewKafkaReader usa as opções de segurança e de leitura da Config.
 func CreateKafkaReader(kafkaURL, topic, groupID string) *kafka.Reader {
 	return kafka.NewReader(kafka.ReaderConfig{
-		Brokers: []string{kafkaURL},
+		Brokers: SplitKafkaBrokers(kafkaURL),
 		Topic:   topic,
 		GroupID: groupID,
 	})
@@ -307,53 +303,103 @@ func CreateKafkaReader(kafkaURL, topic, groupID string) *kafka.Reader {
 
 func CreateKafkaReaderWithConfig(kafkaURL, topic, groupID string) *kafka.Reader {
 	return kafka.NewReader(kafka.ReaderConfig{
-		Brokers: []string{kafkaURL},
+		Brokers: SplitKafkaBrokers(kafkaURL),
 		Topic:   topic,
 		GroupID: groupID,
 	})
 }
 func CreateKafkaWriterWithConfig(kafkaURL, topic string) *kafka.Writer {
 	return &kafka.Writer{
-		Addr:     kafka.TCP(kafkaURL),
+		Addr:     kafka.TCP(SplitKafkaBrokers(kafkaURL)...),
 		Topic:    topic,
 		Balancer: &kafka.LeastBytes{},
 	}
 }
 
 func SyncData(config Config, kafkaReader *kafka.Reader) {
-	db, openErr := sql.Open(config.DestinationType, config.DestinationConnectionString)
-	if openErr != nil {
-		logz.Error("erro ao conectar ao banco de dados de destino: "+openErr.Error(), map[string]interface{}{})
-		return
-	}
-	defer db.Close()
-
-	for {
-		msg, kafkaReaderErr := kafkaReader.ReadMessage(context.Background())
-		if kafkaReaderErr != nil {
-			logz.Error("erro ao ler mensagem do Kafka: "+kafkaReaderErr.Error(), map[string]interface{}{})
-			continue
-		}
-
-		var row = make(map[string]interface{})
-		if unmarshalErr := json.Unmarshal(msg.Value, &row); unmarshalErr != nil {
-			logz.Error("erro ao decodificar mensagem do Kafka: "+unmarshalErr.Error(), map[string]interface{}{})
-			continue
-		}
+	if syncErr := SyncDataContext(context.Background(), config, kafkaReader); syncErr != nil {
+		logz.Error("erro ao sincronizar dados do Kafka: "+syncErr.Error(), map[string]interface{}{})
+	}
+}
 
-		if loadDataErr := s.LoadData(db, config); loadDataErr != nil {
-			logz.Error("erro ao carregar dados no banco de destino: "+loadDataErr.Error(), map[string]interface{}{})
-		}
+// SyncDataContext consome as mensagens do kafkaReader e as grava no destino até ctx ser cancelado.
+// As mensagens são agrupadas em lotes de até kafka.consumer.batchSize (ou as que chegarem em
+// flushInterval), transformadas e gravadas em uma transação, e cada linha substitui a de mesma
+// chave (primaryKey) no destino; as alterações no envelope do Debezium ou publicadas por cdc.sink
+// kafka também podem excluí-la, e as mensagens avro e protobuf são decodificadas com o schema lido de
+// kafka.schemaRegistry. Gravar um lote de novo leva ao mesmo resultado. Os offsets só são
+// confirmados após o commit; se a gravação falhar, o consumo é encerrado com o erro e o lote é lido
+// de novo na próxima execução (entrega at-least-once). O lote em gravação é concluído (ou revertido,
+// se o timeout de consumo expirar) antes de retornar; o cancelamento de ctx não é tratado como erro.
+// Com kafka.consumer.deadLetterTopic, as mensagens que falham não encerram o consumo: vão para o
+// tópico de retentativa, consumido em paralelo com o mesmo grupo após o backoff, e depois para o
+// dead-letter. Com kafka.consumer.routes, kafkaReader também lê os tópicos das rotas, e cada mensagem
+// é gravada na tabela da sua rota.
+func SyncDataContext(ctx context.Context, config Config, kafkaReader *kafka.Reader) error {
+	if config.Kafka.Consumer.DeadLetterTopic == "" {
+		return consumeToDestination(ctx, config, kafkaReader, nil)
+	}
+	// O tópico de retentativa é lido com as mesmas opções e o mesmo grupo do de origem.
+	readerConfig := kafkaReader.Config()
+	transport, transportErr := NewKafkaTransport(config)
+	if transportErr != nil {
+		return transportErr
+	}
+	deadLetters := &kafka.Writer{Addr: kafka.TCP(readerConfig.Brokers...), Balancer: &kafka.Hash{}}
+	if transport != nil {
+		deadLetters.Transport = transport
 	}
+	defer func(writer *kafka.Writer) {
+		_ = writer.Close()
+	}(deadLetters)
+	readerConfig.Topic, readerConfig.GroupTopics = RetryTopic(config), nil
+	retryReader := kafka.NewReader(readerConfig)
+	defer func(reader *kafka.Reader) {
+		_ = reader.Close()
+	}(retryReader)
+
+	consumeCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make([]error, 2)
+	var wg sync.WaitGroup
+	for i, reader := range []*kafka.Reader{kafkaReader, retryReader} {
+		wg.Add(1)
+		go func(i int, reader *kafka.Reader) {
+			defer wg.Done()
+			if errs[i] = consumeToDestination(consumeCtx, config, reader, deadLetters); errs[i] != nil {
+				cancel()
+			}
+		}(i, reader)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
 }
 func RunETL(config Config, kafkaWriter *kafka.Writer) error {
-	db, dbErr := sql.Open(config.SourceType, config.SourceConnectionString)
+	return RunETLContext(context.Background(), config, kafkaWriter)
+}
+
+// RunETLContext publica as linhas da consulta de origem no kafkaWriter, respeitando o cancelamento
+// de ctx e o timeout de extração da Config. As mensagens têm a chave e os cabeçalhos de kafka.producer
+// e são escritas em lotes de batchSize; com kafka.format debezium, cada linha é uma leitura ("r"), e
+// com avro ou protobuf, é serializada com o schema deduzido dos tipos das colunas, registrado em
+// kafka.schemaRegistry após a verificação de compatibilidade. Ao ser interrompido, informa quantas
+// linhas foram publicadas.
+func RunETLContext(ctx context.Context, config Config, kafkaWriter *kafka.Writer) error {
+	if configureErr := ConfigureWriter(kafkaWriter, config); configureErr != nil {
+		return configureErr
+	}
+	db, dbErr := s.OpenSource(ctx, config)
 	if dbErr != nil {
-		return fmt.Errorf("falha ao conectar ao banco de dados de origem: %w", dbErr)
+		return dbErr
+	}
+
+	extractCtx, cancel, timeoutErr := WithStageTimeout(ctx, config.Timeouts.Extract)
+	if timeoutErr != nil {
+		return timeoutErr
 	}
-	defer db.Close()
+	defer cancel()
 
-	rows, rowsErr := db.Query(config.SQLQuery)
+	rows, rowsErr := db.QueryContext(extractCtx, config.SQLQuery)
 	if rowsErr != nil {
 		return fmt.Errorf("falha ao executar a consulta SQL: %w", rowsErr)
 	}
@@ -364,6 +410,42 @@ func RunETL(config Config, kafkaWriter *kafka.Writer) error {
 		return fmt.Errorf("falha ao obter colunas: %w", columnsErr)
 	}
 
+	var serializer *rowSerializer
+	if config.Kafka.Format == "avro" || config.Kafka.Format == "protobuf" {
+		columnTypes, typesErr := rows.ColumnTypes()
+		if typesErr != nil {
+			return fmt.Errorf("falha ao obter os tipos das colunas: %w", typesErr)
+		}
+		databaseTypes := make([]string, len(columnTypes))
+		for i, columnType := range columnTypes {
+			databaseTypes[i] = columnType.DatabaseTypeName()
+		}
+		var serializerErr error
+		if serializer, serializerErr = newRowSerializer(extractCtx, config, MessageSchemaFields(columns, databaseTypes)); serializerErr != nil {
+			return serializerErr
+		}
+	}
+
+	key := ProducerKey(config)
+	headers := messageHeaders(config, meta.NewRunID())
+	batchSize := ProducerBatchSize(config)
+	batch := make([]kafka.Message, 0, batchSize)
+	published := 0
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := kafkaWriter.WriteMessages(extractCtx, batch...); err != nil {
+			if extractCtx.Err() != nil {
+				return interruptedPublish(published, extractCtx.Err())
+			}
+			return fmt.Errorf("falha ao escrever mensagens no Kafka: %w", err)
+		}
+		published += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
 	for rows.Next() {
 		values := make([]interface{}, len(columns))
 		valuePtrs := make([]interface{}, len(columns))
@@ -380,18 +462,48 @@ func RunETL(config Config, kafkaWriter *kafka.Writer) error {
 			rowMap[col] = values[i]
 		}
 
-		message, err := json.Marshal(rowMap)
+		var message []byte
+		var err error
+		switch {
+		case serializer != nil:
+			message, err = serializer.serialize(rowMap)
+		case config.Kafka.Format == "debezium":
+			message, err = json.Marshal(NewDebeziumSnapshot(config, rowMap, time.Now()))
+		default:
+			message, err = json.Marshal(rowMap)
+		}
 		if err != nil {
 			return fmt.Errorf("falha ao serializar linha: %w", err)
 		}
+		messageKey, keyErr := MessageKey(key, rowMap)
+		if keyErr != nil {
+			return keyErr
+		}
 
-		err = kafkaWriter.WriteMessages(context.Background(), kafka.Message{
-			Value: message,
-		})
-		if err != nil {
-			return fmt.Errorf("falha ao escrever mensagem no Kafka: %w", err)
+		batch = append(batch, kafka.Message{Key: messageKey, Value: message, Headers: headers})
+		if len(batch) == batchSize {
+			if flushErr := flush(); flushErr != nil {
+				return flushErr
+			}
 		}
 	}
 
+	if rowsErr := rows.Err(); rowsErr != nil {
+		if extractCtx.Err() != nil {
+			return interruptedPublish(published, extractCtx.Err())
+		}
+		return fmt.Errorf("falha ao ler as linhas: %w", rowsErr)
+	}
+	if flushErr := flush(); flushErr != nil {
+		return flushErr
+	}
+
+	logz.Info(fmt.Sprintf("%d mensagem(ns) publicada(s) no Kafka", published), map[string]interface{}{})
 	return nil
 }
+
+// interruptedPublish registra quantas mensagens foram publicadas antes da interrupção.
+func interruptedPublish(published int, cause error) error {
+	logz.Warn(fmt.Sprintf("publicação interrompida após %d mensagem(ns)", published), map[string]interface{}{})
+	return fmt.Errorf("publicação interrompida após %d mensagem(ns): %w", published, cause)
+}
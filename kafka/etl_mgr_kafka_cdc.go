@@ -0,0 +1,98 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	. "github.com/faelmori/getl/etypes"
+	s "github.com/faelmori/getl/sql"
+	. "github.com/faelmori/getl/utils"
+	"github.com/segmentio/kafka-go"
+	"time"
+)
+
+// ChangePublisher publica as alterações capturadas no tópico do Writer, uma mensagem JSON por
+// alteração, com a chave de kafka.producer (padrão: a chave primária da origem) como chave da
+// mensagem: as alterações de uma mesma linha vão para a mesma partição e mantêm a ordem.
+type ChangePublisher struct {
+	Writer *kafka.Writer
+	// Key é a coluna ou o modelo da chave das mensagens (MessageKey).
+	Key     string
+	Headers []kafka.Header
+	// Config é a da pipeline; com kafka.format debezium, as alterações são publicadas no envelope do Debezium.
+	Config Config
+	// Positions registra a posição da origem quando ela não fica no servidor, como no binlog; nil
+	// na replicação lógica, em que a posição fica no slot.
+	Positions *s.PositionStore
+}
+
+// NewChangeSink cria o ChangeSink da pipeline: o tópico kafkaTopic com cdc.sink kafka, ou o destino da pipeline.
+func NewChangeSink(ctx context.Context, pipeline Pipeline) (s.ChangeSink, error) {
+	if pipeline.CDC.Sink != "kafka" {
+		return s.NewDestinationSink(ctx, pipeline)
+	}
+	if len(KafkaBrokers(pipeline.Config)) == 0 || pipeline.KafkaTopic == "" {
+		return nil, fmt.Errorf("cdc.sink kafka exige kafkaURL (ou kafka.brokers) e kafkaTopic")
+	}
+	publisher := &ChangePublisher{Key: ProducerKey(pipeline.Config), Headers: messageHeaders(pipeline.Config, ""), Config: pipeline.Config}
+	if pipeline.CDC.Mode == "binlog" {
+		positions, storeErr := s.OpenPositionStore(ctx, pipeline.Config)
+		if storeErr != nil {
+			return nil, storeErr
+		}
+		publisher.Positions = positions
+	}
+	writer, writerErr := NewProducerWriter(pipeline.Config)
+	if writerErr != nil {
+		return nil, writerErr
+	}
+	publisher.Writer = writer
+	return publisher, nil
+}
+
+// Position retorna a posição registrada em Positions ou, sem ele, "": a posição fica só no slot.
+// Uma transação publicada mas ainda não registrada pode ser publicada de novo.
+func (p *ChangePublisher) Position(ctx context.Context) (string, error) {
+	if p.Positions == nil {
+		return "", nil
+	}
+	return p.Positions.Load(ctx)
+}
+
+// Apply publica as alterações de uma transação em uma única escrita e registra a posição em Positions.
+func (p *ChangePublisher) Apply(ctx context.Context, events []ChangeEvent, position string) error {
+	messages := make([]kafka.Message, 0, len(events))
+	for _, event := range events {
+		var value []byte
+		var marshalErr error
+		if p.Config.Kafka.Format == "debezium" {
+			value, marshalErr = json.Marshal(NewDebeziumEnvelope(p.Config, event, time.Now()))
+		} else {
+			value, marshalErr = json.Marshal(event)
+		}
+		if marshalErr != nil {
+			return fmt.Errorf("falha ao serializar a alteração de %s: %w", event.Table, marshalErr)
+		}
+		row := event.After
+		if row == nil {
+			row = event.Before
+		}
+		key, keyErr := MessageKey(p.Key, row)
+		if keyErr != nil {
+			return fmt.Errorf("alteração de %s: %w", event.Table, keyErr)
+		}
+		messages = append(messages, kafka.Message{Key: key, Value: value, Headers: p.Headers})
+	}
+	if len(messages) > 0 {
+		if writeErr := p.Writer.WriteMessages(ctx, messages...); writeErr != nil {
+			return fmt.Errorf("falha ao publicar as alterações no tópico %s: %w", p.Writer.Topic, writeErr)
+		}
+	}
+	if p.Positions != nil && position != "" {
+		return p.Positions.Save(ctx, position)
+	}
+	return nil
+}
+
+// Close fecha o Writer, aguardando as mensagens pendentes.
+func (p *ChangePublisher) Close() error { return p.Writer.Close() }
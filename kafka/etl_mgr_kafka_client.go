@@ -0,0 +1,216 @@
+package kafka
+
+import (
+	"fmt"
+	. "github.com/faelmori/getl/etypes"
+	. "github.com/faelmori/getl/utils"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+	"github.com/twmb/franz-go/pkg/kgo"
+	franzsasl "github.com/twmb/franz-go/pkg/sasl"
+	franzplain "github.com/twmb/franz-go/pkg/sasl/plain"
+	franzscram "github.com/twmb/franz-go/pkg/sasl/scram"
+	"time"
+)
+
+// NewKafkaReader cria o Reader de topic no grupo groupID com os brokers, a segurança e as opções de
+// kafka.reader de config.
+func NewKafkaReader(config Config, topic, groupID string) (*kafka.Reader, error) {
+	return NewKafkaGroupReader(config, []string{topic}, groupID)
+}
+
+// NewKafkaGroupReader cria o Reader dos tópicos topics no grupo groupID, como NewKafkaReader. Com mais
+// de um tópico, o Reader usa GroupTopics e exige groupID.
+func NewKafkaGroupReader(config Config, topics []string, groupID string) (*kafka.Reader, error) {
+	if len(topics) == 0 {
+		return nil, fmt.Errorf("nenhum tópico para consumir")
+	}
+	brokers := KafkaBrokers(config)
+	if len(brokers) == 0 {
+		return nil, fmt.Errorf("informe kafkaURL ou kafka.brokers")
+	}
+	dialer, dialerErr := NewKafkaDialer(config)
+	if dialerErr != nil {
+		return nil, dialerErr
+	}
+	commitInterval, intervalErr := ReaderCommitInterval(config)
+	if intervalErr != nil {
+		return nil, intervalErr
+	}
+	options := config.Kafka.Reader
+	readerConfig := kafka.ReaderConfig{
+		Brokers:        brokers,
+		GroupID:        groupID,
+		Dialer:         dialer,
+		MinBytes:       options.MinBytes,
+		MaxBytes:       options.MaxBytes,
+		CommitInterval: commitInterval,
+	}
+	if len(topics) == 1 {
+		readerConfig.Topic = topics[0]
+	} else {
+		readerConfig.GroupTopics = topics
+	}
+	switch options.StartOffset {
+	case "", "earliest":
+		readerConfig.StartOffset = kafka.FirstOffset
+	case "latest":
+		readerConfig.StartOffset = kafka.LastOffset
+	default:
+		return nil, fmt.Errorf("kafka.reader.startOffset desconhecido: %s", options.StartOffset)
+	}
+	switch options.IsolationLevel {
+	case "", "read_uncommitted":
+		readerConfig.IsolationLevel = kafka.ReadUncommitted
+	case "read_committed":
+		readerConfig.IsolationLevel = kafka.ReadCommitted
+	default:
+		return nil, fmt.Errorf("kafka.reader.isolationLevel desconhecido: %s", options.IsolationLevel)
+	}
+	// kafka.NewReader entra em pânico com uma configuração inválida.
+	if validateErr := readerConfig.Validate(); validateErr != nil {
+		return nil, fmt.Errorf("kafka.reader: %w", validateErr)
+	}
+	return kafka.NewReader(readerConfig), nil
+}
+
+// NewKafkaWriter cria o Writer de topic (vazio: o de cada mensagem) com os brokers e a segurança de config.
+func NewKafkaWriter(config Config, topic string) (*kafka.Writer, error) {
+	brokers := KafkaBrokers(config)
+	if len(brokers) == 0 {
+		return nil, fmt.Errorf("informe kafkaURL ou kafka.brokers")
+	}
+	transport, transportErr := NewKafkaTransport(config)
+	if transportErr != nil {
+		return nil, transportErr
+	}
+	writer := &kafka.Writer{
+		Addr:     kafka.TCP(brokers...),
+		Topic:    topic,
+		Balancer: &kafka.LeastBytes{},
+	}
+	if transport != nil {
+		writer.Transport = transport
+	}
+	return writer, nil
+}
+
+// NewKafkaClient cria o cliente das APIs administrativas dos brokers de config, como a leitura e a
+// redefinição dos offsets dos grupos.
+func NewKafkaClient(config Config) (*kafka.Client, error) {
+	brokers := KafkaBrokers(config)
+	if len(brokers) == 0 {
+		return nil, fmt.Errorf("informe kafkaURL ou kafka.brokers")
+	}
+	transport, transportErr := NewKafkaTransport(config)
+	if transportErr != nil {
+		return nil, transportErr
+	}
+	client := &kafka.Client{Addr: kafka.TCP(brokers...), Timeout: 30 * time.Second}
+	if transport != nil {
+		client.Transport = transport
+	}
+	return client, nil
+}
+
+// NewKafkaDialer cria o Dialer das conexões do Reader e dos comandos administrativos, com o TLS, o
+// SASL e o clientID de config.
+func NewKafkaDialer(config Config) (*kafka.Dialer, error) {
+	tlsConfig, tlsErr := KafkaTLSConfig(config)
+	if tlsErr != nil {
+		return nil, tlsErr
+	}
+	mechanism, saslErr := saslMechanism(config.Kafka.SASL)
+	if saslErr != nil {
+		return nil, saslErr
+	}
+	dialer := &kafka.Dialer{
+		Timeout:       10 * time.Second,
+		DualStack:     true,
+		ClientID:      config.Kafka.ClientID,
+		TLS:           tlsConfig,
+		SASLMechanism: mechanism,
+	}
+	if dialer.ClientID == "" {
+		dialer.ClientID = kafka.DefaultClientID
+	}
+	return dialer, nil
+}
+
+// NewKafkaTransport cria o Transport do Writer com o TLS, o SASL e o clientID de config, ou nil sem
+// eles, mantendo o kafka.DefaultTransport.
+func NewKafkaTransport(config Config) (*kafka.Transport, error) {
+	tlsConfig, tlsErr := KafkaTLSConfig(config)
+	if tlsErr != nil {
+		return nil, tlsErr
+	}
+	mechanism, saslErr := saslMechanism(config.Kafka.SASL)
+	if saslErr != nil {
+		return nil, saslErr
+	}
+	if tlsConfig == nil && mechanism == nil && config.Kafka.ClientID == "" {
+		return nil, nil
+	}
+	return &kafka.Transport{TLS: tlsConfig, SASL: mechanism, ClientID: config.Kafka.ClientID}, nil
+}
+
+// franzOptions retorna as opções do cliente franz-go com os brokers, o TLS, o SASL e o clientID de
+// config. O franz-go é usado onde o kafka-go não basta, como no produtor transacional de getl stream.
+func franzOptions(config Config) ([]kgo.Opt, error) {
+	brokers := KafkaBrokers(config)
+	if len(brokers) == 0 {
+		return nil, fmt.Errorf("informe kafkaURL ou kafka.brokers")
+	}
+	tlsConfig, tlsErr := KafkaTLSConfig(config)
+	if tlsErr != nil {
+		return nil, tlsErr
+	}
+	options := []kgo.Opt{kgo.SeedBrokers(brokers...), kgo.DialTimeout(10 * time.Second)}
+	if tlsConfig != nil {
+		options = append(options, kgo.DialTLSConfig(tlsConfig))
+	}
+	if config.Kafka.ClientID != "" {
+		options = append(options, kgo.ClientID(config.Kafka.ClientID))
+	}
+	var mechanism franzsasl.Mechanism
+	auth := config.Kafka.SASL
+	switch auth.Mechanism {
+	case "":
+	case "plain":
+		mechanism = franzplain.Auth{User: auth.Username, Pass: auth.Password}.AsMechanism()
+	case "scram-sha-256":
+		mechanism = franzscram.Auth{User: auth.Username, Pass: auth.Password}.AsSha256Mechanism()
+	case "scram-sha-512":
+		mechanism = franzscram.Auth{User: auth.Username, Pass: auth.Password}.AsSha512Mechanism()
+	default:
+		return nil, fmt.Errorf("kafka.sasl.mechanism desconhecido: %s", auth.Mechanism)
+	}
+	if mechanism != nil {
+		options = append(options, kgo.SASL(mechanism))
+	}
+	return options, nil
+}
+
+// saslMechanism retorna o mecanismo de kafka.sasl, ou nil sem autenticação.
+func saslMechanism(options KafkaSASL) (sasl.Mechanism, error) {
+	switch options.Mechanism {
+	case "":
+		return nil, nil
+	case "plain":
+		return plain.Mechanism{Username: options.Username, Password: options.Password}, nil
+	case "scram-sha-256", "scram-sha-512":
+		algorithm := scram.SHA256
+		if options.Mechanism == "scram-sha-512" {
+			algorithm = scram.SHA512
+		}
+		mechanism, err := scram.Mechanism(algorithm, options.Username, options.Password)
+		if err != nil {
+			return nil, fmt.Errorf("kafka.sasl: %w", err)
+		}
+		return mechanism, nil
+	default:
+		return nil, fmt.Errorf("kafka.sasl.mechanism desconhecido: %s", options.Mechanism)
+	}
+}
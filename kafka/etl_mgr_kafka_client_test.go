@@ -0,0 +1,77 @@
+package kafka
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/faelmori/getl/etypes"
+	"github.com/segmentio/kafka-go"
+)
+
+// TestNewKafkaReader verifica as opções de kafka.reader e a segurança no Dialer do Reader.
+func TestNewKafkaReader(t *testing.T) {
+	config := Config{KafkaURL: "a:9092,b:9092", Kafka: KafkaOptions{
+		ClientID: "getl-test",
+		TLS:      KafkaTLS{Enabled: true, ServerName: "kafka"},
+		SASL:     KafkaSASL{Mechanism: "scram-sha-512", Username: "getl", Password: "secret"},
+		Reader:   KafkaReader{StartOffset: "latest", MinBytes: 10, MaxBytes: 2048, CommitInterval: "2s", IsolationLevel: "read_committed"},
+	}}
+	reader, err := NewKafkaReader(config, "items", "group")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	readerConfig := reader.Config()
+	if len(readerConfig.Brokers) != 2 || readerConfig.StartOffset != kafka.LastOffset || readerConfig.IsolationLevel != kafka.ReadCommitted ||
+		readerConfig.MinBytes != 10 || readerConfig.MaxBytes != 2048 || readerConfig.CommitInterval != 2*time.Second {
+		t.Errorf("ReaderConfig = %+v", readerConfig)
+	}
+	dialer := readerConfig.Dialer
+	if dialer.ClientID != "getl-test" || dialer.TLS == nil || dialer.TLS.ServerName != "kafka" || dialer.SASLMechanism.Name() != "SCRAM-SHA-512" {
+		t.Errorf("Dialer = %+v", dialer)
+	}
+
+	for _, options := range []KafkaOptions{
+		{Reader: KafkaReader{MinBytes: 2048, MaxBytes: 1024}},
+		{Reader: KafkaReader{StartOffset: "middle"}},
+		{SASL: KafkaSASL{Mechanism: "gssapi"}},
+	} {
+		if _, err := NewKafkaReader(Config{KafkaURL: "a:9092", Kafka: options}, "items", "group"); err == nil {
+			t.Errorf("NewKafkaReader(%+v) sem erro", options)
+		}
+	}
+	if _, err := NewKafkaReader(Config{}, "items", "group"); err == nil {
+		t.Errorf("NewKafkaReader() sem brokers e sem erro")
+	}
+
+	groupReader, err := NewKafkaGroupReader(Config{KafkaURL: "a:9092"}, []string{"items", "orders"}, "group")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer groupReader.Close()
+	if groupConfig := groupReader.Config(); groupConfig.Topic != "" || len(groupConfig.GroupTopics) != 2 {
+		t.Errorf("ReaderConfig com dois tópicos = %+v", groupConfig)
+	}
+	if _, err := NewKafkaGroupReader(Config{KafkaURL: "a:9092"}, []string{"items", "orders"}, ""); err == nil {
+		t.Errorf("NewKafkaGroupReader() com dois tópicos sem grupo e sem erro")
+	}
+}
+
+// TestNewKafkaWriter verifica que o Writer só tem um Transport próprio com as opções de segurança.
+func TestNewKafkaWriter(t *testing.T) {
+	writer, err := NewKafkaWriter(Config{KafkaURL: "a:9092, b:9092"}, "items")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if writer.Transport != nil || writer.Addr.String() != "a:9092,b:9092" {
+		t.Errorf("Writer sem segurança: %v %s", writer.Transport, writer.Addr)
+	}
+	config := Config{Kafka: KafkaOptions{Brokers: []string{"c:9093"}, SASL: KafkaSASL{Mechanism: "plain", Username: "getl", Password: "secret"}}}
+	if writer, err = NewKafkaWriter(config, "items"); err != nil {
+		t.Fatal(err)
+	}
+	transport, ok := writer.Transport.(*kafka.Transport)
+	if !ok || transport.SASL == nil || transport.SASL.Name() != "PLAIN" || writer.Addr.String() != "c:9093" {
+		t.Errorf("Writer com SASL: %+v %s", writer.Transport, writer.Addr)
+	}
+}
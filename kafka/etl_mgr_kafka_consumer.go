@@ -0,0 +1,396 @@
+package kafka
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	. "github.com/faelmori/getl/etypes"
+	s "github.com/faelmori/getl/sql"
+	. "github.com/faelmori/getl/utils"
+	"github.com/faelmori/logz"
+	"github.com/segmentio/kafka-go"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// messageReader é a parte do kafka.Reader usada no consumo para o destino.
+type messageReader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Config() kafka.ReaderConfig
+}
+
+// destinationConsumer grava no destino da pipeline os lotes de mensagens lidos do tópico.
+type destinationConsumer struct {
+	config Config
+	reader messageReader
+	db     *sql.DB
+	// routes são as tabelas das rotas de kafka.consumer.routes, na mesma ordem, e fallback a de
+	// destinationTable, que recebe as mensagens sem rota; nil sem destinationTable.
+	routes   []*tableTarget
+	fallback *tableTarget
+	// registry lê os schemas das mensagens avro e protobuf; nil sem kafka.schemaRegistry.url.
+	registry *RegistryClient
+	// deadLetters publica as mensagens que falharam nos tópicos de retentativa e de dead-letter;
+	// nil sem kafka.consumer.deadLetterTopic.
+	deadLetters messageWriter
+	metrics     *ConsumerMetrics
+}
+
+// tableTarget é uma tabela de destino do consumo. O sink é aberto e a tabela é criada na primeira
+// gravação.
+type tableTarget struct {
+	config Config
+	sink   s.ChangeSink
+	ready  bool
+}
+
+// tableBatch são as mensagens de um lote gravadas em uma tabela.
+type tableBatch struct {
+	target   *tableTarget
+	messages []kafka.Message
+	rows     []Data
+	events   []ChangeEvent
+}
+
+// ConsumePipelines consome o kafkaTopic de cada pipeline para o seu destino, em paralelo, até ctx
+// ser cancelado. Se o consumo de uma pipeline falhar, os demais são encerrados e o erro é retornado.
+func ConsumePipelines(ctx context.Context, pipelines []Pipeline) error {
+	for _, pipeline := range pipelines {
+		if len(KafkaBrokers(pipeline.Config)) == 0 || pipeline.KafkaTopic == "" || pipeline.KafkaGroupID == "" {
+			return fmt.Errorf("pipeline %s: o consumo exige kafkaURL (ou kafka.brokers), kafkaTopic e kafkaGroupID", pipeline.Name)
+		}
+	}
+	readers := make([]*kafka.Reader, len(pipelines))
+	for i, pipeline := range pipelines {
+		reader, readerErr := NewKafkaGroupReader(pipeline.Config, ConsumerTopics(pipeline.Config), pipeline.KafkaGroupID)
+		if readerErr != nil {
+			for _, opened := range readers[:i] {
+				_ = opened.Close()
+			}
+			return fmt.Errorf("pipeline %s: %w", pipeline.Name, readerErr)
+		}
+		readers[i] = reader
+	}
+
+	consumeCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make([]error, len(pipelines))
+	var wg sync.WaitGroup
+	for i, pipeline := range pipelines {
+		wg.Add(1)
+		go func(i int, pipeline Pipeline, reader *kafka.Reader) {
+			defer wg.Done()
+			defer func(reader *kafka.Reader) {
+				_ = reader.Close()
+			}(reader)
+			logz.Info(fmt.Sprintf("pipeline %s: consumindo %s para %s", pipeline.Name, strings.Join(ConsumerTopics(pipeline.Config), ", "), strings.Join(destinationTables(pipeline.Config), ", ")), map[string]interface{}{})
+			if consumeErr := SyncDataContext(consumeCtx, pipeline.Config, reader); consumeErr != nil {
+				errs[i] = fmt.Errorf("pipeline %s: %w", pipeline.Name, consumeErr)
+				cancel()
+			}
+		}(i, pipeline, readers[i])
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// consumeToDestination executa o consumo descrito em SyncDataContext de um tópico, o de origem ou o
+// de retentativa. deadLetters é nil sem kafka.consumer.deadLetterTopic.
+func consumeToDestination(ctx context.Context, config Config, reader messageReader, deadLetters messageWriter) error {
+	if reader.Config().GroupID == "" {
+		return fmt.Errorf("o consumo exige kafkaGroupID para confirmar os offsets")
+	}
+	consumer := &destinationConsumer{config: config, reader: reader, deadLetters: deadLetters}
+	for _, route := range config.Kafka.Consumer.Routes {
+		consumer.routes = append(consumer.routes, &tableTarget{config: RouteConfig(config, route)})
+	}
+	if config.DestinationTable != "" {
+		consumer.fallback = &tableTarget{config: config}
+	} else if len(consumer.routes) == 0 {
+		return fmt.Errorf("o consumo exige destinationTable ou kafka.consumer.routes")
+	}
+	for _, target := range consumer.targets() {
+		if _, keyErr := ChangeCaptureKey(target.config); keyErr != nil {
+			return fmt.Errorf("o consumo grava cada mensagem de %s pela chave de destino: %w", target.config.DestinationTable, keyErr)
+		}
+	}
+	flushInterval, intervalErr := ConsumerFlushInterval(config)
+	if intervalErr != nil {
+		return intervalErr
+	}
+
+	db, openErr := s.OpenDestination(ctx, config)
+	if openErr != nil {
+		logz.Error("erro ao conectar ao banco de dados de destino: "+openErr.Error(), map[string]interface{}{})
+		return openErr
+	}
+	consumer.db = db
+	defer consumer.close()
+	topic := config.KafkaTopic
+	if topic == "" {
+		topic = reader.Config().Topic
+	}
+	consumer.metrics = TopicMetrics(topic)
+	if config.Kafka.SchemaRegistry.URL != "" {
+		consumer.registry = NewRegistryClient(config.Kafka.SchemaRegistry)
+	}
+
+	processed := 0
+	for {
+		batch, fetchErr := fetchBatch(ctx, reader, ConsumerBatchSize(config), flushInterval)
+		if wait := retryWait(batch, time.Now()); wait > 0 && fetchErr == nil {
+			// As mensagens do tópico de retentativa esperam o backoff; se ctx for cancelado antes,
+			// o lote não é confirmado e será lido de novo.
+			select {
+			case <-ctx.Done():
+				batch, fetchErr = nil, ctx.Err()
+			case <-time.After(wait):
+			}
+		}
+		if len(batch) > 0 {
+			// O lote já lido é gravado mesmo se ctx for cancelado agora; só o timeout de consumo o interrompe.
+			loaded, loadErr := consumer.load(context.WithoutCancel(ctx), batch)
+			processed += loaded
+			if loadErr != nil {
+				logz.Error(loadErr.Error(), map[string]interface{}{})
+				return loadErr
+			}
+		}
+		if fetchErr != nil {
+			if ctx.Err() != nil {
+				logz.Info(fmt.Sprintf("consumo de %s encerrado após %d mensagem(ns) gravada(s); contadores do tópico: %s", readerTopics(reader), processed, consumer.metrics), map[string]interface{}{})
+				return nil
+			}
+			return fmt.Errorf("erro ao ler mensagem do Kafka: %w", fetchErr)
+		}
+	}
+}
+
+// fetchBatch lê até size mensagens: espera a primeira até ctx ser cancelado e as demais por até
+// flushInterval. Retorna as mensagens lidas mesmo quando a leitura falha.
+func fetchBatch(ctx context.Context, reader messageReader, size int, flushInterval time.Duration) ([]kafka.Message, error) {
+	first, fetchErr := reader.FetchMessage(ctx)
+	if fetchErr != nil {
+		return nil, fetchErr
+	}
+	batch := []kafka.Message{first}
+
+	flushCtx, cancel := context.WithTimeout(ctx, flushInterval)
+	defer cancel()
+	for len(batch) < size {
+		message, nextErr := reader.FetchMessage(flushCtx)
+		if nextErr != nil {
+			if ctx.Err() != nil {
+				return batch, ctx.Err()
+			}
+			if flushCtx.Err() != nil {
+				return batch, nil
+			}
+			return batch, nextErr
+		}
+		batch = append(batch, message)
+	}
+	return batch, nil
+}
+
+// load grava um lote e confirma os offsets. Cada mensagem é uma linha, que substitui a de mesma
+// chave no destino, ou uma alteração (DecodeChangeMessage), aplicada como no cdc; as sem valor
+// (tombstones) são ignoradas. As mensagens vão para a tabela da sua rota (target), e as de cada
+// tabela são gravadas em uma transação; se uma delas falhar, as já gravadas serão gravadas de novo
+// com o lote, com o mesmo resultado. Com o dead-letter, as mensagens que não podem ser decodificadas
+// ou não têm rota e, se o lote de uma tabela falhar, as que falham ao ser gravadas uma a uma vão para
+// route e as demais são gravadas. Sem ele, essas mensagens são registradas e descartadas e uma falha
+// de gravação, ou de leitura do schema de uma mensagem avro ou protobuf, retorna o erro sem confirmar
+// o lote. Retorna quantas mensagens foram aplicadas.
+func (c *destinationConsumer) load(ctx context.Context, batch []kafka.Message) (int, error) {
+	c.metrics.Consumed.Add(int64(len(batch)))
+	var tables []*tableBatch
+	var failed []failedMessage
+	loaded := 0
+	for _, message := range batch {
+		event, ok, decodeErr := c.decode(ctx, message.Value)
+		if decodeErr != nil {
+			if errors.Is(decodeErr, errSchemaUnavailable) && c.deadLetters == nil {
+				// Sem o schema, o lote não é gravado nem confirmado e será lido de novo.
+				return 0, decodeErr
+			}
+			failed = c.reject(failed, message, "decode", decodeErr)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if event.Table == "" {
+			event.Table = message.Topic
+		}
+		if event.Timestamp.IsZero() {
+			event.Timestamp = message.Time
+		}
+		row := event.After
+		if row == nil {
+			row = event.Before
+		}
+		target := c.target(message, row)
+		if target == nil {
+			failed = c.reject(failed, message, "route", fmt.Errorf("nenhuma rota de kafka.consumer.routes atende à mensagem, e destinationTable não foi informado"))
+			continue
+		}
+		i := slices.IndexFunc(tables, func(table *tableBatch) bool { return table.target == target })
+		if i < 0 {
+			i = len(tables)
+			tables = append(tables, &tableBatch{target: target})
+		}
+		tables[i].messages = append(tables[i].messages, message)
+		tables[i].rows = append(tables[i].rows, row)
+		tables[i].events = append(tables[i].events, event)
+		loaded++
+	}
+
+	loadCtx, cancel, timeoutErr := WithStageTimeout(ctx, c.config.Timeouts.Consume)
+	if timeoutErr != nil {
+		return 0, timeoutErr
+	}
+	defer cancel()
+
+	for _, table := range tables {
+		if applyErr := c.apply(loadCtx, table.target, table.rows, table.events); applyErr != nil {
+			if c.deadLetters == nil {
+				return 0, fmt.Errorf("falha ao gravar o lote de %d mensagem(ns) em %s; os offsets não foram confirmados: %w", len(batch), table.target.config.DestinationTable, applyErr)
+			}
+			// Cada mensagem é gravada na sua transação, para separar as que falham.
+			for i := range table.events {
+				if applyErr := c.apply(loadCtx, table.target, table.rows[i:i+1], table.events[i:i+1]); applyErr != nil {
+					failed = append(failed, failedMessage{message: table.messages[i], stage: "load", err: applyErr})
+					loaded--
+				}
+			}
+		}
+	}
+	c.metrics.Loaded.Add(int64(loaded))
+	if routeErr := c.route(loadCtx, failed, time.Now()); routeErr != nil {
+		return loaded, routeErr
+	}
+	if commitErr := c.reader.CommitMessages(loadCtx, batch...); commitErr != nil {
+		return loaded, fmt.Errorf("lote gravado em %s, mas os offsets não foram confirmados e ele será lido de novo: %w", strings.Join(destinationTables(c.config), ", "), commitErr)
+	}
+	return loaded, nil
+}
+
+// reject separa para o dead-letter a mensagem que falhou em stage ou, sem ele, a registra e descarta.
+func (c *destinationConsumer) reject(failed []failedMessage, message kafka.Message, stage string, err error) []failedMessage {
+	if c.deadLetters != nil {
+		return append(failed, failedMessage{message: message, stage: stage, err: err})
+	}
+	logz.Error(fmt.Sprintf("mensagem %d da partição %d de %s descartada: %v", message.Offset, message.Partition, message.Topic, err), map[string]interface{}{})
+	c.metrics.Discarded.Add(1)
+	return failed
+}
+
+// target retorna a tabela da mensagem com a linha row: a da primeira rota que a atende ou, sem
+// nenhuma, a de destinationTable (nil sem ela). Nas mensagens do tópico de retentativa, o tópico
+// comparado é o de origem.
+func (c *destinationConsumer) target(message kafka.Message, row Data) *tableTarget {
+	if len(c.routes) == 0 {
+		return c.fallback
+	}
+	topic := message.Topic
+	if original, ok := header(message, OriginalTopicHeader); ok {
+		topic = original
+	}
+	headers := make(map[string]string, len(message.Headers))
+	for _, h := range message.Headers {
+		headers[h.Key] = string(h.Value)
+	}
+	if i := MatchKafkaRoute(c.config.Kafka.Consumer.Routes, topic, headers, row); i >= 0 {
+		return c.routes[i]
+	}
+	return c.fallback
+}
+
+// apply abre o sink e cria a tabela de target, na sua primeira gravação, e aplica as alterações em
+// uma transação.
+func (c *destinationConsumer) apply(ctx context.Context, target *tableTarget, rows []Data, events []ChangeEvent) error {
+	if target.sink == nil {
+		sink, sinkErr := s.NewDestinationSink(ctx, Pipeline{Name: target.config.DestinationTable, Config: target.config})
+		if sinkErr != nil {
+			return sinkErr
+		}
+		target.sink = sink
+	}
+	if !target.ready {
+		if ensureErr := s.EnsureTableForRows(ctx, c.db, target.config, rows); ensureErr != nil {
+			return fmt.Errorf("falha ao criar a tabela %s: %w", target.config.DestinationTable, ensureErr)
+		}
+		target.ready = true
+	}
+	return target.sink.Apply(ctx, events, "")
+}
+
+// targets retorna as tabelas das rotas e a de destinationTable.
+func (c *destinationConsumer) targets() []*tableTarget {
+	targets := slices.Clone(c.routes)
+	if c.fallback != nil {
+		targets = append(targets, c.fallback)
+	}
+	return targets
+}
+
+// close fecha os sinks abertos.
+func (c *destinationConsumer) close() {
+	for _, target := range c.targets() {
+		if target.sink != nil {
+			_ = target.sink.Close()
+		}
+	}
+}
+
+// destinationTables retorna as tabelas de destino do consumo de config, sem repetições.
+func destinationTables(config Config) []string {
+	var tables []string
+	if config.DestinationTable != "" {
+		tables = append(tables, config.DestinationTable)
+	}
+	for _, route := range config.Kafka.Consumer.Routes {
+		if !slices.Contains(tables, route.Table) {
+			tables = append(tables, route.Table)
+		}
+	}
+	return tables
+}
+
+// readerTopics retorna os tópicos lidos por reader.
+func readerTopics(reader messageReader) string {
+	config := reader.Config()
+	if len(config.GroupTopics) > 0 {
+		return strings.Join(config.GroupTopics, ", ")
+	}
+	return config.Topic
+}
+
+// errSchemaUnavailable indica que o schema de uma mensagem não pôde ser lido do registry ou não é suportado.
+var errSchemaUnavailable = errors.New("schema da mensagem indisponível")
+
+// decode decodifica uma mensagem com DecodeChangeMessage ou, no wire format do schema registry,
+// com o schema Avro ou Protobuf do seu id; essas mensagens são linhas, incluídas ou atualizadas.
+func (c *destinationConsumer) decode(ctx context.Context, value []byte) (ChangeEvent, bool, error) {
+	schemaID, payload, ok := DecodeSchemaMessage(value)
+	if !ok {
+		return DecodeChangeMessage(value)
+	}
+	if c.registry == nil {
+		return ChangeEvent{}, false, fmt.Errorf("%w: a mensagem usa o schema %d do schema registry, mas kafka.schemaRegistry.url não foi configurado", errSchemaUnavailable, schemaID)
+	}
+	codec, codecErr := c.registry.Codec(ctx, schemaID)
+	if codecErr != nil {
+		return ChangeEvent{}, false, fmt.Errorf("%w: %w", errSchemaUnavailable, codecErr)
+	}
+	row, decodeErr := codec.Decode(payload)
+	if decodeErr != nil {
+		return ChangeEvent{}, false, decodeErr
+	}
+	return ChangeEvent{Operation: ChangeUpdate, After: row}, true, nil
+}
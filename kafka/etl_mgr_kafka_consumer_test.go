@@ -0,0 +1,274 @@
+package kafka
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/faelmori/getl/etypes"
+	"github.com/segmentio/kafka-go"
+)
+
+// fakeReader entrega as mensagens em ordem e registra os offsets confirmados.
+type fakeReader struct {
+	mu        sync.Mutex
+	messages  []kafka.Message
+	next      int
+	committed []int64
+}
+
+func (f *fakeReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	f.mu.Lock()
+	if f.next < len(f.messages) {
+		message := f.messages[f.next]
+		f.next++
+		f.mu.Unlock()
+		return message, nil
+	}
+	f.mu.Unlock()
+	<-ctx.Done()
+	return kafka.Message{}, ctx.Err()
+}
+
+func (f *fakeReader) CommitMessages(_ context.Context, msgs ...kafka.Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, message := range msgs {
+		f.committed = append(f.committed, message.Offset)
+	}
+	return nil
+}
+
+func (f *fakeReader) Config() kafka.ReaderConfig { return kafka.ReaderConfig{GroupID: "test"} }
+
+func (f *fakeReader) committedOffsets() []int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]int64(nil), f.committed...)
+}
+
+func consumerConfig(t *testing.T) Config {
+	return Config{
+		DestinationType:             "sqlite3",
+		DestinationConnectionString: filepath.Join(t.TempDir(), "dst.db"),
+		DestinationTable:            "items",
+		PrimaryKey:                  "id",
+		Kafka:                       KafkaOptions{Consumer: KafkaConsumer{BatchSize: 2, FlushInterval: "20ms"}},
+		Transformations: []Transformation{
+			{SourceField: "id", DestinationField: "item_id", Operation: "copy"},
+			{SourceField: "name", DestinationField: "name", Operation: "copy"},
+		},
+	}
+}
+
+func messages(values ...string) []kafka.Message {
+	result := make([]kafka.Message, len(values))
+	for i, value := range values {
+		result[i] = kafka.Message{Topic: "items", Offset: int64(i), Value: []byte(value)}
+	}
+	return result
+}
+
+// TestConsumeToDestination verifica a carga em lotes, a substituição pela chave e a confirmação dos offsets.
+func TestConsumeToDestination(t *testing.T) {
+	config := consumerConfig(t)
+	reader := &fakeReader{messages: messages(
+		`{"id": 1, "name": "a"}`,
+		`not json`,
+		`{"id": 2, "name": "b"}`,
+		`{"id": 1, "name": "a2"}`,
+		`{"id": 3, "name": "c"}`,
+	)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- consumeToDestination(ctx, config, reader, nil) }()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(reader.committedOffsets()) < len(reader.messages) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("consumeToDestination() = %v", err)
+	}
+	if committed := reader.committedOffsets(); len(committed) != 5 {
+		t.Fatalf("offsets confirmados = %v", committed)
+	}
+
+	db, err := sql.Open("sqlite3", config.DestinationConnectionString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	rows, err := db.Query("SELECT item_id, name FROM items ORDER BY item_id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var got []string
+	for rows.Next() {
+		var id int64
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, fmt.Sprintf("%d:%s", id, name))
+	}
+	if strings.Join(got, ",") != "1:a2,2:b,3:c" {
+		t.Errorf("linhas no destino = %v", got)
+	}
+}
+
+// TestConsumeToDestinationFailure verifica que um lote que falha não tem os offsets confirmados.
+func TestConsumeToDestinationFailure(t *testing.T) {
+	config := consumerConfig(t)
+	reader := &fakeReader{messages: messages(`{"id": 1, "name": "a"}`, `{"name": "sem chave"}`)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := consumeToDestination(ctx, config, reader, nil); err == nil {
+		t.Fatalf("consumeToDestination() sem erro com uma mensagem sem a chave")
+	}
+	if committed := reader.committedOffsets(); len(committed) != 0 {
+		t.Errorf("offsets confirmados após a falha = %v", committed)
+	}
+}
+
+// TestConsumeDebeziumEnvelope verifica a aplicação de inclusões, atualizações e exclusões no envelope do Debezium.
+func TestConsumeDebeziumEnvelope(t *testing.T) {
+	config := consumerConfig(t)
+	reader := &fakeReader{messages: messages(
+		`{"before": null, "after": {"id": 1, "name": "a"}, "op": "r", "source": {"table": "items"}, "ts_ms": 1}`,
+		`{"before": null, "after": {"id": 2, "name": "b"}, "op": "c", "source": {"table": "items"}, "ts_ms": 2}`,
+		`{"before": {"id": 1, "name": "a"}, "after": {"id": 1, "name": "a2"}, "op": "u", "source": {"table": "items"}, "ts_ms": 3}`,
+		`{"before": {"id": 2}, "after": null, "op": "d", "source": {"table": "items"}, "ts_ms": 4}`,
+		``,
+	)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- consumeToDestination(ctx, config, reader, nil) }()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(reader.committedOffsets()) < len(reader.messages) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("consumeToDestination() = %v", err)
+	}
+
+	db, err := sql.Open("sqlite3", config.DestinationConnectionString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var count int
+	var name string
+	if err := db.QueryRow("SELECT COUNT(*), MAX(name) FROM items").Scan(&count, &name); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 || name != "a2" {
+		t.Errorf("destino com %d linha(s), nome %s; esperado 1 linha com a2", count, name)
+	}
+}
+
+// TestConsumeRoutes verifica a distribuição das mensagens entre as tabelas das rotas e a de destinationTable.
+func TestConsumeRoutes(t *testing.T) {
+	config := consumerConfig(t)
+	config.Kafka.Consumer.BatchSize = 10
+	config.Kafka.Consumer.Routes = []KafkaRoute{
+		{Topic: "orders", Fields: map[string]string{"kind": "refund"}, Table: "refunds"},
+		{Topic: "orders", Table: "orders", PrimaryKey: "order_id"},
+		{Headers: map[string]string{"table": "customers"}, Table: "customers", Transformations: []Transformation{
+			{SourceField: "id", DestinationField: "customer_id", Operation: "copy"},
+			{SourceField: "name", DestinationField: "customer_name", Operation: "copy"},
+		}},
+	}
+	reader := &fakeReader{messages: []kafka.Message{
+		{Topic: "items", Offset: 0, Value: []byte(`{"id": 1, "name": "a"}`)},
+		{Topic: "orders", Offset: 0, Value: []byte(`{"id": 10, "order_id": 100, "kind": "sale"}`)},
+		{Topic: "orders", Offset: 1, Value: []byte(`{"id": 11, "order_id": 101, "kind": "refund"}`)},
+		{Topic: "items", Offset: 1, Value: []byte(`{"id": 5, "name": "c"}`), Headers: []kafka.Header{{Key: "table", Value: []byte("customers")}}},
+		{Topic: "orders", Offset: 2, Value: []byte(`{"id": 12, "order_id": 102, "kind": "sale"}`)},
+	}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- consumeToDestination(ctx, config, reader, nil) }()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(reader.committedOffsets()) < len(reader.messages) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("consumeToDestination() = %v", err)
+	}
+
+	db, err := sql.Open("sqlite3", config.DestinationConnectionString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for query, want := range map[string]string{
+		"SELECT item_id FROM items":                          "1",
+		"SELECT order_id FROM orders ORDER BY 1":             "100,102",
+		"SELECT order_id FROM refunds":                       "101",
+		"SELECT customer_id || customer_name FROM customers": "5c",
+	} {
+		rows, err := db.Query(query)
+		if err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+		var got []string
+		for rows.Next() {
+			var value string
+			if err := rows.Scan(&value); err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, value)
+		}
+		rows.Close()
+		if strings.Join(got, ",") != want {
+			t.Errorf("%s = %v, esperado %s", query, got, want)
+		}
+	}
+}
+
+// TestConsumeRoutesWithoutFallback verifica que, sem destinationTable, as mensagens sem rota são descartadas.
+func TestConsumeRoutesWithoutFallback(t *testing.T) {
+	config := consumerConfig(t)
+	config.DestinationTable = ""
+	config.Transformations = nil
+	config.Kafka.Consumer.Routes = []KafkaRoute{{Topic: "orders", Table: "orders"}}
+	reader := &fakeReader{messages: []kafka.Message{
+		{Topic: "items", Offset: 0, Value: []byte(`{"id": 1}`)},
+		{Topic: "orders", Offset: 1, Value: []byte(`{"id": 2}`)},
+	}}
+	metrics := TopicMetrics("routes-without-fallback")
+	config.KafkaTopic = "routes-without-fallback"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- consumeToDestination(ctx, config, reader, nil) }()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(reader.committedOffsets()) < len(reader.messages) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("consumeToDestination() = %v", err)
+	}
+	if metrics.Discarded.Value() != 1 || metrics.Loaded.Value() != 1 {
+		t.Errorf("contadores = %s", metrics)
+	}
+
+	config.Kafka.Consumer.Routes = nil
+	if err := consumeToDestination(context.Background(), config, &fakeReader{}, nil); err == nil {
+		t.Errorf("consumeToDestination() sem destinationTable nem rotas e sem erro")
+	}
+}
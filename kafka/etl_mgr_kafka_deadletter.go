@@ -0,0 +1,239 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	. "github.com/faelmori/getl/etypes"
+	. "github.com/faelmori/getl/utils"
+	"github.com/faelmori/logz"
+	"github.com/segmentio/kafka-go"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// messageWriter é a parte do kafka.Writer usada para publicar as mensagens que falharam.
+type messageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+// ConsumerMetrics conta as mensagens consumidas de um tópico: lidas, gravadas no destino,
+// publicadas para nova tentativa, publicadas no dead-letter e descartadas (sem dead-letter).
+type ConsumerMetrics struct {
+	Consumed     expvar.Int
+	Loaded       expvar.Int
+	Retried      expvar.Int
+	DeadLettered expvar.Int
+	Discarded    expvar.Int
+}
+
+// String retorna os contadores em JSON, como as demais variáveis do expvar.
+func (m *ConsumerMetrics) String() string {
+	data, _ := json.Marshal(map[string]int64{
+		"consumed":     m.Consumed.Value(),
+		"loaded":       m.Loaded.Value(),
+		"retried":      m.Retried.Value(),
+		"deadLettered": m.DeadLettered.Value(),
+		"discarded":    m.Discarded.Value(),
+	})
+	return string(data)
+}
+
+var (
+	// consumerMetrics publica os contadores de cada tópico consumido em expvar, visíveis em
+	// /debug/vars quando o processo serve o http.DefaultServeMux.
+	consumerMetrics   = expvar.NewMap("getl_kafka_consumer")
+	consumerMetricsMu sync.Mutex
+)
+
+// TopicMetrics retorna os contadores do consumo de topic, criados no primeiro uso. As mensagens
+// do tópico de retentativa são contadas no tópico de origem.
+func TopicMetrics(topic string) *ConsumerMetrics {
+	consumerMetricsMu.Lock()
+	defer consumerMetricsMu.Unlock()
+	if metrics, ok := consumerMetrics.Get(topic).(*ConsumerMetrics); ok {
+		return metrics
+	}
+	metrics := &ConsumerMetrics{}
+	consumerMetrics.Set(topic, metrics)
+	return metrics
+}
+
+// failedMessage é uma mensagem que não pôde ser decodificada ("decode") ou gravada ("load").
+type failedMessage struct {
+	message kafka.Message
+	stage   string
+	err     error
+}
+
+// route publica as mensagens que falharam no tópico de retentativa ou, esgotadas as tentativas,
+// no dead-letter, em uma única escrita. Os offsets do lote só são confirmados se ela for concluída.
+func (c *destinationConsumer) route(ctx context.Context, failed []failedMessage, now time.Time) error {
+	if len(failed) == 0 {
+		return nil
+	}
+	messages := make([]kafka.Message, 0, len(failed))
+	retried, deadLettered := 0, 0
+	for _, failure := range failed {
+		attempt := headerInt(failure.message, RetryAttemptHeader) + 1
+		message := failureMessage(failure, now)
+		if attempt <= RetryAttempts(c.config) {
+			backoff, backoffErr := RetryBackoff(c.config, attempt)
+			if backoffErr != nil {
+				return backoffErr
+			}
+			message.Topic = RetryTopic(c.config)
+			message.Headers = append(message.Headers,
+				kafka.Header{Key: RetryAttemptHeader, Value: []byte(strconv.Itoa(attempt))},
+				kafka.Header{Key: RetryAtHeader, Value: []byte(strconv.FormatInt(now.Add(backoff).UnixMilli(), 10))})
+			retried++
+		} else {
+			message.Topic = c.config.Kafka.Consumer.DeadLetterTopic
+			message.Headers = append(message.Headers, kafka.Header{Key: RetryAttemptHeader, Value: []byte(strconv.Itoa(attempt - 1))})
+			deadLettered++
+		}
+		logz.Warn(fmt.Sprintf("mensagem %d da partição %d de %s enviada para %s: %v", failure.message.Offset, failure.message.Partition, failure.message.Topic, message.Topic, failure.err), map[string]interface{}{})
+		messages = append(messages, message)
+	}
+	if writeErr := c.deadLetters.WriteMessages(ctx, messages...); writeErr != nil {
+		return fmt.Errorf("falha ao publicar %d mensagem(ns) com erro; os offsets não foram confirmados: %w", len(messages), writeErr)
+	}
+	c.metrics.Retried.Add(int64(retried))
+	c.metrics.DeadLettered.Add(int64(deadLettered))
+	return nil
+}
+
+// failureMessage copia a chave, o valor e os cabeçalhos da mensagem, com a origem e o erro. A
+// origem de uma mensagem já retentada é mantida.
+func failureMessage(failure failedMessage, now time.Time) kafka.Message {
+	original := failure.message
+	topic, partition, offset := original.Topic, strconv.Itoa(original.Partition), strconv.FormatInt(original.Offset, 10)
+	if value, ok := header(original, OriginalTopicHeader); ok {
+		topic = value
+		partition, _ = header(original, OriginalPartitionHeader)
+		offset, _ = header(original, OriginalOffsetHeader)
+	}
+	headers := withoutFailureHeaders(original.Headers)
+	headers = append(headers,
+		kafka.Header{Key: OriginalTopicHeader, Value: []byte(topic)},
+		kafka.Header{Key: OriginalPartitionHeader, Value: []byte(partition)},
+		kafka.Header{Key: OriginalOffsetHeader, Value: []byte(offset)},
+		kafka.Header{Key: ErrorHeader, Value: []byte(failure.err.Error())},
+		kafka.Header{Key: ErrorStageHeader, Value: []byte(failure.stage)},
+		kafka.Header{Key: FailedAtHeader, Value: []byte(now.UTC().Format(time.RFC3339Nano))})
+	return kafka.Message{Key: original.Key, Value: original.Value, Headers: headers}
+}
+
+// withoutFailureHeaders retorna uma cópia dos cabeçalhos sem os de FailureHeaders.
+func withoutFailureHeaders(headers []kafka.Header) []kafka.Header {
+	result := make([]kafka.Header, 0, len(headers))
+	for _, h := range headers {
+		if !slices.Contains(FailureHeaders, h.Key) {
+			result = append(result, h)
+		}
+	}
+	return result
+}
+
+func header(message kafka.Message, key string) (string, bool) {
+	for _, h := range message.Headers {
+		if h.Key == key {
+			return string(h.Value), true
+		}
+	}
+	return "", false
+}
+
+func headerInt(message kafka.Message, key string) int {
+	value, _ := header(message, key)
+	i, _ := strconv.Atoi(value)
+	return i
+}
+
+// retryWait retorna quanto falta para a nova tentativa mais tardia do lote; zero nas mensagens do
+// tópico de origem.
+func retryWait(batch []kafka.Message, now time.Time) time.Duration {
+	var wait time.Duration
+	for _, message := range batch {
+		if value, ok := header(message, RetryAtHeader); ok {
+			if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+				wait = max(wait, time.UnixMilli(ms).Sub(now))
+			}
+		}
+	}
+	return wait
+}
+
+// ReplayOptions configura ReplayDeadLetters.
+type ReplayOptions struct {
+	// Topic é o tópico de destino; sem ele, cada mensagem volta ao seu tópico de origem
+	// (getl-original-topic) ou, na falta dele, a kafkaTopic.
+	Topic string
+	// Max limita as mensagens publicadas de novo (0: todas).
+	Max int
+	// Idle encerra a reinjeção após esse tempo sem novas mensagens no dead-letter (0: sem limite, até
+	// Max mensagens ou o cancelamento do contexto).
+	Idle time.Duration
+}
+
+// ReplayDeadLetters publica de novo as mensagens do dead-letter de config no tópico de origem, sem
+// os cabeçalhos de erro, confirmando cada uma no grupo "<kafkaGroupID>-replay" após a escrita: uma
+// mensagem já reinjetada não é lida de novo. Retorna quantas mensagens foram reinjetadas.
+func ReplayDeadLetters(ctx context.Context, config Config, options ReplayOptions) (int, error) {
+	if config.Kafka.Consumer.DeadLetterTopic == "" || config.KafkaGroupID == "" {
+		return 0, fmt.Errorf("a reinjeção exige kafkaGroupID e kafka.consumer.deadLetterTopic")
+	}
+	reader, readerErr := NewKafkaReader(config, config.Kafka.Consumer.DeadLetterTopic, config.KafkaGroupID+"-replay")
+	if readerErr != nil {
+		return 0, readerErr
+	}
+	defer func(reader *kafka.Reader) {
+		_ = reader.Close()
+	}(reader)
+	writer, writerErr := NewKafkaWriter(config, "")
+	if writerErr != nil {
+		return 0, writerErr
+	}
+	defer func(writer *kafka.Writer) {
+		_ = writer.Close()
+	}(writer)
+	return replayDeadLetters(ctx, config, options, reader, writer)
+}
+
+func replayDeadLetters(ctx context.Context, config Config, options ReplayOptions, reader messageReader, writer messageWriter) (int, error) {
+	replayed := 0
+	for options.Max <= 0 || replayed < options.Max {
+		fetchCtx, cancel := ctx, context.CancelFunc(func() {})
+		if options.Idle > 0 {
+			fetchCtx, cancel = context.WithTimeout(ctx, options.Idle)
+		}
+		message, fetchErr := reader.FetchMessage(fetchCtx)
+		cancel()
+		if fetchErr != nil {
+			if ctx.Err() != nil || fetchCtx.Err() != nil {
+				break
+			}
+			return replayed, fmt.Errorf("erro ao ler o dead-letter: %w", fetchErr)
+		}
+
+		topic := options.Topic
+		if topic == "" {
+			if topic, _ = header(message, OriginalTopicHeader); topic == "" {
+				topic = config.KafkaTopic
+			}
+		}
+		replay := kafka.Message{Topic: topic, Key: message.Key, Value: message.Value, Headers: withoutFailureHeaders(message.Headers)}
+		if writeErr := writer.WriteMessages(ctx, replay); writeErr != nil {
+			return replayed, fmt.Errorf("falha ao reinjetar a mensagem %d do dead-letter em %s: %w", message.Offset, topic, writeErr)
+		}
+		if commitErr := reader.CommitMessages(ctx, message); commitErr != nil {
+			return replayed, fmt.Errorf("mensagem %d reinjetada em %s, mas o offset não foi confirmado: %w", message.Offset, topic, commitErr)
+		}
+		replayed++
+	}
+	logz.Info(fmt.Sprintf("%d mensagem(ns) reinjetada(s) do dead-letter %s", replayed, config.Kafka.Consumer.DeadLetterTopic), map[string]interface{}{})
+	return replayed, nil
+}
@@ -0,0 +1,175 @@
+package kafka
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/faelmori/getl/etypes"
+	. "github.com/faelmori/getl/utils"
+	"github.com/segmentio/kafka-go"
+)
+
+// fakeWriter registra as mensagens publicadas ou, com err, recusa todas.
+type fakeWriter struct {
+	mu       sync.Mutex
+	messages []kafka.Message
+	err      error
+}
+
+func (f *fakeWriter) WriteMessages(_ context.Context, msgs ...kafka.Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return f.err
+	}
+	f.messages = append(f.messages, msgs...)
+	return nil
+}
+
+func (f *fakeWriter) written() []kafka.Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]kafka.Message(nil), f.messages...)
+}
+
+// TestConsumeDeadLetters verifica que as mensagens inválidas e as que falham ao ser gravadas vão
+// para o tópico de retentativa, ou para o dead-letter após as tentativas, sem impedir a carga das
+// demais nem a confirmação dos offsets.
+func TestConsumeDeadLetters(t *testing.T) {
+	config := consumerConfig(t)
+	config.KafkaTopic = "dead-letter-items"
+	config.Kafka.Consumer.DeadLetterTopic = "items-dlq"
+	config.Kafka.Consumer.Retry = KafkaRetry{Attempts: 2, Backoff: "1m"}
+	batch := messages(`{"id": 1, "name": "a"}`, `not json`, `{"name": "sem chave"}`, `{"id": 2, "name": "b"}`)
+	// A mensagem inválida já foi tentada duas vezes e vem do tópico de retentativa.
+	batch[1].Headers = []kafka.Header{
+		{Key: OriginalTopicHeader, Value: []byte("dead-letter-items")},
+		{Key: OriginalPartitionHeader, Value: []byte("3")},
+		{Key: OriginalOffsetHeader, Value: []byte("42")},
+		{Key: RetryAttemptHeader, Value: []byte("2")},
+		{Key: RetryAtHeader, Value: []byte(strconv.FormatInt(time.Now().Add(-time.Second).UnixMilli(), 10))},
+		{Key: "trace", Value: []byte("abc")},
+	}
+	batch[1].Topic = "dead-letter-items-retry"
+	reader := &fakeReader{messages: batch}
+	writer := &fakeWriter{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- consumeToDestination(ctx, config, reader, writer) }()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(reader.committedOffsets()) < len(batch) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("consumeToDestination() = %v", err)
+	}
+	if committed := reader.committedOffsets(); len(committed) != len(batch) {
+		t.Fatalf("offsets confirmados = %v", committed)
+	}
+
+	written := writer.written()
+	if len(written) != 2 {
+		t.Fatalf("mensagens publicadas = %v", written)
+	}
+	dead, retried := written[0], written[1]
+	if dead.Topic != "items-dlq" || string(dead.Value) != "not json" {
+		t.Errorf("dead-letter = %s %q", dead.Topic, dead.Value)
+	}
+	for key, want := range map[string]string{OriginalTopicHeader: "dead-letter-items", OriginalOffsetHeader: "42", RetryAttemptHeader: "2", ErrorStageHeader: "decode", "trace": "abc"} {
+		if value, _ := header(dead, key); value != want {
+			t.Errorf("cabeçalho %s do dead-letter = %q, esperado %q", key, value, want)
+		}
+	}
+	if _, ok := header(dead, RetryAtHeader); ok {
+		t.Errorf("dead-letter com o cabeçalho %s", RetryAtHeader)
+	}
+	if retried.Topic != "dead-letter-items-retry" || string(retried.Value) != `{"name": "sem chave"}` {
+		t.Errorf("retentativa = %s %q", retried.Topic, retried.Value)
+	}
+	if stage, _ := header(retried, ErrorStageHeader); stage != "load" || headerInt(retried, RetryAttemptHeader) != 1 {
+		t.Errorf("retentativa com estágio %s e tentativa %d", stage, headerInt(retried, RetryAttemptHeader))
+	}
+	if wait := retryWait([]kafka.Message{retried}, time.Now()); wait < 59*time.Second || wait > time.Minute {
+		t.Errorf("espera da retentativa = %v", wait)
+	}
+
+	db, err := sql.Open("sqlite3", config.DestinationConnectionString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM items").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("%d linha(s) no destino, esperado 2", count)
+	}
+
+	metrics := TopicMetrics("dead-letter-items")
+	if metrics.Consumed.Value() != 4 || metrics.Loaded.Value() != 2 || metrics.Retried.Value() != 1 || metrics.DeadLettered.Value() != 1 {
+		t.Errorf("contadores = %s", metrics)
+	}
+}
+
+// TestConsumeDeadLettersUnavailable verifica que o lote não é confirmado se as mensagens com erro
+// não puderem ser publicadas.
+func TestConsumeDeadLettersUnavailable(t *testing.T) {
+	config := consumerConfig(t)
+	config.KafkaTopic = "unavailable-items"
+	config.Kafka.Consumer.DeadLetterTopic = "items-dlq"
+	reader := &fakeReader{messages: messages(`{"id": 1, "name": "a"}`, `not json`)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := consumeToDestination(ctx, config, reader, &fakeWriter{err: errors.New("broker indisponível")}); err == nil {
+		t.Fatalf("consumeToDestination() sem erro com o dead-letter indisponível")
+	}
+	if committed := reader.committedOffsets(); len(committed) != 0 {
+		t.Errorf("offsets confirmados = %v", committed)
+	}
+}
+
+// TestReplayDeadLetters verifica que as mensagens voltam ao tópico de origem sem os cabeçalhos de
+// erro e que cada uma é confirmada após a escrita.
+func TestReplayDeadLetters(t *testing.T) {
+	config := Config{KafkaTopic: "items", Kafka: KafkaOptions{Consumer: KafkaConsumer{DeadLetterTopic: "items-dlq"}}}
+	dead := messages(`{"id": 1}`, `{"id": 2}`, `{"id": 3}`)
+	dead[0].Headers = []kafka.Header{{Key: OriginalTopicHeader, Value: []byte("orders")}, {Key: ErrorHeader, Value: []byte("falha")}, {Key: "trace", Value: []byte("abc")}}
+	reader := &fakeReader{messages: dead}
+	writer := &fakeWriter{}
+
+	replayed, err := replayDeadLetters(context.Background(), config, ReplayOptions{Max: 2, Idle: 50 * time.Millisecond}, reader, writer)
+	if err != nil || replayed != 2 {
+		t.Fatalf("replayDeadLetters() = %d, %v", replayed, err)
+	}
+	written := writer.written()
+	if len(written) != 2 || written[0].Topic != "orders" || written[1].Topic != "items" {
+		t.Fatalf("mensagens reinjetadas = %v", written)
+	}
+	if len(written[0].Headers) != 1 || written[0].Headers[0].Key != "trace" {
+		t.Errorf("cabeçalhos reinjetados = %v", written[0].Headers)
+	}
+	if committed := reader.committedOffsets(); len(committed) != 2 {
+		t.Errorf("offsets confirmados = %v", committed)
+	}
+
+	replayed, err = replayDeadLetters(context.Background(), config, ReplayOptions{Topic: "items-again", Idle: 50 * time.Millisecond}, reader, writer)
+	if err != nil || replayed != 1 || writer.written()[2].Topic != "items-again" {
+		t.Errorf("replayDeadLetters() com --to = %d, %v", replayed, err)
+	}
+
+	// Sem Idle, não há limite de espera: a reinjeção só termina em Max.
+	reader = &fakeReader{messages: messages(`{"id": 4}`)}
+	replayed, err = replayDeadLetters(context.Background(), config, ReplayOptions{Max: 1}, reader, writer)
+	if err != nil || replayed != 1 {
+		t.Errorf("replayDeadLetters() sem Idle = %d, %v", replayed, err)
+	}
+}
@@ -0,0 +1,85 @@
+package kafka
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DedupStore guarda, para cada chave de deduplicação de getl stream, o hash da última linha
+// publicada, em um arquivo SQLite local.
+type DedupStore struct {
+	db  *sql.DB
+	ttl time.Duration
+}
+
+// OpenDedupStore abre o estado da deduplicação em path, criando o arquivo e a tabela se preciso.
+// As chaves lembradas há mais de ttl são ignoradas e removidas; com ttl zero, não expiram.
+func OpenDedupStore(ctx context.Context, path string, ttl time.Duration) (*DedupStore, error) {
+	db, openErr := sql.Open("sqlite3", path)
+	if openErr != nil {
+		return nil, fmt.Errorf("falha ao abrir o estado da deduplicação %s: %w", path, openErr)
+	}
+	// Uma conexão só: o SQLite não aceita escritas concorrentes no mesmo arquivo.
+	db.SetMaxOpenConns(1)
+	if _, createErr := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS etl_stream_dedup (
+		dedup_key TEXT PRIMARY KEY,
+		value_hash TEXT NOT NULL,
+		updated_at INTEGER NOT NULL
+	)`); createErr != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("falha ao criar o estado da deduplicação em %s: %w", path, createErr)
+	}
+	return &DedupStore{db: db, ttl: ttl}, nil
+}
+
+// Last retorna o hash da última linha publicada com key, se ela ainda for lembrada em now.
+func (d *DedupStore) Last(ctx context.Context, key string, now time.Time) (string, bool, error) {
+	var hash string
+	var updatedAt int64
+	err := d.db.QueryRowContext(ctx, "SELECT value_hash, updated_at FROM etl_stream_dedup WHERE dedup_key = ?", key).Scan(&hash, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("falha ao ler a chave %s da deduplicação: %w", key, err)
+	}
+	if d.ttl > 0 && now.Sub(time.UnixMilli(updatedAt)) > d.ttl {
+		return "", false, nil
+	}
+	return hash, true, nil
+}
+
+// Save grava os hashes das linhas publicadas em uma transação e remove as chaves expiradas.
+func (d *DedupStore) Save(ctx context.Context, hashes map[string]string, now time.Time) error {
+	tx, txErr := d.db.BeginTx(ctx, nil)
+	if txErr != nil {
+		return fmt.Errorf("falha ao iniciar a transação da deduplicação: %w", txErr)
+	}
+	defer func(tx *sql.Tx) {
+		_ = tx.Rollback()
+	}(tx)
+	for key, hash := range hashes {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO etl_stream_dedup (dedup_key, value_hash, updated_at) VALUES (?, ?, ?)
+			ON CONFLICT (dedup_key) DO UPDATE SET value_hash = excluded.value_hash, updated_at = excluded.updated_at`,
+			key, hash, now.UnixMilli()); err != nil {
+			return fmt.Errorf("falha ao gravar a chave %s da deduplicação: %w", key, err)
+		}
+	}
+	if d.ttl > 0 {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM etl_stream_dedup WHERE updated_at < ?", now.Add(-d.ttl).UnixMilli()); err != nil {
+			return fmt.Errorf("falha ao remover as chaves expiradas da deduplicação: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("falha ao confirmar a transação da deduplicação: %w", err)
+	}
+	return nil
+}
+
+// Close fecha o arquivo do estado.
+func (d *DedupStore) Close() error {
+	return d.db.Close()
+}
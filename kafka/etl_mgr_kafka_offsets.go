@@ -0,0 +1,293 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	. "github.com/faelmori/getl/etypes"
+	. "github.com/faelmori/getl/utils"
+	"github.com/segmentio/kafka-go"
+	"io"
+	"slices"
+	"strconv"
+	"text/tabwriter"
+)
+
+// offsetsClient é a parte do kafka.Client usada para ler e redefinir os offsets dos grupos.
+type offsetsClient interface {
+	metadataClient
+	ListOffsets(ctx context.Context, req *kafka.ListOffsetsRequest) (*kafka.ListOffsetsResponse, error)
+	OffsetFetch(ctx context.Context, req *kafka.OffsetFetchRequest) (*kafka.OffsetFetchResponse, error)
+	OffsetCommit(ctx context.Context, req *kafka.OffsetCommitRequest) (*kafka.OffsetCommitResponse, error)
+	DescribeGroups(ctx context.Context, req *kafka.DescribeGroupsRequest) (*kafka.DescribeGroupsResponse, error)
+}
+
+// metadataClient é a parte do kafka.Client que lê as partições dos tópicos.
+type metadataClient interface {
+	Metadata(ctx context.Context, req *kafka.MetadataRequest) (*kafka.MetadataResponse, error)
+}
+
+// PartitionOffset é a posição de um grupo em uma partição de um tópico.
+type PartitionOffset struct {
+	Topic     string
+	Partition int
+	// Committed é o offset confirmado pelo grupo, o da próxima mensagem a ler, ou -1 sem confirmação.
+	Committed int64
+	// End é o offset da próxima mensagem publicada na partição.
+	End int64
+}
+
+// Lag retorna quantas mensagens da partição o grupo ainda não leu, ou -1 sem offset confirmado.
+func (o PartitionOffset) Lag() int64 {
+	if o.Committed < 0 {
+		return -1
+	}
+	return max(o.End-o.Committed, 0)
+}
+
+// GroupOffsets retorna os offsets confirmados pelo grupo em cada partição do tópico, com o fim de cada uma.
+func GroupOffsets(ctx context.Context, client offsetsClient, groupID, topic string) ([]PartitionOffset, error) {
+	partitions, partitionsErr := topicPartitions(ctx, client, topic)
+	if partitionsErr != nil {
+		return nil, partitionsErr
+	}
+	ends, listErr := listOffsets(ctx, client, topic, partitions, kafka.LastOffsetOf)
+	if listErr != nil {
+		return nil, listErr
+	}
+	response, fetchErr := client.OffsetFetch(ctx, &kafka.OffsetFetchRequest{GroupID: groupID, Topics: map[string][]int{topic: partitions}})
+	if fetchErr == nil {
+		fetchErr = response.Error
+	}
+	if fetchErr != nil {
+		return nil, fmt.Errorf("falha ao ler os offsets do grupo %s: %w", groupID, fetchErr)
+	}
+	committed := map[int]int64{}
+	for _, partition := range response.Topics[topic] {
+		if partition.Error != nil {
+			return nil, fmt.Errorf("falha ao ler o offset do grupo %s na partição %d de %s: %w", groupID, partition.Partition, topic, partition.Error)
+		}
+		committed[partition.Partition] = partition.CommittedOffset
+	}
+
+	offsets := make([]PartitionOffset, len(partitions))
+	for i, partition := range partitions {
+		offsets[i] = PartitionOffset{Topic: topic, Partition: partition, Committed: -1, End: ends[partition].LastOffset}
+		if offset, ok := committed[partition]; ok {
+			offsets[i].Committed = offset
+		}
+	}
+	return offsets, nil
+}
+
+// ResetGroupOffsets redefine os offsets confirmados pelo grupo no tópico para position e retorna os
+// novos offsets; com position.Offsets, as demais partições não são alteradas. O grupo não pode ter
+// consumidores ativos. Com dryRun, os offsets são calculados mas não confirmados.
+func ResetGroupOffsets(ctx context.Context, client offsetsClient, groupID, topic string, position StartPosition, dryRun bool) ([]PartitionOffset, error) {
+	offsets, offsetsErr := GroupOffsets(ctx, client, groupID, topic)
+	if offsetsErr != nil {
+		return nil, offsetsErr
+	}
+	partitions := make([]int, len(offsets))
+	for i, offset := range offsets {
+		partitions[i] = offset.Partition
+	}
+	for partition := range position.Offsets {
+		if !slices.Contains(partitions, partition) {
+			return nil, fmt.Errorf("o tópico %s não tem a partição %d", topic, partition)
+		}
+	}
+
+	var targets map[int]int64
+	var targetsErr error
+	switch {
+	case position.Earliest || len(position.Offsets) > 0:
+		targets, targetsErr = earliestOffsets(ctx, client, topic, partitions)
+	case position.Latest:
+		targets = map[int]int64{}
+		for _, offset := range offsets {
+			targets[offset.Partition] = offset.End
+		}
+	case !position.Time.IsZero():
+		targets, targetsErr = timeOffsets(ctx, client, topic, offsets, position)
+	default:
+		return nil, fmt.Errorf("posição de início vazia")
+	}
+	if targetsErr != nil {
+		return nil, targetsErr
+	}
+
+	commits := make([]kafka.OffsetCommit, 0, len(offsets))
+	for i, offset := range offsets {
+		target := targets[offset.Partition]
+		if len(position.Offsets) > 0 {
+			requested, ok := position.Offsets[offset.Partition]
+			if !ok {
+				continue
+			}
+			// targets tem o início da partição; um offset fora dela seria redefinido pelo startOffset do leitor.
+			if requested < target || requested > offset.End {
+				return nil, fmt.Errorf("o offset %d está fora da partição %d de %s (%d a %d)", requested, offset.Partition, topic, target, offset.End)
+			}
+			target = requested
+		}
+		offsets[i].Committed = target
+		commits = append(commits, kafka.OffsetCommit{Partition: offset.Partition, Offset: target})
+	}
+	if dryRun || len(commits) == 0 {
+		return offsets, nil
+	}
+
+	if activeErr := checkGroupInactive(ctx, client, groupID); activeErr != nil {
+		return nil, activeErr
+	}
+	// Sem geração nem membro, o coordenador aceita a confirmação de um grupo sem consumidores.
+	response, commitErr := client.OffsetCommit(ctx, &kafka.OffsetCommitRequest{GroupID: groupID, GenerationID: -1, Topics: map[string][]kafka.OffsetCommit{topic: commits}})
+	if commitErr != nil {
+		return nil, fmt.Errorf("falha ao redefinir os offsets do grupo %s: %w", groupID, commitErr)
+	}
+	for _, partition := range response.Topics[topic] {
+		if partition.Error != nil {
+			return nil, fmt.Errorf("falha ao redefinir o offset do grupo %s na partição %d de %s: %w", groupID, partition.Partition, topic, partition.Error)
+		}
+	}
+	return offsets, nil
+}
+
+// ResetPipelineOffsets redefine para position os offsets do kafkaGroupID da pipeline no kafkaTopic e
+// nos tópicos das rotas (ConsumerTopics), antes do consumo.
+func ResetPipelineOffsets(ctx context.Context, config Config, position StartPosition) ([]PartitionOffset, error) {
+	client, clientErr := NewKafkaClient(config)
+	if clientErr != nil {
+		return nil, clientErr
+	}
+	var offsets []PartitionOffset
+	for _, topic := range ConsumerTopics(config) {
+		topicOffsets, resetErr := ResetGroupOffsets(ctx, client, config.KafkaGroupID, topic, position, false)
+		if resetErr != nil {
+			return offsets, fmt.Errorf("%s: %w", topic, resetErr)
+		}
+		offsets = append(offsets, topicOffsets...)
+	}
+	return offsets, nil
+}
+
+// PrintPartitionOffsets mostra os offsets do grupo em uma tabela, com o atraso de cada partição.
+func PrintPartitionOffsets(w io.Writer, groupID string, offsets []PartitionOffset) error {
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(table, "GRUPO\tTÓPICO\tPARTIÇÃO\tOFFSET\tFIM\tATRASO")
+	var total int64
+	for _, offset := range offsets {
+		committed, lag := "-", "-"
+		if offset.Committed >= 0 {
+			committed, lag = strconv.FormatInt(offset.Committed, 10), strconv.FormatInt(offset.Lag(), 10)
+			total += offset.Lag()
+		}
+		_, _ = fmt.Fprintf(table, "%s\t%s\t%d\t%s\t%d\t%s\n", groupID, offset.Topic, offset.Partition, committed, offset.End, lag)
+	}
+	_, _ = fmt.Fprintf(table, "%s\t\t\t\t\t%d\n", "TOTAL", total)
+	return table.Flush()
+}
+
+// topicPartitions retorna as partições do tópico, em ordem.
+func topicPartitions(ctx context.Context, client metadataClient, topic string) ([]int, error) {
+	response, metadataErr := client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{topic}})
+	if metadataErr != nil {
+		return nil, fmt.Errorf("falha ao ler as partições de %s: %w", topic, metadataErr)
+	}
+	for _, metadata := range response.Topics {
+		if metadata.Name != topic {
+			continue
+		}
+		if metadata.Error != nil {
+			return nil, fmt.Errorf("falha ao ler as partições de %s: %w", topic, metadata.Error)
+		}
+		partitions := make([]int, len(metadata.Partitions))
+		for i, partition := range metadata.Partitions {
+			partitions[i] = partition.ID
+		}
+		slices.Sort(partitions)
+		return partitions, nil
+	}
+	return nil, fmt.Errorf("tópico %s não encontrado", topic)
+}
+
+// listOffsets pede aos brokers os offsets de request em cada partição. Cada partição só pode ter um
+// pedido por requisição, por isso o início, o fim e os instantes são pedidos separadamente.
+func listOffsets(ctx context.Context, client offsetsClient, topic string, partitions []int, request func(partition int) kafka.OffsetRequest) (map[int]kafka.PartitionOffsets, error) {
+	requests := make([]kafka.OffsetRequest, len(partitions))
+	for i, partition := range partitions {
+		requests[i] = request(partition)
+	}
+	response, listErr := client.ListOffsets(ctx, &kafka.ListOffsetsRequest{Topics: map[string][]kafka.OffsetRequest{topic: requests}})
+	if listErr != nil {
+		return nil, fmt.Errorf("falha ao ler os offsets de %s: %w", topic, listErr)
+	}
+	result := map[int]kafka.PartitionOffsets{}
+	for _, partition := range response.Topics[topic] {
+		if partition.Error != nil {
+			return nil, fmt.Errorf("falha ao ler os offsets da partição %d de %s: %w", partition.Partition, topic, partition.Error)
+		}
+		result[partition.Partition] = partition
+	}
+	for _, partition := range partitions {
+		if _, ok := result[partition]; !ok {
+			return nil, fmt.Errorf("os brokers não retornaram os offsets da partição %d de %s", partition, topic)
+		}
+	}
+	return result, nil
+}
+
+// earliestOffsets retorna o offset da primeira mensagem disponível em cada partição.
+func earliestOffsets(ctx context.Context, client offsetsClient, topic string, partitions []int) (map[int]int64, error) {
+	firsts, listErr := listOffsets(ctx, client, topic, partitions, kafka.FirstOffsetOf)
+	if listErr != nil {
+		return nil, listErr
+	}
+	targets := map[int]int64{}
+	for partition, offsets := range firsts {
+		targets[partition] = offsets.FirstOffset
+	}
+	return targets, nil
+}
+
+// timeOffsets retorna o offset da primeira mensagem a partir de position.Time em cada partição, ou
+// o fim da partição se não houver nenhuma.
+func timeOffsets(ctx context.Context, client offsetsClient, topic string, offsets []PartitionOffset, position StartPosition) (map[int]int64, error) {
+	partitions := make([]int, len(offsets))
+	for i, offset := range offsets {
+		partitions[i] = offset.Partition
+	}
+	found, listErr := listOffsets(ctx, client, topic, partitions, func(partition int) kafka.OffsetRequest {
+		return kafka.TimeOffsetOf(partition, position.Time)
+	})
+	if listErr != nil {
+		return nil, listErr
+	}
+	targets := map[int]int64{}
+	for _, offset := range offsets {
+		targets[offset.Partition] = offset.End
+		for at := range found[offset.Partition].Offsets {
+			if at >= 0 {
+				targets[offset.Partition] = at
+			}
+		}
+	}
+	return targets, nil
+}
+
+// checkGroupInactive retorna um erro se o grupo tiver consumidores, que sobrescreveriam os offsets redefinidos.
+func checkGroupInactive(ctx context.Context, client offsetsClient, groupID string) error {
+	response, describeErr := client.DescribeGroups(ctx, &kafka.DescribeGroupsRequest{GroupIDs: []string{groupID}})
+	if describeErr != nil {
+		return fmt.Errorf("falha ao ler o estado do grupo %s: %w", groupID, describeErr)
+	}
+	for _, group := range response.Groups {
+		if group.Error != nil {
+			return fmt.Errorf("falha ao ler o estado do grupo %s: %w", groupID, group.Error)
+		}
+		if len(group.Members) > 0 {
+			return fmt.Errorf("o grupo %s tem %d consumidor(es) ativo(s) (estado %s); encerre-os antes de redefinir os offsets", groupID, len(group.Members), group.GroupState)
+		}
+	}
+	return nil
+}
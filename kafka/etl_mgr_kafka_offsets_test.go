@@ -0,0 +1,230 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/faelmori/getl/etypes"
+	. "github.com/faelmori/getl/utils"
+	"github.com/segmentio/kafka-go"
+)
+
+// fakeOffsetsClient simula um tópico com as partições 0 e 1: as mensagens de cada partição têm os
+// offsets de first a last-1, e a de offset n foi publicada em base+n minutos.
+type fakeOffsetsClient struct {
+	first, last map[int]int64
+	committed   map[int]int64
+	members     int
+	base        time.Time
+	commits     []kafka.OffsetCommit
+}
+
+func newFakeOffsetsClient() *fakeOffsetsClient {
+	return &fakeOffsetsClient{
+		first:     map[int]int64{0: 10, 1: 0},
+		last:      map[int]int64{0: 100, 1: 50},
+		committed: map[int]int64{0: 60},
+		base:      time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func (f *fakeOffsetsClient) Metadata(_ context.Context, req *kafka.MetadataRequest) (*kafka.MetadataResponse, error) {
+	if req.Topics[0] != "items" {
+		return &kafka.MetadataResponse{Topics: []kafka.Topic{{Name: req.Topics[0], Error: kafka.UnknownTopicOrPartition}}}, nil
+	}
+	return &kafka.MetadataResponse{Topics: []kafka.Topic{{Name: "items", Partitions: []kafka.Partition{{ID: 1}, {ID: 0}}}}}, nil
+}
+
+func (f *fakeOffsetsClient) ListOffsets(_ context.Context, req *kafka.ListOffsetsRequest) (*kafka.ListOffsetsResponse, error) {
+	var partitions []kafka.PartitionOffsets
+	seen := map[int]bool{}
+	for _, request := range req.Topics["items"] {
+		if seen[request.Partition] {
+			return nil, fmt.Errorf("partição %d repetida", request.Partition)
+		}
+		seen[request.Partition] = true
+		offsets := kafka.PartitionOffsets{Partition: request.Partition, FirstOffset: -1, LastOffset: -1, Offsets: map[int64]time.Time{}}
+		switch request.Timestamp {
+		case kafka.FirstOffset:
+			offsets.FirstOffset = f.first[request.Partition]
+		case kafka.LastOffset:
+			offsets.LastOffset = f.last[request.Partition]
+		default:
+			at := time.UnixMilli(request.Timestamp)
+			offset := int64(-1)
+			if minutes := int64(at.Sub(f.base).Minutes()); minutes < f.last[request.Partition] {
+				offset = max(minutes, f.first[request.Partition])
+			}
+			offsets.Offsets[offset] = at
+		}
+		partitions = append(partitions, offsets)
+	}
+	return &kafka.ListOffsetsResponse{Topics: map[string][]kafka.PartitionOffsets{"items": partitions}}, nil
+}
+
+func (f *fakeOffsetsClient) OffsetFetch(_ context.Context, req *kafka.OffsetFetchRequest) (*kafka.OffsetFetchResponse, error) {
+	var partitions []kafka.OffsetFetchPartition
+	for _, partition := range req.Topics["items"] {
+		offset, ok := f.committed[partition]
+		if !ok {
+			offset = -1
+		}
+		partitions = append(partitions, kafka.OffsetFetchPartition{Partition: partition, CommittedOffset: offset})
+	}
+	return &kafka.OffsetFetchResponse{Topics: map[string][]kafka.OffsetFetchPartition{"items": partitions}}, nil
+}
+
+func (f *fakeOffsetsClient) OffsetCommit(_ context.Context, req *kafka.OffsetCommitRequest) (*kafka.OffsetCommitResponse, error) {
+	if req.GenerationID != -1 || req.MemberID != "" {
+		return nil, fmt.Errorf("confirmação com geração %d e membro %q", req.GenerationID, req.MemberID)
+	}
+	f.commits = append(f.commits, req.Topics["items"]...)
+	var partitions []kafka.OffsetCommitPartition
+	for _, commit := range req.Topics["items"] {
+		f.committed[commit.Partition] = commit.Offset
+		partitions = append(partitions, kafka.OffsetCommitPartition{Partition: commit.Partition})
+	}
+	return &kafka.OffsetCommitResponse{Topics: map[string][]kafka.OffsetCommitPartition{"items": partitions}}, nil
+}
+
+func (f *fakeOffsetsClient) DescribeGroups(_ context.Context, req *kafka.DescribeGroupsRequest) (*kafka.DescribeGroupsResponse, error) {
+	group := kafka.DescribeGroupsResponseGroup{GroupID: req.GroupIDs[0], GroupState: "Empty"}
+	if f.members > 0 {
+		group.GroupState = "Stable"
+		group.Members = make([]kafka.DescribeGroupsResponseMember, f.members)
+	}
+	return &kafka.DescribeGroupsResponse{Groups: []kafka.DescribeGroupsResponseGroup{group}}, nil
+}
+
+// TestGroupOffsets verifica os offsets confirmados, o fim e o atraso de cada partição.
+func TestGroupOffsets(t *testing.T) {
+	offsets, err := GroupOffsets(context.Background(), newFakeOffsetsClient(), "group", "items")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []PartitionOffset{{Topic: "items", Partition: 0, Committed: 60, End: 100}, {Topic: "items", Partition: 1, Committed: -1, End: 50}}
+	if fmt.Sprint(offsets) != fmt.Sprint(want) || offsets[0].Lag() != 40 || offsets[1].Lag() != -1 {
+		t.Errorf("GroupOffsets() = %v", offsets)
+	}
+	var out bytes.Buffer
+	if err := PrintPartitionOffsets(&out, "group", offsets); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); len(lines) != 4 || !strings.Contains(lines[2], "-") || !strings.HasSuffix(lines[3], "40") {
+		t.Errorf("PrintPartitionOffsets() =\n%s", out.String())
+	}
+	if _, err := GroupOffsets(context.Background(), newFakeOffsetsClient(), "group", "orders"); err == nil {
+		t.Errorf("GroupOffsets() sem erro com um tópico inexistente")
+	}
+}
+
+// TestResetGroupOffsets verifica a redefinição para o início, o fim, um instante e offsets por partição.
+func TestResetGroupOffsets(t *testing.T) {
+	base := newFakeOffsetsClient().base
+	tests := []struct {
+		position StartPosition
+		want     map[int]int64
+	}{
+		{StartPosition{Earliest: true}, map[int]int64{0: 10, 1: 0}},
+		{StartPosition{Latest: true}, map[int]int64{0: 100, 1: 50}},
+		// Na partição 1 não há mensagens após 70 minutos: o grupo começa no fim.
+		{StartPosition{Time: base.Add(70 * time.Minute)}, map[int]int64{0: 70, 1: 50}},
+		{StartPosition{Offsets: map[int]int64{1: 25}}, map[int]int64{1: 25}},
+	}
+	for _, test := range tests {
+		client := newFakeOffsetsClient()
+		if _, err := ResetGroupOffsets(context.Background(), client, "group", "items", test.position, true); err != nil || len(client.commits) != 0 {
+			t.Fatalf("ResetGroupOffsets(%s, dry-run) = %v, %v", test.position, client.commits, err)
+		}
+		offsets, err := ResetGroupOffsets(context.Background(), client, "group", "items", test.position, false)
+		if err != nil {
+			t.Fatalf("ResetGroupOffsets(%s) = %v", test.position, err)
+		}
+		got := map[int]int64{}
+		for _, commit := range client.commits {
+			got[commit.Partition] = commit.Offset
+		}
+		if fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Errorf("ResetGroupOffsets(%s) confirmou %v, esperado %v", test.position, got, test.want)
+		}
+		if test.position.Offsets != nil && offsets[0].Committed != 60 {
+			t.Errorf("ResetGroupOffsets(%s) alterou a partição 0: %v", test.position, offsets)
+		}
+	}
+
+	for _, position := range []StartPosition{{Offsets: map[int]int64{0: 5}}, {Offsets: map[int]int64{0: 101}}, {Offsets: map[int]int64{2: 0}}} {
+		if _, err := ResetGroupOffsets(context.Background(), newFakeOffsetsClient(), "group", "items", position, false); err == nil {
+			t.Errorf("ResetGroupOffsets(%s) sem erro", position)
+		}
+	}
+	active := newFakeOffsetsClient()
+	active.members = 2
+	if _, err := ResetGroupOffsets(context.Background(), active, "group", "items", StartPosition{Earliest: true}, false); err == nil || len(active.commits) != 0 {
+		t.Errorf("ResetGroupOffsets() com consumidores ativos = %v", err)
+	}
+}
+
+// TestResetGroupOffsetsBroker usa um broker local, e.g. docker run -p 9092:9092 apache/kafka, e só
+// é executado com GETL_KAFKA_TEST_BROKERS (e.g. localhost:9092).
+func TestResetGroupOffsetsBroker(t *testing.T) {
+	brokers := os.Getenv("GETL_KAFKA_TEST_BROKERS")
+	if brokers == "" {
+		t.Skip("GETL_KAFKA_TEST_BROKERS não definido")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	topic := fmt.Sprintf("getl-offsets-%d", time.Now().UnixNano())
+	config := Config{KafkaURL: brokers, KafkaTopic: topic, KafkaGroupID: topic + "-group"}
+	client, err := NewKafkaClient(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := client.CreateTopics(ctx, &kafka.CreateTopicsRequest{Topics: []kafka.TopicConfig{{Topic: topic, NumPartitions: 2, ReplicationFactor: 1}}})
+	if err != nil || created.Errors[topic] != nil {
+		t.Fatalf("CreateTopics() = %v, %v", err, created)
+	}
+
+	writer, err := NewKafkaWriter(config, topic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer.Balancer = &kafka.Hash{}
+	writer.AllowAutoTopicCreation = false
+	middle := time.Now()
+	for i := 0; i < 10; i++ {
+		message := kafka.Message{Key: []byte(fmt.Sprint(i)), Value: []byte(fmt.Sprint(i)), Time: middle.Add(time.Duration(i-5) * time.Hour)}
+		if err := writer.WriteMessages(ctx, message); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = writer.Close()
+
+	for _, test := range []struct {
+		position StartPosition
+		lag      int64
+	}{
+		{StartPosition{Latest: true}, 0},
+		{StartPosition{Earliest: true}, 10},
+		{StartPosition{Time: middle}, 5},
+	} {
+		if _, err := ResetPipelineOffsets(ctx, config, test.position); err != nil {
+			t.Fatalf("ResetPipelineOffsets(%s) = %v", test.position, err)
+		}
+		offsets, err := GroupOffsets(ctx, client, config.KafkaGroupID, topic)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var lag int64
+		for _, offset := range offsets {
+			lag += offset.Lag()
+		}
+		if lag != test.lag {
+			t.Errorf("atraso após %s = %d, esperado %d (%v)", test.position, lag, test.lag, offsets)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package kafka
+
+import (
+	"fmt"
+	. "github.com/faelmori/getl/etypes"
+	. "github.com/faelmori/getl/utils"
+	"github.com/segmentio/kafka-go"
+)
+
+// NewProducerWriter cria o Writer de kafkaTopic com as opções de kafka.producer.
+func NewProducerWriter(config Config) (*kafka.Writer, error) {
+	writer, writerErr := NewKafkaWriter(config, config.KafkaTopic)
+	if writerErr != nil {
+		return nil, writerErr
+	}
+	if configureErr := ConfigureWriter(writer, config); configureErr != nil {
+		return nil, configureErr
+	}
+	return writer, nil
+}
+
+// ConfigureWriter aplica ao writer as opções de kafka.producer, antes da primeira escrita. Com uma
+// chave de mensagem e sem partitioner, as mensagens são distribuídas pelo hash da chave.
+func ConfigureWriter(writer *kafka.Writer, config Config) error {
+	producer := config.Kafka.Producer
+	balancer, balancerErr := producerBalancer(producer.Partitioner, ProducerKey(config) != "")
+	if balancerErr != nil {
+		return balancerErr
+	}
+	if balancer != nil {
+		writer.Balancer = balancer
+	}
+	writer.BatchSize = ProducerBatchSize(config)
+	if producer.Linger != "" {
+		linger, lingerErr := ParseStageTimeout(producer.Linger)
+		if lingerErr != nil {
+			return fmt.Errorf("kafka.producer.linger: %w", lingerErr)
+		}
+		writer.BatchTimeout = linger
+	}
+	switch producer.Compression {
+	case "":
+	case "gzip":
+		writer.Compression = kafka.Gzip
+	case "snappy":
+		writer.Compression = kafka.Snappy
+	case "lz4":
+		writer.Compression = kafka.Lz4
+	case "zstd":
+		writer.Compression = kafka.Zstd
+	default:
+		return fmt.Errorf("kafka.producer.compression desconhecida: %s", producer.Compression)
+	}
+	switch producer.RequiredAcks {
+	case "":
+	case "none":
+		writer.RequiredAcks = kafka.RequireNone
+	case "one":
+		writer.RequiredAcks = kafka.RequireOne
+	case "all":
+		writer.RequiredAcks = kafka.RequireAll
+	default:
+		return fmt.Errorf("kafka.producer.requiredAcks desconhecido: %s", producer.RequiredAcks)
+	}
+	return nil
+}
+
+// producerBalancer retorna o particionador configurado; sem ele, o hash da chave quando as mensagens
+// têm chave ou nil, mantendo o do Writer.
+func producerBalancer(partitioner string, keyed bool) (kafka.Balancer, error) {
+	switch partitioner {
+	case "":
+		if keyed {
+			return &kafka.Hash{}, nil
+		}
+		return nil, nil
+	case "hash":
+		return &kafka.Hash{}, nil
+	case "murmur2":
+		return kafka.Murmur2Balancer{}, nil
+	case "crc32":
+		return kafka.CRC32Balancer{}, nil
+	case "roundrobin":
+		return &kafka.RoundRobin{}, nil
+	case "leastbytes":
+		return &kafka.LeastBytes{}, nil
+	}
+	return nil, fmt.Errorf("kafka.producer.partitioner desconhecido: %s", partitioner)
+}
+
+// messageHeaders converte os cabeçalhos de ProducerHeaders para os da mensagem.
+func messageHeaders(config Config, runID string) []kafka.Header {
+	pairs := ProducerHeaders(config, runID)
+	headers := make([]kafka.Header, 0, len(pairs))
+	for _, pair := range pairs {
+		headers = append(headers, kafka.Header{Key: pair[0], Value: []byte(pair[1])})
+	}
+	return headers
+}
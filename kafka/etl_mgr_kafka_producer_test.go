@@ -0,0 +1,38 @@
+package kafka
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/faelmori/getl/etypes"
+	"github.com/segmentio/kafka-go"
+)
+
+// TestConfigureWriter verifica as opções de kafka.producer aplicadas ao Writer.
+func TestConfigureWriter(t *testing.T) {
+	writer := CreateKafkaWriter("localhost:9092", "items")
+	config := Config{Kafka: KafkaOptions{Producer: KafkaProducer{BatchSize: 500, Linger: "50ms", Compression: "zstd", RequiredAcks: "all"}}}
+	if err := ConfigureWriter(writer, config); err != nil {
+		t.Fatalf("ConfigureWriter() = %v", err)
+	}
+	if _, ok := writer.Balancer.(*kafka.LeastBytes); !ok {
+		t.Errorf("Balancer sem chave = %T, esperado o do Writer", writer.Balancer)
+	}
+	if writer.BatchSize != 500 || writer.BatchTimeout != 50*time.Millisecond || writer.Compression != kafka.Zstd || writer.RequiredAcks != kafka.RequireAll {
+		t.Errorf("Writer = batch %d, linger %s, compressão %v, acks %v", writer.BatchSize, writer.BatchTimeout, writer.Compression, writer.RequiredAcks)
+	}
+
+	keyed := CreateKafkaWriter("localhost:9092", "items")
+	if err := ConfigureWriter(keyed, Config{PrimaryKey: "id"}); err != nil {
+		t.Fatalf("ConfigureWriter() = %v", err)
+	}
+	if _, ok := keyed.Balancer.(*kafka.Hash); !ok || keyed.BatchSize != 100 {
+		t.Errorf("Writer com chave = %T, batch %d", keyed.Balancer, keyed.BatchSize)
+	}
+
+	for _, producer := range []KafkaProducer{{Partitioner: "random"}, {Compression: "brotli"}, {RequiredAcks: "two"}, {Linger: "soon"}} {
+		if err := ConfigureWriter(CreateKafkaWriter("localhost:9092", "items"), Config{Kafka: KafkaOptions{Producer: producer}}); err == nil {
+			t.Errorf("ConfigureWriter(%+v) sem erro", producer)
+		}
+	}
+}
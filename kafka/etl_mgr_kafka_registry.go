@@ -0,0 +1,199 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	. "github.com/faelmori/getl/etypes"
+	. "github.com/faelmori/getl/utils"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// registryContentType é o tipo de conteúdo da API do schema registry.
+const registryContentType = "application/vnd.schemaregistry.v1+json"
+
+// Códigos de erro da API para subject e versão inexistentes.
+const (
+	registrySubjectNotFound = 40401
+	registryVersionNotFound = 40402
+)
+
+// RegistryClient é um cliente da API HTTP de um schema registry compatível com o do Confluent.
+// Os schemas lidos pelo id ficam em cache, já que o schema de um id não muda.
+type RegistryClient struct {
+	Options SchemaRegistry
+	Client  *http.Client
+
+	mu     sync.Mutex
+	codecs map[int]RowCodec
+}
+
+// RegistryError é um erro retornado pela API, com o código de erro do registry.
+type RegistryError struct {
+	StatusCode int
+	Code       int    `json:"error_code"`
+	Message    string `json:"message"`
+}
+
+func (e *RegistryError) Error() string {
+	return fmt.Sprintf("schema registry respondeu %d (%d): %s", e.StatusCode, e.Code, e.Message)
+}
+
+// NewRegistryClient cria o cliente do schema registry de kafka.schemaRegistry.
+func NewRegistryClient(options SchemaRegistry) *RegistryClient {
+	return &RegistryClient{Options: options, Client: &http.Client{Timeout: 30 * time.Second}, codecs: map[int]RowCodec{}}
+}
+
+// CheckCompatibility verifica se o schema do codec é compatível com a última versão do subject,
+// pela regra de compatibilidade configurada no registry. Um subject ainda sem versões é compatível.
+// Retorna os motivos informados pelo registry quando o schema é incompatível.
+func (r *RegistryClient) CheckCompatibility(ctx context.Context, subject string, codec RowCodec) (bool, []string, error) {
+	var response struct {
+		IsCompatible bool     `json:"is_compatible"`
+		Messages     []string `json:"messages"`
+	}
+	path := "/compatibility/subjects/" + url.PathEscape(subject) + "/versions/latest?verbose=true"
+	if err := r.do(ctx, http.MethodPost, path, schemaRequest(codec), &response); err != nil {
+		if registryErr, ok := err.(*RegistryError); ok && (registryErr.Code == registrySubjectNotFound || registryErr.Code == registryVersionNotFound) {
+			return true, nil, nil
+		}
+		return false, nil, err
+	}
+	return response.IsCompatible, response.Messages, nil
+}
+
+// Register registra o schema do codec no subject e retorna o seu id. Registrar um schema já
+// registrado retorna o id existente.
+func (r *RegistryClient) Register(ctx context.Context, subject string, codec RowCodec) (int, error) {
+	var response struct {
+		ID int `json:"id"`
+	}
+	if err := r.do(ctx, http.MethodPost, "/subjects/"+url.PathEscape(subject)+"/versions", schemaRequest(codec), &response); err != nil {
+		return 0, err
+	}
+	r.mu.Lock()
+	r.codecs[response.ID] = codec
+	r.mu.Unlock()
+	return response.ID, nil
+}
+
+// Codec retorna o codec do schema de id, lido do registry na primeira vez.
+func (r *RegistryClient) Codec(ctx context.Context, id int) (RowCodec, error) {
+	r.mu.Lock()
+	codec, ok := r.codecs[id]
+	r.mu.Unlock()
+	if ok {
+		return codec, nil
+	}
+	var response struct {
+		Schema     string `json:"schema"`
+		SchemaType string `json:"schemaType"`
+	}
+	if err := r.do(ctx, http.MethodGet, fmt.Sprintf("/schemas/ids/%d", id), nil, &response); err != nil {
+		return nil, fmt.Errorf("falha ao ler o schema %d: %w", id, err)
+	}
+	codec, err := ParseRowCodec(response.SchemaType, response.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("schema %d: %w", id, err)
+	}
+	r.mu.Lock()
+	r.codecs[id] = codec
+	r.mu.Unlock()
+	return codec, nil
+}
+
+// schemaRequest monta o corpo das requisições com o schema; o tipo AVRO é o padrão e não é enviado.
+func schemaRequest(codec RowCodec) map[string]string {
+	request := map[string]string{"schema": codec.Schema()}
+	if codec.SchemaType() != AvroSchemaType {
+		request["schemaType"] = codec.SchemaType()
+	}
+	return request
+}
+
+func (r *RegistryClient) do(ctx context.Context, method, path string, body, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	request, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(r.Options.URL, "/")+path, reader)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Accept", registryContentType)
+	if body != nil {
+		request.Header.Set("Content-Type", registryContentType)
+	}
+	if r.Options.Username != "" {
+		request.SetBasicAuth(r.Options.Username, r.Options.Password)
+	}
+	response, err := r.Client.Do(request)
+	if err != nil {
+		return fmt.Errorf("falha ao acessar o schema registry: %w", err)
+	}
+	defer func(body io.ReadCloser) {
+		_ = body.Close()
+	}(response.Body)
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return fmt.Errorf("falha ao ler a resposta do schema registry: %w", err)
+	}
+	if response.StatusCode >= 300 {
+		registryErr := &RegistryError{StatusCode: response.StatusCode}
+		if json.Unmarshal(data, registryErr) != nil || registryErr.Message == "" {
+			registryErr.Message = strings.TrimSpace(string(data))
+		}
+		return registryErr
+	}
+	if err := json.Unmarshal(data, result); err != nil {
+		return fmt.Errorf("resposta inválida do schema registry: %w", err)
+	}
+	return nil
+}
+
+// rowSerializer serializa as linhas publicadas com o schema registrado, no wire format do Confluent.
+type rowSerializer struct {
+	codec    RowCodec
+	schemaID int
+}
+
+// newRowSerializer deduz o schema de kafka.format das colunas da consulta, verifica a sua
+// compatibilidade com a última versão do subject e o registra antes da primeira mensagem.
+func newRowSerializer(ctx context.Context, config Config, fields []SchemaField) (*rowSerializer, error) {
+	codec, codecErr := NewRowCodec(config.Kafka.Format, SchemaRecordName(config), fields)
+	if codecErr != nil {
+		return nil, codecErr
+	}
+	registry := NewRegistryClient(config.Kafka.SchemaRegistry)
+	subject := SchemaSubject(config)
+	compatible, reasons, checkErr := registry.CheckCompatibility(ctx, subject, codec)
+	if checkErr != nil {
+		return nil, fmt.Errorf("falha ao verificar a compatibilidade do schema com o subject %s: %w", subject, checkErr)
+	}
+	if !compatible {
+		return nil, fmt.Errorf("o schema das colunas da consulta é incompatível com a última versão do subject %s: %s", subject, strings.Join(reasons, "; "))
+	}
+	schemaID, registerErr := registry.Register(ctx, subject, codec)
+	if registerErr != nil {
+		return nil, fmt.Errorf("falha ao registrar o schema no subject %s: %w", subject, registerErr)
+	}
+	return &rowSerializer{codec: codec, schemaID: schemaID}, nil
+}
+
+func (e *rowSerializer) serialize(row Data) ([]byte, error) {
+	payload, err := e.codec.Encode(row)
+	if err != nil {
+		return nil, err
+	}
+	return EncodeSchemaMessage(e.schemaID, payload), nil
+}
@@ -0,0 +1,160 @@
+package kafka
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/faelmori/getl/etypes"
+	. "github.com/faelmori/getl/utils"
+	"github.com/segmentio/kafka-go"
+)
+
+// fakeRegistry simula a API do schema registry: guarda os schemas por subject e, com incompatible,
+// recusa qualquer schema novo de um subject existente.
+type fakeRegistry struct {
+	mu           sync.Mutex
+	schemas      []map[string]string
+	subjects     map[string][]int
+	incompatible bool
+}
+
+func (f *fakeRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w.Header().Set("Content-Type", registryContentType)
+	var request map[string]string
+	if r.Method == http.MethodPost {
+		_ = json.NewDecoder(r.Body).Decode(&request)
+	}
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(parts) == 5 && parts[0] == "compatibility":
+		if len(f.subjects[parts[2]]) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error_code": 40401, "message": "Subject not found."}`))
+			return
+		}
+		compatible := !f.incompatible
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"is_compatible": compatible, "messages": []string{"campo removido"}})
+	case len(parts) == 3 && parts[0] == "subjects":
+		f.schemas = append(f.schemas, request)
+		f.subjects[parts[1]] = append(f.subjects[parts[1]], len(f.schemas))
+		_ = json.NewEncoder(w).Encode(map[string]int{"id": len(f.schemas)})
+	case len(parts) == 3 && parts[0] == "schemas":
+		if id, err := strconv.Atoi(parts[2]); err == nil && id >= 1 && id <= len(f.schemas) {
+			_ = json.NewEncoder(w).Encode(f.schemas[id-1])
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error_code": 40403, "message": "Schema not found"}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newFakeRegistry(t *testing.T) (*fakeRegistry, string) {
+	registry := &fakeRegistry{subjects: map[string][]int{}}
+	server := httptest.NewServer(registry)
+	t.Cleanup(server.Close)
+	return registry, server.URL
+}
+
+// TestRowSerializer verifica o registro do schema, a verificação de compatibilidade e a leitura
+// das mensagens com o schema obtido pelo id.
+func TestRowSerializer(t *testing.T) {
+	registry, url := newFakeRegistry(t)
+	fields := MessageSchemaFields([]string{"id", "name"}, []string{"INTEGER", "TEXT"})
+	for _, format := range []string{"avro", "protobuf"} {
+		config := Config{KafkaTopic: "items", SourceTable: "items", Kafka: KafkaOptions{Format: format, SchemaRegistry: SchemaRegistry{URL: url, Subject: "items-" + format}}}
+		serializer, err := newRowSerializer(context.Background(), config, fields)
+		if err != nil {
+			t.Fatalf("%s: newRowSerializer() = %v", format, err)
+		}
+		message, err := serializer.serialize(Data{"id": int64(1), "name": "a"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		schemaID, payload, ok := DecodeSchemaMessage(message)
+		if !ok || schemaID != serializer.schemaID {
+			t.Fatalf("%s: mensagem fora do wire format: %v", format, message)
+		}
+		codec, err := NewRegistryClient(config.Kafka.SchemaRegistry).Codec(context.Background(), schemaID)
+		if err != nil {
+			t.Fatalf("%s: Codec() = %v", format, err)
+		}
+		if row, err := codec.Decode(payload); err != nil || row["id"] != int64(1) || row["name"] != "a" {
+			t.Errorf("%s: Decode() = %v, %v", format, row, err)
+		}
+	}
+	registry.mu.Lock()
+	if registry.schemas[0]["schemaType"] != "" || registry.schemas[1]["schemaType"] != ProtobufSchemaType {
+		t.Errorf("schemaType registrados = %v", registry.schemas)
+	}
+	registry.incompatible = true
+	registry.mu.Unlock()
+	config := Config{KafkaTopic: "items", Kafka: KafkaOptions{Format: "avro", SchemaRegistry: SchemaRegistry{URL: url, Subject: "items-avro"}}}
+	if _, err := newRowSerializer(context.Background(), config, fields); err == nil || !strings.Contains(err.Error(), "campo removido") {
+		t.Errorf("newRowSerializer() com schema incompatível = %v", err)
+	}
+}
+
+// TestConsumeSchemaMessages verifica a carga de mensagens avro ao lado de mensagens JSON e que,
+// sem o schema registry, o lote não é gravado nem confirmado.
+func TestConsumeSchemaMessages(t *testing.T) {
+	_, url := newFakeRegistry(t)
+	config := consumerConfig(t)
+	config.KafkaTopic = "items"
+	config.Kafka.Format = "avro"
+	config.Kafka.SchemaRegistry = SchemaRegistry{URL: url}
+	serializer, err := newRowSerializer(context.Background(), config, MessageSchemaFields([]string{"id", "name"}, []string{"BIGINT", "VARCHAR"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _ := serializer.serialize(Data{"id": int64(1), "name": "a"})
+	second, _ := serializer.serialize(Data{"id": int64(2), "name": "b"})
+	batch := messages(string(first), `{"id": 3, "name": "c"}`, string(second))
+
+	reader := &fakeReader{messages: batch}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- consumeToDestination(ctx, config, reader, nil) }()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(reader.committedOffsets()) < len(reader.messages) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("consumeToDestination() = %v", err)
+	}
+	db, err := sql.Open("sqlite3", config.DestinationConnectionString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM items WHERE name IN ('a', 'b', 'c')").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Errorf("%d linha(s) no destino, esperado 3", count)
+	}
+
+	config = consumerConfig(t)
+	reader = &fakeReader{messages: []kafka.Message{batch[0]}}
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := consumeToDestination(ctx, config, reader, nil); err == nil {
+		t.Errorf("consumeToDestination() sem erro com uma mensagem avro e sem schema registry")
+	}
+	if committed := reader.committedOffsets(); len(committed) != 0 {
+		t.Errorf("offsets confirmados sem o schema = %v", committed)
+	}
+}
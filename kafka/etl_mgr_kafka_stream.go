@@ -0,0 +1,332 @@
+package kafka
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	. "github.com/faelmori/getl/etypes"
+	. "github.com/faelmori/getl/utils"
+	"github.com/faelmori/logz"
+	"github.com/segmentio/kafka-go"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"sync"
+	"time"
+)
+
+// newStreamSession cria a sessão transacional do franz-go de getl stream: consome kafkaTopic no
+// kafkaGroupID e publica em kafka.stream.outputTopic com o transactionalID de config, confirmando
+// os offsets consumidos na transação de cada lote. O cliente refaz a época do produtor após uma
+// transação que falha, e um produtor mais novo com o mesmo id interrompe o anterior.
+func newStreamSession(config Config) (*kgo.GroupTransactSession, error) {
+	timeout, timeoutErr := StreamTransactionTimeout(config)
+	if timeoutErr != nil {
+		return nil, timeoutErr
+	}
+	balancer, balancerErr := producerBalancer(config.Kafka.Producer.Partitioner, ProducerKey(config) != "")
+	if balancerErr != nil {
+		return nil, balancerErr
+	}
+	if balancer == nil {
+		balancer = &kafka.RoundRobin{}
+	}
+	options, optionsErr := franzOptions(config)
+	if optionsErr != nil {
+		return nil, optionsErr
+	}
+	options = append(options,
+		kgo.TransactionalID(StreamTransactionalID(config)),
+		kgo.TransactionTimeout(timeout),
+		kgo.DefaultProduceTopic(config.Kafka.Stream.OutputTopic),
+		kgo.RecordPartitioner(balancerPartitioner(balancer)),
+		kgo.ConsumerGroup(config.KafkaGroupID),
+		kgo.ConsumeTopics(config.KafkaTopic),
+		kgo.RequireStableFetchOffsets(),
+	)
+	reader := config.Kafka.Reader
+	switch reader.StartOffset {
+	case "", "earliest":
+		options = append(options, kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()))
+	case "latest":
+		options = append(options, kgo.ConsumeResetOffset(kgo.NewOffset().AtEnd()))
+	default:
+		return nil, fmt.Errorf("kafka.reader.startOffset desconhecido: %s", reader.StartOffset)
+	}
+	switch reader.IsolationLevel {
+	case "", "read_committed":
+		options = append(options, kgo.FetchIsolationLevel(kgo.ReadCommitted()))
+	case "read_uncommitted":
+		options = append(options, kgo.FetchIsolationLevel(kgo.ReadUncommitted()))
+	default:
+		return nil, fmt.Errorf("kafka.reader.isolationLevel desconhecido: %s", reader.IsolationLevel)
+	}
+	if reader.MinBytes > 0 {
+		options = append(options, kgo.FetchMinBytes(int32(reader.MinBytes)))
+	}
+	if reader.MaxBytes > 0 {
+		options = append(options, kgo.FetchMaxBytes(int32(reader.MaxBytes)))
+	}
+	session, sessionErr := kgo.NewGroupTransactSession(options...)
+	if sessionErr != nil {
+		return nil, fmt.Errorf("falha ao criar o produtor transacional %s: %w", StreamTransactionalID(config), sessionErr)
+	}
+	return session, nil
+}
+
+// balancerPartitioner distribui as mensagens do franz-go com o kafka.Balancer de
+// kafka.producer.partitioner, como as publicadas com o kafka-go.
+func balancerPartitioner(balancer kafka.Balancer) kgo.Partitioner {
+	return kgo.BasicConsistentPartitioner(func(string) func(*kgo.Record, int) int {
+		var partitions []int
+		return func(record *kgo.Record, n int) int {
+			if len(partitions) != n {
+				partitions = make([]int, n)
+				for i := range partitions {
+					partitions[i] = i
+				}
+			}
+			return balancer.Balance(kafka.Message{Key: record.Key, Value: record.Value}, partitions...)
+		}
+	})
+}
+
+// pollBatch lê até size mensagens da sessão, como fetchBatch: espera a primeira até ctx ser cancelado
+// e as demais por até flushInterval. Retorna as mensagens lidas mesmo quando a leitura falha.
+func pollBatch(ctx context.Context, session *kgo.GroupTransactSession, size int, flushInterval time.Duration) ([]*kgo.Record, error) {
+	var batch []*kgo.Record
+	pollCtx := ctx
+	for len(batch) < size {
+		fetches := session.PollRecords(pollCtx, size-len(batch))
+		if fetches.IsClientClosed() {
+			return batch, kgo.ErrClientClosed
+		}
+		var fetchErr error
+		fetches.EachError(func(topic string, partition int32, err error) {
+			if fetchErr == nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+				fetchErr = fmt.Errorf("partição %d de %s: %w", partition, topic, err)
+			}
+		})
+		batch = append(batch, fetches.Records()...)
+		switch {
+		case fetchErr != nil:
+			return batch, fetchErr
+		case ctx.Err() != nil:
+			return batch, ctx.Err()
+		case pollCtx.Err() != nil:
+			return batch, nil
+		}
+		if len(batch) > 0 && pollCtx == ctx {
+			flushCtx, cancel := context.WithTimeout(ctx, flushInterval)
+			defer cancel()
+			pollCtx = flushCtx
+		}
+	}
+	return batch, nil
+}
+
+// StreamPipelines executa getl stream em cada pipeline, em paralelo, até ctx ser cancelado. Se uma
+// delas falhar, as demais são encerradas e o erro é retornado.
+func StreamPipelines(ctx context.Context, pipelines []Pipeline) error {
+	for _, pipeline := range pipelines {
+		if len(KafkaBrokers(pipeline.Config)) == 0 || pipeline.KafkaTopic == "" || pipeline.KafkaGroupID == "" || pipeline.Kafka.Stream.OutputTopic == "" {
+			return fmt.Errorf("pipeline %s: o stream exige kafkaURL (ou kafka.brokers), kafkaTopic, kafkaGroupID e kafka.stream.outputTopic", pipeline.Name)
+		}
+	}
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make([]error, len(pipelines))
+	var wg sync.WaitGroup
+	for i, pipeline := range pipelines {
+		wg.Add(1)
+		go func(i int, pipeline Pipeline) {
+			defer wg.Done()
+			logz.Info(fmt.Sprintf("pipeline %s: stream de %s para %s", pipeline.Name, pipeline.KafkaTopic, pipeline.Kafka.Stream.OutputTopic), map[string]interface{}{})
+			if streamErr := StreamContext(streamCtx, pipeline.Config); streamErr != nil {
+				errs[i] = fmt.Errorf("pipeline %s: %w", pipeline.Name, streamErr)
+				cancel()
+			}
+		}(i, pipeline)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// StreamContext consome kafkaTopic, aplica as transformations às linhas JSON e as publica em
+// kafka.stream.outputTopic até ctx ser cancelado. Cada lote de kafka.consumer.batchSize mensagens é
+// publicado em uma transação que também confirma os seus offsets no kafkaGroupID: os consumidores
+// com isolationLevel read_committed veem as linhas de um lote só quando os offsets são confirmados,
+// e um lote abortado é lido de novo, sem duplicar a saída. A entrada é lida com read_committed,
+// salvo kafka.reader.isolationLevel. Com kafka.stream.dedup.key, as linhas iguais à última publicada
+// com a mesma chave são descartadas. A deduplicação não faz parte da transação: o estado é gravado
+// depois dela, de modo que uma falha entre as duas gravações pode deixar passar uma duplicata, mas
+// não perde linhas. A deduplicação vale com uma instância por kafkaGroupID.
+func StreamContext(ctx context.Context, config Config) error {
+	var store *DedupStore
+	if config.Kafka.Stream.Dedup.Key != "" {
+		ttl, ttlErr := DedupTTL(config)
+		if ttlErr != nil {
+			return ttlErr
+		}
+		var storeErr error
+		if store, storeErr = OpenDedupStore(ctx, DedupPath(config), ttl); storeErr != nil {
+			return storeErr
+		}
+		defer func(store *DedupStore) {
+			_ = store.Close()
+		}(store)
+	}
+	session, sessionErr := newStreamSession(config)
+	if sessionErr != nil {
+		return sessionErr
+	}
+	defer session.Close()
+	return streamMessages(ctx, config, session, store)
+}
+
+// streamCounts conta as mensagens de getl stream: publicadas, descartadas como duplicatas e
+// descartadas por não poderem ser decodificadas ou transformadas.
+type streamCounts struct {
+	published, duplicates, invalid int
+}
+
+func streamMessages(ctx context.Context, config Config, session *kgo.GroupTransactSession, store *DedupStore) error {
+	flushInterval, intervalErr := ConsumerFlushInterval(config)
+	if intervalErr != nil {
+		return intervalErr
+	}
+	var total streamCounts
+	for {
+		batch, fetchErr := pollBatch(ctx, session, ConsumerBatchSize(config), flushInterval)
+		if len(batch) > 0 {
+			// O lote já lido é publicado mesmo se ctx for cancelado agora; só o timeout de consumo o interrompe.
+			counts, streamErr := streamBatch(context.WithoutCancel(ctx), config, session, store, batch)
+			total.published += counts.published
+			total.duplicates += counts.duplicates
+			total.invalid += counts.invalid
+			if streamErr != nil {
+				logz.Error(streamErr.Error(), map[string]interface{}{})
+				return streamErr
+			}
+		}
+		if fetchErr != nil {
+			if ctx.Err() != nil {
+				logz.Info(fmt.Sprintf("stream de %s para %s encerrado após %d linha(s) publicada(s), %d duplicata(s) e %d mensagem(ns) inválida(s)", config.KafkaTopic, config.Kafka.Stream.OutputTopic, total.published, total.duplicates, total.invalid), map[string]interface{}{})
+				return nil
+			}
+			return fmt.Errorf("erro ao ler mensagem do Kafka: %w", fetchErr)
+		}
+	}
+}
+
+// streamBatch transforma um lote e o publica em uma transação com os seus offsets. As mensagens sem
+// valor (tombstones) são ignoradas, e as que não podem ser decodificadas ou transformadas são
+// registradas e descartadas. Se a transação for abortada, a sessão volta aos offsets confirmados.
+func streamBatch(ctx context.Context, config Config, session *kgo.GroupTransactSession, store *DedupStore, batch []*kgo.Record) (streamCounts, error) {
+	var counts streamCounts
+	loadCtx, cancel, timeoutErr := WithStageTimeout(ctx, config.Timeouts.Consume)
+	if timeoutErr != nil {
+		return counts, timeoutErr
+	}
+	defer cancel()
+	if beginErr := session.Begin(); beginErr != nil {
+		return counts, fmt.Errorf("falha ao iniciar a transação: %w", beginErr)
+	}
+	abort := func(cause error) (streamCounts, error) {
+		if _, abortErr := session.End(context.WithoutCancel(ctx), kgo.TryAbort); abortErr != nil {
+			logz.Warn(fmt.Sprintf("falha ao abortar a transação de %s; ela expira após kafka.stream.transactionTimeout: %v", StreamTransactionalID(config), abortErr), map[string]interface{}{})
+		}
+		return counts, fmt.Errorf("lote de %d mensagem(ns) abortado; ele será lido de novo: %w", len(batch), cause)
+	}
+
+	now := time.Now()
+	key := ProducerKey(config)
+	headers := messageHeaders(config, "")
+	recordHeaders := make([]kgo.RecordHeader, len(headers))
+	for i, header := range headers {
+		recordHeaders[i] = kgo.RecordHeader{Key: header.Key, Value: header.Value}
+	}
+	records := make([]*kgo.Record, 0, len(batch))
+	hashes := map[string]string{}
+	for _, message := range batch {
+		if message.Value == nil {
+			continue
+		}
+		value, row, transformErr := streamRow(config, message.Value)
+		if transformErr != nil {
+			logz.Error(fmt.Sprintf("mensagem %d da partição %d de %s descartada: %v", message.Offset, message.Partition, message.Topic, transformErr), map[string]interface{}{})
+			counts.invalid++
+			continue
+		}
+		if store != nil {
+			dedupKey, keyErr := MessageKey(config.Kafka.Stream.Dedup.Key, row)
+			if keyErr != nil {
+				logz.Error(fmt.Sprintf("mensagem %d da partição %d de %s descartada: %v", message.Offset, message.Partition, message.Topic, keyErr), map[string]interface{}{})
+				counts.invalid++
+				continue
+			}
+			if dedupKey != nil {
+				sum := sha256.Sum256(value)
+				hash := hex.EncodeToString(sum[:])
+				last, seen := hashes[string(dedupKey)]
+				if !seen {
+					var lastErr error
+					if last, seen, lastErr = store.Last(loadCtx, string(dedupKey), now); lastErr != nil {
+						return abort(lastErr)
+					}
+				}
+				if seen && last == hash {
+					counts.duplicates++
+					continue
+				}
+				hashes[string(dedupKey)] = hash
+			}
+		}
+		messageKey, keyErr := MessageKey(key, row)
+		if keyErr != nil {
+			logz.Error(fmt.Sprintf("mensagem %d da partição %d de %s descartada: %v", message.Offset, message.Partition, message.Topic, keyErr), map[string]interface{}{})
+			counts.invalid++
+			continue
+		}
+		records = append(records, &kgo.Record{Key: messageKey, Value: value, Headers: recordHeaders, Timestamp: now})
+	}
+
+	if produceErr := session.ProduceSync(loadCtx, records...).FirstErr(); produceErr != nil {
+		return abort(fmt.Errorf("falha ao publicar em %s: %w", config.Kafka.Stream.OutputTopic, produceErr))
+	}
+	committed, endErr := session.End(loadCtx, kgo.TryCommit)
+	if endErr != nil {
+		return counts, fmt.Errorf("falha ao confirmar a transação de %s: %w", StreamTransactionalID(config), endErr)
+	}
+	if !committed {
+		// A sessão abortou a transação, como após um rebalanceamento do grupo; o lote é lido de novo.
+		logz.Warn(fmt.Sprintf("lote de %d mensagem(ns) abortado; ele será lido de novo", len(batch)), map[string]interface{}{})
+		return streamCounts{}, nil
+	}
+	counts.published = len(records)
+	if store != nil && len(hashes) > 0 {
+		if saveErr := store.Save(loadCtx, hashes, now); saveErr != nil {
+			return counts, fmt.Errorf("transação confirmada, mas o estado da deduplicação não foi gravado: %w", saveErr)
+		}
+	}
+	return counts, nil
+}
+
+// streamRow decodifica a linha JSON de uma mensagem, aplica as transformations e retorna o valor
+// publicado e a linha transformada.
+func streamRow(config Config, value []byte) ([]byte, Data, error) {
+	row, decodeErr := DecodeMessageRow(value)
+	if decodeErr != nil {
+		return nil, nil, decodeErr
+	}
+	transformed, transformErr := ApplyTransformations([]Data{row}, config.Transformations)
+	if transformErr != nil {
+		return nil, nil, transformErr
+	}
+	output, marshalErr := json.Marshal(transformed[0])
+	if marshalErr != nil {
+		return nil, nil, fmt.Errorf("falha ao serializar a linha: %w", marshalErr)
+	}
+	return output, transformed[0], nil
+}
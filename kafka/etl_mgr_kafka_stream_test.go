@@ -0,0 +1,310 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/faelmori/getl/etypes"
+	"github.com/segmentio/kafka-go"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// TestBalancerPartitioner verifica que as mensagens do stream vão para as partições escolhidas pelo
+// balancer do kafka-go.
+func TestBalancerPartitioner(t *testing.T) {
+	partitioner := balancerPartitioner(&kafka.Hash{}).ForTopic("items")
+	for _, key := range []string{"1", "2", "abc", "pedido-42"} {
+		record := &kgo.Record{Key: []byte(key)}
+		want := (&kafka.Hash{}).Balance(kafka.Message{Key: record.Key}, 0, 1, 2, 3, 4)
+		if got := partitioner.Partition(record, 5); got != want {
+			t.Errorf("Partition(%s) = %d, esperado %d", key, got, want)
+		}
+	}
+}
+
+// TestNewStreamSession verifica a validação das opções do stream.
+func TestNewStreamSession(t *testing.T) {
+	base := Config{KafkaURL: "localhost:9092", KafkaTopic: "items", KafkaGroupID: "group", Kafka: KafkaOptions{Stream: KafkaStream{OutputTopic: "items-upper"}}}
+	session, err := newStreamSession(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session.Close()
+
+	for name, change := range map[string]func(*Config){
+		"startOffset":        func(c *Config) { c.Kafka.Reader.StartOffset = "middle" },
+		"isolationLevel":     func(c *Config) { c.Kafka.Reader.IsolationLevel = "serializable" },
+		"partitioner":        func(c *Config) { c.Kafka.Producer.Partitioner = "random" },
+		"transactionTimeout": func(c *Config) { c.Kafka.Stream.TransactionTimeout = "soon" },
+		"sasl":               func(c *Config) { c.Kafka.SASL.Mechanism = "gssapi" },
+		"brokers":            func(c *Config) { c.KafkaURL = "" },
+	} {
+		config := base
+		change(&config)
+		if session, err := newStreamSession(config); err == nil {
+			session.Close()
+			t.Errorf("newStreamSession() aceitou %s inválido", name)
+		}
+	}
+}
+
+// streamBrokerConfig cria os tópicos de entrada (2 partições) e de saída (3 partições) de um teste
+// contra o broker, com as opções de configs no tópico de saída.
+func streamBrokerConfig(t *testing.T, brokers string, configs ...kafka.ConfigEntry) Config {
+	topic := fmt.Sprintf("getl-stream-%d", time.Now().UnixNano())
+	config := Config{
+		KafkaURL:     brokers,
+		KafkaTopic:   topic,
+		KafkaGroupID: topic + "-group",
+		PrimaryKey:   "id",
+		Transformations: []Transformation{
+			{SourceField: "id", DestinationField: "id", Operation: "copy"},
+			{SourceField: "name", DestinationField: "name", Operation: "uppercase"},
+		},
+		Kafka: KafkaOptions{
+			Consumer: KafkaConsumer{FlushInterval: "200ms"},
+			Stream: KafkaStream{
+				OutputTopic: topic + "-upper",
+				Dedup:       KafkaDedup{Key: "id", Path: filepath.Join(t.TempDir(), "dedup.db")},
+			},
+		},
+	}
+	client, err := NewKafkaClient(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := client.CreateTopics(context.Background(), &kafka.CreateTopicsRequest{Topics: []kafka.TopicConfig{
+		{Topic: config.KafkaTopic, NumPartitions: 2, ReplicationFactor: 1},
+		{Topic: config.Kafka.Stream.OutputTopic, NumPartitions: 3, ReplicationFactor: 1, ConfigEntries: configs},
+	}})
+	if err != nil || created.Errors[config.KafkaTopic] != nil || created.Errors[config.Kafka.Stream.OutputTopic] != nil {
+		t.Fatalf("CreateTopics() = %v, %v", err, created)
+	}
+	return config
+}
+
+// produceInput publica values na partição de kafkaTopic; os valores vazios são tombstones.
+func produceInput(t *testing.T, config Config, partition int, values ...string) {
+	client, err := NewKafkaClient(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := make([]kafka.Record, len(values))
+	for i, value := range values {
+		if value != "" {
+			records[i].Value = kafka.NewBytes([]byte(value))
+		}
+	}
+	response, err := client.Produce(context.Background(), &kafka.ProduceRequest{
+		Topic: config.KafkaTopic, Partition: partition, RequiredAcks: kafka.RequireAll, Records: kafka.NewRecordReader(records...),
+	})
+	if err == nil {
+		err = response.Error
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readOutput lê kafka.stream.outputTopic do início com o isolationLevel informado, até não haver
+// mais mensagens, e retorna as mensagens como "chave=valor", ordenadas.
+func readOutput(t *testing.T, config Config, level kgo.IsolationLevel) []string {
+	options, err := franzOptions(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := kgo.NewClient(append(options,
+		kgo.ConsumeTopics(config.Kafka.Stream.OutputTopic),
+		kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()),
+		kgo.FetchIsolationLevel(level),
+	)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	var values []string
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		fetches := client.PollFetches(ctx)
+		cancel()
+		for _, record := range fetches.Records() {
+			values = append(values, string(record.Key)+"="+string(record.Value))
+		}
+		if len(fetches.Records()) == 0 {
+			break
+		}
+	}
+	sort.Strings(values)
+	return values
+}
+
+// runStream executa StreamContext até o grupo confirmar os offsets ends da entrada, ou até o
+// stream falhar, e retorna o erro do stream.
+func runStream(t *testing.T, config Config, ends map[int]int64) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- StreamContext(ctx, config) }()
+
+	client, err := NewKafkaClient(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.After(time.Minute)
+	for {
+		select {
+		case err := <-done:
+			return err
+		case <-deadline:
+			cancel()
+			<-done
+			t.Fatal("o stream não confirmou os offsets da entrada")
+		case <-time.After(200 * time.Millisecond):
+		}
+		offsets, err := GroupOffsets(ctx, client, config.KafkaGroupID, config.KafkaTopic)
+		if err != nil {
+			continue
+		}
+		caughtUp := true
+		for _, offset := range offsets {
+			caughtUp = caughtUp && offset.Committed >= ends[offset.Partition]
+		}
+		if caughtUp {
+			cancel()
+			return <-done
+		}
+	}
+}
+
+// TestStreamContextBroker verifica a transformação, a deduplicação e a publicação com os offsets em
+// uma transação, lida com read_committed. Usa um broker local, e.g. docker run -p 9092:9092
+// apache/kafka, e só é executado com GETL_KAFKA_TEST_BROKERS (e.g. localhost:9092).
+func TestStreamContextBroker(t *testing.T) {
+	brokers := os.Getenv("GETL_KAFKA_TEST_BROKERS")
+	if brokers == "" {
+		t.Skip("GETL_KAFKA_TEST_BROKERS não definido")
+	}
+	config := streamBrokerConfig(t, brokers)
+	produceInput(t, config, 0, `{"id": 1, "name": "a"}`, `not json`, `{"id": 1, "name": "a"}`, ``)
+	produceInput(t, config, 1, `{"id": 2, "name": "b"}`, `{"id": 1, "name": "a2"}`)
+	if err := runStream(t, config, map[int]int64{0: 4, 1: 2}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{`1={"id":1,"name":"A"}`, `1={"id":1,"name":"A2"}`, `2={"id":2,"name":"B"}`}
+	if got := readOutput(t, config, kgo.ReadCommitted()); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("mensagens publicadas = %v, esperado %v", got, want)
+	}
+
+	// Um novo run lê só as mensagens novas; as linhas iguais às últimas publicadas são duplicatas.
+	produceInput(t, config, 1, `{"id": 2, "name": "b"}`, `{"id": 1, "name": "a2"}`, `{"id": 2, "name": "c"}`)
+	if err := runStream(t, config, map[int]int64{0: 4, 1: 5}); err != nil {
+		t.Fatal(err)
+	}
+	want = append(want, `2={"id":2,"name":"C"}`)
+	sort.Strings(want)
+	if got := readOutput(t, config, kgo.ReadCommitted()); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("mensagens publicadas = %v, esperado %v", got, want)
+	}
+}
+
+// TestStreamContextAbortBroker verifica que uma publicação recusada aborta a transação: nada é lido
+// com read_committed, os offsets e o estado da deduplicação não são gravados e, corrigido o tópico,
+// o próximo run publica o lote uma vez. Só é executado com GETL_KAFKA_TEST_BROKERS.
+func TestStreamContextAbortBroker(t *testing.T) {
+	brokers := os.Getenv("GETL_KAFKA_TEST_BROKERS")
+	if brokers == "" {
+		t.Skip("GETL_KAFKA_TEST_BROKERS não definido")
+	}
+	// O tópico de saída recusa lotes maiores que 1 KiB, como a linha de id 2.
+	config := streamBrokerConfig(t, brokers, kafka.ConfigEntry{ConfigName: "max.message.bytes", ConfigValue: "1024"})
+	large := strings.Repeat("x", 4096)
+	produceInput(t, config, 0, `{"id": 1, "name": "a"}`, `{"id": 2, "name": "`+large+`"}`)
+	err := runStream(t, config, map[int]int64{0: 2})
+	if err == nil || !strings.Contains(err.Error(), "abortado") {
+		t.Fatalf("StreamContext() = %v, esperado o abort do lote", err)
+	}
+	if got := readOutput(t, config, kgo.ReadCommitted()); len(got) != 0 {
+		t.Errorf("mensagens de uma transação abortada lidas com read_committed: %v", got)
+	}
+	client, err := NewKafkaClient(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offsets, err := GroupOffsets(context.Background(), client, config.KafkaGroupID, config.KafkaTopic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, offset := range offsets {
+		if offset.Committed > 0 {
+			t.Errorf("offset %d confirmado na partição %d após o abort", offset.Committed, offset.Partition)
+		}
+	}
+	store, err := OpenDedupStore(context.Background(), config.Kafka.Stream.Dedup.Path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, seen, err := store.Last(context.Background(), "1", time.Now()); err != nil || seen {
+		t.Errorf("estado da deduplicação gravado após o abort: %v, %v", seen, err)
+	}
+	_ = store.Close()
+
+	altered, err := client.AlterConfigs(context.Background(), &kafka.AlterConfigsRequest{Resources: []kafka.AlterConfigRequestResource{{
+		ResourceType: kafka.ResourceTypeTopic,
+		ResourceName: config.Kafka.Stream.OutputTopic,
+		Configs:      []kafka.AlterConfigRequestConfig{{Name: "max.message.bytes", Value: "1048588"}},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for resource, alterErr := range altered.Errors {
+		if alterErr != nil {
+			t.Fatalf("AlterConfigs(%s) = %v", resource.Name, alterErr)
+		}
+	}
+	if err := runStream(t, config, map[int]int64{0: 2}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{`1={"id":1,"name":"A"}`, `2={"id":2,"name":"` + strings.ToUpper(large) + `"}`}
+	if got := readOutput(t, config, kgo.ReadCommitted()); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("mensagens publicadas após o abort = %d, esperado %d", len(got), len(want))
+	}
+}
+
+// TestDedupStore verifica a expiração das chaves lembradas.
+func TestDedupStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "dedup.db")
+	store, err := OpenDedupStore(ctx, path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	if err := store.Save(ctx, map[string]string{"a": "1", "b": "2"}, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(ctx, map[string]string{"b": "3"}, now.Add(50*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if hash, seen, err := store.Last(ctx, "a", now.Add(30*time.Minute)); err != nil || !seen || hash != "1" {
+		t.Errorf("Last(a) = %s, %v, %v", hash, seen, err)
+	}
+	if _, seen, _ := store.Last(ctx, "a", now.Add(90*time.Minute)); seen {
+		t.Errorf("Last(a) lembrada após o ttl")
+	}
+	_ = store.Close()
+
+	// O estado é mantido no arquivo.
+	if store, err = OpenDedupStore(ctx, path, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if hash, seen, err := store.Last(ctx, "b", now.Add(90*time.Minute)); err != nil || !seen || hash != "3" {
+		t.Errorf("Last(b) = %s, %v, %v", hash, seen, err)
+	}
+}
@@ -0,0 +1,122 @@
+package meta
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/elgris/sqrl"
+	. "github.com/faelmori/getl/utils"
+	"strings"
+	"time"
+)
+
+// CDCPositionTable é a tabela do destino onde a captura de alterações registra a posição da origem
+// (e.g. o LSN do PostgreSQL) até onde as alterações foram aplicadas.
+const CDCPositionTable = "etl_cdc_positions"
+
+// CreateCDCPositionSchema cria a tabela de posições no banco, se ela ainda não existir.
+func CreateCDCPositionSchema(ctx context.Context, db *sql.DB, driver string) error {
+	text := "VARCHAR"
+	if driver == "godror" || driver == "oracle" {
+		text = "VARCHAR2"
+	}
+	columns := fmt.Sprintf(`(
+		stream_name %[1]s(255) NOT NULL PRIMARY KEY,
+		source_position %[1]s(255) NOT NULL,
+		updated_at %[1]s(40) NOT NULL
+	)`, text)
+
+	var createTableQuery string
+	switch driver {
+	case "sqlserver", "mssql":
+		createTableQuery = fmt.Sprintf("IF OBJECT_ID('%[1]s', 'U') IS NULL CREATE TABLE %[1]s %[2]s", CDCPositionTable, columns)
+	case "godror", "oracle":
+		createTableQuery = fmt.Sprintf("CREATE TABLE %s %s", CDCPositionTable, columns)
+	default:
+		createTableQuery = fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s %s", CDCPositionTable, columns)
+	}
+
+	if _, err := db.ExecContext(ctx, createTableQuery); err != nil {
+		// O Oracle não tem IF NOT EXISTS: ORA-00955 indica que a tabela já existe.
+		if strings.Contains(err.Error(), "ORA-00955") {
+			return nil
+		}
+		return fmt.Errorf("falha ao criar a tabela de posições: %w", err)
+	}
+	return nil
+}
+
+// LoadCDCPosition retorna a última posição registrada para o stream, ou "" se não houver nenhuma.
+func LoadCDCPosition(ctx context.Context, db *sql.DB, driver, stream string) (string, error) {
+	query, args, err := sqrl.Select("source_position").
+		From(CDCPositionTable).
+		Where(sqrl.Eq{"stream_name": stream}).
+		PlaceholderFormat(PlaceholderFormatFor(driver)).
+		ToSql()
+	if err != nil {
+		return "", fmt.Errorf("falha ao gerar a consulta de posição: %w", err)
+	}
+
+	var position string
+	scanErr := db.QueryRowContext(ctx, query, args...).Scan(&position)
+	if errors.Is(scanErr, sql.ErrNoRows) {
+		return "", nil
+	}
+	if scanErr != nil {
+		return "", fmt.Errorf("falha ao ler a posição de %s: %w", stream, scanErr)
+	}
+	return position, nil
+}
+
+// SaveCDCPosition grava a posição do stream. Chamado com a transação que aplica as alterações,
+// a posição só é confirmada junto com elas.
+func SaveCDCPosition(ctx context.Context, tx execer, driver, stream, position string) error {
+	updatedAt := time.Now().UTC().Format(time.RFC3339Nano)
+	placeholder := PlaceholderFormatFor(driver)
+
+	update, updateArgs, err := sqrl.Update(CDCPositionTable).
+		Set("source_position", position).
+		Set("updated_at", updatedAt).
+		Where(sqrl.Eq{"stream_name": stream}).
+		PlaceholderFormat(placeholder).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("falha ao gerar a atualização da posição: %w", err)
+	}
+	result, err := tx.ExecContext(ctx, update, updateArgs...)
+	if err != nil {
+		return fmt.Errorf("falha ao atualizar a posição de %s: %w", stream, err)
+	}
+	if affected, _ := result.RowsAffected(); affected > 0 {
+		return nil
+	}
+
+	insert, insertArgs, err := sqrl.Insert(CDCPositionTable).
+		Columns("stream_name", "source_position", "updated_at").
+		Values(stream, position, updatedAt).
+		PlaceholderFormat(placeholder).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("falha ao gerar a inserção da posição: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, insert, insertArgs...); err != nil {
+		return fmt.Errorf("falha ao inserir a posição de %s: %w", stream, err)
+	}
+	return nil
+}
+
+// DeleteCDCPosition remove a posição registrada do stream.
+func DeleteCDCPosition(ctx context.Context, db *sql.DB, driver, stream string) error {
+	query, args, err := sqrl.Delete(CDCPositionTable).
+		Where(sqrl.Eq{"stream_name": stream}).
+		PlaceholderFormat(PlaceholderFormatFor(driver)).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("falha ao gerar a remoção da posição: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("falha ao remover a posição de %s: %w", stream, err)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package meta
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/elgris/sqrl"
+	. "github.com/faelmori/getl/utils"
+	"strings"
+	"time"
+)
+
+// CheckpointTable é a tabela do destino onde os checkpoints das cargas em lotes são gravados.
+const CheckpointTable = "etl_checkpoints"
+
+const (
+	// CheckpointRunning indica que a pipeline tem lotes confirmados e ainda não terminou.
+	CheckpointRunning = "running"
+	// CheckpointDone indica que todos os lotes da pipeline foram confirmados.
+	CheckpointDone = "done"
+)
+
+// Checkpoint é o progresso de uma pipeline em uma execução: quantos lotes e linhas foram
+// confirmados e, com Checkpoint.Key, o valor da chave da última linha confirmada.
+type Checkpoint struct {
+	RunID     string
+	Pipeline  string
+	Batches   int64
+	Rows      int64
+	LastKey   string
+	Status    string
+	UpdatedAt string
+}
+
+// execer é satisfeito por *sql.DB e *sql.Tx, para gravar o checkpoint na transação do lote.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// NewRunID gera o identificador de uma execução, ordenável pela data de início.
+func NewRunID() string {
+	suffix := make([]byte, 3)
+	_, _ = rand.Read(suffix)
+	return time.Now().UTC().Format("20060102T150405") + "-" + hex.EncodeToString(suffix)
+}
+
+// CreateCheckpointSchema cria a tabela de checkpoints no banco, se ela ainda não existir.
+func CreateCheckpointSchema(ctx context.Context, db *sql.DB, driver string) error {
+	text, number := "VARCHAR", "BIGINT"
+	if driver == "godror" || driver == "oracle" {
+		text, number = "VARCHAR2", "NUMBER(19)"
+	}
+	columns := fmt.Sprintf(`(
+		run_id %[1]s(64) NOT NULL,
+		pipeline %[1]s(255) NOT NULL,
+		batches %[2]s NOT NULL,
+		row_count %[2]s NOT NULL,
+		last_key %[1]s(1024),
+		status %[1]s(16) NOT NULL,
+		updated_at %[1]s(40) NOT NULL,
+		PRIMARY KEY (run_id, pipeline)
+	)`, text, number)
+
+	var createTableQuery string
+	switch driver {
+	case "sqlserver", "mssql":
+		createTableQuery = fmt.Sprintf("IF OBJECT_ID('%[1]s', 'U') IS NULL CREATE TABLE %[1]s %[2]s", CheckpointTable, columns)
+	case "godror", "oracle":
+		createTableQuery = fmt.Sprintf("CREATE TABLE %s %s", CheckpointTable, columns)
+	default:
+		createTableQuery = fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s %s", CheckpointTable, columns)
+	}
+
+	if _, err := db.ExecContext(ctx, createTableQuery); err != nil {
+		// O Oracle não tem IF NOT EXISTS: ORA-00955 indica que a tabela já existe.
+		if strings.Contains(err.Error(), "ORA-00955") {
+			return nil
+		}
+		return fmt.Errorf("falha ao criar a tabela de checkpoints: %w", err)
+	}
+	return nil
+}
+
+// LoadCheckpoint retorna o checkpoint da pipeline na execução, ou nil se ela não gravou nenhum lote.
+func LoadCheckpoint(ctx context.Context, db *sql.DB, driver, runID, pipeline string) (*Checkpoint, error) {
+	query, args, err := sqrl.Select("batches", "row_count", "last_key", "status", "updated_at").
+		From(CheckpointTable).
+		Where(sqrl.Eq{"run_id": runID, "pipeline": pipeline}).
+		PlaceholderFormat(PlaceholderFormatFor(driver)).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("falha ao gerar a consulta de checkpoint: %w", err)
+	}
+
+	checkpoint := Checkpoint{RunID: runID, Pipeline: pipeline}
+	var lastKey sql.NullString
+	scanErr := db.QueryRowContext(ctx, query, args...).Scan(&checkpoint.Batches, &checkpoint.Rows, &lastKey, &checkpoint.Status, &checkpoint.UpdatedAt)
+	if errors.Is(scanErr, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if scanErr != nil {
+		return nil, fmt.Errorf("falha ao ler o checkpoint: %w", scanErr)
+	}
+	checkpoint.LastKey = lastKey.String
+	return &checkpoint, nil
+}
+
+// SaveCheckpoint grava o checkpoint. Chamado com a transação do lote, o checkpoint só é
+// confirmado junto com as linhas que ele registra.
+func SaveCheckpoint(ctx context.Context, tx execer, driver string, checkpoint Checkpoint) error {
+	checkpoint.UpdatedAt = time.Now().UTC().Format(time.RFC3339Nano)
+	var lastKey interface{}
+	if checkpoint.LastKey != "" {
+		lastKey = checkpoint.LastKey
+	}
+	placeholder := PlaceholderFormatFor(driver)
+
+	update, updateArgs, err := sqrl.Update(CheckpointTable).
+		Set("batches", checkpoint.Batches).
+		Set("row_count", checkpoint.Rows).
+		Set("last_key", lastKey).
+		Set("status", checkpoint.Status).
+		Set("updated_at", checkpoint.UpdatedAt).
+		Where(sqrl.Eq{"run_id": checkpoint.RunID, "pipeline": checkpoint.Pipeline}).
+		PlaceholderFormat(placeholder).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("falha ao gerar a atualização do checkpoint: %w", err)
+	}
+	result, err := tx.ExecContext(ctx, update, updateArgs...)
+	if err != nil {
+		return fmt.Errorf("falha ao atualizar o checkpoint: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected > 0 {
+		return nil
+	}
+
+	insert, insertArgs, err := sqrl.Insert(CheckpointTable).
+		Columns("run_id", "pipeline", "batches", "row_count", "last_key", "status", "updated_at").
+		Values(checkpoint.RunID, checkpoint.Pipeline, checkpoint.Batches, checkpoint.Rows, lastKey, checkpoint.Status, checkpoint.UpdatedAt).
+		PlaceholderFormat(placeholder).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("falha ao gerar a inserção do checkpoint: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, insert, insertArgs...); err != nil {
+		return fmt.Errorf("falha ao inserir o checkpoint: %w", err)
+	}
+	return nil
+}
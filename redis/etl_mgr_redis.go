@@ -0,0 +1,30 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	. "github.com/faelmori/getl/utils"
+	"github.com/redis/go-redis/v9"
+)
+
+// NewRedisClient cria o cliente da connection string redis://[usuário:senha@]host:porta[/db]
+// (rediss:// com TLS). As opções do go-redis podem ser passadas na query, e.g. ?dial_timeout=3s.
+func NewRedisClient(connectionString string) (*redis.Client, error) {
+	options, parseErr := redis.ParseURL(connectionString)
+	if parseErr != nil {
+		return nil, fmt.Errorf("connection string do Redis inválida %s: %w", MaskConnectionString(connectionString), parseErr)
+	}
+	return redis.NewClient(options), nil
+}
+
+// Ping testa a conexão com o Redis da connection string.
+func Ping(ctx context.Context, connectionString string) error {
+	client, clientErr := NewRedisClient(connectionString)
+	if clientErr != nil {
+		return clientErr
+	}
+	defer func(client *redis.Client) {
+		_ = client.Close()
+	}(client)
+	return client.Ping(ctx).Err()
+}
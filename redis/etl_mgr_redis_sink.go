@@ -0,0 +1,105 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	. "github.com/faelmori/getl/etypes"
+	. "github.com/faelmori/getl/utils"
+	"github.com/faelmori/logz"
+	"github.com/redis/go-redis/v9"
+	"maps"
+	"slices"
+	"time"
+)
+
+// Sink grava as linhas no destino Redis como hashes, entradas de um stream ou elementos de uma lista,
+// conforme redis.sink.type.
+type Sink struct {
+	client *redis.Client
+	config Config
+	ttl    time.Duration
+}
+
+// OpenSink conecta à destinationConnectionString.
+func OpenSink(config Config) (*Sink, error) {
+	ttl, ttlErr := RedisSinkTTL(config)
+	if ttlErr != nil {
+		return nil, ttlErr
+	}
+	client, clientErr := NewRedisClient(config.DestinationConnectionString)
+	if clientErr != nil {
+		return nil, clientErr
+	}
+	return &Sink{client: client, config: config, ttl: ttl}, nil
+}
+
+// Write grava as linhas em transações MULTI/EXEC de redis.batchSize linhas e retorna quantas foram
+// gravadas. Com hash, cada linha substitui o hash da sua chave, sem os campos nulos, e recebe o TTL;
+// com stream, é uma entrada com as colunas não nulas, e o stream é limitado a maxLen; com list, é
+// acrescentada ao fim da lista em JSON. Se uma transação falhar, as anteriores já foram gravadas.
+func (s *Sink) Write(ctx context.Context, rows []Data) (int, error) {
+	sinkType := RedisSinkType(s.config)
+	batchSize := RedisBatchSize(s.config)
+	written := 0
+	for start := 0; start < len(rows); start += batchSize {
+		batch := rows[start:min(start+batchSize, len(rows))]
+		if _, txErr := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, row := range batch {
+				if writeErr := s.write(ctx, pipe, sinkType, row); writeErr != nil {
+					return writeErr
+				}
+			}
+			return nil
+		}); txErr != nil {
+			return written, fmt.Errorf("falha ao gravar no Redis após %d de %d linha(s): %w", written, len(rows), txErr)
+		}
+		written += len(batch)
+	}
+	logz.Info(fmt.Sprintf("%d linha(s) gravada(s) no Redis como %s", written, sinkType), map[string]interface{}{})
+	return written, nil
+}
+
+// write enfileira na transação os comandos que gravam uma linha.
+func (s *Sink) write(ctx context.Context, pipe redis.Pipeliner, sinkType string, row Data) error {
+	key, keyErr := RedisRowKey(s.config, row)
+	if keyErr != nil {
+		return keyErr
+	}
+	if sinkType == "list" {
+		encoded, encodeErr := json.Marshal(row)
+		if encodeErr != nil {
+			return fmt.Errorf("falha ao codificar a linha de %s em JSON: %w", key, encodeErr)
+		}
+		pipe.RPush(ctx, key, encoded)
+		return nil
+	}
+
+	// As colunas em ordem alfabética, para que as entradas do stream tenham sempre a mesma ordem.
+	var fields []interface{}
+	for _, column := range slices.Sorted(maps.Keys(row)) {
+		if value, ok := RedisFieldValue(row[column]); ok {
+			fields = append(fields, column, value)
+		}
+	}
+	if sinkType == "stream" {
+		if len(fields) == 0 {
+			return fmt.Errorf("linha sem colunas não nulas para o stream %s", key)
+		}
+		pipe.XAdd(ctx, &redis.XAddArgs{Stream: key, MaxLen: s.config.Redis.Sink.MaxLen, Approx: true, Values: fields})
+		return nil
+	}
+	pipe.Del(ctx, key)
+	if len(fields) > 0 {
+		pipe.HSet(ctx, key, fields...)
+		if s.ttl > 0 {
+			pipe.Expire(ctx, key, s.ttl)
+		}
+	}
+	return nil
+}
+
+// Close fecha a conexão.
+func (s *Sink) Close() error {
+	return s.client.Close()
+}
@@ -0,0 +1,176 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	. "github.com/faelmori/getl/etypes"
+	. "github.com/faelmori/getl/utils"
+	"github.com/faelmori/logz"
+	"github.com/redis/go-redis/v9"
+	"slices"
+	"strings"
+)
+
+// Source lê as linhas de uma origem Redis: os hashes de redis.source.pattern ou as entradas de
+// redis.source.stream.
+type Source struct {
+	client *redis.Client
+	config Config
+	// read são os ids das entradas do stream lidas por Read e ainda não confirmadas por Ack.
+	read []string
+}
+
+// OpenSource conecta à sourceConnectionString e, com stream, cria o grupo de consumo se preciso,
+// a partir do início do stream.
+func OpenSource(ctx context.Context, config Config) (*Source, error) {
+	client, clientErr := NewRedisClient(config.SourceConnectionString)
+	if clientErr != nil {
+		return nil, clientErr
+	}
+	source := &Source{client: client, config: config}
+	if stream := config.Redis.Source.Stream; stream != "" {
+		group, _ := RedisGroup(config)
+		if createErr := client.XGroupCreateMkStream(ctx, stream, group, "0").Err(); createErr != nil && !strings.HasPrefix(createErr.Error(), "BUSYGROUP") {
+			_ = client.Close()
+			return nil, fmt.Errorf("falha ao criar o grupo %s do stream %s: %w", group, stream, createErr)
+		}
+	}
+	return source, nil
+}
+
+// Read lê as linhas da origem. Cada hash é uma linha com os seus campos; cada entrada do stream,
+// uma linha com os campos da entrada. Os valores são textos. Com keyField, a linha também traz a
+// chave do hash ou o id da entrada.
+func (s *Source) Read(ctx context.Context) ([]Data, error) {
+	if s.config.Redis.Source.Stream != "" {
+		return s.readStream(ctx)
+	}
+	return s.readKeys(ctx)
+}
+
+// readKeys lê os hashes cujas chaves atendem o pattern, percorridas com SCAN.
+func (s *Source) readKeys(ctx context.Context) ([]Data, error) {
+	pattern := s.config.Redis.Source.Pattern
+	batchSize := int64(RedisBatchSize(s.config))
+	seen := map[string]bool{}
+	var rows []Data
+	var cursor uint64
+	for {
+		keys, next, scanErr := s.client.ScanType(ctx, cursor, pattern, batchSize, "hash").Result()
+		if scanErr != nil {
+			return nil, fmt.Errorf("falha ao listar as chaves %s: %w", pattern, scanErr)
+		}
+		// O SCAN pode retornar a mesma chave mais de uma vez.
+		keys = slices.DeleteFunc(keys, func(key string) bool {
+			duplicate := seen[key]
+			seen[key] = true
+			return duplicate
+		})
+		if len(keys) > 0 {
+			cmds := make([]*redis.MapStringStringCmd, len(keys))
+			if _, pipelineErr := s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+				for i, key := range keys {
+					cmds[i] = pipe.HGetAll(ctx, key)
+				}
+				return nil
+			}); pipelineErr != nil {
+				return nil, fmt.Errorf("falha ao ler os hashes %s: %w", pattern, pipelineErr)
+			}
+			for i, cmd := range cmds {
+				// Uma chave removida depois do SCAN não tem campos.
+				if values := cmd.Val(); len(values) > 0 {
+					rows = append(rows, s.row(keys[i], values))
+				}
+			}
+		}
+		if cursor = next; cursor == 0 {
+			break
+		}
+	}
+	logz.Info(fmt.Sprintf("%d hash(es) lido(s) de %s", len(rows), pattern), map[string]interface{}{})
+	return rows, nil
+}
+
+// readStream lê, com o grupo, primeiro as entradas pendentes do consumidor, entregues em uma
+// execução anterior e não confirmadas, e depois as novas, até o fim do stream.
+func (s *Source) readStream(ctx context.Context) ([]Data, error) {
+	stream := s.config.Redis.Source.Stream
+	group, consumer := RedisGroup(s.config)
+	batchSize := int64(RedisBatchSize(s.config))
+	var rows []Data
+	pending := 0
+	for _, start := range []string{"0", ">"} {
+		id := start
+		for {
+			streams, readErr := s.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+				Group: group, Consumer: consumer, Streams: []string{stream, id}, Count: batchSize, Block: -1,
+			}).Result()
+			if errors.Is(readErr, redis.Nil) {
+				break
+			}
+			if readErr != nil {
+				return nil, fmt.Errorf("falha ao ler o stream %s no grupo %s: %w", stream, group, readErr)
+			}
+			if len(streams) == 0 || len(streams[0].Messages) == 0 {
+				break
+			}
+			for _, message := range streams[0].Messages {
+				s.read = append(s.read, message.ID)
+				if start == "0" {
+					pending++
+				}
+				// Uma entrada pendente removida do stream não tem campos; ela só é confirmada.
+				if len(message.Values) == 0 {
+					continue
+				}
+				values := make(map[string]string, len(message.Values))
+				for field, value := range message.Values {
+					values[field] = fmt.Sprint(value)
+				}
+				rows = append(rows, s.row(message.ID, values))
+			}
+			if start == "0" {
+				id = streams[0].Messages[len(streams[0].Messages)-1].ID
+			}
+		}
+	}
+	logz.Info(fmt.Sprintf("%d entrada(s) lida(s) do stream %s no grupo %s, %d pendente(s) de execuções anteriores", len(s.read), stream, group, pending), map[string]interface{}{})
+	return rows, nil
+}
+
+// row converte os campos de um hash ou de uma entrada em uma linha.
+func (s *Source) row(key string, values map[string]string) Data {
+	row := make(Data, len(values)+1)
+	for field, value := range values {
+		row[field] = value
+	}
+	if keyField := s.config.Redis.Source.KeyField; keyField != "" {
+		row[keyField] = key
+	}
+	return row
+}
+
+// Ack confirma no grupo as entradas do stream lidas por Read. Deve ser chamado após a carga: as
+// entradas não confirmadas são lidas de novo na próxima execução.
+func (s *Source) Ack(ctx context.Context) error {
+	if len(s.read) == 0 {
+		return nil
+	}
+	stream := s.config.Redis.Source.Stream
+	group, _ := RedisGroup(s.config)
+	batchSize := RedisBatchSize(s.config)
+	for len(s.read) > 0 {
+		ids := s.read[:min(batchSize, len(s.read))]
+		if ackErr := s.client.XAck(ctx, stream, group, ids...).Err(); ackErr != nil {
+			return fmt.Errorf("falha ao confirmar %d entrada(s) do stream %s no grupo %s: %w", len(s.read), stream, group, ackErr)
+		}
+		s.read = s.read[len(ids):]
+	}
+	return nil
+}
+
+// Close fecha a conexão.
+func (s *Source) Close() error {
+	return s.client.Close()
+}
@@ -0,0 +1,178 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	. "github.com/faelmori/getl/etypes"
+)
+
+func sinkConfig(server *miniredis.Miniredis, sink RedisSink) Config {
+	return Config{
+		DestinationType:             RedisType,
+		DestinationConnectionString: "redis://" + server.Addr() + "/0",
+		DestinationTable:            "items",
+		PrimaryKey:                  "id",
+		Redis:                       RedisOptions{Sink: sink, BatchSize: 2},
+	}
+}
+
+// TestSinkHash verifica que cada linha substitui o hash da sua chave, sem os campos nulos e com o TTL.
+func TestSinkHash(t *testing.T) {
+	server := miniredis.RunT(t)
+	server.HSet("items:1", "stale", "x")
+	sink, err := OpenSink(sinkConfig(server, RedisSink{TTL: "1h"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	rows := []Data{
+		{"id": int64(1), "name": "a", "price": 1.5, "note": nil},
+		{"id": int64(2), "name": "b", "tags": []interface{}{"x", "y"}},
+		{"id": int64(3), "created": time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)},
+	}
+	written, err := sink.Write(context.Background(), rows)
+	if err != nil || written != 3 {
+		t.Fatalf("Write() = %d, %v", written, err)
+	}
+	want := map[string]map[string]string{
+		"items:1": {"id": "1", "name": "a", "price": "1.5"},
+		"items:2": {"id": "2", "name": "b", "tags": `["x","y"]`},
+		"items:3": {"id": "3", "created": "2024-05-01T12:00:00Z"},
+	}
+	for key, fields := range want {
+		got := map[string]string{}
+		hashFields, _ := server.HKeys(key)
+		for _, field := range hashFields {
+			got[field] = server.HGet(key, field)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(fields) {
+			t.Errorf("%s = %v, esperado %v", key, got, fields)
+		}
+		if ttl := server.TTL(key); ttl != time.Hour {
+			t.Errorf("TTL(%s) = %v", key, ttl)
+		}
+	}
+
+	if _, err := sink.Write(context.Background(), []Data{{"name": "sem chave"}}); err == nil {
+		t.Error("Write() sem a coluna da chave não falhou")
+	}
+}
+
+// TestSinkStreamAndList verifica a gravação como entradas de stream e elementos JSON de uma lista.
+func TestSinkStreamAndList(t *testing.T) {
+	server := miniredis.RunT(t)
+	rows := []Data{{"id": int64(1), "name": "a"}, {"id": int64(2), "name": nil}, {"id": int64(3), "name": "c"}}
+
+	stream, err := OpenSink(sinkConfig(server, RedisSink{Type: "stream", Key: "events:{name}", MaxLen: 10}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	if _, err := stream.Write(context.Background(), rows[:1]); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := server.Stream("events:a")
+	if err != nil || len(entries) != 1 || fmt.Sprint(entries[0].Values) != "[id 1 name a]" {
+		t.Errorf("events:a = %v, %v", entries, err)
+	}
+
+	list, err := OpenSink(sinkConfig(server, RedisSink{Type: "list"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer list.Close()
+	if _, err := list.Write(context.Background(), rows); err != nil {
+		t.Fatal(err)
+	}
+	elements, err := server.List("items")
+	if err != nil || fmt.Sprint(elements) != `[{"id":1,"name":"a"} {"id":2,"name":null} {"id":3,"name":"c"}]` {
+		t.Errorf("items = %v, %v", elements, err)
+	}
+}
+
+// TestSourcePattern verifica a leitura dos hashes de um pattern, com a chave em keyField.
+func TestSourcePattern(t *testing.T) {
+	server := miniredis.RunT(t)
+	for i := 1; i <= 5; i++ {
+		server.HSet(fmt.Sprintf("items:%d", i), "id", fmt.Sprint(i), "name", fmt.Sprintf("item %d", i))
+	}
+	server.HSet("orders:1", "id", "1")
+	if err := server.Set("items:count", "5"); err != nil {
+		t.Fatal(err)
+	}
+
+	config := Config{SourceType: RedisType, SourceConnectionString: "redis://" + server.Addr(),
+		Redis: RedisOptions{Source: RedisSource{Pattern: "items:*", KeyField: "redis_key"}, BatchSize: 2}}
+	source, err := OpenSource(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer source.Close()
+	rows, err := source.Read(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i]["id"].(string) < rows[j]["id"].(string) })
+	if len(rows) != 5 || fmt.Sprint(rows[4]) != "map[id:5 name:item 5 redis_key:items:5]" {
+		t.Errorf("Read() = %v", rows)
+	}
+	if err := source.Ack(context.Background()); err != nil {
+		t.Errorf("Ack() sem stream = %v", err)
+	}
+}
+
+// TestSourceStream verifica a leitura do stream pelo grupo: as entradas não confirmadas são lidas de
+// novo pela execução seguinte e as confirmadas, não.
+func TestSourceStream(t *testing.T) {
+	server := miniredis.RunT(t)
+	config := Config{SourceType: RedisType, SourceConnectionString: "redis://" + server.Addr(),
+		Redis: RedisOptions{Source: RedisSource{Stream: "events", KeyField: "entry_id"}, BatchSize: 2}}
+	ctx := context.Background()
+	read := func() ([]Data, *Source) {
+		t.Helper()
+		source, err := OpenSource(ctx, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = source.Close() })
+		rows, err := source.Read(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rows, source
+	}
+
+	// O grupo é criado com o stream, a partir do início.
+	if rows, _ := read(); len(rows) != 0 {
+		t.Fatalf("Read() de um stream vazio = %v", rows)
+	}
+	for i := 1; i <= 3; i++ {
+		if _, err := server.XAdd("events", fmt.Sprintf("%d-0", i), []string{"id", fmt.Sprint(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if rows, _ := read(); len(rows) != 3 || fmt.Sprint(rows[0]) != "map[entry_id:1-0 id:1]" {
+		t.Fatalf("primeira leitura = %v", rows)
+	}
+
+	// Sem Ack, a execução seguinte lê as três pendentes e a nova.
+	if _, err := server.XAdd("events", "4-0", []string{"id", "4"}); err != nil {
+		t.Fatal(err)
+	}
+	rows, source := read()
+	if len(rows) != 4 || rows[3]["id"] != "4" {
+		t.Fatalf("leitura após a falha = %v", rows)
+	}
+	if err := source.Ack(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if rows, _ := read(); len(rows) != 0 {
+		t.Errorf("leitura após o Ack = %v", rows)
+	}
+}
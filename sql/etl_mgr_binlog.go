@@ -0,0 +1,365 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	. "github.com/faelmori/getl/etypes"
+	. "github.com/faelmori/getl/utils"
+	"github.com/faelmori/logz"
+	gomysql "github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/go-sql-driver/mysql"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Intervalo dos heartbeats pedidos ao servidor; sem eventos nem heartbeats por binlogReadTimeout,
+// a conexão de replicação é considerada perdida.
+const (
+	binlogHeartbeatPeriod = 10 * time.Second
+	binlogReadTimeout     = 3 * binlogHeartbeatPeriod
+)
+
+// StreamMySQLChanges lê do binlog, como réplica, as alterações de SourceTable e as entrega ao sink,
+// uma transação da origem por vez, até ctx ser cancelado. A leitura começa na posição registrada
+// pelo sink: o arquivo e a posição do binlog ou, com cdc.gtid, o conjunto de GTIDs aplicados; sem
+// ela, na posição corrente do servidor. As transações que não alteram SourceTable também avançam a
+// posição, registrada no máximo a cada 10s. Com once, retorna ao alcançar a posição do servidor no
+// início da leitura. Retorna quantas alterações foram aplicadas.
+func StreamMySQLChanges(ctx context.Context, config Config, sink ChangeSink, once bool) (int, error) {
+	source, sourceErr := OpenSource(ctx, config)
+	if sourceErr != nil {
+		return 0, sourceErr
+	}
+	columns, columnsErr := binlogColumns(ctx, source, config)
+	if columnsErr != nil {
+		return 0, columnsErr
+	}
+	var version string
+	if versionErr := source.QueryRowContext(ctx, "SELECT VERSION()").Scan(&version); versionErr != nil {
+		return 0, fmt.Errorf("falha ao consultar a versão da origem: %w", versionErr)
+	}
+	mariaDB := strings.Contains(version, "MariaDB")
+	if config.CDC.GTID && mariaDB {
+		return 0, fmt.Errorf("cdc.gtid não é suportado no MariaDB; use a posição do arquivo do binlog")
+	}
+
+	server, serverGTIDs, statusErr := binlogServerPosition(ctx, source)
+	if statusErr != nil {
+		return 0, statusErr
+	}
+	if config.CDC.GTID && serverGTIDs == "" {
+		return 0, fmt.Errorf("cdc.gtid exige gtid_mode=ON na origem")
+	}
+	position, positionErr := sink.Position(ctx)
+	if positionErr != nil {
+		return 0, positionErr
+	}
+	if position == "" {
+		position = server.String()
+		if config.CDC.GTID {
+			position = serverGTIDs
+		}
+		logz.Warn(fmt.Sprintf("nenhuma posição registrada para %s; lendo o binlog a partir de %s", ReplicationSlot(config), position), map[string]interface{}{})
+	}
+
+	syncer, syncerErr := newBinlogSyncer(config, mariaDB)
+	if syncerErr != nil {
+		return 0, syncerErr
+	}
+	defer syncer.Close()
+
+	var start BinlogPosition
+	var gtids, target *gomysql.MysqlGTIDSet
+	var streamer *replication.BinlogStreamer
+	var startErr error
+	if config.CDC.GTID {
+		var parseErr error
+		if gtids, parseErr = ParseGTIDSet(position); parseErr != nil {
+			return 0, fmt.Errorf("posição registrada de %s: %w", ReplicationSlot(config), parseErr)
+		}
+		if target, parseErr = ParseGTIDSet(serverGTIDs); parseErr != nil {
+			return 0, parseErr
+		}
+		streamer, startErr = syncer.StartSyncGTID(gtids.Clone())
+	} else {
+		var parseErr error
+		if start, parseErr = ParseBinlogPosition(position); parseErr != nil {
+			return 0, fmt.Errorf("posição registrada de %s: %w", ReplicationSlot(config), parseErr)
+		}
+		streamer, startErr = syncer.StartSync(gomysql.Position{Name: start.File, Pos: start.Offset})
+	}
+	if startErr != nil {
+		return 0, fmt.Errorf("falha ao iniciar a leitura do binlog: %w", startErr)
+	}
+
+	decoder := NewBinlogDecoder(config.SourceTable, columns, start.File, start.Offset)
+	// caughtUp indica se a leitura alcançou a posição do servidor no início, onde para com once.
+	caughtUp := func() bool {
+		if config.CDC.GTID {
+			return gtids.Contain(target)
+		}
+		return !(BinlogPosition{File: decoder.File, Offset: decoder.Offset}).Before(server)
+	}
+	if once && caughtUp() {
+		return 0, nil
+	}
+
+	applied := 0
+	saved := time.Now()
+	for {
+		event, readErr := streamer.GetEvent(ctx)
+		if readErr != nil {
+			if ctx.Err() != nil {
+				return applied, nil
+			}
+			return applied, fmt.Errorf("falha ao ler o binlog: %w", readErr)
+		}
+
+		events, commit, decodeErr := decoder.Decode(event)
+		var columnsChanged *ErrBinlogColumns
+		if errors.As(decodeErr, &columnsChanged) {
+			// A estrutura da tabela mudou: relê as colunas e interpreta o evento de novo.
+			if columns, columnsErr = binlogColumns(ctx, source, config); columnsErr != nil {
+				return applied, columnsErr
+			}
+			decoder.SetColumns(columns)
+			events, commit, decodeErr = decoder.Decode(event)
+		}
+		if decodeErr != nil {
+			return applied, fmt.Errorf("binlog %s:%d: %w", decoder.File, decoder.Offset, decodeErr)
+		}
+		if !commit {
+			continue
+		}
+
+		current := BinlogPosition{File: decoder.File, Offset: decoder.Offset}.String()
+		if config.CDC.GTID {
+			if decoder.GTID != "" {
+				if updateErr := gtids.Update(decoder.GTID); updateErr != nil {
+					return applied, fmt.Errorf("GTID inválido %q: %w", decoder.GTID, updateErr)
+				}
+			}
+			current = gtids.String()
+		}
+		if len(events) > 0 || time.Since(saved) >= standbyInterval {
+			for i := range events {
+				events[i].Position = current
+			}
+			if applyErr := sink.Apply(ctx, events, current); applyErr != nil {
+				return applied, fmt.Errorf("binlog %s:%d: %w", decoder.File, decoder.Offset, applyErr)
+			}
+			saved = time.Now()
+			if len(events) > 0 {
+				applied += len(events)
+				logz.Info(fmt.Sprintf("%d alteração(ões) de %s aplicada(s) até %s:%d", len(events), config.SourceTable, decoder.File, decoder.Offset), map[string]interface{}{})
+			}
+		}
+		if once && caughtUp() {
+			return applied, nil
+		}
+	}
+}
+
+// newBinlogSyncer cria o replication.BinlogSyncer do go-mysql com o endereço, o usuário e o TLS da
+// string de conexão de origem. As falhas de conexão não são refeitas pelo go-mysql: a posição só
+// avança com o que o sink confirmou, e getl cdc run retoma a partir dela.
+func newBinlogSyncer(config Config, mariaDB bool) (*replication.BinlogSyncer, error) {
+	cfg, parseErr := mysql.ParseDSN(config.SourceConnectionString)
+	if parseErr != nil {
+		return nil, fmt.Errorf("string de conexão de origem inválida: %w", parseErr)
+	}
+	flavor := gomysql.MySQLFlavor
+	if mariaDB {
+		flavor = gomysql.MariaDBFlavor
+	}
+	return replication.NewBinlogSyncer(replication.BinlogSyncerConfig{
+		ServerID:                BinlogServerID(config),
+		Flavor:                  flavor,
+		Host:                    cfg.Addr,
+		User:                    cfg.User,
+		Password:                cfg.Passwd,
+		TLSConfig:               cfg.TLS,
+		TimestampStringLocation: time.UTC,
+		HeartbeatPeriod:         binlogHeartbeatPeriod,
+		ReadTimeout:             binlogReadTimeout,
+		DisableRetrySync:        true,
+		// Os erros chegam por GetEvent e são registrados pelo logz.
+		Logger: slog.New(slog.DiscardHandler),
+	}), nil
+}
+
+// binlogColumns lê de information_schema as colunas de SourceTable, na ordem da tabela.
+func binlogColumns(ctx context.Context, db *sql.DB, config Config) ([]BinlogColumn, error) {
+	schema, table, qualified := strings.Cut(config.SourceTable, ".")
+	query := "SELECT COLUMN_NAME, DATA_TYPE, COLUMN_TYPE FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? ORDER BY ORDINAL_POSITION"
+	args := []interface{}{schema}
+	if qualified {
+		query = strings.Replace(query, "DATABASE()", "?", 1)
+		args = []interface{}{schema, table}
+	}
+	rows, rowsErr := db.QueryContext(ctx, query, args...)
+	if rowsErr != nil {
+		return nil, fmt.Errorf("falha ao ler as colunas de %s: %w", config.SourceTable, rowsErr)
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	var columns []BinlogColumn
+	for rows.Next() {
+		var name, dataType, columnType string
+		if scanErr := rows.Scan(&name, &dataType, &columnType); scanErr != nil {
+			return nil, fmt.Errorf("falha ao ler as colunas de %s: %w", config.SourceTable, scanErr)
+		}
+		columns = append(columns, NewBinlogColumn(name, dataType, columnType))
+	}
+	if rowsErr := rows.Err(); rowsErr != nil {
+		return nil, rowsErr
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("tabela %s não encontrada na origem", config.SourceTable)
+	}
+	return columns, nil
+}
+
+// binlogServerPosition retorna a posição corrente do binlog e, no MySQL, o gtid_executed.
+func binlogServerPosition(ctx context.Context, db *sql.DB) (BinlogPosition, string, error) {
+	// SHOW MASTER STATUS foi substituído por SHOW BINARY LOG STATUS no MySQL 8.2.
+	rows, rowsErr := db.QueryContext(ctx, "SHOW BINARY LOG STATUS")
+	if rowsErr != nil {
+		rows, rowsErr = db.QueryContext(ctx, "SHOW MASTER STATUS")
+	}
+	if rowsErr != nil {
+		return BinlogPosition{}, "", fmt.Errorf("falha ao consultar a posição do binlog: %w", rowsErr)
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	names, namesErr := rows.Columns()
+	if namesErr != nil {
+		return BinlogPosition{}, "", namesErr
+	}
+	if !rows.Next() {
+		if rowsErr := rows.Err(); rowsErr != nil {
+			return BinlogPosition{}, "", rowsErr
+		}
+		return BinlogPosition{}, "", fmt.Errorf("o binlog não está habilitado na origem (log_bin)")
+	}
+	values := make([]sql.NullString, len(names))
+	pointers := make([]interface{}, len(names))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	if scanErr := rows.Scan(pointers...); scanErr != nil {
+		return BinlogPosition{}, "", fmt.Errorf("falha ao consultar a posição do binlog: %w", scanErr)
+	}
+
+	var file, offset, gtids string
+	for i, name := range names {
+		switch strings.ToLower(name) {
+		case "file":
+			file = values[i].String
+		case "position":
+			offset = values[i].String
+		case "executed_gtid_set":
+			gtids = strings.ReplaceAll(values[i].String, "\n", "")
+		}
+	}
+	position, parseErr := ParseBinlogPosition(file + ":" + offset)
+	return position, gtids, parseErr
+}
+
+// installBinlogCapture verifica se o binlog registra as linhas e grava no destino a posição corrente,
+// a partir da qual getl cdc run lerá as alterações. Uma posição já registrada é mantida.
+func installBinlogCapture(ctx context.Context, db *sql.DB, config Config) error {
+	var format, image string
+	if err := db.QueryRowContext(ctx, "SELECT @@global.binlog_format, @@global.binlog_row_image").Scan(&format, &image); err != nil {
+		return fmt.Errorf("falha ao consultar a configuração do binlog: %w", err)
+	}
+	if !strings.EqualFold(format, "ROW") {
+		return fmt.Errorf("a captura pelo binlog exige binlog_format=ROW (atual: %s)", format)
+	}
+	if !strings.EqualFold(image, "FULL") {
+		logz.Warn(fmt.Sprintf("binlog_row_image=%s: as exclusões trarão só as colunas que identificam a linha; use FULL para receber as imagens completas", image), map[string]interface{}{})
+	}
+	if _, columnsErr := binlogColumns(ctx, db, config); columnsErr != nil {
+		return columnsErr
+	}
+
+	store, storeErr := OpenPositionStore(ctx, config)
+	if storeErr != nil {
+		return storeErr
+	}
+	position, loadErr := store.Load(ctx)
+	if loadErr != nil {
+		return loadErr
+	}
+	if position == "" {
+		current, gtids, statusErr := binlogServerPosition(ctx, db)
+		if statusErr != nil {
+			return statusErr
+		}
+		position = current.String()
+		if config.CDC.GTID {
+			if gtids == "" {
+				return fmt.Errorf("cdc.gtid exige gtid_mode=ON na origem")
+			}
+			position = gtids
+		}
+		if saveErr := store.Save(ctx, position); saveErr != nil {
+			return saveErr
+		}
+	}
+	logz.Info(fmt.Sprintf("captura de alterações instalada em %s: binlog a partir de %s", config.SourceTable, position), map[string]interface{}{})
+	return nil
+}
+
+// uninstallBinlogCapture remove a posição registrada; o binlog da origem não é alterado.
+func uninstallBinlogCapture(ctx context.Context, config Config) error {
+	store, storeErr := OpenPositionStore(ctx, config)
+	if storeErr != nil {
+		return storeErr
+	}
+	if deleteErr := store.Delete(ctx); deleteErr != nil {
+		return deleteErr
+	}
+	logz.Info(fmt.Sprintf("captura de alterações removida de %s: posição %s apagada", config.SourceTable, ReplicationSlot(config)), map[string]interface{}{})
+	return nil
+}
+
+// binlogCaptureStatus preenche o status do modo binlog: a posição registrada e quanto do binlog
+// corrente falta ler.
+func binlogCaptureStatus(ctx context.Context, db *sql.DB, config Config, status *ChangeCaptureStatus) error {
+	store, storeErr := OpenPositionStore(ctx, config)
+	if storeErr != nil {
+		return storeErr
+	}
+	position, loadErr := store.Load(ctx)
+	if loadErr != nil {
+		return loadErr
+	}
+	if position == "" {
+		status.State = "ausente"
+		return nil
+	}
+	status.State = "instalada"
+	status.Position = position
+
+	current, _, statusErr := binlogServerPosition(ctx, db)
+	if statusErr != nil {
+		return statusErr
+	}
+	if saved, parseErr := ParseBinlogPosition(position); parseErr == nil && !config.CDC.GTID {
+		if saved.File == current.File && saved.Offset <= current.Offset {
+			status.Pending = fmt.Sprintf("%d byte(s) de binlog", current.Offset-saved.Offset)
+			return nil
+		}
+	}
+	status.Pending = "binlog em " + current.String()
+	return nil
+}
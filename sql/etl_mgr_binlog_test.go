@@ -0,0 +1,109 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	. "github.com/faelmori/getl/etypes"
+)
+
+// TestStreamMySQLChangesServer usa um MySQL com o binlog em ROW, o padrão desde o 8.0, e.g. docker run
+// -p 3306:3306 -e MYSQL_ROOT_PASSWORD=getl -e MYSQL_DATABASE=shop mysql:8.4, e só é executado com
+// GETL_MYSQL_TEST_DSN (e.g. root:getl@tcp(localhost:3306)/shop).
+func TestStreamMySQLChangesServer(t *testing.T) {
+	dsn := os.Getenv("GETL_MYSQL_TEST_DSN")
+	if dsn == "" {
+		t.Skip("GETL_MYSQL_TEST_DSN não definido")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	table := fmt.Sprintf("getl_binlog_%d", time.Now().UnixNano())
+	source, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = source.Close()
+	}()
+	if _, err := source.ExecContext(ctx, fmt.Sprintf("CREATE TABLE %s (id INT PRIMARY KEY, name VARCHAR(100), price DECIMAL(10,2), active BOOLEAN)", table)); err != nil {
+		t.Fatal(err)
+	}
+
+	destination := filepath.Join(t.TempDir(), "items.db")
+	config := Config{
+		SourceType: "mysql", SourceConnectionString: dsn, SourceTable: table,
+		DestinationType: "sqlite3", DestinationConnectionString: destination, DestinationTable: "items",
+		PrimaryKey: "id", CDC: ChangeCapture{Mode: "binlog"},
+		Transformations: []Transformation{
+			{SourceField: "id", DestinationField: "id", Operation: "copy"},
+			{SourceField: "name", DestinationField: "name", Operation: "copy"},
+			{SourceField: "price", DestinationField: "price", Operation: "copy"},
+		},
+	}
+	t.Cleanup(func() {
+		_ = UninstallChangeCapture(context.Background(), config)
+		_, _ = source.Exec("DROP TABLE " + table)
+	})
+
+	target, err := OpenDestination(ctx, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := target.ExecContext(ctx, "CREATE TABLE items (id INT PRIMARY KEY, name TEXT, price TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+	if err := InstallChangeCapture(ctx, config); err != nil {
+		t.Fatal(err)
+	}
+	sink, err := NewDestinationSink(ctx, Pipeline{Name: "items", Config: config})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A atualização de id 2 para 3 muda a chave: a linha antiga deve sair do destino.
+	for _, statement := range []string{
+		"INSERT INTO %s VALUES (1, 'a', 1.50, true), (2, 'b', 2, false), (4, 'd', 4, true)",
+		"UPDATE %s SET name = 'O''Brien' WHERE id = 1",
+		"UPDATE %s SET id = 3 WHERE id = 2",
+		"DELETE FROM %s WHERE id = 4",
+	} {
+		if _, err := source.ExecContext(ctx, fmt.Sprintf(statement, table)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	applied, err := StreamMySQLChanges(ctx, config, sink, true)
+	if err != nil || applied != 6 {
+		t.Fatalf("StreamMySQLChanges() = %d, %v", applied, err)
+	}
+	rows, err := target.QueryContext(ctx, "SELECT id, name, price FROM items ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for rows.Next() {
+		var id int
+		var name, price string
+		if err := rows.Scan(&id, &name, &price); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, fmt.Sprintf("%d %s %s", id, name, price))
+	}
+	_ = rows.Close()
+	// O DECIMAL chega como texto, sem perder a escala.
+	if want := []string{"1 O'Brien 1.50", "3 b 2.00"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("destino = %v, esperado %v", got, want)
+	}
+
+	// A posição registrada no destino faz a próxima leitura começar após as alterações aplicadas.
+	if applied, err := StreamMySQLChanges(ctx, config, sink, true); err != nil || applied != 0 {
+		t.Errorf("StreamMySQLChanges() repetido = %d, %v", applied, err)
+	}
+}
@@ -0,0 +1,627 @@
+package sql
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/elgris/sqrl"
+	. "github.com/faelmori/getl/etypes"
+	"github.com/faelmori/getl/meta"
+	. "github.com/faelmori/getl/utils"
+	"github.com/faelmori/logz"
+	"io"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// defaultCaptureInterval é o intervalo entre os ciclos de getl cdc run quando a pipeline não tem syncInterval.
+const defaultCaptureInterval = 10 * time.Second
+
+// purgeBatchSize é o máximo de ids por DELETE do changelog; o Oracle aceita até 1000 valores em um IN.
+const purgeBatchSize = 1000
+
+// change é uma alteração lida do changelog da origem.
+type change struct {
+	ID        int64
+	Operation string
+	Key       string
+	Image     sql.NullString
+}
+
+// ChangeSink recebe as alterações capturadas, na ordem em que ocorreram na origem.
+type ChangeSink interface {
+	// Position retorna a última posição da origem confirmada pelo sink, ou "" se ele não a registra.
+	Position(ctx context.Context) (string, error)
+	// Apply grava as alterações de uma transação da origem ou de um lote do changelog. position,
+	// quando informada, é registrada junto com elas.
+	Apply(ctx context.Context, events []ChangeEvent, position string) error
+	Close() error
+}
+
+// ChangeSinkFactory cria o ChangeSink de uma pipeline.
+type ChangeSinkFactory func(ctx context.Context, pipeline Pipeline) (ChangeSink, error)
+
+// PositionStore registra no destino, em etl_cdc_positions, a posição lida da origem por uma
+// captura que lê o log (WAL ou binlog), com o nome do slot.
+type PositionStore struct {
+	db     *sql.DB
+	driver string
+	stream string
+}
+
+// OpenPositionStore abre o registro de posições da pipeline no banco de destino, criando a tabela se preciso.
+func OpenPositionStore(ctx context.Context, config Config) (*PositionStore, error) {
+	db, dbErr := OpenDestination(ctx, config)
+	if dbErr != nil {
+		return nil, dbErr
+	}
+	if schemaErr := meta.CreateCDCPositionSchema(ctx, db, config.DestinationType); schemaErr != nil {
+		return nil, schemaErr
+	}
+	return &PositionStore{db: db, driver: config.DestinationType, stream: ReplicationSlot(config)}, nil
+}
+
+// Load retorna a posição registrada, ou "" se não houver nenhuma.
+func (p *PositionStore) Load(ctx context.Context) (string, error) {
+	return meta.LoadCDCPosition(ctx, p.db, p.driver, p.stream)
+}
+
+// Save registra a posição fora de uma transação do destino.
+func (p *PositionStore) Save(ctx context.Context, position string) error {
+	return meta.SaveCDCPosition(ctx, p.db, p.driver, p.stream, position)
+}
+
+// Delete remove a posição registrada.
+func (p *PositionStore) Delete(ctx context.Context) error {
+	return meta.DeleteCDCPosition(ctx, p.db, p.driver, p.stream)
+}
+
+// destinationSink aplica as alterações na tabela de destino. Quando a captura lê o log da origem, a
+// posição é gravada em etl_cdc_positions na mesma transação.
+type destinationSink struct {
+	db        *sql.DB
+	config    Config
+	key       string
+	positions *PositionStore
+}
+
+// NewDestinationSink cria o ChangeSink que aplica as alterações da pipeline no banco de destino.
+func NewDestinationSink(ctx context.Context, pipeline Pipeline) (ChangeSink, error) {
+	config := pipeline.Config
+	key, keyErr := ChangeCaptureKey(config)
+	if keyErr != nil {
+		return nil, keyErr
+	}
+	db, dbErr := OpenDestination(ctx, config)
+	if dbErr != nil {
+		return nil, dbErr
+	}
+	if config.Deletes.Soft() {
+		if columnErr := ensureDeleteColumn(ctx, db, config); columnErr != nil {
+			return nil, columnErr
+		}
+	}
+	sink := &destinationSink{db: db, config: config, key: key}
+	if config.CDC.Streaming() {
+		var storeErr error
+		if sink.positions, storeErr = OpenPositionStore(ctx, config); storeErr != nil {
+			return nil, storeErr
+		}
+	}
+	return sink, nil
+}
+
+func (d *destinationSink) Position(ctx context.Context) (string, error) {
+	if d.positions == nil {
+		return "", nil
+	}
+	return d.positions.Load(ctx)
+}
+
+// Apply aplica as alterações em uma transação. Cada alteração remove a linha pela chave de destino
+// e, nas inclusões e atualizações, grava a nova imagem transformada; aplicar a mesma alteração de
+// novo leva ao mesmo resultado. Uma atualização com a imagem anterior também remove a linha da
+// chave anterior, se a chave mudou. Com deletes.mode soft, as exclusões só marcam a linha.
+func (d *destinationSink) Apply(ctx context.Context, events []ChangeEvent, position string) error {
+	insertConfig := d.config
+	insertConfig.UpdateKey = ""
+
+	tx, txErr := d.db.BeginTx(ctx, nil)
+	if txErr != nil {
+		return fmt.Errorf("falha ao iniciar a transação: %w", txErr)
+	}
+	for i, event := range events {
+		image := event.After
+		if event.Operation == ChangeDelete {
+			image = event.Before
+		}
+		if image == nil {
+			return rollbackChangeBatch(tx, fmt.Errorf("alteração %d de %s sem imagem da linha", i+1, event.Table))
+		}
+		keyValue, keyErr := ChangeKeyValue(d.config, image)
+		if keyErr != nil {
+			return rollbackChangeBatch(tx, fmt.Errorf("alteração %d de %s: %w", i+1, event.Table, keyErr))
+		}
+
+		if event.Operation == ChangeDelete && d.config.Deletes.Soft() {
+			if deleteErr := propagateDeletes(ctx, tx, d.config, []interface{}{keyValue}); deleteErr != nil {
+				return rollbackChangeBatch(tx, deleteErr)
+			}
+			continue
+		}
+		keys := []interface{}{keyValue}
+		// Uma atualização que mudou a chave primária também remove a linha da chave antiga.
+		if event.Operation == ChangeUpdate && event.Before != nil {
+			if oldKey, oldKeyErr := ChangeKeyValue(d.config, event.Before); oldKeyErr == nil && DeleteKeyString(oldKey) != DeleteKeyString(keyValue) {
+				if !d.config.Deletes.Soft() {
+					keys = append(keys, oldKey)
+				} else if deleteErr := propagateDeletes(ctx, tx, d.config, []interface{}{oldKey}); deleteErr != nil {
+					return rollbackChangeBatch(tx, deleteErr)
+				}
+			}
+		}
+		deleteQuery, deleteArgs, deleteQueryErr := sqrl.Delete().From(d.config.DestinationTable).
+			Where(sqrl.Eq{d.key: keys}).PlaceholderFormat(PlaceholderFormatFor(d.config.DestinationType)).ToSql()
+		if deleteQueryErr != nil {
+			return rollbackChangeBatch(tx, deleteQueryErr)
+		}
+		if _, execErr := tx.ExecContext(ctx, deleteQuery, deleteArgs...); execErr != nil {
+			return rollbackChangeBatch(tx, fmt.Errorf("falha ao remover a linha %v do destino: %w", keys, execErr))
+		}
+		if event.Operation == ChangeDelete {
+			continue
+		}
+
+		transformed, transformErr := ApplyTransformations([]Data{event.After}, d.config.Transformations)
+		if transformErr != nil {
+			return rollbackChangeBatch(tx, fmt.Errorf("alteração %d de %s: %w", i+1, event.Table, transformErr))
+		}
+		if d.config.Deletes.Soft() {
+			transformed[0][DeleteColumn(d.config)] = ActiveValue(d.config)
+		}
+		if execErr := insertRow(ctx, tx, insertConfig, transformed[0]); execErr != nil {
+			return rollbackChangeBatch(tx, fmt.Errorf("falha ao gravar a linha %v no destino: %w", keyValue, execErr))
+		}
+	}
+	if position != "" && d.positions != nil {
+		if saveErr := meta.SaveCDCPosition(ctx, tx, d.positions.driver, d.positions.stream, position); saveErr != nil {
+			return rollbackChangeBatch(tx, saveErr)
+		}
+	}
+	if commitErr := tx.Commit(); commitErr != nil {
+		return fmt.Errorf("falha ao confirmar as alterações: %w", commitErr)
+	}
+	return nil
+}
+
+// Close não fecha o banco de destino: o pool é compartilhado e fechado por CloseConnections.
+func (d *destinationSink) Close() error { return nil }
+
+// ChangeCaptureStatus resume a captura de alterações de uma pipeline. Pending são as alterações
+// no changelog, o WAL retido pelo slot ou o binlog ainda não lido; Position é a última posição
+// confirmada pelo slot ou registrada no destino.
+type ChangeCaptureStatus struct {
+	Pipeline string
+	Table    string
+	Mode     string
+	State    string
+	Pending  string
+	Position string
+}
+
+// InstallChangeCapture prepara a origem para a captura de alterações. No modo triggers, cria o
+// changelog e (re)cria os triggers que gravam nele as alterações de SourceTable; os triggers são
+// sempre recriados, para incluir colunas novas na imagem da linha. Na replicação lógica, cria a
+// publicação (pgoutput) e o slot, que passa a reter o WAL a partir deste ponto. No modo binlog,
+// verifica a configuração do servidor e registra no destino a posição corrente do binlog.
+func InstallChangeCapture(ctx context.Context, config Config) error {
+	db, dbErr := OpenSource(ctx, config)
+	if dbErr != nil {
+		return dbErr
+	}
+	switch {
+	case config.CDC.Logical():
+		return installReplication(ctx, db, config)
+	case config.CDC.Mode == "binlog":
+		return installBinlogCapture(ctx, db, config)
+	}
+	if schemaErr := createChangelogSchema(ctx, db, config); schemaErr != nil {
+		return schemaErr
+	}
+
+	triggers, triggersErr := changeCaptureTriggers(ctx, db, config)
+	if triggersErr != nil {
+		return triggersErr
+	}
+	for _, trigger := range triggers {
+		if dropErr := dropTrigger(ctx, db, config.SourceType, trigger); dropErr != nil {
+			return dropErr
+		}
+		if createErr := createTrigger(ctx, db, config.SourceType, trigger); createErr != nil {
+			return createErr
+		}
+	}
+	logz.Info(fmt.Sprintf("captura de alterações instalada em %s: %d trigger(s) gravando em %s", config.SourceTable, len(triggers), ChangelogTable(config)), map[string]interface{}{})
+	return nil
+}
+
+// UninstallChangeCapture desfaz InstallChangeCapture. No modo triggers, o changelog é mantido, pois
+// pode ser compartilhado com outras tabelas; as alterações pendentes de SourceTable ficam nele. Na
+// replicação lógica, o slot e a publicação são removidos e o WAL retido é liberado. No modo binlog,
+// a posição registrada no destino é removida.
+func UninstallChangeCapture(ctx context.Context, config Config) error {
+	db, dbErr := OpenSource(ctx, config)
+	if dbErr != nil {
+		return dbErr
+	}
+	switch {
+	case config.CDC.Logical():
+		return uninstallReplication(ctx, db, config)
+	case config.CDC.Mode == "binlog":
+		return uninstallBinlogCapture(ctx, config)
+	}
+	installed, listErr := installedTriggers(ctx, db, config.SourceType)
+	if listErr != nil {
+		return listErr
+	}
+	triggers, triggersErr := ChangeCaptureTriggers(config, []string{config.PrimaryKey})
+	if triggersErr != nil {
+		return triggersErr
+	}
+
+	dropped := 0
+	for _, trigger := range triggers {
+		if !installed[strings.ToLower(trigger.Name)] {
+			continue
+		}
+		if dropErr := dropTrigger(ctx, db, config.SourceType, trigger); dropErr != nil {
+			return dropErr
+		}
+		dropped++
+	}
+	logz.Info(fmt.Sprintf("captura de alterações removida de %s: %d trigger(s)", config.SourceTable, dropped), map[string]interface{}{})
+	return nil
+}
+
+// ApplyChanges consome o changelog de SourceTable em ordem, entregando ao sink lotes de até
+// ChangeCapture.BatchSize alterações. Cada lote é removido do changelog depois de aplicado; se a
+// remoção falhar, o lote é entregue de novo no ciclo seguinte. Retorna quantas alterações foram aplicadas.
+func ApplyChanges(ctx context.Context, config Config, sink ChangeSink) (int, error) {
+	source, sourceErr := OpenSource(ctx, config)
+	if sourceErr != nil {
+		return 0, sourceErr
+	}
+
+	applied := 0
+	batchSize := ChangeBatchSize(config)
+	for {
+		changes, readErr := readChanges(ctx, source, config, batchSize)
+		if readErr != nil {
+			return applied, readErr
+		}
+		if len(changes) == 0 {
+			return applied, nil
+		}
+
+		events := make([]ChangeEvent, 0, len(changes))
+		for _, c := range changes {
+			if !c.Image.Valid {
+				return applied, fmt.Errorf("alteração %d de %s sem imagem da linha", c.ID, config.SourceTable)
+			}
+			row, decodeErr := DecodeRowImage(c.Image.String)
+			if decodeErr != nil {
+				return applied, fmt.Errorf("alteração %d: %w", c.ID, decodeErr)
+			}
+			event := ChangeEvent{Operation: c.Operation, Table: config.SourceTable, After: row}
+			if c.Operation == ChangeDelete {
+				event.Before, event.After = row, nil
+			}
+			events = append(events, event)
+		}
+
+		if applyErr := sink.Apply(ctx, events, ""); applyErr != nil {
+			return applied, applyErr
+		}
+		if purgeErr := purgeChanges(ctx, source, config, changes); purgeErr != nil {
+			return applied, purgeErr
+		}
+		applied += len(changes)
+		logz.Info(fmt.Sprintf("%d alteração(ões) de %s aplicada(s)", len(changes), config.SourceTable), map[string]interface{}{})
+
+		if len(changes) < batchSize {
+			return applied, nil
+		}
+	}
+}
+
+// CaptureChanges entrega as alterações de cada pipeline ao sink criado por newSink (sem ele, o
+// destino da pipeline) até ctx ser cancelado. No modo triggers, o changelog é lido a cada intervalo:
+// sem interval, vale o syncInterval da pipeline ou, na falta dele, 10s. Na replicação lógica, as
+// alterações são recebidas continuamente e interval é a espera antes de reconectar após uma falha.
+// Com once, aplica as alterações pendentes uma vez e retorna o primeiro erro; nos ciclos contínuos,
+// os erros são registrados e o ciclo seguinte tenta de novo. O cancelamento não é tratado como erro.
+func CaptureChanges(ctx context.Context, pipelines []Pipeline, interval time.Duration, once bool, newSink ChangeSinkFactory) error {
+	if newSink == nil {
+		newSink = NewDestinationSink
+	}
+
+	intervals := make([]time.Duration, len(pipelines))
+	for i, pipeline := range pipelines {
+		intervals[i] = interval
+		if intervals[i] == 0 && pipeline.SyncInterval != "" {
+			var intervalErr error
+			intervals[i], intervalErr = ParseSyncInterval(pipeline.SyncInterval)
+			if intervalErr != nil {
+				return fmt.Errorf("pipeline %s: %w", pipeline.Name, intervalErr)
+			}
+		}
+		if intervals[i] == 0 {
+			intervals[i] = defaultCaptureInterval
+		}
+	}
+
+	sinks := make([]ChangeSink, len(pipelines))
+	defer func() {
+		for _, sink := range sinks {
+			if sink != nil {
+				_ = sink.Close()
+			}
+		}
+	}()
+	for i, pipeline := range pipelines {
+		var sinkErr error
+		if sinks[i], sinkErr = newSink(ctx, pipeline); sinkErr != nil {
+			return fmt.Errorf("pipeline %s: %w", pipeline.Name, sinkErr)
+		}
+	}
+
+	if once {
+		for i, pipeline := range pipelines {
+			if _, captureErr := captureOnce(ctx, pipeline.Config, sinks[i], true); captureErr != nil {
+				return fmt.Errorf("pipeline %s: %w", pipeline.Name, captureErr)
+			}
+		}
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	for i, pipeline := range pipelines {
+		wg.Add(1)
+		go func(pipeline Pipeline, sink ChangeSink, interval time.Duration) {
+			defer wg.Done()
+			captureLoop(ctx, pipeline, sink, interval)
+		}(pipeline, sinks[i], intervals[i])
+	}
+	wg.Wait()
+	return nil
+}
+
+// captureOnce executa um ciclo de captura: lê o changelog ou recebe o WAL ou o binlog até ctx ser
+// cancelado (com once, até alcançar a posição corrente do servidor).
+func captureOnce(ctx context.Context, config Config, sink ChangeSink, once bool) (int, error) {
+	switch {
+	case config.CDC.Logical():
+		return StreamPostgresChanges(ctx, config, sink, once)
+	case config.CDC.Mode == "binlog":
+		return StreamMySQLChanges(ctx, config, sink, once)
+	}
+	return ApplyChanges(ctx, config, sink)
+}
+
+// captureLoop executa os ciclos de captura da pipeline, um imediatamente e os seguintes a cada intervalo.
+func captureLoop(ctx context.Context, pipeline Pipeline, sink ChangeSink, interval time.Duration) {
+	switch {
+	case pipeline.CDC.Logical():
+		logz.Info(fmt.Sprintf("pipeline %s: recebendo alterações de %s pelo slot %s", pipeline.Name, pipeline.SourceTable, ReplicationSlot(pipeline.Config)), map[string]interface{}{})
+	case pipeline.CDC.Mode == "binlog":
+		logz.Info(fmt.Sprintf("pipeline %s: recebendo alterações de %s pelo binlog (server_id %d)", pipeline.Name, pipeline.SourceTable, BinlogServerID(pipeline.Config)), map[string]interface{}{})
+	default:
+		logz.Info(fmt.Sprintf("pipeline %s: aplicando alterações de %s a cada %s", pipeline.Name, pipeline.SourceTable, interval), map[string]interface{}{})
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	total := 0
+	for {
+		applied, captureErr := captureOnce(ctx, pipeline.Config, sink, false)
+		total += applied
+		if captureErr != nil && ctx.Err() == nil {
+			logz.Error(fmt.Sprintf("pipeline %s: falha ao aplicar as alterações: %v", pipeline.Name, captureErr), map[string]interface{}{})
+		}
+
+		select {
+		case <-ctx.Done():
+			logz.Info(fmt.Sprintf("pipeline %s: captura encerrada após %d alteração(ões) aplicada(s)", pipeline.Name, total), map[string]interface{}{})
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ChangeCaptureStatuses informa, para cada pipeline com captura configurada, o que está instalado
+// na origem e as alterações pendentes.
+func ChangeCaptureStatuses(ctx context.Context, pipelines []Pipeline) ([]ChangeCaptureStatus, error) {
+	var statuses []ChangeCaptureStatus
+	for _, pipeline := range pipelines {
+		config := pipeline.Config
+		db, dbErr := OpenSource(ctx, config)
+		if dbErr != nil {
+			return nil, fmt.Errorf("pipeline %s: %w", pipeline.Name, dbErr)
+		}
+
+		status := ChangeCaptureStatus{Pipeline: pipeline.Name, Table: config.SourceTable, Mode: config.CDC.Mode}
+		var statusErr error
+		switch {
+		case config.CDC.Logical():
+			statusErr = replicationStatus(ctx, db, config, &status)
+		case config.CDC.Mode == "binlog":
+			statusErr = binlogCaptureStatus(ctx, db, config, &status)
+		default:
+			statusErr = changelogStatus(ctx, db, config, &status)
+		}
+		if statusErr != nil {
+			return nil, fmt.Errorf("pipeline %s: %w", pipeline.Name, statusErr)
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// changelogStatus preenche o status do modo triggers: os triggers instalados e as alterações no changelog.
+func changelogStatus(ctx context.Context, db *sql.DB, config Config, status *ChangeCaptureStatus) error {
+	installed, listErr := installedTriggers(ctx, db, config.SourceType)
+	if listErr != nil {
+		return listErr
+	}
+	triggers, triggersErr := ChangeCaptureTriggers(config, []string{config.PrimaryKey})
+	if triggersErr != nil {
+		return triggersErr
+	}
+
+	count := 0
+	for _, trigger := range triggers {
+		if installed[strings.ToLower(trigger.Name)] {
+			count++
+		}
+	}
+	switch {
+	case count == 0:
+		status.State = "ausente"
+	case count < len(triggers):
+		status.State = fmt.Sprintf("incompleta (%d/%d triggers)", count, len(triggers))
+	default:
+		status.State = "instalada"
+	}
+	if count == 0 {
+		return nil
+	}
+
+	query, args, queryErr := sqrl.Select("COUNT(*)").From(ChangelogTable(config)).
+		Where(sqrl.Eq{"table_name": config.SourceTable}).
+		PlaceholderFormat(PlaceholderFormatFor(config.SourceType)).ToSql()
+	if queryErr != nil {
+		return queryErr
+	}
+	var pending int64
+	if countErr := db.QueryRowContext(ctx, query, args...).Scan(&pending); countErr != nil {
+		return fmt.Errorf("falha ao contar as alterações pendentes: %w", countErr)
+	}
+	status.Pending = fmt.Sprintf("%d alteração(ões)", pending)
+	return nil
+}
+
+// PrintChangeCaptureStatuses escreve a situação da captura de alterações de cada pipeline.
+func PrintChangeCaptureStatuses(w io.Writer, statuses []ChangeCaptureStatus) error {
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(table, "PIPELINE\tTABELA\tMODO\tSTATUS\tPENDENTE\tPOSIÇÃO")
+	for _, status := range statuses {
+		_, _ = fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s\n", status.Pipeline, status.Table, status.Mode, status.State, cmp.Or(status.Pending, "-"), cmp.Or(status.Position, "-"))
+	}
+	return table.Flush()
+}
+
+// changeCaptureTriggers lê as colunas de SourceTable na origem e monta os triggers de captura.
+func changeCaptureTriggers(ctx context.Context, db *sql.DB, config Config) ([]Trigger, error) {
+	rows, rowsErr := db.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %s WHERE 1 = 0", config.SourceTable))
+	if rowsErr != nil {
+		return nil, fmt.Errorf("falha ao ler as colunas de %s: %w", config.SourceTable, rowsErr)
+	}
+	columns, columnsErr := rows.Columns()
+	_ = rows.Close()
+	if columnsErr != nil {
+		return nil, fmt.Errorf("falha ao ler as colunas de %s: %w", config.SourceTable, columnsErr)
+	}
+	return ChangeCaptureTriggers(config, columns)
+}
+
+// createChangelogSchema cria o changelog na origem, se ele ainda não existir.
+func createChangelogSchema(ctx context.Context, db *sql.DB, config Config) error {
+	table := ChangelogTable(config)
+	var createTableQuery string
+	switch TriggerDialect(config.SourceType) {
+	case "sqlite":
+		createTableQuery = fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id INTEGER PRIMARY KEY AUTOINCREMENT, table_name TEXT NOT NULL, operation CHAR(1) NOT NULL, pk TEXT NOT NULL, row_image TEXT, changed_at TEXT NOT NULL DEFAULT (strftime('%%Y-%%m-%%d %%H:%%M:%%f', 'now')))", table)
+	case "postgres":
+		createTableQuery = fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id BIGSERIAL PRIMARY KEY, table_name VARCHAR(255) NOT NULL, operation CHAR(1) NOT NULL, pk VARCHAR(255) NOT NULL, row_image TEXT, changed_at TIMESTAMPTZ NOT NULL DEFAULT now())", table)
+	case "mysql":
+		createTableQuery = fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id BIGINT AUTO_INCREMENT PRIMARY KEY, table_name VARCHAR(255) NOT NULL, operation CHAR(1) NOT NULL, pk VARCHAR(255) NOT NULL, row_image LONGTEXT, changed_at TIMESTAMP(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6))", table)
+	case "oracle":
+		createTableQuery = fmt.Sprintf("CREATE TABLE %s (id NUMBER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY, table_name VARCHAR2(255) NOT NULL, operation CHAR(1) NOT NULL, pk VARCHAR2(255) NOT NULL, row_image CLOB, changed_at TIMESTAMP DEFAULT SYSTIMESTAMP NOT NULL)", table)
+	case "sqlserver":
+		createTableQuery = fmt.Sprintf("IF OBJECT_ID('%[1]s', 'U') IS NULL CREATE TABLE %[1]s (id BIGINT IDENTITY(1,1) PRIMARY KEY, table_name NVARCHAR(255) NOT NULL, operation CHAR(1) NOT NULL, pk NVARCHAR(255) NOT NULL, row_image NVARCHAR(MAX), changed_at DATETIME2 NOT NULL DEFAULT SYSUTCDATETIME())", table)
+	default:
+		return fmt.Errorf("captura de alterações por triggers não suportada para o banco de dados: %s", config.SourceType)
+	}
+
+	if _, err := db.ExecContext(ctx, createTableQuery); err != nil {
+		// O Oracle não tem IF NOT EXISTS: ORA-00955 indica que a tabela já existe.
+		if strings.Contains(err.Error(), "ORA-00955") {
+			return nil
+		}
+		return fmt.Errorf("falha ao criar o changelog %s: %w", table, err)
+	}
+	return nil
+}
+
+// readChanges lê do changelog até limit alterações de SourceTable, na ordem em que ocorreram.
+func readChanges(ctx context.Context, db *sql.DB, config Config, limit int) ([]change, error) {
+	selectChanges := sqrl.Select("id", "operation", "pk", "row_image").From(ChangelogTable(config)).
+		Where(sqrl.Eq{"table_name": config.SourceTable}).OrderBy("id")
+	query, args, queryErr := LimitRows(selectChanges, config.SourceType, limit).
+		PlaceholderFormat(PlaceholderFormatFor(config.SourceType)).ToSql()
+	if queryErr != nil {
+		return nil, queryErr
+	}
+	rows, rowsErr := db.QueryContext(ctx, query, args...)
+	if rowsErr != nil {
+		return nil, fmt.Errorf("falha ao ler o changelog %s: %w", ChangelogTable(config), rowsErr)
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	var changes []change
+	for rows.Next() {
+		var c change
+		if scanErr := rows.Scan(&c.ID, &c.Operation, &c.Key, &c.Image); scanErr != nil {
+			return nil, fmt.Errorf("falha ao ler o changelog %s: %w", ChangelogTable(config), scanErr)
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
+
+// rollbackChangeBatch reverte o lote de alterações; elas continuam no changelog para o próximo ciclo.
+func rollbackChangeBatch(tx *sql.Tx, cause error) error {
+	rollbackErr := tx.Rollback()
+	if rollbackErr != nil && !errors.Is(rollbackErr, sql.ErrTxDone) {
+		logz.Error("falha ao reverter a transação: "+rollbackErr.Error(), map[string]interface{}{})
+	}
+	return cause
+}
+
+// purgeChanges remove do changelog as alterações aplicadas no destino, pelos seus ids. Um id menor
+// que os lidos pode ser de uma transação confirmada depois da leitura; ele fica no changelog e é
+// aplicado no ciclo seguinte.
+func purgeChanges(ctx context.Context, db *sql.DB, config Config, changes []change) error {
+	for start := 0; start < len(changes); start += purgeBatchSize {
+		batch := changes[start:min(start+purgeBatchSize, len(changes))]
+		ids := make([]int64, len(batch))
+		for i, c := range batch {
+			ids[i] = c.ID
+		}
+		query, args, queryErr := sqrl.Delete().From(ChangelogTable(config)).Where(sqrl.Eq{"id": ids}).
+			PlaceholderFormat(PlaceholderFormatFor(config.SourceType)).ToSql()
+		if queryErr != nil {
+			return queryErr
+		}
+		if _, err := db.ExecContext(ctx, query, args...); err != nil {
+			return fmt.Errorf("falha ao limpar o changelog %s: %w", ChangelogTable(config), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	. "github.com/faelmori/getl/etypes"
+	"github.com/faelmori/getl/meta"
+	. "github.com/faelmori/getl/utils"
+	"github.com/faelmori/logz"
+)
+
+// checkpointRun identifica a execução e a pipeline cujos lotes são registrados em etl_checkpoints.
+// Checkpoint é o progresso já confirmado, quando a execução está sendo retomada.
+type checkpointRun struct {
+	RunID      string
+	Pipeline   string
+	Checkpoint *meta.Checkpoint
+}
+
+// ResumeETLPipelinesContext retoma a execução runID de ExecuteETLPipelinesContext. As pipelines com
+// checkpoint continuam após o último lote confirmado e as já concluídas não são executadas de novo.
+func ResumeETLPipelinesContext(ctx context.Context, runID string, configPath string, pipelineNames []string, outputPath, outputFormat string, needCheck bool, checkMethod string) error {
+	return executeETLRun(ctx, runID, true, configPath, pipelineNames, outputPath, outputFormat, needCheck, checkMethod)
+}
+
+// loadRunCheckpoints lê do destino de cada pipeline o checkpoint da execução runID.
+// Pipelines sem checkpoint na configuração são executadas de novo desde o início.
+func loadRunCheckpoints(ctx context.Context, runID string, pipelines []Pipeline) (map[string]*meta.Checkpoint, error) {
+	checkpoints := map[string]*meta.Checkpoint{}
+	for _, pipeline := range pipelines {
+		config := pipeline.Config
+		if !config.Checkpoint.Enabled() {
+			logz.Warn(fmt.Sprintf("pipeline %s não usa checkpoint e será executada desde o início", pipeline.Name), map[string]interface{}{})
+			continue
+		}
+
+		checkpoint, checkpointErr := readRunCheckpoint(ctx, config, runID, pipeline.Name)
+		if checkpointErr != nil {
+			return nil, fmt.Errorf("falha ao ler o checkpoint da pipeline %s: %w", pipeline.Name, checkpointErr)
+		}
+		if checkpoint != nil {
+			checkpoints[pipeline.Name] = checkpoint
+		}
+	}
+
+	if len(checkpoints) == 0 {
+		return nil, fmt.Errorf("nenhum checkpoint da execução %s encontrado nas pipelines selecionadas", runID)
+	}
+	return checkpoints, nil
+}
+
+// readRunCheckpoint lê do destino da pipeline o checkpoint dela na execução.
+func readRunCheckpoint(ctx context.Context, config Config, runID, pipeline string) (*meta.Checkpoint, error) {
+	db, dbErr := OpenDestination(ctx, config)
+	if dbErr != nil {
+		return nil, dbErr
+	}
+	if schemaErr := meta.CreateCheckpointSchema(ctx, db, config.DestinationType); schemaErr != nil {
+		return nil, schemaErr
+	}
+	return meta.LoadCheckpoint(ctx, db, config.DestinationType, runID, pipeline)
+}
+
+// prepareCheckpoint garante a tabela de checkpoints no destino e retorna o ponto de partida da carga:
+// o checkpoint da execução retomada ou um novo, sem lotes.
+func prepareCheckpoint(ctx context.Context, db *sql.DB, config Config, run checkpointRun) (*meta.Checkpoint, error) {
+	_, schemaErr := retryTransient(ctx, config.Pool, "criar a tabela de checkpoints", func() error {
+		return meta.CreateCheckpointSchema(ctx, db, config.DestinationType)
+	})
+	if schemaErr != nil {
+		return nil, schemaErr
+	}
+
+	if run.Checkpoint != nil {
+		logz.Info(fmt.Sprintf("retomando a pipeline %s da execução %s: %d lote(s), %d linha(s) já confirmada(s)", run.Pipeline, run.Checkpoint.RunID, run.Checkpoint.Batches, run.Checkpoint.Rows), map[string]interface{}{})
+		checkpoint := *run.Checkpoint
+		return &checkpoint, nil
+	}
+
+	if run.RunID == "" {
+		run.RunID = meta.NewRunID()
+	}
+	logz.Info(fmt.Sprintf("pipeline %s: carga em lotes de %d linha(s), execução %s", run.Pipeline, config.Checkpoint.BatchSize, run.RunID), map[string]interface{}{})
+	return &meta.Checkpoint{RunID: run.RunID, Pipeline: run.Pipeline, Status: meta.CheckpointRunning}, nil
+}
+
+// loadBatches grava as linhas em lotes de Checkpoint.BatchSize. Cada lote é confirmado em sua própria
+// transação junto com o checkpoint, então uma interrupção reverte apenas o lote em andamento.
+// data são as linhas de origem, de onde sai o valor da chave de checkpoint de cada lote.
+func loadBatches(ctx context.Context, db *sql.DB, config Config, checkpoint *meta.Checkpoint, data, transformedData []Data) error {
+	batchSize := config.Checkpoint.BatchSize
+	for start := 0; ; start += batchSize {
+		end := min(start+batchSize, len(transformedData))
+
+		next := *checkpoint
+		if end > start {
+			next.Batches++
+			next.Rows += int64(end - start)
+			next.LastKey = CheckpointKeyValue(data[end-1][CheckpointKey(config)])
+		}
+		if end == len(transformedData) {
+			next.Status = meta.CheckpointDone
+		}
+
+		// Um erro transitório antes do commit reverte o lote, que é gravado de novo.
+		var tx *sql.Tx
+		attempts, writeErr := retryTransient(ctx, config.Pool, fmt.Sprintf("gravar o lote %d", next.Batches), func() error {
+			var err error
+			if tx, _, err = writeRows(ctx, db, config, transformedData[start:end], nil); err != nil {
+				return err
+			}
+			if saveErr := meta.SaveCheckpoint(ctx, tx, config.DestinationType, next); saveErr != nil {
+				_ = tx.Rollback()
+				tx = nil
+				return saveErr
+			}
+			return nil
+		})
+		if writeErr != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return interruptedBatch(checkpoint, ctxErr)
+			}
+			return interruptedBatch(checkpoint, fmt.Errorf("falha no lote %d após %d tentativa(s): %w", next.Batches, attempts, writeErr))
+		}
+		if commitErr := tx.Commit(); commitErr != nil {
+			logz.Error("Failed to commit transaction: "+commitErr.Error(), map[string]interface{}{})
+			return fmt.Errorf("falha ao confirmar o lote %d, retome com getl sync --resume %s: %w", next.Batches, checkpoint.RunID, commitErr)
+		}
+		*checkpoint = next
+
+		if checkpoint.Status == meta.CheckpointDone {
+			break
+		}
+		logz.Info(fmt.Sprintf("lote %d confirmado: %d linha(s) no total", checkpoint.Batches, checkpoint.Rows), map[string]interface{}{})
+	}
+
+	logz.Info(fmt.Sprintf("%d linha(s) carregada(s) no banco de destino com sucesso (execução %s: %d lote(s), %d linha(s))", len(transformedData), checkpoint.RunID, checkpoint.Batches, checkpoint.Rows), map[string]interface{}{})
+	return nil
+}
+
+// interruptedBatch informa como retomar a carga após a reversão do lote em andamento.
+// Os lotes anteriores já estão confirmados no destino.
+func interruptedBatch(checkpoint *meta.Checkpoint, cause error) error {
+	logz.Warn(fmt.Sprintf("carga interrompida com %d linha(s) confirmada(s) em %d lote(s); retome com getl sync --resume %s", checkpoint.Rows, checkpoint.Batches, checkpoint.RunID), map[string]interface{}{})
+	return fmt.Errorf("carga interrompida com %d linha(s) confirmada(s) em %d lote(s), retome com getl sync --resume %s: %w", checkpoint.Rows, checkpoint.Batches, checkpoint.RunID, cause)
+}
@@ -0,0 +1,49 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/faelmori/getl/etypes"
+	. "github.com/faelmori/getl/utils"
+)
+
+// TestExtractAfterTimeCheckpoint retoma a extração depois de uma chave de checkpoint de data. O
+// SQLite grava as datas em outro formato que RFC 3339, então a chave precisa ser comparada como data.
+func TestExtractAfterTimeCheckpoint(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "events.db")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(db *sql.DB) {
+		_ = db.Close()
+	}(db)
+	if _, err := db.ExecContext(ctx, "CREATE TABLE events (id INTEGER, created_at TIMESTAMP)"); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i := range 3 {
+		if _, err := db.ExecContext(ctx, "INSERT INTO events VALUES (?, ?)", i+1, start.Add(time.Duration(i)*time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config := Config{
+		SourceType: "sqlite3", SourceConnectionString: path, SourceTable: "events",
+		Checkpoint:      Checkpointing{BatchSize: 10, Key: "created_at"},
+		Transformations: []Transformation{{SourceField: "id", DestinationField: "id", Operation: "copy"}},
+	}
+	lastKey := CheckpointKeyParam(CheckpointKeyValue(start.Add(time.Minute)))
+	rows, _, err := extractDataWithTypes(ctx, db, config, lastKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0]["id"] != int64(3) {
+		t.Errorf("extractDataWithTypes() = %v, esperado só a linha 3", rows)
+	}
+}
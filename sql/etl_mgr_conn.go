@@ -1,48 +1,251 @@
 package sql
 
 import (
+	"cmp"
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	. "github.com/faelmori/getl/etypes"
+	etlredis "github.com/faelmori/getl/redis"
+	. "github.com/faelmori/getl/utils"
+	"github.com/faelmori/logz"
+	"io"
+	"sync"
+	"text/tabwriter"
 	"time"
 )
 
-// connectDB estabelece uma conexão com o banco de dados especificado na configuração.
-// config: a configuração contendo o tipo de banco de dados e a string de conexão.
-// Retorna um ponteiro para a conexão com o banco de dados e um erro, se houver.
-func connectDB(config Config) (*sql.DB, error) {
-	db, err := sql.Open(config.DestinationType, config.DestinationConnectionString)
-	if err != nil {
-		return nil, fmt.Errorf("falha ao conectar ao banco de dados: %w", err)
+const (
+	defaultRetries      = 3
+	defaultRetryBackoff = 500 * time.Millisecond
+)
+
+// ConnectionManager mantém um pool *sql.DB por driver e DSN, compartilhado entre as etapas e as
+// pipelines de uma execução. Os pools são fechados por Close, não por quem os usa.
+type ConnectionManager struct {
+	mu    sync.Mutex
+	pools map[string]pooledDB
+}
+
+// pooledDB é um pool aberto e as opções de Pool com que foi criado.
+type pooledDB struct {
+	db   *sql.DB
+	pool Pool
+}
+
+// connections é o gerenciador usado pelas funções de extração e carga.
+var connections = NewConnectionManager()
+
+// NewConnectionManager cria um gerenciador sem pools abertos.
+func NewConnectionManager() *ConnectionManager {
+	return &ConnectionManager{pools: map[string]pooledDB{}}
+}
+
+// Open retorna o pool do driver e DSN, criado com as opções de pool na primeira chamada, depois de
+// verificar a conexão com Ping. Erros transitórios são repetidos com backoff exponencial e jitter.
+// Um pool já aberto com outras opções de pool é um erro: as opções não podem valer para as duas chamadas.
+func (m *ConnectionManager) Open(ctx context.Context, driver, dsn string, pool Pool) (*sql.DB, error) {
+	key := driver + "\x00" + dsn
+	target := driver + " " + MaskConnectionString(dsn)
+
+	m.mu.Lock()
+	pooled, cached := m.pools[key]
+	if cached && !SamePoolOptions(pooled.pool, pool) {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("o pool de %s já foi aberto com outras opções de pool; use as mesmas opções nas pipelines com esse driver e DSN", target)
+	}
+	if !cached {
+		db, openErr := sql.Open(driver, dsn)
+		if openErr != nil {
+			m.mu.Unlock()
+			return nil, fmt.Errorf("falha ao abrir a conexão %s: %w", driver, openErr)
+		}
+		if configureErr := configurePool(db, pool); configureErr != nil {
+			m.mu.Unlock()
+			_ = db.Close()
+			return nil, configureErr
+		}
+		pooled = pooledDB{db: db, pool: pool}
+		m.pools[key] = pooled
+	}
+	m.mu.Unlock()
+
+	attempts, pingErr := retryTransient(ctx, pool, "conectar em "+target, func() error {
+		return pooled.db.PingContext(ctx)
+	})
+	if pingErr != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("conexão com %s interrompida: %w", target, ctx.Err())
+		}
+		return nil, fmt.Errorf("falha ao conectar em %s após %d tentativa(s): %w", target, attempts, pingErr)
 	}
+	return pooled.db, nil
+}
+
+// Close fecha todos os pools abertos pelo gerenciador.
+func (m *ConnectionManager) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	// Verifica a conexão
-	if err := db.Ping(); err != nil {
-		return nil, fmt.Errorf("falha ao verificar a conexão com o banco de dados: %w", err)
+	var errs []error
+	for key, pooled := range m.pools {
+		if closeErr := pooled.db.Close(); closeErr != nil {
+			errs = append(errs, closeErr)
+		}
+		delete(m.pools, key)
 	}
+	return errors.Join(errs...)
+}
 
+// errRedisNotSQL é o erro dos comandos que exigem um banco SQL em uma pipeline com origem ou destino redis.
+var errRedisNotSQL = errors.New("o Redis não é um banco SQL: a origem e o destino redis são usados só por getl sync e getl load")
+
+// OpenSource retorna o pool compartilhado do banco de origem da Config.
+func OpenSource(ctx context.Context, config Config) (*sql.DB, error) {
+	if config.SourceType == RedisType {
+		return nil, errRedisNotSQL
+	}
+	db, err := connections.Open(ctx, config.SourceType, config.SourceConnectionString, config.Pool)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao conectar ao banco de origem: %w", err)
+	}
+	return db, nil
+}
+
+// OpenDestination retorna o pool compartilhado do banco de destino da Config.
+func OpenDestination(ctx context.Context, config Config) (*sql.DB, error) {
+	if config.DestinationType == RedisType {
+		return nil, errRedisNotSQL
+	}
+	db, err := connections.Open(ctx, config.DestinationType, config.DestinationConnectionString, config.Pool)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao conectar ao banco de destino: %w", err)
+	}
 	return db, nil
 }
 
-// reconnectDB tenta restabelecer a conexão com o banco de dados especificado na configuração.
-// config: a configuração contendo o tipo de banco de dados e a string de conexão.
-// Tenta reconectar até 5 vezes com um intervalo de 10 segundos entre as tentativas.
-// Retorna um ponteiro para a conexão com o banco de dados e um erro, se houver.
-func reconnectDB(config Config) (*sql.DB, error) {
-	var db *sql.DB
-	var err error
-	maxRetries := 5
-	retryInterval := 10 * time.Second
+// CloseConnections fecha os pools compartilhados. Chamado ao fim da execução do getl.
+func CloseConnections() error {
+	return connections.Close()
+}
 
-	for i := 0; i < maxRetries; i++ {
-		db, err = sql.Open(config.DestinationType, config.DestinationConnectionString)
+// configurePool aplica as opções de Pool ao *sql.DB. Valores zero mantêm o padrão do database/sql.
+func configurePool(db *sql.DB, pool Pool) error {
+	if pool.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(pool.MaxOpenConns)
+	}
+	if pool.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(pool.MaxIdleConns)
+	}
+	lifetime, lifetimeErr := ParseDuration(pool.ConnMaxLifetime)
+	if lifetimeErr != nil {
+		return fmt.Errorf("pool.connMaxLifetime: %w", lifetimeErr)
+	}
+	if lifetime > 0 {
+		db.SetConnMaxLifetime(lifetime)
+	}
+	idleTime, idleTimeErr := ParseDuration(pool.ConnMaxIdleTime)
+	if idleTimeErr != nil {
+		return fmt.Errorf("pool.connMaxIdleTime: %w", idleTimeErr)
+	}
+	if idleTime > 0 {
+		db.SetConnMaxIdleTime(idleTime)
+	}
+	return nil
+}
+
+// retryTransient executa operation, repetindo erros transitórios até Pool.Retries vezes com backoff
+// exponencial e jitter. action descreve a operação nos avisos, e.g. "conectar em mysql ...". Retorna o
+// número de tentativas feitas e o erro da última; com ctx cancelado, não há nova tentativa.
+func retryTransient(ctx context.Context, pool Pool, action string, operation func() error) (int, error) {
+	retries := pool.Retries
+	if retries == 0 {
+		retries = defaultRetries
+	}
+	backoff, backoffErr := ParseDuration(pool.RetryBackoff)
+	if backoffErr != nil {
+		return 0, fmt.Errorf("pool.retryBackoff: %w", backoffErr)
+	}
+	backoff = cmp.Or(backoff, defaultRetryBackoff)
+
+	for attempt := 1; ; attempt++ {
+		err := operation()
 		if err == nil {
-			if pingErr := db.Ping(); pingErr == nil {
-				return db, nil
-			}
+			return attempt, nil
+		}
+		if ctx.Err() != nil || !IsTransientError(err) || attempt > retries {
+			return attempt, err
+		}
+
+		wait := BackoffWithJitter(backoff, attempt)
+		logz.Warn(fmt.Sprintf("falha transitória ao %s (%v), nova tentativa em %s", action, err, wait.Round(time.Millisecond)), map[string]interface{}{})
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return attempt, err
 		}
-		time.Sleep(retryInterval)
 	}
+}
+
+// PingResult é o resultado do teste de uma conexão configurada.
+type PingResult struct {
+	Name    string
+	Driver  string
+	Target  string
+	Latency time.Duration
+	Err     error
+}
 
-	return nil, fmt.Errorf("falha ao reconectar ao banco de dados após %d tentativas: %w", maxRetries, err)
+// PingConnections testa as conexões de origem e destino das pipelines, uma vez por driver e DSN,
+// com as novas tentativas configuradas em Pool. Target traz o DSN com a senha ocultada.
+func PingConnections(ctx context.Context, pipelines []Pipeline) []PingResult {
+	var results []PingResult
+	seen := map[string]bool{}
+	check := func(name, driver, dsn string, pool Pool) {
+		if dsn == "" || seen[driver+"\x00"+dsn] {
+			return
+		}
+		seen[driver+"\x00"+dsn] = true
+
+		result := PingResult{Name: name, Driver: driver, Target: MaskConnectionString(dsn)}
+		if driver == RedisType {
+			started := time.Now()
+			result.Err = etlredis.Ping(ctx, dsn)
+			result.Latency = time.Since(started)
+			results = append(results, result)
+			return
+		}
+		db, openErr := connections.Open(ctx, driver, dsn, pool)
+		if openErr != nil {
+			result.Err = openErr
+		} else {
+			started := time.Now()
+			result.Err = db.PingContext(ctx)
+			result.Latency = time.Since(started)
+		}
+		results = append(results, result)
+	}
+
+	for _, pipeline := range pipelines {
+		check(cmp.Or(pipeline.Source, pipeline.Name+".source"), pipeline.SourceType, pipeline.SourceConnectionString, pipeline.Pool)
+		check(cmp.Or(pipeline.Destination, pipeline.Name+".destination"), pipeline.DestinationType, pipeline.DestinationConnectionString, pipeline.Pool)
+	}
+	return results
+}
+
+// PrintPingResults escreve os resultados em uma tabela e retorna quantas conexões falharam.
+func PrintPingResults(w io.Writer, results []PingResult) (int, error) {
+	failed := 0
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(table, "CONEXÃO\tDRIVER\tALVO\tLATÊNCIA\tSTATUS")
+	for _, result := range results {
+		status, latency := "ok", result.Latency.Round(time.Microsecond).String()
+		if result.Err != nil {
+			failed++
+			status, latency = "erro: "+result.Err.Error(), "-"
+		}
+		_, _ = fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\n", result.Name, result.Driver, result.Target, latency, status)
+	}
+	return failed, table.Flush()
 }
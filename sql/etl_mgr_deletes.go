@@ -0,0 +1,150 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/elgris/sqrl"
+	. "github.com/faelmori/getl/etypes"
+	. "github.com/faelmori/getl/utils"
+	"github.com/faelmori/logz"
+	"time"
+)
+
+// deleteChunkSize limita as chaves de cada DELETE ou UPDATE ... WHERE chave IN (...).
+const deleteChunkSize = 500
+
+// detectDeletedKeys compara as chaves do destino com as das linhas extraídas e transformadas e
+// retorna as que não estão mais na origem. Retorna um erro, antes de qualquer alteração no
+// destino, se elas passam de deletes.maxPercent das linhas do destino.
+func detectDeletedKeys(ctx context.Context, db *sql.DB, config Config, rows []Data) ([]interface{}, error) {
+	key, keyErr := ChangeCaptureKey(config)
+	if keyErr != nil {
+		return nil, keyErr
+	}
+	keys, keysErr := destinationKeys(ctx, db, config, key)
+	if keysErr != nil {
+		return nil, keysErr
+	}
+	missing := MissingKeys(keys, rows, key)
+	if thresholdErr := CheckDeleteThreshold(config, len(missing), len(keys)); thresholdErr != nil {
+		return nil, thresholdErr
+	}
+	return missing, nil
+}
+
+// destinationKeys lê as chaves das linhas do destino; na exclusão lógica, só as das linhas não marcadas.
+func destinationKeys(ctx context.Context, db *sql.DB, config Config, key string) ([]interface{}, error) {
+	query := sqrl.Select(key).From(config.DestinationTable)
+	if config.Deletes.Soft() {
+		column := DeleteColumn(config)
+		if config.Deletes.Flag {
+			query = query.Where(sqrl.Or{sqrl.Eq{column: nil}, sqrl.Eq{column: false}})
+		} else {
+			query = query.Where(sqrl.Eq{column: nil})
+		}
+	}
+	sqlQuery, args, queryErr := query.PlaceholderFormat(PlaceholderFormatFor(config.DestinationType)).ToSql()
+	if queryErr != nil {
+		return nil, queryErr
+	}
+	rows, rowsErr := db.QueryContext(ctx, sqlQuery, args...)
+	if rowsErr != nil {
+		return nil, fmt.Errorf("falha ao ler as chaves de %s: %w", config.DestinationTable, rowsErr)
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	var keys []interface{}
+	for rows.Next() {
+		var value interface{}
+		if scanErr := rows.Scan(&value); scanErr != nil {
+			return nil, fmt.Errorf("falha ao ler as chaves de %s: %w", config.DestinationTable, scanErr)
+		}
+		if value != nil {
+			keys = append(keys, value)
+		}
+	}
+	return keys, rows.Err()
+}
+
+// propagateDeletes remove do destino, ou marca em deletes.column, as linhas com as chaves informadas.
+func propagateDeletes(ctx context.Context, tx *sql.Tx, config Config, keys []interface{}) error {
+	now := time.Now()
+	for start := 0; start < len(keys); start += deleteChunkSize {
+		chunk := keys[start:min(start+deleteChunkSize, len(keys))]
+		statement, args, statementErr := deleteStatement(config, chunk, now)
+		if statementErr != nil {
+			return statementErr
+		}
+		if _, execErr := tx.ExecContext(ctx, statement, args...); execErr != nil {
+			return fmt.Errorf("falha ao propagar as exclusões para %s: %w", config.DestinationTable, execErr)
+		}
+	}
+	return nil
+}
+
+// logPropagatedDeletes registra quantas exclusões a comparação de chaves propagou.
+func logPropagatedDeletes(config Config, count int) {
+	if count == 0 {
+		return
+	}
+	action := "removida(s) do destino"
+	if config.Deletes.Soft() {
+		action = "marcada(s) em " + DeleteColumn(config)
+	}
+	logz.Info(fmt.Sprintf("%d linha(s) de %s excluída(s) na origem: %s", count, config.DestinationTable, action), map[string]interface{}{})
+}
+
+// applyDeletedKeys propaga as exclusões em uma transação própria, após uma carga em lotes.
+func applyDeletedKeys(ctx context.Context, db *sql.DB, config Config, keys []interface{}) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	tx, txErr := db.BeginTx(ctx, nil)
+	if txErr != nil {
+		return fmt.Errorf("falha ao iniciar a transação: %w", txErr)
+	}
+	if deleteErr := propagateDeletes(ctx, tx, config, keys); deleteErr != nil {
+		_ = tx.Rollback()
+		return deleteErr
+	}
+	if commitErr := tx.Commit(); commitErr != nil {
+		return fmt.Errorf("falha ao confirmar as exclusões: %w", commitErr)
+	}
+	logPropagatedDeletes(config, len(keys))
+	return nil
+}
+
+// deleteStatement monta o DELETE ou, na exclusão lógica, o UPDATE das linhas com as chaves informadas.
+func deleteStatement(config Config, keys []interface{}, now time.Time) (string, []interface{}, error) {
+	key, keyErr := ChangeCaptureKey(config)
+	if keyErr != nil {
+		return "", nil, keyErr
+	}
+	format := PlaceholderFormatFor(config.DestinationType)
+	if config.Deletes.Soft() {
+		return sqrl.Update(config.DestinationTable).Set(DeleteColumn(config), DeletedValue(config, now)).
+			Where(sqrl.Eq{key: keys}).PlaceholderFormat(format).ToSql()
+	}
+	return sqrl.Delete().From(config.DestinationTable).Where(sqrl.Eq{key: keys}).PlaceholderFormat(format).ToSql()
+}
+
+// ensureDeleteColumn acrescenta a coluna da exclusão lógica à tabela de destino, se ela não existir.
+func ensureDeleteColumn(ctx context.Context, db *sql.DB, config Config) error {
+	column := DeleteColumn(config)
+	rows, probeErr := db.QueryContext(ctx, fmt.Sprintf("SELECT %s FROM %s WHERE 1 = 0", column, config.DestinationTable))
+	if probeErr == nil {
+		return rows.Close()
+	}
+	typeName := GetVendorSqlType(config.DestinationType, DeleteColumnType(config))
+	if typeName == "" {
+		return fmt.Errorf("tipo de campo não mapeado: %s", DeleteColumnType(config))
+	}
+	if _, alterErr := db.ExecContext(ctx, AddColumnStatement(config.DestinationType, config.DestinationTable, column, typeName)); alterErr != nil {
+		return fmt.Errorf("falha ao criar a coluna %s em %s: %w", column, config.DestinationTable, alterErr)
+	}
+	logz.Info(fmt.Sprintf("coluna %s criada em %s para a exclusão lógica", column, config.DestinationTable), map[string]interface{}{})
+	return nil
+}
@@ -0,0 +1,178 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	. "github.com/faelmori/getl/etypes"
+	. "github.com/faelmori/getl/utils"
+	"github.com/faelmori/logz"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultPartitionWorkers = 4
+	defaultPartitionRetries = 2
+)
+
+// PlanPartitions resolve as consultas das partições da Config: as faixas explícitas ou, com count,
+// as calculadas a partir de MIN e MAX da coluna de partição na origem.
+func PlanPartitions(ctx context.Context, db *sql.DB, config Config) ([]PartitionQuery, error) {
+	partitioning := config.Partitioning
+	if len(partitioning.Ranges) > 0 {
+		return BuildPartitionQueries(config, extractFields(config), partitioning.Ranges, false)
+	}
+
+	boundsQuery, boundsArgs, boundsErr := PartitionBoundsQuery(config)
+	if boundsErr != nil {
+		return nil, fmt.Errorf("falha ao construir a consulta de limites das partições: %w", boundsErr)
+	}
+	var minValue, maxValue interface{}
+	if scanErr := db.QueryRowContext(ctx, boundsQuery, boundsArgs...).Scan(&minValue, &maxValue); scanErr != nil {
+		return nil, fmt.Errorf("falha ao consultar os limites de %s: %w", partitioning.Column, scanErr)
+	}
+
+	ranges, splitErr := SplitPartitionRange(minValue, maxValue, partitioning.Count)
+	if splitErr != nil {
+		return nil, splitErr
+	}
+	return BuildPartitionQueries(config, extractFields(config), ranges, true)
+}
+
+// partitionWorkers retorna quantas partições podem ser extraídas ao mesmo tempo.
+func partitionWorkers(partitioning Partitioning, partitions int) int {
+	workers := partitioning.Workers
+	if workers <= 0 {
+		workers = defaultPartitionWorkers
+	}
+	return max(min(workers, partitions), 1)
+}
+
+// extractPartitionedWithTypes executa as consultas das partições em paralelo, limitadas por
+// Partitioning.Workers, e junta as linhas na ordem das partições. Uma partição que falha é
+// repetida até Partitioning.Retries vezes; se ainda assim falhar, as demais são canceladas.
+func extractPartitionedWithTypes(ctx context.Context, db *sql.DB, config Config) ([]Data, map[string]string, error) {
+	partitions, planErr := PlanPartitions(ctx, db, config)
+	if planErr != nil {
+		logz.Error("Failed to plan partitions: "+planErr.Error(), map[string]interface{}{})
+		return nil, nil, planErr
+	}
+
+	retries := config.Partitioning.Retries
+	if retries == 0 {
+		retries = defaultPartitionRetries
+	}
+	workers := partitionWorkers(config.Partitioning, len(partitions))
+	logz.Info(fmt.Sprintf("Extraindo %d partição(ões) de %s com até %d em paralelo", len(partitions), config.Partitioning.Column, workers), map[string]interface{}{})
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type partitionResult struct {
+		data  []Data
+		types map[string]string
+	}
+	results := make([]partitionResult, len(partitions))
+
+	var firstErr error
+	var errOnce sync.Once
+	var completed atomic.Int32
+	var wg sync.WaitGroup
+
+	jobs := make(chan PartitionQuery)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for partition := range jobs {
+				data, types, err := extractPartition(ctx, db, partition, len(partitions), max(retries, 0))
+				if err != nil {
+					errOnce.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					continue
+				}
+				results[partition.Index] = partitionResult{data: data, types: types}
+				logz.Info(fmt.Sprintf("Partições concluídas: %d de %d", completed.Add(1), len(partitions)), map[string]interface{}{})
+			}
+		}()
+	}
+
+feed:
+	for _, partition := range partitions {
+		select {
+		case jobs <- partition:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, nil, firstErr
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, nil, ctxErr
+	}
+
+	var data []Data
+	var columnTypes map[string]string
+	for _, result := range results {
+		data = append(data, result.data...)
+		if columnTypes == nil {
+			columnTypes = result.types
+		}
+	}
+	return data, columnTypes, nil
+}
+
+// extractPartition executa a consulta de uma partição, com novas tentativas e espera exponencial entre elas.
+func extractPartition(ctx context.Context, db *sql.DB, partition PartitionQuery, total, retries int) ([]Data, map[string]string, error) {
+	label := fmt.Sprintf("partição %d/%d [%s]", partition.Index+1, total, partition.Label)
+
+	for attempt := 1; ; attempt++ {
+		started := time.Now()
+		data, types, err := queryWithTypes(ctx, db, partition.Query, partition.Args)
+		if err == nil {
+			logz.Info(fmt.Sprintf("%s: %d linha(s) em %s", label, len(data), time.Since(started).Round(time.Millisecond)), map[string]interface{}{})
+			return data, types, nil
+		}
+		if ctx.Err() != nil || errors.Is(err, context.Canceled) {
+			return nil, nil, fmt.Errorf("%s interrompida: %w", label, err)
+		}
+		if attempt > retries {
+			logz.Error(fmt.Sprintf("%s falhou após %d tentativa(s): %v", label, attempt, err), map[string]interface{}{})
+			return nil, nil, fmt.Errorf("falha na %s após %d tentativa(s): %w", label, attempt, err)
+		}
+
+		backoff := time.Duration(1<<(attempt-1)) * time.Second
+		logz.Warn(fmt.Sprintf("%s falhou (%v), nova tentativa em %s", label, err, backoff), map[string]interface{}{})
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, nil, fmt.Errorf("%s interrompida: %w", label, ctx.Err())
+		}
+	}
+}
+
+// queryWithTypes executa a consulta e lê todas as linhas com os tipos das colunas.
+func queryWithTypes(ctx context.Context, db *sql.DB, query string, args []interface{}) ([]Data, map[string]string, error) {
+	rows, rowsErr := db.QueryContext(ctx, query, args...)
+	if rowsErr != nil {
+		return nil, nil, rowsErr
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	data, types, scanErr := scanRowsWithTypes(rows, 0)
+	if scanErr != nil {
+		return nil, nil, scanErr
+	}
+	return data, types, nil
+}
@@ -0,0 +1,310 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	. "github.com/faelmori/getl/etypes"
+	. "github.com/faelmori/getl/utils"
+	"github.com/faelmori/logz"
+	"github.com/jackc/pglogrepl"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgproto3"
+	"strings"
+	"time"
+)
+
+// standbyInterval é o intervalo entre as confirmações enviadas ao servidor durante a replicação.
+// Com once, as confirmações pedem resposta e são mais frequentes, para notar logo o fim do WAL pendente.
+const (
+	standbyInterval     = 10 * time.Second
+	onceStandbyInterval = time.Second
+)
+
+// StreamPostgresChanges recebe do slot de replicação lógica as alterações de SourceTable e as
+// entrega ao sink, uma transação da origem por vez, até ctx ser cancelado. A replicação começa na
+// posição confirmada pelo sink (ou, sem ela, na do slot); transações até essa posição são
+// descartadas, pois já foram aplicadas. Depois de cada transação aplicada, a posição é confirmada
+// ao servidor, que pode então liberar o WAL anterior. Com once, retorna ao alcançar a posição do
+// WAL no início da chamada. Retorna quantas alterações foram aplicadas.
+func StreamPostgresChanges(ctx context.Context, config Config, sink ChangeSink, once bool) (int, error) {
+	source, sourceErr := OpenSource(ctx, config)
+	if sourceErr != nil {
+		return 0, sourceErr
+	}
+	if prepareErr := installReplication(ctx, source, config); prepareErr != nil {
+		return 0, prepareErr
+	}
+
+	var start pglogrepl.LSN
+	position, positionErr := sink.Position(ctx)
+	if positionErr != nil {
+		return 0, positionErr
+	}
+	if position != "" {
+		var parseErr error
+		if start, parseErr = pglogrepl.ParseLSN(position); parseErr != nil {
+			return 0, fmt.Errorf("posição registrada de %s: %w", ReplicationSlot(config), parseErr)
+		}
+	}
+	var target pglogrepl.LSN
+	if once {
+		if queryErr := source.QueryRowContext(ctx, "SELECT pg_current_wal_lsn()::text").Scan(&target); queryErr != nil {
+			return 0, fmt.Errorf("falha ao ler a posição corrente do WAL: %w", queryErr)
+		}
+		if target <= start {
+			return 0, nil
+		}
+	}
+
+	decoder, decoderErr := NewLogicalDecoder(config.CDC.Mode, config.SourceTable)
+	if decoderErr != nil {
+		return 0, decoderErr
+	}
+	conn, connErr := startReplication(ctx, config, start)
+	if connErr != nil {
+		return 0, connErr
+	}
+	defer func() {
+		closeCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = conn.Close(closeCtx)
+	}()
+
+	interval := standbyInterval
+	if once {
+		interval = onceStandbyInterval
+	}
+	stream := &replicationStream{conn: conn, flushed: start}
+	defer func() {
+		// Ao encerrar, confirma a última transação aplicada.
+		closeCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = stream.confirm(closeCtx, false)
+	}()
+
+	applied := 0
+	open := false
+	nextStatus := time.Now().Add(interval)
+	for {
+		if time.Now().After(nextStatus) {
+			if confirmErr := stream.confirm(ctx, once); confirmErr != nil {
+				return applied, confirmErr
+			}
+			nextStatus = time.Now().Add(interval)
+		}
+
+		receiveCtx, cancel := context.WithDeadline(ctx, nextStatus)
+		message, receiveErr := conn.ReceiveMessage(receiveCtx)
+		cancel()
+		if receiveErr != nil {
+			if ctx.Err() != nil {
+				return applied, nil
+			}
+			if pgconn.Timeout(receiveErr) {
+				continue
+			}
+			return applied, fmt.Errorf("falha ao receber o WAL de %s: %w", ReplicationSlot(config), receiveErr)
+		}
+
+		switch message := message.(type) {
+		case *pgproto3.ErrorResponse:
+			return applied, fmt.Errorf("replicação de %s interrompida: %w", ReplicationSlot(config), pgconn.ErrorResponseToPgError(message))
+		case *pgproto3.CopyData:
+			if len(message.Data) == 0 {
+				continue
+			}
+			switch message.Data[0] {
+			case pglogrepl.PrimaryKeepaliveMessageByteID:
+				keepalive, parseErr := pglogrepl.ParsePrimaryKeepaliveMessage(message.Data[1:])
+				if parseErr != nil {
+					return applied, fmt.Errorf("keepalive de replicação inválido: %w", parseErr)
+				}
+				if once && !open && keepalive.ServerWALEnd >= target {
+					return applied, nil
+				}
+				if keepalive.ReplyRequested {
+					nextStatus = time.Now()
+				}
+			case pglogrepl.XLogDataByteID:
+				xlog, parseErr := pglogrepl.ParseXLogData(message.Data[1:])
+				if parseErr != nil {
+					return applied, fmt.Errorf("mensagem de WAL inválida: %w", parseErr)
+				}
+				events, commit, decodeErr := decoder.Decode(xlog.WALData)
+				if decodeErr != nil {
+					return applied, fmt.Errorf("LSN %s: %w", xlog.WALStart, decodeErr)
+				}
+				open = !commit
+				if !commit {
+					continue
+				}
+
+				// No commit, WALStart é o fim da transação: a posição de onde a replicação recomeça.
+				if xlog.WALStart <= start {
+					continue
+				}
+				if len(events) > 0 {
+					lsn := xlog.WALStart.String()
+					for i := range events {
+						events[i].Position = lsn
+					}
+					if applyErr := sink.Apply(ctx, events, lsn); applyErr != nil {
+						return applied, fmt.Errorf("LSN %s: %w", lsn, applyErr)
+					}
+					applied += len(events)
+					logz.Info(fmt.Sprintf("%d alteração(ões) de %s aplicada(s) até o LSN %s", len(events), config.SourceTable, lsn), map[string]interface{}{})
+				}
+				stream.flushed = xlog.WALStart
+				if once && xlog.WALStart >= target {
+					return applied, nil
+				}
+			}
+		}
+	}
+}
+
+// replicationStream guarda a conexão de replicação e a última posição aplicada pelo sink.
+type replicationStream struct {
+	conn    *pgconn.PgConn
+	flushed pglogrepl.LSN
+}
+
+// confirm informa ao servidor a última posição aplicada, que pode então liberar o WAL anterior. Com
+// reply, pede um keepalive em resposta, com a posição corrente do WAL.
+func (s *replicationStream) confirm(ctx context.Context, reply bool) error {
+	update := pglogrepl.StandbyStatusUpdate{WALWritePosition: s.flushed, ReplyRequested: reply}
+	if err := pglogrepl.SendStandbyStatusUpdate(ctx, s.conn, update); err != nil {
+		return fmt.Errorf("falha ao confirmar a posição %s: %w", s.flushed, err)
+	}
+	return nil
+}
+
+// startReplication abre a conexão de replicação e inicia a leitura do slot a partir de start
+// (0 para a posição confirmada do slot).
+func startReplication(ctx context.Context, config Config, start pglogrepl.LSN) (*pgconn.PgConn, error) {
+	connConfig, parseErr := pgconn.ParseConfig(config.SourceConnectionString)
+	if parseErr != nil {
+		return nil, fmt.Errorf("string de conexão de origem inválida: %w", parseErr)
+	}
+	connConfig.RuntimeParams["replication"] = "database"
+	conn, connErr := pgconn.ConnectConfig(ctx, connConfig)
+	if connErr != nil {
+		return nil, fmt.Errorf("falha ao abrir a conexão de replicação: %w", connErr)
+	}
+
+	options := pglogrepl.StartReplicationOptions{Mode: pglogrepl.LogicalReplication, PluginArgs: replicationOptions(config)}
+	if startErr := pglogrepl.StartReplication(ctx, conn, ReplicationSlot(config), start, options); startErr != nil {
+		_ = conn.Close(ctx)
+		return nil, fmt.Errorf("falha ao iniciar a replicação: %w", startErr)
+	}
+	return conn, nil
+}
+
+// replicationOptions monta as opções do plugin para START_REPLICATION.
+func replicationOptions(config Config) []string {
+	if config.CDC.Mode == "wal2json" {
+		table := config.SourceTable
+		if !strings.Contains(table, ".") {
+			table = "*." + table
+		}
+		return []string{`"format-version" '2'`, `"include-transaction" 'true'`, `"include-timestamp" 'true'`, fmt.Sprintf(`"add-tables" '%s'`, strings.ReplaceAll(table, "'", "''"))}
+	}
+	return []string{"proto_version '1'", fmt.Sprintf("publication_names '%s'", strings.ReplaceAll(ReplicationPublication(config), "'", "''"))}
+}
+
+// installReplication confere a REPLICA IDENTITY da tabela e cria a publicação (só para o pgoutput)
+// e o slot de replicação, se ainda não existirem.
+func installReplication(ctx context.Context, db *sql.DB, config Config) error {
+	if identityErr := checkReplicaIdentity(ctx, db, config); identityErr != nil {
+		return identityErr
+	}
+	slot := ReplicationSlot(config)
+	if config.CDC.Mode == "pgoutput" {
+		publication := ReplicationPublication(config)
+		var count int
+		if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM pg_publication WHERE pubname = $1", publication).Scan(&count); err != nil {
+			return fmt.Errorf("falha ao consultar a publicação %s: %w", publication, err)
+		}
+		if count == 0 {
+			if _, err := db.ExecContext(ctx, fmt.Sprintf("CREATE PUBLICATION %s FOR TABLE %s", publication, config.SourceTable)); err != nil {
+				return fmt.Errorf("falha ao criar a publicação %s: %w", publication, err)
+			}
+			logz.Info(fmt.Sprintf("publicação %s criada para %s", publication, config.SourceTable), map[string]interface{}{})
+		}
+	}
+
+	var count int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM pg_replication_slots WHERE slot_name = $1", slot).Scan(&count); err != nil {
+		return fmt.Errorf("falha ao consultar o slot %s: %w", slot, err)
+	}
+	if count == 0 {
+		if _, err := db.ExecContext(ctx, "SELECT pg_create_logical_replication_slot($1, $2)", slot, config.CDC.Mode); err != nil {
+			return fmt.Errorf("falha ao criar o slot %s: %w", slot, err)
+		}
+		logz.Info(fmt.Sprintf("slot de replicação %s (%s) criado para %s", slot, config.CDC.Mode, config.SourceTable), map[string]interface{}{})
+	}
+	return nil
+}
+
+// checkReplicaIdentity exige REPLICA IDENTITY FULL em SourceTable. Com a identidade padrão, as
+// atualizações não trazem as colunas TOAST não alteradas, e gravar a nova imagem no destino as
+// apagaria; as exclusões também trariam só a chave.
+func checkReplicaIdentity(ctx context.Context, db *sql.DB, config Config) error {
+	var identity string
+	if err := db.QueryRowContext(ctx, "SELECT relreplident::text FROM pg_class WHERE oid = $1::regclass", config.SourceTable).Scan(&identity); err != nil {
+		return fmt.Errorf("falha ao consultar a REPLICA IDENTITY de %s: %w", config.SourceTable, err)
+	}
+	if identity != "f" {
+		return fmt.Errorf("a replicação lógica exige REPLICA IDENTITY FULL em %s; execute ALTER TABLE %s REPLICA IDENTITY FULL", config.SourceTable, config.SourceTable)
+	}
+	return nil
+}
+
+// uninstallReplication remove o slot de replicação e a publicação criados por installReplication.
+// Um slot em uso por getl cdc run não pode ser removido.
+func uninstallReplication(ctx context.Context, db *sql.DB, config Config) error {
+	slot := ReplicationSlot(config)
+	var count int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM pg_replication_slots WHERE slot_name = $1", slot).Scan(&count); err != nil {
+		return fmt.Errorf("falha ao consultar o slot %s: %w", slot, err)
+	}
+	if count > 0 {
+		if _, err := db.ExecContext(ctx, "SELECT pg_drop_replication_slot($1)", slot); err != nil {
+			return fmt.Errorf("falha ao remover o slot %s: %w", slot, err)
+		}
+	}
+	if config.CDC.Mode == "pgoutput" {
+		publication := ReplicationPublication(config)
+		if _, err := db.ExecContext(ctx, fmt.Sprintf("DROP PUBLICATION IF EXISTS %s", publication)); err != nil {
+			return fmt.Errorf("falha ao remover a publicação %s: %w", publication, err)
+		}
+	}
+	logz.Info(fmt.Sprintf("captura de alterações removida de %s: slot %s", config.SourceTable, slot), map[string]interface{}{})
+	return nil
+}
+
+// replicationStatus preenche o status da replicação lógica: o slot, a posição confirmada e o WAL retido.
+func replicationStatus(ctx context.Context, db *sql.DB, config Config, status *ChangeCaptureStatus) error {
+	var active bool
+	var confirmed string
+	var retained int64
+	err := db.QueryRowContext(ctx, `SELECT active, COALESCE(confirmed_flush_lsn::text, ''),
+		COALESCE(pg_wal_lsn_diff(pg_current_wal_lsn(), confirmed_flush_lsn), 0)::bigint
+		FROM pg_replication_slots WHERE slot_name = $1`, ReplicationSlot(config)).Scan(&active, &confirmed, &retained)
+	if errors.Is(err, sql.ErrNoRows) {
+		status.State = "ausente"
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("falha ao consultar o slot %s: %w", ReplicationSlot(config), err)
+	}
+	status.State = "instalada"
+	if active {
+		status.State = "ativa"
+	}
+	status.Pending = fmt.Sprintf("%d byte(s) de WAL", retained)
+	status.Position = confirmed
+	return nil
+}
@@ -0,0 +1,309 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	. "github.com/faelmori/getl/etypes"
+	. "github.com/faelmori/getl/utils"
+	"github.com/faelmori/logz"
+	"github.com/goccy/go-json"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// TypeMapping descreve como um campo de destino tem o tipo convertido do banco de origem para o de destino.
+type TypeMapping struct {
+	Field           string
+	SourceType      string
+	DestinationType string
+}
+
+// LoadPlan reúne o que LoadData faria no destino para uma pipeline, sem executar nada nele:
+// a consulta de extração, o DDL da tabela, o mapeamento de tipos e uma amostra das linhas transformadas.
+type LoadPlan struct {
+	Pipeline    string
+	Config      Config
+	Query       string
+	QueryArgs   []interface{}
+	CreateTable string
+	Types       []TypeMapping
+	Sample      []Data
+	QueryPlan   []string
+	Partitions  []PartitionQuery
+}
+
+// PlanLoad monta o LoadPlan da Config. Apenas o banco de origem é acessado, para ler os tipos
+// das colunas e até sampleSize linhas; com explain, o EXPLAIN da consulta de extração também é executado.
+// Uma origem redis com pattern é lida com SCAN, sem EXPLAIN; uma com stream não pode ser planejada,
+// pois ler o stream entrega as entradas ao grupo de consumo.
+func PlanLoad(config Config, sampleSize int, explain bool) (*LoadPlan, error) {
+	if config.SourceType == RedisType {
+		return planRedisLoad(config, sampleSize)
+	}
+	plan := &LoadPlan{Config: config}
+	sampleSize = max(sampleSize, 0)
+
+	var buildQueryErr error
+	plan.Query, plan.QueryArgs, buildQueryErr = buildExtractQuery(config)
+	if buildQueryErr != nil {
+		return nil, fmt.Errorf("falha ao construir a consulta de extração: %w", buildQueryErr)
+	}
+
+	db, dbErr := OpenSource(context.Background(), config)
+	if dbErr != nil {
+		return nil, dbErr
+	}
+
+	rows, rowsErr := db.Query(plan.Query, plan.QueryArgs...)
+	if rowsErr != nil {
+		return nil, fmt.Errorf("falha ao executar a consulta de extração: %w", rowsErr)
+	}
+	// Uma amostra vazia ainda traz os tipos das colunas; sampleSize 0 não deve ler a tabela inteira.
+	sample, sourceTypes, scanErr := scanRowsWithTypes(rows, max(sampleSize, 1))
+	_ = rows.Close()
+	if scanErr != nil {
+		return nil, fmt.Errorf("falha ao ler a amostra: %w", scanErr)
+	}
+	if len(sample) > sampleSize {
+		sample = sample[:sampleSize]
+	}
+
+	if typesErr := planTypes(plan, sample, sourceTypes); typesErr != nil {
+		return nil, typesErr
+	}
+
+	if config.Partitioning.Enabled() {
+		var partitionsErr error
+		plan.Partitions, partitionsErr = PlanPartitions(context.Background(), db, config)
+		if partitionsErr != nil {
+			return nil, partitionsErr
+		}
+	}
+
+	if explain {
+		var explainErr error
+		plan.QueryPlan, explainErr = ExplainQuery(db, config.SourceType, plan.Query, plan.QueryArgs)
+		if explainErr != nil {
+			return nil, explainErr
+		}
+	}
+
+	return plan, nil
+}
+
+// planRedisLoad monta o LoadPlan de uma origem redis com pattern. Os hashes são lidos com SCAN e os
+// tipos das colunas, deduzidos dos valores lidos.
+func planRedisLoad(config Config, sampleSize int) (*LoadPlan, error) {
+	if stream := config.Redis.Source.Stream; stream != "" {
+		group, _ := RedisGroup(config)
+		return nil, fmt.Errorf("uma origem redis com stream não pode ser planejada: ler o stream %s entregaria as entradas ao grupo %s", stream, group)
+	}
+	plan := &LoadPlan{Config: config, Query: fmt.Sprintf("SCAN 0 MATCH %s TYPE hash", config.Redis.Source.Pattern)}
+	sampleSize = max(sampleSize, 0)
+	source, sample, sourceTypes, extractErr := extractRedis(context.Background(), config)
+	if extractErr != nil {
+		return nil, extractErr
+	}
+	_ = source.Close()
+	if len(sample) > sampleSize {
+		sample = sample[:sampleSize]
+	}
+	if typesErr := planTypes(plan, sample, sourceTypes); typesErr != nil {
+		return nil, typesErr
+	}
+	return plan, nil
+}
+
+// planTypes preenche no plano o DDL de destino, o mapeamento de tipos e a amostra transformada, a
+// partir das linhas e dos tipos lidos da origem.
+func planTypes(plan *LoadPlan, sample []Data, sourceTypes map[string]string) error {
+	config := plan.Config
+	fieldsDest, fieldsDestErr := destinationFieldTypes(config, sourceTypes)
+	if fieldsDestErr != nil {
+		return fieldsDestErr
+	}
+
+	var createTableErr error
+	plan.CreateTable, createTableErr = BuildCreateTableQuery(config, fieldsDest)
+	if createTableErr != nil {
+		return fmt.Errorf("falha ao gerar o DDL de destino: %w", createTableErr)
+	}
+
+	for field, sourceType := range fieldsDest {
+		plan.Types = append(plan.Types, TypeMapping{
+			Field:           field,
+			SourceType:      sourceType,
+			DestinationType: GetVendorSqlType(config.DestinationType, sourceType),
+		})
+	}
+	sort.Slice(plan.Types, func(i, j int) bool { return plan.Types[i].Field < plan.Types[j].Field })
+
+	var transformErr error
+	plan.Sample, transformErr = ApplyTransformations(sample, config.Transformations)
+	if transformErr != nil {
+		return fmt.Errorf("falha ao aplicar as transformações na amostra: %w", transformErr)
+	}
+	return nil
+}
+
+// ExplainQuery executa o EXPLAIN da consulta no banco de origem e retorna o plano, uma linha por registro.
+// Oracle e SQL Server não têm um EXPLAIN que retorna linhas, então usam EXPLAIN PLAN FOR + DBMS_XPLAN
+// e SET SHOWPLAN_TEXT na mesma conexão.
+func ExplainQuery(db *sql.DB, driver, query string, args []interface{}) ([]string, error) {
+	ctx := context.Background()
+	conn, connErr := db.Conn(ctx)
+	if connErr != nil {
+		return nil, fmt.Errorf("falha ao obter conexão com a origem: %w", connErr)
+	}
+	defer func(conn *sql.Conn) {
+		_ = conn.Close()
+	}(conn)
+
+	var rows *sql.Rows
+	var rowsErr error
+	switch driver {
+	case "sqlite3", "sqlite":
+		rows, rowsErr = conn.QueryContext(ctx, "EXPLAIN QUERY PLAN "+query, args...)
+	case "postgres", "mysql":
+		rows, rowsErr = conn.QueryContext(ctx, "EXPLAIN "+query, args...)
+	case "godror", "oracle":
+		if _, execErr := conn.ExecContext(ctx, "EXPLAIN PLAN FOR "+query, args...); execErr != nil {
+			return nil, fmt.Errorf("falha ao executar EXPLAIN PLAN: %w", execErr)
+		}
+		rows, rowsErr = conn.QueryContext(ctx, "SELECT PLAN_TABLE_OUTPUT FROM TABLE(DBMS_XPLAN.DISPLAY())")
+	case "sqlserver", "mssql":
+		if _, execErr := conn.ExecContext(ctx, "SET SHOWPLAN_TEXT ON"); execErr != nil {
+			return nil, fmt.Errorf("falha ao ativar SHOWPLAN_TEXT: %w", execErr)
+		}
+		defer func(conn *sql.Conn) {
+			_, _ = conn.ExecContext(ctx, "SET SHOWPLAN_TEXT OFF")
+		}(conn)
+		rows, rowsErr = conn.QueryContext(ctx, query, args...)
+	default:
+		return nil, fmt.Errorf("EXPLAIN não suportado para o driver %s", driver)
+	}
+	if rowsErr != nil {
+		return nil, fmt.Errorf("falha ao executar o EXPLAIN: %w", rowsErr)
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	// O SQL Server devolve o plano em mais de um result set.
+	lines := []string{}
+	for {
+		columns, columnsErr := rows.Columns()
+		if columnsErr != nil {
+			return nil, fmt.Errorf("falha ao ler o plano: %w", columnsErr)
+		}
+		for rows.Next() {
+			values := make([]sql.NullString, len(columns))
+			pointers := make([]interface{}, len(columns))
+			for i := range values {
+				pointers[i] = &values[i]
+			}
+			if scanErr := rows.Scan(pointers...); scanErr != nil {
+				return nil, fmt.Errorf("falha ao ler o plano: %w", scanErr)
+			}
+			fields := make([]string, len(values))
+			for i, value := range values {
+				fields[i] = value.String
+			}
+			lines = append(lines, strings.Join(fields, " | "))
+		}
+		if !rows.NextResultSet() {
+			break
+		}
+	}
+
+	return lines, rows.Err()
+}
+
+// PrintLoadPlan escreve o LoadPlan em formato legível. As senhas das connection strings são ocultadas.
+func PrintLoadPlan(w io.Writer, plan *LoadPlan) error {
+	config, marshalErr := MarshalConfig(MaskConfigSecrets(plan.Config), "yaml")
+	if marshalErr != nil {
+		return marshalErr
+	}
+
+	_, _ = fmt.Fprintf(w, "== pipeline %s (dry-run: nada será gravado no destino)\n\n", plan.Pipeline)
+	_, _ = fmt.Fprintf(w, "-- configuração resolvida\n%s\n", config)
+	_, _ = fmt.Fprintf(w, "-- consulta de extração\n%s\n", plan.Query)
+	if len(plan.QueryArgs) > 0 {
+		_, _ = fmt.Fprintf(w, "-- argumentos: %v\n", plan.QueryArgs)
+	}
+	if len(plan.Partitions) > 0 {
+		workers := partitionWorkers(plan.Config.Partitioning, len(plan.Partitions))
+		_, _ = fmt.Fprintf(w, "\n-- partições (%d, até %d em paralelo)\n", len(plan.Partitions), workers)
+		for _, partition := range plan.Partitions {
+			_, _ = fmt.Fprintf(w, "%d. %s\n   %s", partition.Index+1, partition.Label, partition.Query)
+			if len(partition.Args) > 0 {
+				_, _ = fmt.Fprintf(w, " %v", partition.Args)
+			}
+			_, _ = fmt.Fprintln(w)
+		}
+	}
+	_, _ = fmt.Fprintf(w, "\n-- DDL de destino (%s)\n%s;\n\n", plan.Config.DestinationType, plan.CreateTable)
+
+	_, _ = fmt.Fprintln(w, "-- mapeamento de tipos")
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(table, "CAMPO\tORIGEM\tDESTINO")
+	for _, mapping := range plan.Types {
+		_, _ = fmt.Fprintf(table, "%s\t%s\t%s\n", mapping.Field, mapping.SourceType, mapping.DestinationType)
+	}
+	if flushErr := table.Flush(); flushErr != nil {
+		return flushErr
+	}
+
+	_, _ = fmt.Fprintf(w, "\n-- amostra (%d linha(s) transformada(s))\n", len(plan.Sample))
+	for _, row := range plan.Sample {
+		line, rowErr := json.Marshal(row)
+		if rowErr != nil {
+			return fmt.Errorf("falha ao serializar a amostra: %w", rowErr)
+		}
+		_, _ = fmt.Fprintf(w, "%s\n", line)
+	}
+
+	if plan.QueryPlan != nil {
+		_, _ = fmt.Fprintf(w, "\n-- plano de execução na origem (%s)\n", plan.Config.SourceType)
+		for _, line := range plan.QueryPlan {
+			_, _ = fmt.Fprintln(w, line)
+		}
+	}
+	_, _ = fmt.Fprintln(w)
+
+	return nil
+}
+
+// DryRunETLPipelines imprime o LoadPlan de cada pipeline selecionada, sem gravar no destino.
+// Sem nomes informados, todas as pipelines do arquivo são consideradas.
+func DryRunETLPipelines(w io.Writer, configPath string, pipelineNames []string, sampleSize int, explain bool) error {
+	pipelines, loadConfigErr := LoadPipelinesFile(configPath)
+	if loadConfigErr != nil {
+		logz.Error(fmt.Sprintf("falha ao carregar a configuração: %v", loadConfigErr), map[string]interface{}{})
+		return loadConfigErr
+	}
+
+	selected, selectErr := SelectPipelines(pipelines, pipelineNames)
+	if selectErr != nil {
+		logz.Error(fmt.Sprintf("falha ao selecionar as pipelines: %v", selectErr), map[string]interface{}{})
+		return selectErr
+	}
+
+	for _, pipeline := range selected {
+		plan, planErr := PlanLoad(pipeline.Config, sampleSize, explain)
+		if planErr != nil {
+			logz.Error(fmt.Sprintf("falha ao planejar a pipeline %s: %v", pipeline.Name, planErr), map[string]interface{}{})
+			return fmt.Errorf("falha ao planejar a pipeline %s: %w", pipeline.Name, planErr)
+		}
+		plan.Pipeline = pipeline.Name
+		if printErr := PrintLoadPlan(w, plan); printErr != nil {
+			return printErr
+		}
+	}
+
+	return nil
+}
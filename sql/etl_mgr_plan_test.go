@@ -0,0 +1,37 @@
+package sql
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	. "github.com/faelmori/getl/etypes"
+)
+
+// TestPlanLoadRedis planeja uma origem redis com pattern e recusa uma com stream, sem criar o grupo.
+func TestPlanLoadRedis(t *testing.T) {
+	server := miniredis.RunT(t)
+	server.HSet("items:1", "id", "1", "name", "a")
+	server.HSet("items:2", "id", "2", "name", "b")
+	config := Config{
+		SourceType: RedisType, SourceConnectionString: "redis://" + server.Addr() + "/0",
+		DestinationType: "sqlite3", DestinationTable: "items",
+		Redis: RedisOptions{Source: RedisSource{Pattern: "items:*"}},
+	}
+
+	plan, err := PlanLoad(config, 1, true)
+	if err != nil {
+		t.Fatalf("PlanLoad() falhou: %v", err)
+	}
+	if len(plan.Sample) != 1 || len(plan.Types) != 2 || !strings.Contains(plan.CreateTable, "items") {
+		t.Errorf("PlanLoad() = amostra %v, tipos %v, DDL %q", plan.Sample, plan.Types, plan.CreateTable)
+	}
+
+	config.Redis.Source = RedisSource{Stream: "events"}
+	if _, err := PlanLoad(config, 1, false); err == nil || !strings.Contains(err.Error(), "stream") {
+		t.Errorf("PlanLoad() com stream = %v, esperado erro", err)
+	}
+	if server.Exists("events") {
+		t.Error("PlanLoad() com stream criou o stream events")
+	}
+}
@@ -0,0 +1,98 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	. "github.com/faelmori/getl/etypes"
+	etlredis "github.com/faelmori/getl/redis"
+	. "github.com/faelmori/getl/utils"
+	"github.com/faelmori/logz"
+)
+
+// extractRedis lê as linhas da origem redis, com o timeout de extração, e deduz os tipos das colunas
+// dos valores. O Source retornado confirma as entradas lidas de um stream em ackRedis, após a carga.
+func extractRedis(ctx context.Context, config Config) (*etlredis.Source, []Data, map[string]string, error) {
+	extractCtx, cancel, timeoutErr := WithStageTimeout(ctx, config.Timeouts.Extract)
+	if timeoutErr != nil {
+		return nil, nil, nil, timeoutErr
+	}
+	defer cancel()
+
+	source, openErr := etlredis.OpenSource(extractCtx, config)
+	if openErr != nil {
+		return nil, nil, nil, fmt.Errorf("falha ao conectar à origem redis: %w", openErr)
+	}
+	rows, readErr := source.Read(extractCtx)
+	if readErr != nil {
+		_ = source.Close()
+		return nil, nil, nil, readErr
+	}
+	return source, rows, MessageFieldTypes(rows), nil
+}
+
+// ackRedis confirma as entradas do stream lidas por source, depois que a carga foi gravada; sem
+// source (uma origem SQL), não faz nada.
+func ackRedis(ctx context.Context, source *etlredis.Source) error {
+	if source == nil {
+		return nil
+	}
+	if ackErr := source.Ack(ctx); ackErr != nil {
+		logz.Error(ackErr.Error(), map[string]interface{}{})
+		return fmt.Errorf("carga gravada, mas as entradas não foram confirmadas e serão lidas de novo: %w", ackErr)
+	}
+	return nil
+}
+
+// loadToRedis executa uma pipeline com destino redis: as linhas da origem, SQL ou redis, recebem as
+// transformations e são gravadas conforme redis.sink, em transações de redis.batchSize linhas.
+func loadToRedis(ctx context.Context, config Config) error {
+	var data []Data
+	var source *etlredis.Source
+	var extractErr error
+	if config.SourceType == RedisType {
+		source, data, _, extractErr = extractRedis(ctx, config)
+	} else {
+		data, _, extractErr = extractDataWithTypes(ctx, nil, config, nil)
+	}
+	if extractErr != nil {
+		logz.Error("Failed to extract data: "+extractErr.Error(), map[string]interface{}{})
+		return extractErr
+	}
+	if source != nil {
+		defer func(source *etlredis.Source) {
+			_ = source.Close()
+		}(source)
+	}
+
+	transformedData, transformedDataErr := ApplyTransformations(data, config.Transformations)
+	if transformedDataErr != nil {
+		logz.Error("Failed to apply transformations: "+transformedDataErr.Error(), map[string]interface{}{})
+		return transformedDataErr
+	}
+	if config.OutputPath != "" {
+		if saveDataErr := SaveData(config.OutputPath, transformedData, config.OutputFormat); saveDataErr != nil {
+			logz.Error("Failed to save data: "+saveDataErr.Error(), map[string]interface{}{})
+			return saveDataErr
+		}
+	}
+
+	loadCtx, cancel, timeoutErr := WithStageTimeout(ctx, config.Timeouts.Load)
+	if timeoutErr != nil {
+		return timeoutErr
+	}
+	defer cancel()
+
+	sink, sinkErr := etlredis.OpenSink(config)
+	if sinkErr != nil {
+		logz.Error(sinkErr.Error(), map[string]interface{}{})
+		return sinkErr
+	}
+	defer func(sink *etlredis.Sink) {
+		_ = sink.Close()
+	}(sink)
+	if _, writeErr := sink.Write(loadCtx, transformedData); writeErr != nil {
+		logz.Error(writeErr.Error(), map[string]interface{}{})
+		return writeErr
+	}
+	return ackRedis(loadCtx, source)
+}
@@ -0,0 +1,126 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/elgris/sqrl"
+	. "github.com/faelmori/getl/etypes"
+	. "github.com/faelmori/getl/utils"
+	"github.com/faelmori/logz"
+	"maps"
+	"time"
+)
+
+// loadSCD2 grava as linhas transformadas como versões (loadMode scd2), em uma única transação. Uma
+// chave sem versão corrente ganha a primeira; se alguma coluna rastreada mudou, a versão corrente
+// é fechada e uma nova é inserida; linhas sem alteração não tocam o destino.
+func loadSCD2(ctx context.Context, db *sql.DB, config Config, rows []Data) error {
+	key, keyErr := ChangeCaptureKey(config)
+	if keyErr != nil {
+		return keyErr
+	}
+	current, currentErr := currentVersions(ctx, db, config, key)
+	if currentErr != nil {
+		logz.Error(currentErr.Error(), map[string]interface{}{})
+		return currentErr
+	}
+
+	settings := SCD2Settings(config)
+	insertConfig := config
+	insertConfig.UpdateKey = ""
+	format := PlaceholderFormatFor(config.DestinationType)
+	// O fim da versão fechada é o mesmo instante que o início da nova.
+	now := time.Now().UTC()
+
+	tx, txErr := db.BeginTx(ctx, nil)
+	if txErr != nil {
+		logz.Error(fmt.Sprintf("Failed to start transaction: %v", txErr), map[string]interface{}{})
+		return fmt.Errorf("Failed to start transaction: %w", txErr)
+	}
+	inserted, closed := 0, 0
+	for i, row := range rows {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return rollbackInterruptedLoad(tx, i, len(rows), ctxErr)
+		}
+		if rowErr := ValidateSCD2Row(config, key, row); rowErr != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("linha %d: %w", i+1, rowErr)
+		}
+
+		keyValue := DeleteKeyString(row[key])
+		if version, found := current[keyValue]; found {
+			if !SCD2Changed(version, row, SCD2TrackedColumns(config, key, row)) {
+				continue
+			}
+			closeQuery, closeArgs, closeQueryErr := sqrl.Update(config.DestinationTable).
+				Set(settings.ValidTo, now).Set(settings.CurrentFlag, false).
+				Where(sqrl.Eq{key: row[key], settings.CurrentFlag: true}).PlaceholderFormat(format).ToSql()
+			if closeQueryErr != nil {
+				_ = tx.Rollback()
+				return closeQueryErr
+			}
+			if _, execErr := tx.ExecContext(ctx, closeQuery, closeArgs...); execErr != nil {
+				if ctxErr := ctx.Err(); ctxErr != nil {
+					return rollbackInterruptedLoad(tx, i, len(rows), ctxErr)
+				}
+				_ = tx.Rollback()
+				return fmt.Errorf("falha ao fechar a versão corrente de %v: %w", row[key], execErr)
+			}
+			closed++
+		}
+
+		version := maps.Clone(row)
+		version[settings.ValidFrom] = now
+		version[settings.ValidTo] = nil
+		version[settings.CurrentFlag] = true
+		if execErr := insertRow(ctx, tx, insertConfig, version); execErr != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return rollbackInterruptedLoad(tx, i, len(rows), ctxErr)
+			}
+			_ = tx.Rollback()
+			logz.Error("Failed to execute insert query: "+execErr.Error(), map[string]interface{}{})
+			return fmt.Errorf("Failed to execute insert query: %w", execErr)
+		}
+		// Uma chave repetida na extração é comparada com a versão que acabou de ser inserida.
+		current[keyValue] = row
+		inserted++
+	}
+
+	if commitErr := tx.Commit(); commitErr != nil {
+		logz.Error("Failed to commit transaction: "+commitErr.Error(), map[string]interface{}{})
+		return fmt.Errorf("Failed to commit transaction: %w", commitErr)
+	}
+	logz.Info(fmt.Sprintf("%d versão(ões) inserida(s) em %s: %d nova(s) chave(s), %d alterada(s); %d linha(s) sem alteração", inserted, config.DestinationTable, inserted-closed, closed, len(rows)-inserted), map[string]interface{}{})
+	return nil
+}
+
+// currentVersions lê as versões correntes do destino, indexadas pela chave normalizada.
+func currentVersions(ctx context.Context, db *sql.DB, config Config, key string) (map[string]Data, error) {
+	settings := SCD2Settings(config)
+	query, args, queryErr := sqrl.Select("*").From(config.DestinationTable).Where(sqrl.Eq{settings.CurrentFlag: true}).
+		PlaceholderFormat(PlaceholderFormatFor(config.DestinationType)).ToSql()
+	if queryErr != nil {
+		return nil, queryErr
+	}
+	rows, rowsErr := db.QueryContext(ctx, query, args...)
+	if rowsErr != nil {
+		return nil, fmt.Errorf("falha ao ler as versões correntes de %s (a tabela precisa das colunas %s, %s, %s e %s): %w",
+			config.DestinationTable, settings.SurrogateKey, settings.ValidFrom, settings.ValidTo, settings.CurrentFlag, rowsErr)
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	versions, _, scanErr := scanRowsWithTypes(rows, 0)
+	if scanErr != nil {
+		return nil, fmt.Errorf("falha ao ler as versões correntes de %s: %w", config.DestinationTable, scanErr)
+	}
+	current := make(map[string]Data, len(versions))
+	for _, version := range versions {
+		if value := version[key]; value != nil {
+			current[DeleteKeyString(value)] = version
+		}
+	}
+	return current, nil
+}
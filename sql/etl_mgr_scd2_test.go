@@ -0,0 +1,45 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/faelmori/getl/etypes"
+)
+
+// TestLoadSCD2TimeColumn sincroniza duas vezes as mesmas linhas com uma coluna de horário, que o
+// SQLite devolve como texto em outro formato, e espera que a segunda carga não crie versões.
+func TestLoadSCD2TimeColumn(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "scd2.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(db *sql.DB) {
+		_ = db.Close()
+	}(db)
+	if _, err := db.ExecContext(ctx, `CREATE TABLE customers (sk INTEGER PRIMARY KEY AUTOINCREMENT, id INTEGER,
+		name TEXT, updated_at TEXT, valid_from TEXT, valid_to TEXT, is_current BOOLEAN)`); err != nil {
+		t.Fatal(err)
+	}
+
+	config := Config{DestinationType: "sqlite", DestinationTable: "customers", PrimaryKey: "id", LoadMode: "scd2"}
+	updatedAt := time.Date(2024, 3, 1, 9, 30, 15, 250000000, time.FixedZone("BRT", -3*3600))
+	rows := []Data{{"id": int64(1), "name": "Ana", "updated_at": updatedAt}}
+	for run := 1; run <= 2; run++ {
+		if err := loadSCD2(ctx, db, config, rows); err != nil {
+			t.Fatalf("carga %d: %v", run, err)
+		}
+	}
+
+	var versions int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM customers").Scan(&versions); err != nil {
+		t.Fatal(err)
+	}
+	if versions != 1 {
+		t.Fatalf("versões = %d, esperava 1", versions)
+	}
+}
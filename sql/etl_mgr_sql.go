@@ -1,12 +1,17 @@
 package sql
 
 import (
+	"context"
 	"database/sql"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"github.com/charmbracelet/lipgloss"
 	_ "github.com/denisenkom/go-mssqldb"
+	"github.com/elgris/sqrl"
 	. "github.com/faelmori/getl/etypes"
+	"github.com/faelmori/getl/meta"
+	etlredis "github.com/faelmori/getl/redis"
 	. "github.com/faelmori/getl/utils"
 	//ui "github.com/faelmori/kbx/mods/ui/components"
 	"github.com/faelmori/gkbxsrv/utils"
@@ -17,9 +22,12 @@ import (
 	_ "github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
 	"gopkg.in/yaml.v3"
+	"maps"
 	"os"
+	"reflect"
+	"slices"
+	"sort"
 	"strings"
-	"time"
 )
 
 func ShowDataTableFromConfig(fileConfigPath string, export bool, exportPath string, outputFormat string) error {
@@ -39,7 +47,11 @@ func ShowDataTableFromConfig(fileConfigPath string, export bool, exportPath stri
 		}
 	}
 
-	handler, err := GetDataTableHandlerFromQuery(config.SourceType, config.SourceConnectionString, sqlQuery)
+	db, err := OpenSource(context.Background(), config)
+	if err != nil {
+		return err
+	}
+	handler, err := GetDataTableHandlerFromQuery(db, sqlQuery)
 	if err != nil {
 		return err
 	}
@@ -72,62 +84,108 @@ func ShowDataTableFromConfig(fileConfigPath string, export bool, exportPath stri
 	return ui.StartTableScreen(handler, customStyles)
 }
 func ExtractDataWithTypes(dbSQL *sql.DB, config Config) ([]Data, map[string]string, error) {
-	var db *sql.DB
-	var dbErr error
-	if dbSQL == nil {
-		db, dbErr = sql.Open(config.SourceType, config.SourceConnectionString)
+	return ExtractDataWithTypesContext(context.Background(), dbSQL, config)
+}
+
+// ExtractDataWithTypesContext é a variante de ExtractDataWithTypes que respeita o cancelamento de ctx
+// e o timeout de extração da Config. Sem dbSQL, é usado o pool compartilhado da origem (OpenSource).
+func ExtractDataWithTypesContext(ctx context.Context, dbSQL *sql.DB, config Config) ([]Data, map[string]string, error) {
+	return extractDataWithTypes(ctx, dbSQL, config, nil)
+}
+
+// extractDataWithTypes extrai os dados da origem. Com lastKey, a extração retoma após a última
+// chave de checkpoint confirmada (CheckpointKey).
+func extractDataWithTypes(ctx context.Context, dbSQL *sql.DB, config Config, lastKey interface{}) ([]Data, map[string]string, error) {
+	db := dbSQL
+	if db == nil {
+		var dbErr error
+		db, dbErr = OpenSource(ctx, config)
 		if dbErr != nil {
 			logz.Error("Failed to connect to source database: "+dbErr.Error(), map[string]interface{}{})
 			return nil, nil, dbErr
 		}
-	} else {
-		db = dbSQL
 	}
-	defer func(db *sql.DB) {
-		_ = db.Close()
-	}(db)
 
-	logz.Info("Starting data extraction", map[string]interface{}{})
+	extractCtx, cancel, timeoutErr := WithStageTimeout(ctx, config.Timeouts.Extract)
+	if timeoutErr != nil {
+		return nil, nil, timeoutErr
+	}
+	defer cancel()
 
-	var rows *sql.Rows
-	var SQLQueryArgs []interface{}
-	var rowsErr error
-	var buildQueryErr error
+	logz.Info("Starting data extraction", map[string]interface{}{})
 
-	if config.SQLQuery == "" {
-		var fields []string
-		var transformationsList []Transformation
-		transformationsList = config.Transformations
-		for i, t := range transformationsList {
-			fields = append(fields, t.SourceField)
-			if t.Type == "" {
-				transformationsList[i].Type = "string"
-			}
-		}
-		config.SQLQuery, SQLQueryArgs, buildQueryErr = BuilExtractdQuery(config, fields)
-		if buildQueryErr != nil {
-			logz.Error("Failed to build query: "+buildQueryErr.Error(), map[string]interface{}{})
-			return nil, nil, buildQueryErr
+	if config.Partitioning.Enabled() {
+		data, columnTypes, partitionErr := extractPartitionedWithTypes(extractCtx, db, config)
+		if partitionErr != nil && extractCtx.Err() != nil {
+			logz.Warn("extração particionada interrompida", map[string]interface{}{})
+			return nil, nil, fmt.Errorf("extração interrompida: %w", extractCtx.Err())
 		}
+		return data, columnTypes, partitionErr
 	}
 
-	//logz.DebugLog("Running query: "+config.SQLQuery, map[string]interface{}{})
+	sqlQuery, sqlQueryArgs, buildQueryErr := buildExtractQuery(config)
+	if lastKey != nil {
+		sqlQuery, sqlQueryArgs, buildQueryErr = BuildCheckpointQuery(config, extractFields(config), lastKey)
+	}
+	if buildQueryErr != nil {
+		logz.Error("Failed to build query: "+buildQueryErr.Error(), map[string]interface{}{})
+		return nil, nil, buildQueryErr
+	}
 
-	if len(SQLQueryArgs) > 0 {
-		rows, rowsErr = db.Query(config.SQLQuery, SQLQueryArgs...)
-	} else {
-		rows, rowsErr = db.Query(config.SQLQuery)
+	//logz.DebugLog("Running query: "+sqlQuery, map[string]interface{}{})
+
+	// Um erro transitório refaz a consulta; as linhas lidas na tentativa anterior são descartadas.
+	var data []Data
+	var columnTypes map[string]string
+	attempts, extractErr := retryTransient(extractCtx, config.Pool, "extrair de "+config.SourceType, func() error {
+		rows, rowsErr := db.QueryContext(extractCtx, sqlQuery, sqlQueryArgs...)
+		if rowsErr != nil {
+			logz.Error("Failed on query execution: "+rowsErr.Error(), map[string]interface{}{})
+			return rowsErr
+		}
+		defer func(rows *sql.Rows) {
+			_ = rows.Close()
+		}(rows)
+
+		var scanErr error
+		data, columnTypes, scanErr = scanRowsWithTypes(rows, 0)
+		return scanErr
+	})
+	if extractErr != nil {
+		if extractCtx.Err() != nil {
+			logz.Warn(fmt.Sprintf("extração interrompida após %d linha(s)", len(data)), map[string]interface{}{})
+			return nil, nil, fmt.Errorf("extração interrompida após %d linha(s): %w", len(data), extractCtx.Err())
+		}
+		return nil, nil, fmt.Errorf("falha na extração após %d tentativa(s): %w", attempts, extractErr)
 	}
+	return data, columnTypes, nil
+}
 
-	if rowsErr != nil {
-		logz.Error("Failed on query execution: "+rowsErr.Error(), map[string]interface{}{})
-		return nil, nil, rowsErr
+// buildExtractQuery retorna a consulta de extração: a sqlQuery da configuração ou, sem ela,
+// a consulta montada por BuilExtractdQuery com os campos de origem das transformações.
+// Com checkpoint, a consulta é ordenada pela chave de checkpoint.
+func buildExtractQuery(config Config) (string, []interface{}, error) {
+	if config.Checkpoint.Enabled() {
+		return BuildCheckpointQuery(config, extractFields(config), nil)
 	}
+	if config.SQLQuery != "" {
+		return config.SQLQuery, nil, nil
+	}
+	return BuilExtractdQuery(config, extractFields(config))
+}
 
-	defer func(rows *sql.Rows) {
-		_ = rows.Close()
-	}(rows)
+// extractFields retorna os campos de origem lidos pelas transformações.
+func extractFields(config Config) []string {
+	fields := make([]string, 0, len(config.Transformations))
+	for _, t := range config.Transformations {
+		fields = append(fields, t.SourceField)
+	}
+	return fields
+}
 
+// scanRowsWithTypes lê as linhas do resultado junto com o tipo de cada coluna na origem.
+// Com limit maior que zero, para de ler após limit linhas.
+func scanRowsWithTypes(rows *sql.Rows, limit int) ([]Data, map[string]string, error) {
 	var data []Data
 	columns, columnsErr := rows.Columns()
 	if columnsErr != nil {
@@ -147,6 +205,10 @@ func ExtractDataWithTypes(dbSQL *sql.DB, config Config) ([]Data, map[string]stri
 	}
 
 	for rows.Next() {
+		if limit > 0 && len(data) >= limit {
+			break
+		}
+
 		rowData := make([]interface{}, len(columns))
 		rowPointers := make([]interface{}, len(columns))
 		for i := range rowData {
@@ -165,99 +227,204 @@ func ExtractDataWithTypes(dbSQL *sql.DB, config Config) ([]Data, map[string]stri
 		data = append(data, row)
 	}
 
+	// Em caso de erro, as linhas já lidas são retornadas para que o chamador informe o progresso.
+	if rowsErr := rows.Err(); rowsErr != nil {
+		logz.Error("Failed to read rows: "+rowsErr.Error(), map[string]interface{}{})
+		return data, nil, rowsErr
+	}
+
 	return data, columnTypeMap, nil
 }
 func EnsureTableExistsWithTypes(db *sql.DB, config Config, fields map[string]string) error {
+	return EnsureTableExistsWithTypesContext(context.Background(), db, config, fields)
+}
+
+// EnsureTableExistsWithTypesContext é a variante de EnsureTableExistsWithTypes que respeita o cancelamento de ctx.
+func EnsureTableExistsWithTypesContext(ctx context.Context, db *sql.DB, config Config, fields map[string]string) error {
+	createTableQuery, buildErr := BuildCreateTableQuery(config, fields)
+	if buildErr != nil {
+		return buildErr
+	}
+
+	_, createTableQueryErr := db.ExecContext(ctx, createTableQuery)
+	if createTableQueryErr != nil {
+		logz.Error(fmt.Sprintf("falha ao criar a tabela: %v", createTableQueryErr), map[string]interface{}{})
+		return createTableQueryErr
+	}
+
+	return nil
+}
+
+// EnsureTableForRows cria a tabela de destino para linhas que não vêm de uma consulta à origem,
+// como as mensagens do Kafka: vale o type das transformações ou o tipo deduzido dos valores.
+func EnsureTableForRows(ctx context.Context, db *sql.DB, config Config, rows []Data) error {
+	fieldsDest, fieldsDestErr := destinationFieldTypes(config, MessageFieldTypes(rows))
+	if fieldsDestErr != nil {
+		return fieldsDestErr
+	}
+	return EnsureTableExistsWithTypesContext(ctx, db, config, fieldsDest)
+}
+
+// BuildCreateTableQuery monta o CREATE TABLE que EnsureTableExistsWithTypes executa no destino,
+// convertendo os tipos da origem para os do driver de destino. As colunas seguem a ordem alfabética.
+// Com loadMode scd2, a tabela também tem a chave gerada de cada versão e as colunas de vigência.
+func BuildCreateTableQuery(config Config, fields map[string]string) (string, error) {
 	if config.DestinationTable == "" {
 		logz.Error("nome da tabela não informado", map[string]interface{}{})
-		return fmt.Errorf("nome da tabela não informado")
+		return "", fmt.Errorf("nome da tabela não informado")
+	}
+	if len(fields) == 0 {
+		logz.Error("nenhum campo para criar a tabela", map[string]interface{}{})
+		return "", fmt.Errorf("nenhum campo para criar a tabela %s", config.DestinationTable)
+	}
+
+	fieldNames := make([]string, 0, len(fields))
+	for fieldName := range fields {
+		fieldNames = append(fieldNames, fieldName)
 	}
+	sort.Strings(fieldNames)
 
-	var createTableQuery string
-	var fieldsDest = make(map[string]string)
-	createTableQuery = fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (", config.DestinationTable)
-	for fieldName, fieldType := range fields {
+	scd2 := config.LoadMode == "scd2"
+	columns := make([]string, 0, len(fieldNames)+4)
+	if scd2 {
+		columns = append(columns, SurrogateKeyDefinition(config.DestinationType, SCD2Settings(config).SurrogateKey))
+	}
+	for _, fieldName := range fieldNames {
+		fieldType := fields[fieldName]
 		typeName := GetVendorSqlType(
 			config.DestinationType,
 			fieldType,
 		)
 		if typeName == "" {
 			logz.Error(fmt.Sprintf("tipo de campo não mapeado: %s", fieldType), map[string]interface{}{})
-			return fmt.Errorf("tipo de campo não mapeado: %s", fieldType)
+			return "", fmt.Errorf("tipo de campo não mapeado: %s", fieldType)
 		}
-		if config.UpdateKey == fieldName {
-			createTableQuery += fmt.Sprintf("%s %s %s, ", fieldName, typeName, "PRIMARY KEY")
+		if config.UpdateKey == fieldName && !scd2 {
+			columns = append(columns, fmt.Sprintf("%s %s %s", fieldName, typeName, "PRIMARY KEY"))
 		} else {
-			createTableQuery += fmt.Sprintf("%s %s, ", fieldName, typeName)
+			columns = append(columns, fmt.Sprintf("%s %s", fieldName, typeName))
 		}
-		fieldsDest[fieldName] = typeName
 	}
-	createTableQuery = createTableQuery[:len(createTableQuery)-2] + ")"
+	if scd2 {
+		settings := SCD2Settings(config)
+		timestampType := GetVendorSqlType(config.DestinationType, "TIMESTAMP")
+		columns = append(columns,
+			fmt.Sprintf("%s %s", settings.ValidFrom, timestampType),
+			fmt.Sprintf("%s %s", settings.ValidTo, timestampType),
+			fmt.Sprintf("%s %s", settings.CurrentFlag, GetVendorSqlType(config.DestinationType, "BOOLEAN")),
+		)
+	}
 
-	//logz.DebugLog("Campos de destino: "+config.DestinationType+" - "+fmt.Sprintf("%v", fieldsDest), map[string]interface{}{})
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", config.DestinationTable, strings.Join(columns, ", ")), nil
+}
 
-	_, createTableQueryErr := db.Exec(createTableQuery)
-	if createTableQueryErr != nil {
-		logz.Error(fmt.Sprintf("falha ao criar a tabela: %v", createTableQueryErr), map[string]interface{}{})
-		return createTableQueryErr
+// destinationFieldTypes retorna os campos de destino com o tipo de origem de cada um.
+// Sem transformações, os campos de destino são as próprias colunas extraídas; com elas,
+// vale o type da transformação ou, na falta dele, o tipo da coluna de origem.
+func destinationFieldTypes(config Config, sourceTypes map[string]string) (map[string]string, error) {
+	fieldsDest := make(map[string]string)
+	if len(config.Transformations) == 0 {
+		for field, fieldType := range sourceTypes {
+			fieldsDest[field] = fieldType
+		}
+		return fieldsDest, nil
 	}
 
-	return nil
+	for _, t := range config.Transformations {
+		fieldType := t.Type
+		if fieldType == "" {
+			sourceType, ok := sourceTypes[t.SourceField]
+			if !ok {
+				logz.Error("Failed to get field type: "+t.SourceField, map[string]interface{}{})
+				return nil, fmt.Errorf("Failed to get field type: %s", t.SourceField)
+			}
+			fieldType = sourceType
+		}
+		fieldsDest[t.DestinationField] = fieldType
+	}
+
+	return fieldsDest, nil
 }
 func ExtractData(dbSQL *sql.DB, config Config) ([]Data, []string, error) {
+	return ExtractDataContext(context.Background(), dbSQL, config)
+}
+
+// ExtractDataContext é a variante de ExtractData que respeita o cancelamento de ctx
+// e o timeout de extração da Config. Sem dbSQL, é usado o pool compartilhado da origem (OpenSource).
+func ExtractDataContext(ctx context.Context, dbSQL *sql.DB, config Config) ([]Data, []string, error) {
 	if config.SQLQuery == "" {
 		logz.Error("query SQL não informada", map[string]interface{}{})
 		return nil, nil, fmt.Errorf("query SQL não informada")
 	}
 
-	var db *sql.DB
-	var dbErr error
-	if dbSQL == nil {
-		db, dbErr = sql.Open(config.SourceType, config.SourceConnectionString)
+	db := dbSQL
+	if db == nil {
+		var dbErr error
+		db, dbErr = OpenSource(ctx, config)
 		if dbErr != nil {
 			logz.Error(fmt.Sprintf("falha ao conectar ao banco de dados: %v", dbErr), map[string]interface{}{})
 			return nil, nil, dbErr
 		}
-	} else {
-		db = dbSQL
 	}
-	defer func(db *sql.DB) {
-		_ = db.Close()
-	}(db)
 
-	rows, queryErr := db.Query(config.SQLQuery)
-	if queryErr != nil {
-		logz.Error(fmt.Sprintf("falha ao executar a query SQL: %v", queryErr), map[string]interface{}{})
-		return nil, nil, queryErr
+	extractCtx, cancel, timeoutErr := WithStageTimeout(ctx, config.Timeouts.Extract)
+	if timeoutErr != nil {
+		return nil, nil, timeoutErr
 	}
-	defer func(rows *sql.Rows) {
-		_ = rows.Close()
-	}(rows)
+	defer cancel()
 
+	// Um erro transitório refaz a consulta; as linhas lidas na tentativa anterior são descartadas.
 	var data []Data
-	columns, columnsErr := rows.Columns()
-	if columnsErr != nil {
-		logz.Error(fmt.Sprintf("falha ao obter colunas: %v", columnsErr), map[string]interface{}{})
-		return nil, nil, columnsErr
-	}
-
-	for rows.Next() {
-		rowData := make([]interface{}, len(columns))
-		rowPointers := make([]interface{}, len(columns))
-		for i := range rowData {
-			rowPointers[i] = &rowData[i]
+	var columns []string
+	attempts, extractErr := retryTransient(extractCtx, config.Pool, "extrair de "+config.SourceType, func() error {
+		data, columns = nil, nil
+		rows, queryErr := db.QueryContext(extractCtx, config.SQLQuery)
+		if queryErr != nil {
+			logz.Error(fmt.Sprintf("falha ao executar a query SQL: %v", queryErr), map[string]interface{}{})
+			return queryErr
+		}
+		defer func(rows *sql.Rows) {
+			_ = rows.Close()
+		}(rows)
+
+		var columnsErr error
+		columns, columnsErr = rows.Columns()
+		if columnsErr != nil {
+			logz.Error(fmt.Sprintf("falha ao obter colunas: %v", columnsErr), map[string]interface{}{})
+			return columnsErr
 		}
 
-		if scanErr := rows.Scan(rowPointers...); scanErr != nil {
-			logz.Error(fmt.Sprintf("falha ao escanear os dados da linha: %v", scanErr), map[string]interface{}{})
-			return nil, nil, scanErr
+		for rows.Next() {
+			rowData := make([]interface{}, len(columns))
+			rowPointers := make([]interface{}, len(columns))
+			for i := range rowData {
+				rowPointers[i] = &rowData[i]
+			}
+
+			if scanErr := rows.Scan(rowPointers...); scanErr != nil {
+				logz.Error(fmt.Sprintf("falha ao escanear os dados da linha: %v", scanErr), map[string]interface{}{})
+				return scanErr
+			}
+
+			row := make(Data)
+			for i, colName := range columns {
+				row[colName] = rowData[i]
+			}
+			data = append(data, row)
 		}
 
-		row := make(Data)
-		for i, colName := range columns {
-			row[colName] = rowData[i]
+		if rowsErr := rows.Err(); rowsErr != nil {
+			logz.Error(fmt.Sprintf("falha ao ler as linhas: %v", rowsErr), map[string]interface{}{})
+			return rowsErr
 		}
-		data = append(data, row)
+		return nil
+	})
+	if extractErr != nil {
+		if extractCtx.Err() != nil {
+			logz.Warn(fmt.Sprintf("extração interrompida após %d linha(s)", len(data)), map[string]interface{}{})
+			return nil, nil, fmt.Errorf("extração interrompida após %d linha(s): %w", len(data), extractCtx.Err())
+		}
+		return nil, nil, fmt.Errorf("falha na extração após %d tentativa(s): %w", attempts, extractErr)
 	}
 
 	if config.OutputPath != "" {
@@ -408,61 +575,97 @@ func SaveDataToJSON(filePath string, data []Data) error {
 	return nil
 }
 func LoadData(dbSQL *sql.DB, config Config) error {
-	var db *sql.DB
-	var dbErr error
+	return LoadDataContext(context.Background(), dbSQL, config)
+}
 
-	if dbSQL == nil {
-		db, dbErr = sql.Open(config.DestinationType, config.DestinationConnectionString)
+// LoadDataContext é a variante de LoadData que respeita o cancelamento de ctx e os timeouts
+// de extração e carga da Config. A carga é feita em uma única transação: se ctx for cancelado
+// (e.g. SIGINT) ou o timeout expirar no meio da carga, a transação é revertida e o erro informa
+// quantas linhas haviam sido gravadas. Sem dbSQL, é usado o pool compartilhado do destino (OpenDestination).
+func LoadDataContext(ctx context.Context, dbSQL *sql.DB, config Config) error {
+	return loadDataContext(ctx, dbSQL, config, checkpointRun{Pipeline: config.DestinationTable})
+}
+
+// loadDataContext executa a carga. Com Checkpoint.BatchSize, as linhas são confirmadas em lotes e o
+// progresso é registrado para a execução run; um run com Checkpoint retoma a carga após o último lote.
+func loadDataContext(ctx context.Context, dbSQL *sql.DB, config Config, run checkpointRun) error {
+	if config.DestinationType == RedisType {
+		return loadToRedis(ctx, config)
+	}
+	db := dbSQL
+	if db == nil {
+		var dbErr error
+		db, dbErr = OpenDestination(ctx, config)
 		if dbErr != nil {
 			logz.Error("Failed to connect to destination database: "+dbErr.Error(), map[string]interface{}{})
 			return dbErr
 		}
-	} else {
-		db = dbSQL
 	}
-	defer func(db *sql.DB) {
-		_ = db.Close()
-	}(db)
 
-	var fieldsWithType map[string]string
+	var checkpoint *meta.Checkpoint
+	var lastKey interface{}
+	resumed := false
+	if config.Checkpoint.Enabled() {
+		var checkpointErr error
+		checkpoint, checkpointErr = prepareCheckpoint(ctx, db, config, run)
+		if checkpointErr != nil {
+			logz.Error(checkpointErr.Error(), map[string]interface{}{})
+			return checkpointErr
+		}
+		if checkpoint.Status == meta.CheckpointDone {
+			logz.Info(fmt.Sprintf("pipeline %s já concluída na execução %s, nada a retomar", checkpoint.Pipeline, checkpoint.RunID), map[string]interface{}{})
+			return nil
+		}
+		if checkpoint.LastKey != "" {
+			lastKey = CheckpointKeyParam(checkpoint.LastKey)
+		}
+		resumed = checkpoint.Rows > 0
+	}
+
+	// Com a origem redis, as entradas de um stream são confirmadas só depois da carga.
+	var redisSource *etlredis.Source
 	var data []Data
+	var fieldsWithType map[string]string
 	var fieldsErr error
-
-	data, fieldsWithType, fieldsErr = ExtractDataWithTypes(nil, config)
+	if config.SourceType == RedisType {
+		redisSource, data, fieldsWithType, fieldsErr = extractRedis(ctx, config)
+	} else {
+		data, fieldsWithType, fieldsErr = extractDataWithTypes(ctx, nil, config, lastKey)
+	}
 	if fieldsErr != nil {
 		logz.Error("Failed to extract data: "+fieldsErr.Error(), map[string]interface{}{})
 		return fieldsErr
 	}
+	if redisSource != nil {
+		defer func(source *etlredis.Source) {
+			_ = source.Close()
+		}(redisSource)
+	}
 
-	var fieldsDest map[string]string
-	var fieldsList []string
-	if config.Transformations != nil {
-		for _, t := range config.Transformations {
-			if t.Type == "" {
-				if fieldType, ok := fieldsWithType[t.SourceField]; ok {
-					t.Type = fieldType
-				} else {
-					logz.Error("Failed to get field type: "+t.SourceField, map[string]interface{}{})
-					return fmt.Errorf("Failed to get field type: %s", t.SourceField)
-				}
-			} else {
-				fieldsWithType[t.SourceField] = t.Type
-			}
-			fieldsDest[t.DestinationField] = t.Type
-			fieldsList = append(fieldsList, t.DestinationField)
-		}
+	fieldsDest, fieldsDestErr := destinationFieldTypes(config, fieldsWithType)
+	if fieldsDestErr != nil {
+		return fieldsDestErr
 	}
 
-	fieldsDest = fieldsWithType
-	fieldsList = make([]string, 0, len(fieldsDest))
-	for field := range fieldsDest {
-		fieldsList = append(fieldsList, field)
+	loadCtx, cancel, timeoutErr := WithStageTimeout(ctx, config.Timeouts.Load)
+	if timeoutErr != nil {
+		return timeoutErr
 	}
+	defer cancel()
 
-	if ensureTableExistsWithTypesErr := EnsureTableExistsWithTypes(db, config, fieldsDest); ensureTableExistsWithTypesErr != nil {
+	_, ensureTableExistsWithTypesErr := retryTransient(loadCtx, config.Pool, "criar a tabela "+config.DestinationTable, func() error {
+		return EnsureTableExistsWithTypesContext(loadCtx, db, config, fieldsDest)
+	})
+	if ensureTableExistsWithTypesErr != nil {
 		logz.Error("Failed to ensure table exists: "+ensureTableExistsWithTypesErr.Error(), map[string]interface{}{})
 		return ensureTableExistsWithTypesErr
 	}
+	if len(config.Triggers) > 0 {
+		if _, applyTriggersErr := ApplyTriggers(loadCtx, db, config, false); applyTriggersErr != nil {
+			logz.Error("falha ao aplicar os triggers: "+applyTriggersErr.Error(), map[string]interface{}{})
+			return applyTriggersErr
+		}
+	}
 
 	transformedData, transformedDataErr := ApplyTransformations(data, config.Transformations)
 	if transformedDataErr != nil {
@@ -477,55 +680,58 @@ func LoadData(dbSQL *sql.DB, config Config) error {
 		}
 	}
 
-	tx, txErr := db.Begin()
-	if txErr != nil {
-		logz.Error(fmt.Sprintf("Failed to start transaction: %v", txErr), map[string]interface{}{})
-		return fmt.Errorf("Failed to start transaction: %w", txErr)
-	}
-	var insertQuery string
-	for _, row := range transformedData {
-		var columns, values, conlictFallback strings.Builder
-		columns.WriteString(fmt.Sprintf("INSERT INTO %s (", config.DestinationTable))
-		values.WriteString("VALUES (")
-		i := 0
-		for col, val := range row {
-			if i > 0 {
-				columns.WriteString(", ")
-				values.WriteString(", ")
-			}
-			columns.WriteString(col)
-			values.WriteString(formatValue(val))
-			if config.UpdateKey != "" {
-				if i > 0 {
-					conlictFallback.WriteString(", ")
-				}
-				conlictFallback.WriteString(fmt.Sprintf("%s = %s", col, formatValue(val)))
-			}
-			i++
+	if config.Deletes.Soft() {
+		if columnErr := ensureDeleteColumn(loadCtx, db, config); columnErr != nil {
+			logz.Error(columnErr.Error(), map[string]interface{}{})
+			return columnErr
 		}
+		// As linhas presentes na origem deixam de estar marcadas, inclusive as que voltaram a ela.
+		for _, row := range transformedData {
+			row[DeleteColumn(config)] = ActiveValue(config)
+		}
+	}
+	if config.LoadMode == "scd2" {
+		if scd2Err := loadSCD2(loadCtx, db, config, transformedData); scd2Err != nil {
+			return scd2Err
+		}
+		return ackRedis(loadCtx, redisSource)
+	}
 
-		// Por hora vou checar só o primeiro campo. Depois implemento o resto da lógica
-		var checkQuery strings.Builder
-		if config.UpdateKey != "" {
-			checkQuery.WriteString(fmt.Sprintf(") ON CONFLICT (%s) DO UPDATE SET %s", config.UpdateKey, conlictFallback.String()))
+	var deletedKeys []interface{}
+	if config.Deletes.Detect == "keys" {
+		if resumed {
+			// As linhas confirmadas antes da interrupção não foram extraídas de novo.
+			logz.Warn(fmt.Sprintf("exclusões não propagadas para %s ao retomar a carga; serão na próxima execução completa", config.DestinationTable), map[string]interface{}{})
 		} else {
-			values.WriteString(")")
-			values.WriteString(";")
+			var detectErr error
+			if deletedKeys, detectErr = detectDeletedKeys(loadCtx, db, config, transformedData); detectErr != nil {
+				logz.Error(detectErr.Error(), map[string]interface{}{})
+				return detectErr
+			}
 		}
-		columns.WriteString(") ")
-		insertQuery = columns.String() + values.String()
-		if conlictFallback.Len() > 0 {
-			insertQuery += checkQuery.String() + ";"
-		} else {
-			insertQuery += ";"
+	}
+
+	if checkpoint != nil {
+		if batchesErr := loadBatches(loadCtx, db, config, checkpoint, data, transformedData); batchesErr != nil {
+			return batchesErr
 		}
-		_, err := db.Exec(insertQuery)
-		if err != nil {
-			_ = tx.Rollback()
-			//logz.DebugLog(fmt.Sprintf("Failed to execute insert query: %v", insertQuery), map[string]interface{}{})
-			logz.Error("Failed to execute insert query: "+err.Error(), map[string]interface{}{})
-			return fmt.Errorf("Failed to execute insert query: %w", err)
+		return applyDeletedKeys(loadCtx, db, config, deletedKeys)
+	}
+
+	// Um erro transitório antes do commit reverte a transação e a carga é refeita desde o início.
+	var tx *sql.Tx
+	var loaded int
+	attempts, writeErr := retryTransient(loadCtx, config.Pool, "carregar "+config.DestinationTable, func() error {
+		var err error
+		tx, loaded, err = writeRows(loadCtx, db, config, transformedData, deletedKeys)
+		return err
+	})
+	if writeErr != nil {
+		if ctxErr := loadCtx.Err(); ctxErr != nil {
+			return rollbackInterruptedLoad(nil, loaded, len(transformedData), ctxErr)
 		}
+		logz.Error(writeErr.Error(), map[string]interface{}{})
+		return fmt.Errorf("falha na carga após %d tentativa(s): %w", attempts, writeErr)
 	}
 
 	if commitErr := tx.Commit(); commitErr != nil {
@@ -534,43 +740,228 @@ func LoadData(dbSQL *sql.DB, config Config) error {
 		return fmt.Errorf("Failed to commit transaction: %w", commitErr)
 	}
 
-	logz.Info("Dados carregados no banco de destino com sucesso", map[string]interface{}{})
+	logz.Info(fmt.Sprintf("%d linha(s) carregada(s) no banco de destino com sucesso", len(transformedData)), map[string]interface{}{})
+	logPropagatedDeletes(config, len(deletedKeys))
 
-	return nil
+	return ackRedis(loadCtx, redisSource)
+}
+
+// writeRows abre uma transação e grava as linhas e as exclusões propagadas, sem confirmá-la. Em caso de
+// erro, a transação é revertida e loaded informa quantas linhas haviam sido gravadas.
+func writeRows(ctx context.Context, db *sql.DB, config Config, rows []Data, deletedKeys []interface{}) (tx *sql.Tx, loaded int, err error) {
+	tx, txErr := db.BeginTx(ctx, nil)
+	if txErr != nil {
+		return nil, 0, fmt.Errorf("Failed to start transaction: %w", txErr)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			tx = nil
+		}
+	}()
+
+	for i, row := range rows {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return tx, i, ctxErr
+		}
+		if insertErr := insertRow(ctx, tx, config, row); insertErr != nil {
+			return tx, i, fmt.Errorf("Failed to execute insert query: %w", insertErr)
+		}
+	}
+	if deleteErr := propagateDeletes(ctx, tx, config, deletedKeys); deleteErr != nil {
+		return tx, len(rows), deleteErr
+	}
+	return tx, len(rows), nil
+}
+
+// rollbackInterruptedLoad reverte a transação de uma carga cancelada e informa o progresso. Com tx nil,
+// a transação já foi revertida.
+func rollbackInterruptedLoad(tx *sql.Tx, loaded, total int, cause error) error {
+	if tx != nil {
+		rollbackErr := tx.Rollback()
+		if rollbackErr != nil && !errors.Is(rollbackErr, sql.ErrTxDone) {
+			logz.Error("falha ao reverter a transação: "+rollbackErr.Error(), map[string]interface{}{})
+		}
+	}
+	logz.Warn(fmt.Sprintf("carga interrompida após %d de %d linha(s); transação revertida, nada foi gravado", loaded, total), map[string]interface{}{})
+	return fmt.Errorf("carga interrompida após %d de %d linha(s), transação revertida: %w", loaded, total, cause)
+}
+
+// insertRow grava uma linha na transação, com os valores como argumentos do INSERT. Com UpdateKey, a
+// linha existente com a mesma chave é atualizada: no PostgreSQL, no SQLite e no MySQL pelo próprio
+// INSERT; no Oracle e no SQL Server, por um UPDATE pela chave, seguido do INSERT se nenhuma linha
+// foi encontrada.
+func insertRow(ctx context.Context, tx *sql.Tx, config Config, row Data) error {
+	columns := slices.Sorted(maps.Keys(row))
+	values := make([]interface{}, len(columns))
+	for i, column := range columns {
+		value, valueErr := insertValue(row[column])
+		if valueErr != nil {
+			return fmt.Errorf("coluna %s: %w", column, valueErr)
+		}
+		values[i] = value
+	}
+	format := PlaceholderFormatFor(config.DestinationType)
+
+	dialect := TriggerDialect(config.DestinationType)
+	if config.UpdateKey != "" && (dialect == "oracle" || dialect == "sqlserver") {
+		update := sqrl.Update(config.DestinationTable).Where(sqrl.Eq{config.UpdateKey: row[config.UpdateKey]})
+		for i, column := range columns {
+			if column != config.UpdateKey {
+				update = update.Set(column, values[i])
+			}
+		}
+		if len(columns) == 1 {
+			update = update.Set(config.UpdateKey, row[config.UpdateKey])
+		}
+		updateQuery, updateArgs, updateQueryErr := update.PlaceholderFormat(format).ToSql()
+		if updateQueryErr != nil {
+			return updateQueryErr
+		}
+		result, updateErr := tx.ExecContext(ctx, updateQuery, updateArgs...)
+		if updateErr != nil {
+			return updateErr
+		}
+		if affected, _ := result.RowsAffected(); affected > 0 {
+			return nil
+		}
+	}
+
+	insert := sqrl.Insert(config.DestinationTable).Columns(columns...).Values(values...)
+	if clause := upsertClause(config, columns); clause != "" {
+		insert = insert.Suffix(clause)
+	}
+	insertQuery, insertArgs, insertQueryErr := insert.PlaceholderFormat(format).ToSql()
+	if insertQueryErr != nil {
+		return insertQueryErr
+	}
+	_, insertErr := tx.ExecContext(ctx, insertQuery, insertArgs...)
+	return insertErr
+}
+
+// upsertClause retorna a cláusula do INSERT que atualiza a linha existente com a mesma UpdateKey no
+// PostgreSQL, no SQLite e no MySQL; sem UpdateKey ou nos demais bancos, "".
+func upsertClause(config Config, columns []string) string {
+	if config.UpdateKey == "" {
+		return ""
+	}
+	var assignments []string
+	switch TriggerDialect(config.DestinationType) {
+	case "postgres", "sqlite":
+		for _, column := range columns {
+			if column != config.UpdateKey {
+				assignments = append(assignments, fmt.Sprintf("%s = EXCLUDED.%s", column, column))
+			}
+		}
+		if len(assignments) == 0 {
+			return fmt.Sprintf("ON CONFLICT (%s) DO NOTHING", config.UpdateKey)
+		}
+		return fmt.Sprintf("ON CONFLICT (%s) DO UPDATE SET %s", config.UpdateKey, strings.Join(assignments, ", "))
+	case "mysql":
+		for _, column := range columns {
+			if column != config.UpdateKey {
+				assignments = append(assignments, fmt.Sprintf("%s = VALUES(%s)", column, column))
+			}
+		}
+		if len(assignments) == 0 {
+			assignments = append(assignments, fmt.Sprintf("%s = %s", config.UpdateKey, config.UpdateKey))
+		}
+		return "ON DUPLICATE KEY UPDATE " + strings.Join(assignments, ", ")
+	}
+	return ""
+}
+
+// insertValue converte o valor de uma coluna no argumento do INSERT. Mapas e listas, que vêm de
+// mensagens e imagens JSON, são gravados como JSON; os demais valores vão ao driver como estão.
+func insertValue(value interface{}) (interface{}, error) {
+	if _, isBytes := value.([]byte); isBytes || value == nil {
+		return value, nil
+	}
+	if kind := reflect.ValueOf(value).Kind(); kind == reflect.Map || kind == reflect.Slice {
+		encoded, encodeErr := json.Marshal(value)
+		if encodeErr != nil {
+			return nil, fmt.Errorf("falha ao codificar o valor em JSON: %w", encodeErr)
+		}
+		return string(encoded), nil
+	}
+	return value, nil
 }
 func ExecuteETL(configPath, outputPath, outputFormat string, needCheck bool, checkMethod string) error {
+	return ExecuteETLPipelinesContext(context.Background(), configPath, nil, outputPath, outputFormat, needCheck, checkMethod)
+}
+
+// ExecuteETLPipelines executa as pipelines do arquivo de configuração, na ordem do arquivo.
+// Sem nomes informados, todas as pipelines são executadas.
+func ExecuteETLPipelines(configPath string, pipelineNames []string, outputPath, outputFormat string, needCheck bool, checkMethod string) error {
+	return ExecuteETLPipelinesContext(context.Background(), configPath, pipelineNames, outputPath, outputFormat, needCheck, checkMethod)
+}
+
+// ExecuteETLPipelinesContext é a variante de ExecuteETLPipelines que respeita o cancelamento de ctx.
+// Quando ctx é cancelado, a pipeline em andamento é revertida e as seguintes não são iniciadas.
+func ExecuteETLPipelinesContext(ctx context.Context, configPath string, pipelineNames []string, outputPath, outputFormat string, needCheck bool, checkMethod string) error {
+	return executeETLRun(ctx, meta.NewRunID(), false, configPath, pipelineNames, outputPath, outputFormat, needCheck, checkMethod)
+}
+
+// executeETLRun executa as pipelines selecionadas como a execução runID. Com resume, as pipelines
+// com checkpoint continuam após o último lote confirmado naquela execução.
+func executeETLRun(ctx context.Context, runID string, resume bool, configPath string, pipelineNames []string, outputPath, outputFormat string, needCheck bool, checkMethod string) error {
 	logz.Info("Iniciando o processo de GETl", map[string]interface{}{})
 
 	// Carregar a configuração
-	config, loadConfigErr := LoadConfigFile(configPath)
+	pipelines, loadConfigErr := LoadPipelinesFile(configPath)
 	if loadConfigErr != nil {
 		logz.Error(fmt.Sprintf("falha ao carregar a configuração: %v", loadConfigErr), map[string]interface{}{})
 		return loadConfigErr
 	}
 
-	// Carregar os dados no banco de destino
-	if outputPath != "" {
-		config.OutputPath = outputPath
-	}
-	if outputFormat != "" {
-		config.OutputFormat = outputFormat
+	selected, selectErr := SelectPipelines(pipelines, pipelineNames)
+	if selectErr != nil {
+		logz.Error(fmt.Sprintf("falha ao selecionar as pipelines: %v", selectErr), map[string]interface{}{})
+		return selectErr
 	}
 
-	if needCheck {
-		config.NeedCheck = needCheck
-		if checkMethod != "" {
-			config.CheckMethod = checkMethod
-		} else {
-			logz.Error("método de verificação não informado", map[string]interface{}{})
-			return fmt.Errorf("método de verificação não informado")
+	checkpoints := map[string]*meta.Checkpoint{}
+	if resume {
+		var checkpointsErr error
+		checkpoints, checkpointsErr = loadRunCheckpoints(ctx, runID, selected)
+		if checkpointsErr != nil {
+			logz.Error(checkpointsErr.Error(), map[string]interface{}{})
+			return checkpointsErr
 		}
 	}
 
-	// Extrair os dados, transformar e carregar no destino
-	loadDataErr := LoadData(nil, config)
-	if loadDataErr != nil {
-		logz.Error(fmt.Sprintf("falha ao carregar os dados no destino: %v", loadDataErr), map[string]interface{}{})
-		return loadConfigErr
+	for i, pipeline := range selected {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			logz.Warn(fmt.Sprintf("processo interrompido: %d de %d pipeline(s) concluída(s)", i, len(selected)), map[string]interface{}{})
+			return fmt.Errorf("processo interrompido antes da pipeline %s: %w", pipeline.Name, ctxErr)
+		}
+		logz.Info(fmt.Sprintf("Executando a pipeline %s (%d de %d)", pipeline.Name, i+1, len(selected)), map[string]interface{}{})
+
+		config := pipeline.Config
+		if outputPath != "" {
+			config.OutputPath = outputPath
+		}
+		if outputFormat != "" {
+			config.OutputFormat = outputFormat
+		}
+
+		if needCheck {
+			config.NeedCheck = needCheck
+			if checkMethod != "" {
+				config.CheckMethod = checkMethod
+			} else {
+				logz.Error("método de verificação não informado", map[string]interface{}{})
+				return fmt.Errorf("método de verificação não informado")
+			}
+		}
+
+		// Extrair os dados, transformar e carregar no destino
+		run := checkpointRun{RunID: runID, Pipeline: pipeline.Name, Checkpoint: checkpoints[pipeline.Name]}
+		loadDataErr := loadDataContext(ctx, nil, config, run)
+		if loadDataErr != nil {
+			logz.Error(fmt.Sprintf("falha ao carregar os dados no destino: %v", loadDataErr), map[string]interface{}{})
+			return fmt.Errorf("falha ao executar a pipeline %s: %w", pipeline.Name, loadDataErr)
+		}
 	}
 
 	logz.Info("Processo de GETl finalizado com sucesso", map[string]interface{}{})
@@ -616,27 +1007,3 @@ func ExecuteETLJobs() error {
 
 	return nil
 }
-func formatValue(val interface{}) string {
-	if val == nil {
-		return "NULL"
-	}
-	switch v := val.(type) {
-	case string:
-		return fmt.Sprintf("'%s'", v)
-	case int, int8, int16, int32, int64:
-		return fmt.Sprintf("%d", v)
-	case uint, uint8, uint16, uint32, uint64:
-		return fmt.Sprintf("%d", v)
-	case float32, float64:
-		return fmt.Sprintf("%f", v)
-	case bool:
-		if v {
-			return "TRUE"
-		}
-		return "FALSE"
-	case time.Time:
-		return fmt.Sprintf("'%s'", v.Format("2006-01-02 15:04:05"))
-	default:
-	}
-	return fmt.Sprintf("'%v'", val)
-}
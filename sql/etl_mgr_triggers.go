@@ -2,71 +2,288 @@ package sql
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"fmt"
 	. "github.com/faelmori/getl/etypes"
+	. "github.com/faelmori/getl/utils"
+	"github.com/faelmori/logz"
+	"io"
+	"strings"
+	"text/tabwriter"
 	"text/template"
 )
 
+// triggerData é o que os templates de trigger recebem: o Trigger com Table resolvida e o evento
+// já interpretado, com as operações unidas como o dialeto espera.
+type triggerData struct {
+	Trigger
+	Timing     string
+	Operations string
+}
+
+// TriggerStatus indica se um trigger configurado existe no banco de destino.
+type TriggerStatus struct {
+	Pipeline  string
+	Trigger   Trigger
+	Installed bool
+}
+
 // createTrigger cria um trigger no banco de dados especificado.
 // db: a conexão com o banco de dados.
-// dbType: o tipo de banco de dados (e.g., sqlite, postgres, mysql).
+// dbType: o tipo de banco de dados (e.g., sqlite3, postgres, mysql, godror, sqlserver).
 // trigger: a estrutura do trigger a ser criado.
 // Retorna um erro, se houver.
-func createTrigger(db *sql.DB, dbType string, trigger Trigger) error {
-	tmpl, ok := triggerTemplates[dbType]
-	if !ok {
-		return fmt.Errorf("template de trigger não encontrado para o banco de dados: %s", dbType)
+func createTrigger(ctx context.Context, db *sql.DB, dbType string, trigger Trigger) error {
+	statements, err := BuildTriggerStatements(dbType, trigger)
+	if err != nil {
+		return err
 	}
+	for _, statement := range statements {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("falha ao criar trigger %s: %w", trigger.Name, err)
+		}
+	}
+	return nil
+}
 
-	t, err := template.New("trigger").Parse(tmpl)
+// dropTrigger remove o trigger, se ele existir.
+func dropTrigger(ctx context.Context, db *sql.DB, dbType string, trigger Trigger) error {
+	statements, err := renderTriggerTemplates(dropTriggerTemplates, dbType, trigger)
 	if err != nil {
-		return fmt.Errorf("falha ao analisar template de trigger: %w", err)
+		return err
+	}
+	for _, statement := range statements {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("falha ao remover trigger %s: %w", trigger.Name, err)
+		}
 	}
+	return nil
+}
 
-	var query bytes.Buffer
-	if err := t.Execute(&query, trigger); err != nil {
-		return fmt.Errorf("falha ao executar template de trigger: %w", err)
+// BuildTriggerStatements gera os comandos que criam o trigger no dialeto do driver, na ordem de execução.
+func BuildTriggerStatements(dbType string, trigger Trigger) ([]string, error) {
+	return renderTriggerTemplates(triggerTemplates, dbType, trigger)
+}
+
+// renderTriggerTemplates valida o trigger e executa os templates do dialeto do driver.
+func renderTriggerTemplates(templates map[string][]string, dbType string, trigger Trigger) ([]string, error) {
+	dialect := TriggerDialect(dbType)
+	tmpls, ok := templates[dialect]
+	if !ok {
+		return nil, fmt.Errorf("template de trigger não encontrado para o banco de dados: %s", dbType)
+	}
+	if trigger.Name == "" || trigger.Table == "" {
+		return nil, fmt.Errorf("trigger sem nome ou tabela: %+v", trigger)
 	}
 
-	_, err = db.Exec(query.String())
-	if err != nil {
-		return fmt.Errorf("falha ao criar trigger: %w", err)
+	event, eventErr := ValidateTriggerEvent(dbType, trigger.Event)
+	if eventErr != nil {
+		return nil, fmt.Errorf("trigger %s: %w", trigger.Name, eventErr)
+	}
+	separator := " OR "
+	if dialect == "sqlserver" {
+		separator = ", "
 	}
+	data := triggerData{Trigger: trigger, Timing: event.Timing, Operations: strings.Join(event.Operations, separator)}
+	data.Statement = strings.TrimRight(strings.TrimSpace(trigger.Statement), ";")
 
-	return nil
+	statements := make([]string, 0, len(tmpls))
+	for _, tmpl := range tmpls {
+		t, err := template.New("trigger").Parse(tmpl)
+		if err != nil {
+			return nil, fmt.Errorf("falha ao analisar template de trigger: %w", err)
+		}
+		var query bytes.Buffer
+		if err := t.Execute(&query, data); err != nil {
+			return nil, fmt.Errorf("falha ao executar template de trigger: %w", err)
+		}
+		statements = append(statements, strings.TrimSpace(query.String()))
+	}
+	return statements, nil
+}
+
+// configTriggers retorna os triggers da Config com Table resolvida para a tabela de destino.
+func configTriggers(config Config) []Trigger {
+	triggers := make([]Trigger, 0, len(config.Triggers))
+	for _, trigger := range config.Triggers {
+		if trigger.Table == "" {
+			trigger.Table = config.DestinationTable
+		}
+		triggers = append(triggers, trigger)
+	}
+	return triggers
+}
+
+// ApplyTriggers cria no destino os triggers da Config que ainda não existem. Com replace, os
+// existentes são removidos e recriados, para aplicar mudanças de evento ou statement.
+// Retorna quantos triggers foram criados.
+func ApplyTriggers(ctx context.Context, db *sql.DB, config Config, replace bool) (int, error) {
+	installed, listErr := installedTriggers(ctx, db, config.DestinationType)
+	if listErr != nil {
+		return 0, listErr
+	}
+
+	applied := 0
+	for _, trigger := range configTriggers(config) {
+		if installed[strings.ToLower(trigger.Name)] {
+			if !replace {
+				continue
+			}
+			if dropErr := dropTrigger(ctx, db, config.DestinationType, trigger); dropErr != nil {
+				return applied, dropErr
+			}
+		}
+		if createErr := createTrigger(ctx, db, config.DestinationType, trigger); createErr != nil {
+			return applied, createErr
+		}
+		logz.Info(fmt.Sprintf("trigger %s aplicado em %s (%s)", trigger.Name, trigger.Table, trigger.Event), map[string]interface{}{})
+		applied++
+	}
+	return applied, nil
+}
+
+// DropTriggers remove do destino os triggers da Config que existem. Retorna quantos foram removidos.
+func DropTriggers(ctx context.Context, db *sql.DB, config Config) (int, error) {
+	installed, listErr := installedTriggers(ctx, db, config.DestinationType)
+	if listErr != nil {
+		return 0, listErr
+	}
+
+	dropped := 0
+	for _, trigger := range configTriggers(config) {
+		if !installed[strings.ToLower(trigger.Name)] {
+			continue
+		}
+		if dropErr := dropTrigger(ctx, db, config.DestinationType, trigger); dropErr != nil {
+			return dropped, dropErr
+		}
+		logz.Info(fmt.Sprintf("trigger %s removido de %s", trigger.Name, trigger.Table), map[string]interface{}{})
+		dropped++
+	}
+	return dropped, nil
+}
+
+// TriggerStatuses informa, para cada trigger da Config, se ele existe no destino.
+func TriggerStatuses(ctx context.Context, db *sql.DB, pipeline string, config Config) ([]TriggerStatus, error) {
+	installed, listErr := installedTriggers(ctx, db, config.DestinationType)
+	if listErr != nil {
+		return nil, listErr
+	}
+
+	var statuses []TriggerStatus
+	for _, trigger := range configTriggers(config) {
+		statuses = append(statuses, TriggerStatus{Pipeline: pipeline, Trigger: trigger, Installed: installed[strings.ToLower(trigger.Name)]})
+	}
+	return statuses, nil
+}
+
+// PrintTriggerStatuses escreve os triggers configurados e se existem no destino.
+func PrintTriggerStatuses(w io.Writer, statuses []TriggerStatus) error {
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(table, "PIPELINE\tTRIGGER\tTABELA\tEVENTO\tSTATUS")
+	for _, status := range statuses {
+		state := "ausente"
+		if status.Installed {
+			state = "aplicado"
+		}
+		_, _ = fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\n", status.Pipeline, status.Trigger.Name, status.Trigger.Table, status.Trigger.Event, state)
+	}
+	return table.Flush()
+}
+
+// installedTriggers retorna os nomes, em minúsculas, dos triggers que existem no banco.
+func installedTriggers(ctx context.Context, db *sql.DB, dbType string) (map[string]bool, error) {
+	query, ok := listTriggersQueries[TriggerDialect(dbType)]
+	if !ok {
+		return nil, fmt.Errorf("consulta de triggers não encontrada para o banco de dados: %s", dbType)
+	}
+
+	rows, rowsErr := db.QueryContext(ctx, query)
+	if rowsErr != nil {
+		return nil, fmt.Errorf("falha ao listar os triggers: %w", rowsErr)
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	installed := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if scanErr := rows.Scan(&name); scanErr != nil {
+			return nil, fmt.Errorf("falha ao listar os triggers: %w", scanErr)
+		}
+		installed[strings.ToLower(name)] = true
+	}
+	return installed, rows.Err()
 }
 
 // triggerTemplates contém os templates de triggers para diferentes tipos de bancos de dados.
-var triggerTemplates = map[string]string{
-	"sqlite": `
+// Cada comando da lista é executado separadamente, na ordem.
+var triggerTemplates = map[string][]string{
+	"sqlite": {`
         CREATE TRIGGER IF NOT EXISTS {{.Name}}
-        {{.Event}} ON {{.Table}}
+        {{.Timing}} {{.Operations}} ON {{.Table}}
         FOR EACH ROW
         BEGIN
-            {{.Statement}}
-        END;
-    `,
-	"postgres": `
+            {{.Statement}};
+        END
+    `},
+	"postgres": {`
         CREATE OR REPLACE FUNCTION {{.Name}}_func() RETURNS TRIGGER AS $$
         BEGIN
             {{.Statement}};
-            RETURN NEW;
+            RETURN COALESCE(NEW, OLD);
         END;
-        $$ LANGUAGE plpgsql;
-
+        $$ LANGUAGE plpgsql
+    `, `
         CREATE TRIGGER {{.Name}}
-        {{.Event}} ON {{.Table}}
+        {{.Timing}} {{.Operations}} ON {{.Table}}
         FOR EACH ROW
-        EXECUTE FUNCTION {{.Name}}_func();
-    `,
-	"mysql": `
+        EXECUTE FUNCTION {{.Name}}_func()
+    `},
+	"mysql": {`
         CREATE TRIGGER {{.Name}}
-        {{.Event}} ON {{.Table}}
+        {{.Timing}} {{.Operations}} ON {{.Table}}
+        FOR EACH ROW
+        BEGIN
+            {{.Statement}};
+        END
+    `},
+	"oracle": {`
+        CREATE OR REPLACE TRIGGER {{.Name}}
+        {{.Timing}} {{.Operations}} ON {{.Table}}
         FOR EACH ROW
         BEGIN
             {{.Statement}};
         END;
-    `,
-	// Adicione outros templates conforme necessário
+    `},
+	// O SQL Server não tem triggers por linha: o statement usa as tabelas inserted e deleted.
+	"sqlserver": {`
+        CREATE OR ALTER TRIGGER {{.Name}} ON {{.Table}}
+        {{.Timing}} {{.Operations}}
+        AS
+        BEGIN
+            SET NOCOUNT ON;
+            {{.Statement}};
+        END
+    `},
+}
+
+// dropTriggerTemplates removem o trigger sem falhar quando ele não existe.
+var dropTriggerTemplates = map[string][]string{
+	"sqlite":    {`DROP TRIGGER IF EXISTS {{.Name}}`},
+	"postgres":  {`DROP TRIGGER IF EXISTS {{.Name}} ON {{.Table}}`, `DROP FUNCTION IF EXISTS {{.Name}}_func()`},
+	"mysql":     {`DROP TRIGGER IF EXISTS {{.Name}}`},
+	"oracle":    {`BEGIN EXECUTE IMMEDIATE 'DROP TRIGGER {{.Name}}'; EXCEPTION WHEN OTHERS THEN IF SQLCODE != -4080 THEN RAISE; END IF; END;`},
+	"sqlserver": {`DROP TRIGGER IF EXISTS {{.Name}}`},
+}
+
+// listTriggersQueries listam os nomes dos triggers do esquema corrente.
+var listTriggersQueries = map[string]string{
+	"sqlite":    `SELECT name FROM sqlite_master WHERE type = 'trigger'`,
+	"postgres":  `SELECT tgname FROM pg_trigger WHERE NOT tgisinternal`,
+	"mysql":     `SELECT TRIGGER_NAME FROM information_schema.TRIGGERS WHERE TRIGGER_SCHEMA = DATABASE()`,
+	"oracle":    `SELECT TRIGGER_NAME FROM USER_TRIGGERS`,
+	"sqlserver": `SELECT name FROM sys.triggers WHERE parent_class = 1`,
 }
@@ -1,6 +1,7 @@
 package etl
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"github.com/faelmori/getl/meta"
@@ -22,12 +23,23 @@ func NewSyncService(db *sql.DB, interval time.Duration, tableName string) *SyncS
 }
 
 func (s *SyncService) Start() {
+	_ = s.StartContext(context.Background())
+}
+
+// StartContext verifica as alterações a cada intervalo até ctx ser cancelado.
+// A verificação em andamento é concluída antes de retornar; o cancelamento não é tratado como erro.
+func (s *SyncService) StartContext(ctx context.Context) error {
 	ticker := time.NewTicker(s.interval)
 	defer ticker.Stop()
 
+	checks := 0
 	for {
 		select {
+		case <-ctx.Done():
+			fmt.Printf("Sincronização encerrada após %d verificação(ões).\n", checks)
+			return nil
 		case <-ticker.C:
+			checks++
 			changed, err := meta.CheckAndUpdateHashes(s.db, s.tableName)
 			if err != nil {
 				fmt.Printf("Erro ao verificar e atualizar hashes: %v\n", err)
@@ -0,0 +1,329 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	. "github.com/faelmori/getl/etypes"
+	"github.com/hamba/avro/v2"
+	"math"
+	"math/big"
+	"reflect"
+	"time"
+)
+
+// avroCodec é o RowCodec dos schemas Avro, serializados pelo hamba/avro: o valor é o registro no
+// encoding binário, sem o cabeçalho dos arquivos de dados.
+type avroCodec struct {
+	schema string
+	root   *avro.RecordSchema
+}
+
+// NewAvroCodec cria um schema Avro de um registro name, no namespace getl, com um campo anulável
+// por coluna. Os horários são timestamp-millis e as datas, date.
+func NewAvroCodec(name string, fields []SchemaField) (RowCodec, error) {
+	type schemaField struct {
+		Name    string        `json:"name"`
+		Type    []interface{} `json:"type"`
+		Default interface{}   `json:"default"`
+	}
+	record := struct {
+		Type      string        `json:"type"`
+		Name      string        `json:"name"`
+		Namespace string        `json:"namespace"`
+		Fields    []schemaField `json:"fields"`
+	}{Type: "record", Name: name, Namespace: "getl", Fields: make([]schemaField, 0, len(fields))}
+	for _, field := range fields {
+		var fieldType interface{} = field.Kind
+		switch field.Kind {
+		case TimestampField:
+			fieldType = map[string]string{"type": "long", "logicalType": "timestamp-millis"}
+		case DateField:
+			fieldType = map[string]string{"type": "int", "logicalType": "date"}
+		}
+		record.Fields = append(record.Fields, schemaField{Name: field.Name, Type: []interface{}{"null", fieldType}})
+	}
+	schema, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	return ParseAvroCodec(string(schema))
+}
+
+// ParseAvroCodec cria o codec de um schema Avro cujo tipo principal é um registro. A decodificação
+// aceita todos os tipos do Avro; os registros aninhados e os mapas são Data, os arrays são
+// []interface{}, os decimal são texto e as datas e timestamps, time.Time em UTC.
+func ParseAvroCodec(schema string) (RowCodec, error) {
+	// Um cache por schema: versões diferentes de um subject definem os mesmos nomes.
+	parsed, err := avro.ParseWithCache(schema, "", &avro.SchemaCache{})
+	if err != nil {
+		return nil, fmt.Errorf("schema Avro inválido: %w", err)
+	}
+	root, ok := parsed.(*avro.RecordSchema)
+	if !ok {
+		return nil, fmt.Errorf("o schema Avro das mensagens deve ser um record, não %s", parsed.Type())
+	}
+	return &avroCodec{schema: schema, root: root}, nil
+}
+
+func (c *avroCodec) SchemaType() string { return AvroSchemaType }
+func (c *avroCodec) Schema() string     { return c.schema }
+
+func (c *avroCodec) Encode(row Data) ([]byte, error) {
+	record, err := avroFieldValue(c.root, row)
+	if err != nil {
+		return nil, err
+	}
+	return avro.Marshal(c.root, record)
+}
+
+func (c *avroCodec) Decode(payload []byte) (Data, error) {
+	var record interface{}
+	if err := avro.Unmarshal(c.root, payload, &record); err != nil {
+		return nil, fmt.Errorf("mensagem Avro inválida: %w", err)
+	}
+	row, ok := avroRowValue(c.root, record).(Data)
+	if !ok {
+		return nil, fmt.Errorf("mensagem Avro vazia")
+	}
+	return row, nil
+}
+
+// avroFieldValue converte o valor de uma coluna para o tipo Go que o hamba/avro serializa no tipo
+// schema. Os registros e os mapas aceitam Data; as uniões usam o primeiro tipo não nulo.
+func avroFieldValue(schema avro.Schema, value interface{}) (interface{}, error) {
+	if ref, ok := schema.(*avro.RefSchema); ok {
+		schema = ref.Schema()
+	}
+	logical := ""
+	if typed := avroLogical(schema); typed != nil {
+		logical = string(typed.Type())
+	}
+
+	switch s := schema.(type) {
+	case *avro.UnionSchema:
+		if value == nil {
+			return nil, nil
+		}
+		for _, branch := range s.Types() {
+			if ref, ok := branch.(*avro.RefSchema); ok {
+				branch = ref.Schema()
+			}
+			if branch.Type() == avro.Null {
+				continue
+			}
+			branchValue, err := avroFieldValue(branch, value)
+			if err != nil {
+				return nil, err
+			}
+			// Numa união, o hamba/avro lê os mapas como o nome do tipo escolhido e o valor.
+			if _, ok := branchValue.(map[string]interface{}); ok {
+				return map[string]interface{}{avroTypeName(branch): branchValue}, nil
+			}
+			return branchValue, nil
+		}
+	case *avro.RecordSchema:
+		row, ok := value.(Data)
+		if !ok {
+			row, ok = value.(map[string]interface{})
+		}
+		if !ok {
+			return nil, fmt.Errorf("o valor %v não é um registro", value)
+		}
+		record := make(map[string]interface{}, len(s.Fields()))
+		for _, field := range s.Fields() {
+			fieldValue, err := avroFieldValue(field.Type(), row[field.Name()])
+			if err != nil {
+				return nil, fmt.Errorf("campo %s: %w", field.Name(), err)
+			}
+			record[field.Name()] = fieldValue
+		}
+		return record, nil
+	case *avro.ArraySchema:
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("o valor %v não é uma lista", value)
+		}
+		values := make([]interface{}, len(items))
+		for i, item := range items {
+			itemValue, err := avroFieldValue(s.Items(), item)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = itemValue
+		}
+		return values, nil
+	case *avro.MapSchema:
+		entries, ok := value.(Data)
+		if !ok {
+			entries, ok = value.(map[string]interface{})
+		}
+		if !ok {
+			return nil, fmt.Errorf("o valor %v não é um mapa", value)
+		}
+		values := make(map[string]interface{}, len(entries))
+		for key, item := range entries {
+			itemValue, err := avroFieldValue(s.Values(), item)
+			if err != nil {
+				return nil, err
+			}
+			values[key] = itemValue
+		}
+		return values, nil
+	}
+	if value == nil {
+		return nil, nil
+	}
+
+	switch {
+	case schema.Type() == avro.Boolean:
+		return schemaBool(value)
+	case schema.Type() == avro.Int && logical == string(avro.Date):
+		date, err := schemaTime(value)
+		y, m, d := date.Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC), err
+	case schema.Type() == avro.Long && (logical == string(avro.TimestampMillis) || logical == string(avro.TimestampMicros)):
+		return schemaTime(value)
+	case schema.Type() == avro.Int && logical == "":
+		i, err := schemaInt64(value)
+		if err == nil && (i < math.MinInt32 || i > math.MaxInt32) {
+			err = fmt.Errorf("%d excede um inteiro de 32 bits", i)
+		}
+		return int32(i), err
+	case schema.Type() == avro.Long && logical == "":
+		return schemaInt64(value)
+	case schema.Type() == avro.Float:
+		f, err := schemaFloat64(value)
+		return float32(f), err
+	case schema.Type() == avro.Double:
+		return schemaFloat64(value)
+	case schema.Type() == avro.String:
+		return schemaString(value), nil
+	case schema.Type() == avro.Bytes && logical == "":
+		return schemaBytes(value), nil
+	}
+	name := string(schema.Type())
+	if logical != "" {
+		name += " (" + logical + ")"
+	}
+	return nil, fmt.Errorf("a serialização do tipo Avro %s não é suportada", name)
+}
+
+// avroRowValue converte um valor decodificado pelo hamba/avro para os tipos das linhas: inteiros
+// int64, float64, registros e mapas Data, decimal em texto com a escala do schema e horários em UTC.
+func avroRowValue(schema avro.Schema, value interface{}) interface{} {
+	// Nas uniões, os tipos complexos chegam como um mapa do nome do tipo para o valor.
+	if union, ok := schema.(*avro.UnionSchema); ok {
+		if wrapped, ok := value.(map[string]interface{}); ok && len(wrapped) == 1 {
+			for name, item := range wrapped {
+				if branch := avroBranch(union, isAvroTypeName(name)); branch != nil {
+					return avroRowValue(branch, item)
+				}
+			}
+		}
+	}
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case int:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case float32:
+		return float64(v)
+	case time.Time:
+		return v.UTC()
+	case *big.Rat:
+		scale := 0
+		if decimal, ok := avroLogical(avroBranch(schema, isAvroDecimal)).(*avro.DecimalLogicalSchema); ok {
+			scale = decimal.Scale()
+		}
+		return v.FloatString(scale)
+	case []interface{}:
+		var items avro.Schema
+		if array, ok := avroBranch(schema, isAvroType(avro.Array)).(*avro.ArraySchema); ok {
+			items = array.Items()
+		}
+		values := make([]interface{}, len(v))
+		for i, item := range v {
+			values[i] = avroRowValue(items, item)
+		}
+		return values
+	case map[string]interface{}:
+		row := make(Data, len(v))
+		switch s := avroBranch(schema, isAvroType(avro.Record, avro.Map)).(type) {
+		case *avro.RecordSchema:
+			for _, field := range s.Fields() {
+				row[field.Name()] = avroRowValue(field.Type(), v[field.Name()])
+			}
+		case *avro.MapSchema:
+			for key, item := range v {
+				row[key] = avroRowValue(s.Values(), item)
+			}
+		default:
+			for key, item := range v {
+				row[key] = avroRowValue(nil, item)
+			}
+		}
+		return row
+	}
+	// Os fixed são arrays de bytes de tamanho fixo.
+	if array := reflect.ValueOf(value); array.Kind() == reflect.Array && array.Type().Elem().Kind() == reflect.Uint8 {
+		data := make([]byte, array.Len())
+		reflect.Copy(reflect.ValueOf(data), array)
+		return data
+	}
+	return value
+}
+
+// avroBranch retorna o schema, ou o tipo da união, que satisfaz match; nil se nenhum satisfaz.
+func avroBranch(schema avro.Schema, match func(avro.Schema) bool) avro.Schema {
+	candidates := []avro.Schema{schema}
+	if union, ok := schema.(*avro.UnionSchema); ok {
+		candidates = union.Types()
+	}
+	for _, candidate := range candidates {
+		if ref, ok := candidate.(*avro.RefSchema); ok {
+			candidate = ref.Schema()
+		}
+		if candidate != nil && match(candidate) {
+			return candidate
+		}
+	}
+	return nil
+}
+
+func isAvroType(types ...avro.Type) func(avro.Schema) bool {
+	return func(schema avro.Schema) bool {
+		for _, t := range types {
+			if schema.Type() == t {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func isAvroTypeName(name string) func(avro.Schema) bool {
+	return func(schema avro.Schema) bool { return avroTypeName(schema) == name }
+}
+
+// avroTypeName é o nome completo dos tipos com nome e o tipo dos demais.
+func avroTypeName(schema avro.Schema) string {
+	if named, ok := schema.(avro.NamedSchema); ok {
+		return named.FullName()
+	}
+	return string(schema.Type())
+}
+
+func isAvroDecimal(schema avro.Schema) bool {
+	_, ok := avroLogical(schema).(*avro.DecimalLogicalSchema)
+	return ok
+}
+
+// avroLogical retorna o logical type do schema, ou nil.
+func avroLogical(schema avro.Schema) avro.LogicalSchema {
+	if typed, ok := schema.(avro.LogicalTypeSchema); ok {
+		return typed.Logical()
+	}
+	return nil
+}
@@ -0,0 +1,349 @@
+package utils
+
+import (
+	"fmt"
+	. "github.com/faelmori/getl/etypes"
+	gomysql "github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BinlogColumn descreve uma coluna da tabela capturada, lida de information_schema.COLUMNS: o binlog
+// traz só o tipo físico de cada coluna, sem nome, sinal ou os valores de ENUM e SET.
+type BinlogColumn struct {
+	Name     string
+	DataType string
+	Unsigned bool
+	Values   []string
+}
+
+// NewBinlogColumn cria a BinlogColumn a partir de DATA_TYPE e COLUMN_TYPE de information_schema.COLUMNS.
+func NewBinlogColumn(name, dataType, columnType string) BinlogColumn {
+	column := BinlogColumn{Name: name, DataType: strings.ToLower(dataType)}
+	columnType = strings.ToLower(columnType)
+	column.Unsigned = strings.Contains(columnType, "unsigned")
+	if column.DataType == "enum" || column.DataType == "set" {
+		column.Values = parseEnumValues(columnType)
+	}
+	return column
+}
+
+// parseEnumValues lê os valores de "enum('a','b”c')" ou "set(...)".
+func parseEnumValues(columnType string) []string {
+	start := strings.IndexByte(columnType, '(')
+	if start < 0 {
+		return nil
+	}
+	var values []string
+	var value strings.Builder
+	quoted := false
+	for i := start + 1; i < len(columnType); i++ {
+		c := columnType[i]
+		switch {
+		case c == '\'' && quoted && i+1 < len(columnType) && columnType[i+1] == '\'':
+			value.WriteByte('\'')
+			i++
+		case c == '\'':
+			if quoted {
+				values = append(values, value.String())
+				value.Reset()
+			}
+			quoted = !quoted
+		case quoted:
+			value.WriteByte(c)
+		}
+	}
+	return values
+}
+
+// textual indica se os bytes da coluna são texto; BLOB, BINARY e as geometrias permanecem []byte.
+func (c BinlogColumn) textual() bool {
+	return strings.Contains(c.DataType, "text") || strings.Contains(c.DataType, "char") || c.DataType == "json" || c.DataType == ""
+}
+
+// BinlogDecoder converte os eventos do binlog lidos pelo replication.BinlogSyncer do go-mysql em
+// alterações, acumulando as da transação corrente da tabela capturada.
+type BinlogDecoder struct {
+	table   string
+	columns []BinlogColumn
+	events  []ChangeEvent
+	open    bool
+
+	// File e Offset são o arquivo do binlog e a posição do próximo evento.
+	File   string
+	Offset uint32
+	// GTID é o GTID da transação corrente ou da última confirmada; "" sem gtid_mode.
+	GTID string
+}
+
+// NewBinlogDecoder cria o decodificador das alterações de table ("tabela" ou "banco.tabela"), cujas
+// colunas são columns, na ordem da tabela; file e offset são a posição em que a leitura começa.
+func NewBinlogDecoder(table string, columns []BinlogColumn, file string, offset uint32) *BinlogDecoder {
+	return &BinlogDecoder{table: table, columns: columns, File: file, Offset: offset}
+}
+
+// SetColumns atualiza as colunas da tabela capturada, depois de uma alteração de estrutura.
+func (d *BinlogDecoder) SetColumns(columns []BinlogColumn) { d.columns = columns }
+
+// InTransaction indica se há uma transação aberta, cujas alterações ainda não foram retornadas.
+func (d *BinlogDecoder) InTransaction() bool { return d.open }
+
+// ErrBinlogColumns indica que o evento tem um número de colunas diferente do conhecido para a tabela.
+type ErrBinlogColumns struct {
+	Table    string
+	Expected int
+	Got      int
+}
+
+func (e *ErrBinlogColumns) Error() string {
+	return fmt.Sprintf("o binlog traz %d coluna(s) de %s, mas a tabela tem %d", e.Got, e.Table, e.Expected)
+}
+
+// Decode interpreta um evento do binlog. No commit, retorna as alterações da transação e true; File
+// e Offset passam a ser a posição após a transação.
+func (d *BinlogDecoder) Decode(event *replication.BinlogEvent) ([]ChangeEvent, bool, error) {
+	// Eventos artificiais, como o ROTATE enviado no início da leitura, não têm posição.
+	if event.Header.LogPos > 0 && event.Header.EventType != replication.ROTATE_EVENT {
+		d.Offset = event.Header.LogPos
+	}
+
+	switch e := event.Event.(type) {
+	case *replication.RotateEvent:
+		d.File = string(e.NextLogName)
+		d.Offset = uint32(e.Position)
+	case *replication.GTIDEvent:
+		next, gtidErr := e.GTIDNext()
+		if gtidErr != nil {
+			return nil, false, fmt.Errorf("evento GTID: %w", gtidErr)
+		}
+		d.GTID = next.String()
+	case *replication.MariadbGTIDEvent:
+		d.GTID = e.GTID.String()
+		// Sem a flag FL_STANDALONE, o GTID abre uma transação, sem BEGIN.
+		if !e.IsStandalone() {
+			d.begin()
+		}
+	case *replication.QueryEvent:
+		query := strings.TrimSpace(string(e.Query))
+		switch {
+		case strings.EqualFold(query, "BEGIN"):
+			d.begin()
+		case strings.EqualFold(query, "COMMIT"), !d.open:
+			// COMMIT encerra as transações de tabelas não transacionais; DDL fora de transação é confirmado sozinho.
+			return d.commit(), true, nil
+		}
+	case *replication.XIDEvent:
+		return d.commit(), true, nil
+	case *replication.RowsEvent:
+		return nil, false, d.rows(event.Header, e)
+	case *replication.TransactionPayloadEvent:
+		// Uma transação comprimida (binlog_transaction_compression) traz os eventos dentro do payload.
+		for _, inner := range e.Events {
+			events, commit, innerErr := d.Decode(inner)
+			if innerErr != nil || commit {
+				return events, commit, innerErr
+			}
+		}
+	}
+	// FORMAT_DESCRIPTION, TABLE_MAP, PREVIOUS_GTIDS, ROWS_QUERY, HEARTBEAT e os demais não alteram linhas.
+	return nil, false, nil
+}
+
+func (d *BinlogDecoder) begin() {
+	d.open = true
+	d.events = nil
+}
+
+func (d *BinlogDecoder) commit() []ChangeEvent {
+	events := d.events
+	d.events = nil
+	d.open = false
+	return events
+}
+
+// rows converte as linhas de um evento WRITE_ROWS, UPDATE_ROWS ou DELETE_ROWS da tabela capturada.
+func (d *BinlogDecoder) rows(header *replication.EventHeader, event *replication.RowsEvent) error {
+	table := event.Table
+	if table == nil || !matchesTable(d.table, string(table.Schema), string(table.Table)) {
+		return nil
+	}
+	name := string(table.Schema) + "." + string(table.Table)
+	if len(table.ColumnType) != len(d.columns) {
+		return &ErrBinlogColumns{Table: name, Expected: len(d.columns), Got: len(table.ColumnType)}
+	}
+
+	operation := ChangeInsert
+	step := 1
+	switch header.EventType {
+	case replication.UPDATE_ROWS_EVENTv0, replication.UPDATE_ROWS_EVENTv1, replication.UPDATE_ROWS_EVENTv2,
+		replication.MARIADB_UPDATE_ROWS_COMPRESSED_EVENT_V1:
+		// As linhas de UPDATE_ROWS vêm aos pares: a imagem anterior e a nova.
+		operation, step = ChangeUpdate, 2
+	case replication.DELETE_ROWS_EVENTv0, replication.DELETE_ROWS_EVENTv1, replication.DELETE_ROWS_EVENTv2,
+		replication.MARIADB_DELETE_ROWS_COMPRESSED_EVENT_V1:
+		operation = ChangeDelete
+	case replication.PARTIAL_UPDATE_ROWS_EVENT:
+		return fmt.Errorf("atualizações parciais de JSON não são suportadas; use binlog_row_value_options vazio")
+	}
+
+	timestamp := time.Unix(int64(header.Timestamp), 0).UTC()
+	for i := 0; i+step <= len(event.Rows); i += step {
+		change := ChangeEvent{Operation: operation, Table: name, Timestamp: timestamp}
+		row, rowErr := d.row(event, table, i)
+		if rowErr != nil {
+			return fmt.Errorf("evento de linhas de %s: %w", name, rowErr)
+		}
+		switch operation {
+		case ChangeInsert:
+			change.After = row
+		case ChangeDelete:
+			change.Before = row
+		case ChangeUpdate:
+			change.Before = row
+			if change.After, rowErr = d.row(event, table, i+1); rowErr != nil {
+				return fmt.Errorf("evento de linhas de %s: %w", name, rowErr)
+			}
+			// Com binlog_row_image MINIMAL ou NOBLOB, as colunas ausentes da nova imagem não mudaram.
+			for column, value := range change.Before {
+				if _, found := change.After[column]; !found {
+					change.After[column] = value
+				}
+			}
+		}
+		d.events = append(d.events, change)
+	}
+	return nil
+}
+
+// row converte a i-ésima imagem de linha do evento, sem as colunas ausentes do binlog_row_image.
+func (d *BinlogDecoder) row(event *replication.RowsEvent, table *replication.TableMapEvent, i int) (Data, error) {
+	skipped := map[int]bool{}
+	if i < len(event.SkippedColumns) {
+		for _, index := range event.SkippedColumns[i] {
+			skipped[index] = true
+		}
+	}
+	row := Data{}
+	for index, value := range event.Rows[i] {
+		if skipped[index] || index >= len(d.columns) {
+			continue
+		}
+		column := d.columns[index]
+		converted, valueErr := binlogValue(value, table.ColumnType[index], column)
+		if valueErr != nil {
+			return nil, fmt.Errorf("coluna %s: %w", column.Name, valueErr)
+		}
+		row[column.Name] = converted
+	}
+	return row, nil
+}
+
+// binlogValue converte o valor decodificado pelo go-mysql nos tipos retornados pelo driver na
+// extração: inteiros com o sinal da coluna, float64 e texto; DECIMAL, datas e horas já vêm como
+// texto no formato do MySQL, ENUM e SET viram seus nomes e BLOB, BINARY e geometrias, []byte.
+func binlogValue(value interface{}, columnType byte, column BinlogColumn) (interface{}, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case int8:
+		if column.Unsigned {
+			return int64(uint8(v)), nil
+		}
+		return int64(v), nil
+	case int16:
+		if column.Unsigned {
+			return int64(uint16(v)), nil
+		}
+		return int64(v), nil
+	case int32:
+		if column.Unsigned && columnType == gomysql.MYSQL_TYPE_INT24 {
+			return int64(uint32(v) & 0xffffff), nil
+		}
+		if column.Unsigned {
+			return int64(uint32(v)), nil
+		}
+		return int64(v), nil
+	case int64:
+		switch {
+		case column.DataType == "enum":
+			if v <= 0 || int(v) > len(column.Values) {
+				return "", nil
+			}
+			return column.Values[v-1], nil
+		case column.DataType == "set":
+			var names []string
+			for i, name := range column.Values {
+				if v&(1<<uint(i)) != 0 {
+					names = append(names, name)
+				}
+			}
+			return strings.Join(names, ","), nil
+		case column.Unsigned && uint64(v) > math.MaxInt64:
+			return uint64(v), nil
+		}
+		return v, nil
+	case int:
+		return int64(v), nil
+	case float32:
+		return float64(v), nil
+	case string:
+		// O go-mysql pode apontar os textos para o buffer do evento.
+		return strings.Clone(v), nil
+	case []byte:
+		if column.textual() {
+			return string(v), nil
+		}
+		return append([]byte(nil), v...), nil
+	case *replication.JsonDiff:
+		return nil, fmt.Errorf("atualizações parciais de JSON não são suportadas; use binlog_row_value_options vazio")
+	}
+	return value, nil
+}
+
+// BinlogPosition é a posição de leitura do binlog, registrada como "arquivo:posição".
+type BinlogPosition struct {
+	File   string
+	Offset uint32
+}
+
+// ParseBinlogPosition interpreta uma posição no formato "binlog.000003:1234".
+func ParseBinlogPosition(position string) (BinlogPosition, error) {
+	file, offset, ok := strings.Cut(position, ":")
+	value, err := strconv.ParseUint(offset, 10, 32)
+	if !ok || file == "" || err != nil {
+		return BinlogPosition{}, fmt.Errorf("posição do binlog inválida: %q", position)
+	}
+	return BinlogPosition{File: file, Offset: uint32(value)}, nil
+}
+
+func (p BinlogPosition) String() string { return fmt.Sprintf("%s:%d", p.File, p.Offset) }
+
+// Before indica se p é anterior a other; os arquivos do binlog têm um sufixo numérico crescente.
+func (p BinlogPosition) Before(other BinlogPosition) bool {
+	return gomysql.Position{Name: p.File, Pos: p.Offset}.Compare(gomysql.Position{Name: other.File, Pos: other.Offset}) < 0
+}
+
+// ParseGTIDSet interpreta um conjunto no formato de gtid_executed: "uuid:1-5:7,uuid2:1-3".
+func ParseGTIDSet(text string) (*gomysql.MysqlGTIDSet, error) {
+	set, err := gomysql.ParseMysqlGTIDSet(strings.ReplaceAll(text, "\n", ""))
+	if err != nil {
+		return nil, fmt.Errorf("conjunto de GTIDs inválido: %q: %w", text, err)
+	}
+	return set.(*gomysql.MysqlGTIDSet), nil
+}
+
+// BinlogServerID retorna o server_id com que o getl se registra como réplica.
+func BinlogServerID(config Config) uint32 {
+	if config.CDC.ServerID > 0 {
+		return config.CDC.ServerID
+	}
+	// Um valor alto e estável, para não colidir com os server_id usuais das réplicas.
+	var hash uint32 = 2166136261
+	for _, c := range []byte(ReplicationSlot(config)) {
+		hash = (hash ^ uint32(c)) * 16777619
+	}
+	return hash | 0x40000000
+}
@@ -0,0 +1,215 @@
+package utils
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	. "github.com/faelmori/getl/etypes"
+	gomysql "github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+)
+
+// binlogEvent monta um evento como o entregue pelo BinlogSyncer, com o tipo e a posição no cabeçalho.
+func binlogEvent(eventType replication.EventType, logPos uint32, event replication.Event) *replication.BinlogEvent {
+	return &replication.BinlogEvent{Header: &replication.EventHeader{EventType: eventType, LogPos: logPos}, Event: event}
+}
+
+// binlogTableMap descreve a tabela com as colunas id INT, name VARCHAR(100) e price DECIMAL(10,2).
+func binlogTableMap(id uint64, schema, table string) *replication.TableMapEvent {
+	return &replication.TableMapEvent{TableID: id, Schema: []byte(schema), Table: []byte(table), ColumnCount: 3,
+		ColumnType: []byte{gomysql.MYSQL_TYPE_LONG, gomysql.MYSQL_TYPE_VARCHAR, gomysql.MYSQL_TYPE_NEWDECIMAL}}
+}
+
+// TestNewBinlogColumn verifica a leitura do sinal e dos valores de ENUM e SET.
+func TestNewBinlogColumn(t *testing.T) {
+	column := NewBinlogColumn("size", "ENUM", "enum('p','m''x','g')")
+	if column.DataType != "enum" || !reflect.DeepEqual(column.Values, []string{"p", "m'x", "g"}) {
+		t.Errorf("NewBinlogColumn() = %#v", column)
+	}
+	if column := NewBinlogColumn("n", "int", "int(10) unsigned"); !column.Unsigned || column.Values != nil {
+		t.Errorf("NewBinlogColumn() = %#v", column)
+	}
+}
+
+// TestBinlogDecoder verifica a conversão de uma transação com inclusão, atualização e exclusão,
+// ignorando as alterações de outras tabelas, e a posição após o commit.
+func TestBinlogDecoder(t *testing.T) {
+	columns := []BinlogColumn{
+		NewBinlogColumn("id", "int", "int(11)"),
+		NewBinlogColumn("name", "varchar", "varchar(100)"),
+		NewBinlogColumn("price", "decimal", "decimal(10,2)"),
+	}
+	decoder := NewBinlogDecoder("items", columns, "binlog.000001", 4)
+
+	items, others := binlogTableMap(1, "shop", "items"), binlogTableMap(2, "shop", "others")
+	row := []interface{}{int32(1), "a", "12.50"}
+	rowWithNull := []interface{}{int32(1), nil, "-12.50"}
+	events := []*replication.BinlogEvent{
+		binlogEvent(replication.ROTATE_EVENT, 0, &replication.RotateEvent{Position: 4, NextLogName: []byte("binlog.000002")}),
+		binlogEvent(replication.GTID_EVENT, 100, &replication.GTIDEvent{SID: make([]byte, 16), GNO: 7}),
+		binlogEvent(replication.QUERY_EVENT, 200, &replication.QueryEvent{Query: []byte("BEGIN")}),
+		binlogEvent(replication.TABLE_MAP_EVENT, 300, items),
+		binlogEvent(replication.TABLE_MAP_EVENT, 400, others),
+		binlogEvent(replication.WRITE_ROWS_EVENTv2, 500, &replication.RowsEvent{Table: items, Rows: [][]interface{}{row}}),
+		binlogEvent(replication.WRITE_ROWS_EVENTv2, 600, &replication.RowsEvent{Table: others, Rows: [][]interface{}{row}}),
+		binlogEvent(replication.UPDATE_ROWS_EVENTv2, 700, &replication.RowsEvent{Table: items, Rows: [][]interface{}{row, rowWithNull}}),
+		binlogEvent(replication.DELETE_ROWS_EVENTv2, 800, &replication.RowsEvent{Table: items, Rows: [][]interface{}{rowWithNull}}),
+	}
+	for i, event := range events {
+		if changes, commit, err := decoder.Decode(event); err != nil || commit || changes != nil {
+			t.Fatalf("Decode(%d) = %v, %v, %v", i, changes, commit, err)
+		}
+	}
+	if !decoder.InTransaction() {
+		t.Fatalf("InTransaction() = false após BEGIN")
+	}
+
+	changes, commit, err := decoder.Decode(binlogEvent(replication.XID_EVENT, 900, &replication.XIDEvent{XID: 1}))
+	if err != nil || !commit {
+		t.Fatalf("Decode(XID) = %v, %v", commit, err)
+	}
+	want := []ChangeEvent{
+		{Operation: ChangeInsert, Table: "shop.items", After: Data{"id": int64(1), "name": "a", "price": "12.50"}},
+		{Operation: ChangeUpdate, Table: "shop.items", Before: Data{"id": int64(1), "name": "a", "price": "12.50"}, After: Data{"id": int64(1), "name": nil, "price": "-12.50"}},
+		{Operation: ChangeDelete, Table: "shop.items", Before: Data{"id": int64(1), "name": nil, "price": "-12.50"}},
+	}
+	for i := range changes {
+		changes[i].Timestamp = time.Time{}
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("Decode(XID) = %#v, esperado %#v", changes, want)
+	}
+	if decoder.File != "binlog.000002" || decoder.Offset != 900 {
+		t.Errorf("posição = %s:%d", decoder.File, decoder.Offset)
+	}
+	if decoder.GTID != "00000000-0000-0000-0000-000000000000:7" {
+		t.Errorf("GTID = %s", decoder.GTID)
+	}
+
+	// Com binlog_row_image MINIMAL, a coluna ausente da nova imagem mantém o valor anterior.
+	minimal := &replication.RowsEvent{Table: items, Rows: [][]interface{}{row, {int32(1), "b", nil}}, SkippedColumns: [][]int{{}, {2}}}
+	if _, _, err := decoder.Decode(binlogEvent(replication.UPDATE_ROWS_EVENTv2, 1000, minimal)); err != nil {
+		t.Fatal(err)
+	}
+	changes, _, _ = decoder.Decode(binlogEvent(replication.XID_EVENT, 1100, &replication.XIDEvent{XID: 2}))
+	if len(changes) != 1 || !reflect.DeepEqual(changes[0].After, Data{"id": int64(1), "name": "b", "price": "12.50"}) {
+		t.Errorf("Decode() com imagem mínima = %#v", changes)
+	}
+
+	decoder.SetColumns(columns[:2])
+	_, _, err = decoder.Decode(binlogEvent(replication.WRITE_ROWS_EVENTv2, 1200, &replication.RowsEvent{Table: items, Rows: [][]interface{}{row}}))
+	var columnsErr *ErrBinlogColumns
+	if !errors.As(err, &columnsErr) || columnsErr.Expected != 2 || columnsErr.Got != 3 {
+		t.Errorf("Decode() com colunas alteradas = %v", err)
+	}
+	if _, _, err := decoder.Decode(binlogEvent(replication.PARTIAL_UPDATE_ROWS_EVENT, 1300, &replication.RowsEvent{Table: binlogTableMap(1, "shop", "items")})); err == nil {
+		t.Errorf("Decode() de atualização parcial de JSON sem erro")
+	}
+}
+
+// TestBinlogValue verifica a conversão dos valores do go-mysql nos tipos da extração.
+func TestBinlogValue(t *testing.T) {
+	enum := NewBinlogColumn("size", "enum", "enum('p','m''x','g')")
+	set := NewBinlogColumn("tags", "set", "set('a','b','c')")
+	tests := []struct {
+		name       string
+		value      interface{}
+		columnType byte
+		column     BinlogColumn
+		want       interface{}
+	}{
+		{"tinyint", int8(-1), gomysql.MYSQL_TYPE_TINY, BinlogColumn{}, int64(-1)},
+		{"tinyint unsigned", int8(-1), gomysql.MYSQL_TYPE_TINY, BinlogColumn{Unsigned: true}, int64(255)},
+		{"mediumint unsigned", int32(-1), gomysql.MYSQL_TYPE_INT24, BinlogColumn{Unsigned: true}, int64(0xffffff)},
+		{"bigint unsigned", int64(-1), gomysql.MYSQL_TYPE_LONGLONG, BinlogColumn{Unsigned: true}, uint64(1<<64 - 1)},
+		{"float", float32(1.5), gomysql.MYSQL_TYPE_FLOAT, BinlogColumn{}, float64(1.5)},
+		{"year", 2024, gomysql.MYSQL_TYPE_YEAR, BinlogColumn{}, int64(2024)},
+		{"enum", int64(2), gomysql.MYSQL_TYPE_STRING, enum, "m'x"},
+		{"set", int64(5), gomysql.MYSQL_TYPE_STRING, set, "a,c"},
+		{"text", []byte("hi"), gomysql.MYSQL_TYPE_BLOB, BinlogColumn{DataType: "text"}, "hi"},
+		{"blob", []byte{0xca, 0xfe}, gomysql.MYSQL_TYPE_BLOB, BinlogColumn{DataType: "blob"}, []byte{0xca, 0xfe}},
+		{"json", `{"a":1}`, gomysql.MYSQL_TYPE_JSON, BinlogColumn{DataType: "json"}, `{"a":1}`},
+	}
+	for _, test := range tests {
+		value, err := binlogValue(test.value, test.columnType, test.column)
+		if err != nil || !reflect.DeepEqual(value, test.want) {
+			t.Errorf("%s: binlogValue() = %#v, %v", test.name, value, err)
+		}
+	}
+	if _, err := binlogValue(&replication.JsonDiff{}, gomysql.MYSQL_TYPE_JSON, BinlogColumn{DataType: "json"}); err == nil {
+		t.Errorf("binlogValue() de atualização parcial de JSON sem erro")
+	}
+}
+
+// TestBinlogPositions verifica as posições por arquivo e os conjuntos de GTIDs.
+func TestBinlogPositions(t *testing.T) {
+	position, err := ParseBinlogPosition("binlog.000003:1234")
+	if err != nil || position != (BinlogPosition{File: "binlog.000003", Offset: 1234}) || position.String() != "binlog.000003:1234" {
+		t.Fatalf("ParseBinlogPosition() = %v, %v", position, err)
+	}
+	if !position.Before(BinlogPosition{File: "binlog.000004", Offset: 4}) || position.Before(position) {
+		t.Errorf("Before() incorreto")
+	}
+	for _, invalid := range []string{"", "binlog.000003", ":4", "binlog.000003:x"} {
+		if _, err := ParseBinlogPosition(invalid); err == nil {
+			t.Errorf("ParseBinlogPosition(%q) sem erro", invalid)
+		}
+	}
+
+	uuid := "3e11fa47-71ca-11e1-9e33-c80aa9429562"
+	set, err := ParseGTIDSet(uuid + ":1-5:7,\n" + "4e11fa47-71ca-11e1-9e33-c80aa9429562:2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := set.Update(uuid + ":6"); err != nil {
+		t.Fatal(err)
+	}
+	if got := set.String(); got != uuid+":1-7,4e11fa47-71ca-11e1-9e33-c80aa9429562:2" {
+		t.Errorf("String() = %s", got)
+	}
+	for _, invalid := range []string{"x:1", uuid + ":5-3"} {
+		if _, err := ParseGTIDSet(invalid); err == nil {
+			t.Errorf("ParseGTIDSet(%q) sem erro", invalid)
+		}
+	}
+}
+
+// TestBinlogServerID verifica o server_id padrão, estável e fora da faixa usual, e o configurado.
+func TestBinlogServerID(t *testing.T) {
+	config := Config{SourceTable: "items"}
+	id := BinlogServerID(config)
+	if id&0x40000000 == 0 || id != BinlogServerID(config) {
+		t.Errorf("BinlogServerID() = %d", id)
+	}
+	config.CDC.ServerID = 42
+	if id := BinlogServerID(config); id != 42 {
+		t.Errorf("BinlogServerID() = %d", id)
+	}
+}
+
+// TestValidateBinlogChangeCapture verifica as regras do mode binlog e de cdc.gtid.
+func TestValidateBinlogChangeCapture(t *testing.T) {
+	base := `sourceType: mysql
+sourceConnectionString: user:pass@tcp(localhost:3306)/shop
+destinationType: sqlite3
+destinationConnectionString: b.db
+sourceTable: items
+primaryKey: id
+`
+	tests := map[string]string{
+		"cdc:\n  mode: pgoutput\n":                                "cdc.mode",
+		"cdc:\n  mode: triggers\n  gtid: true\n":                  "cdc.gtid",
+		"cdc:\n  mode: binlog\n  sink: kafka\nkafkaURL: k:9092\n": "cdc.sink",
+	}
+	for document, path := range tests {
+		errs := ValidateConfigData([]byte(base+document), "yaml")
+		if len(errs) != 1 || errs[0].Path != path {
+			t.Errorf("ValidateConfigData(%q) = %v, esperado erro em %s", document, errs, path)
+		}
+	}
+	if errs := ValidateConfigData([]byte(base+"cdc:\n  mode: binlog\n  serverId: 1001\n  gtid: true\n"), "yaml"); len(errs) > 0 {
+		t.Errorf("ValidateConfigData() com binlog válido = %v", errs)
+	}
+}
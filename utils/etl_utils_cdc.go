@@ -0,0 +1,237 @@
+package utils
+
+import (
+	"cmp"
+	"fmt"
+	. "github.com/faelmori/getl/etypes"
+	"github.com/goccy/go-json"
+	"regexp"
+	"strings"
+)
+
+// DefaultChangelogTable é a tabela de alterações usada quando ChangeCapture.Changelog não é informado.
+const DefaultChangelogTable = "getl_changelog"
+
+const defaultChangeBatchSize = 1000
+
+// Operações gravadas na coluna operation do changelog.
+const (
+	ChangeInsert = "I"
+	ChangeUpdate = "U"
+	ChangeDelete = "D"
+)
+
+var nonIdentifierChars = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// ChangelogTable retorna a tabela de alterações da Config.
+func ChangelogTable(config Config) string {
+	return cmp.Or(config.CDC.Changelog, DefaultChangelogTable)
+}
+
+// ChangeBatchSize retorna quantas alterações são aplicadas por transação no destino.
+func ChangeBatchSize(config Config) int {
+	if config.CDC.BatchSize > 0 {
+		return config.CDC.BatchSize
+	}
+	return defaultChangeBatchSize
+}
+
+// ChangeCaptureTriggers monta os triggers AFTER INSERT, UPDATE e DELETE que gravam as alterações de
+// SourceTable no changelog. columns são as colunas da tabela, incluídas na imagem da linha; nas
+// exclusões a imagem é a da linha removida, para que as transformações possam calcular a chave de destino.
+// Uma atualização que muda a chave primária é gravada como a exclusão da linha antiga, seguida da
+// atualização com a nova, para que a linha antiga seja removida do destino.
+func ChangeCaptureTriggers(config Config, columns []string) ([]Trigger, error) {
+	if config.SourceTable == "" {
+		return nil, fmt.Errorf("captura de alterações exige sourceTable")
+	}
+	if config.PrimaryKey == "" {
+		return nil, fmt.Errorf("captura de alterações exige primaryKey")
+	}
+	if !containsFold(columns, config.PrimaryKey) {
+		return nil, fmt.Errorf("chave primária %s não encontrada nas colunas de %s", config.PrimaryKey, config.SourceTable)
+	}
+
+	dialect := TriggerDialect(config.SourceType)
+	switch dialect {
+	case "sqlite", "postgres", "mysql", "oracle", "sqlserver":
+	default:
+		return nil, fmt.Errorf("captura de alterações por triggers não suportada para o banco de dados: %s", config.SourceType)
+	}
+
+	prefix := "getl_cdc_" + strings.Trim(nonIdentifierChars.ReplaceAllString(strings.ToLower(config.SourceTable), "_"), "_")
+	events := []struct{ suffix, operation, event, row string }{
+		{"ins", ChangeInsert, "AFTER INSERT", "NEW"},
+		{"upd", ChangeUpdate, "AFTER UPDATE", "NEW"},
+		{"del", ChangeDelete, "AFTER DELETE", "OLD"},
+	}
+
+	triggers := make([]Trigger, 0, len(events))
+	for _, e := range events {
+		statement := changelogInsertStatement(dialect, config, columns, e.operation, e.row, false)
+		if e.operation == ChangeUpdate {
+			statement = changelogInsertStatement(dialect, config, columns, ChangeDelete, "OLD", true) + ";\n" + statement
+		}
+		triggers = append(triggers, Trigger{
+			Name:      prefix + "_" + e.suffix,
+			Table:     config.SourceTable,
+			Event:     e.event,
+			Statement: statement,
+		})
+	}
+	return triggers, nil
+}
+
+// changelogInsertStatement gera o INSERT no changelog feito pelo trigger de uma operação.
+// row é NEW ou OLD; no SQL Server, que não tem triggers por linha, vira inserted ou deleted.
+// Com keyChanged, a linha de OLD só é gravada se a atualização mudou a chave primária; no SQL
+// Server, as linhas de deleted cuja chave não está em inserted.
+func changelogInsertStatement(dialect string, config Config, columns []string, operation, row string, keyChanged bool) string {
+	insert := fmt.Sprintf("INSERT INTO %s (table_name, operation, pk, row_image)", ChangelogTable(config))
+	table := "'" + strings.ReplaceAll(config.SourceTable, "'", "''") + "'"
+	key := config.PrimaryKey
+
+	if dialect == "sqlserver" {
+		source := "inserted"
+		if row == "OLD" {
+			source = "deleted"
+		}
+		fields := make([]string, len(columns))
+		for i, column := range columns {
+			fields[i] = "r." + column
+		}
+		statement := fmt.Sprintf("%s SELECT %s, '%s', r.%s, (SELECT %s FOR JSON PATH, WITHOUT_ARRAY_WRAPPER, INCLUDE_NULL_VALUES) FROM %s r",
+			insert, table, operation, key, strings.Join(fields, ", "), source)
+		if keyChanged {
+			statement += fmt.Sprintf(" WHERE NOT EXISTS (SELECT 1 FROM inserted i WHERE i.%s = r.%s)", key, key)
+		}
+		return statement
+	}
+
+	if dialect == "oracle" {
+		row = ":" + row
+	}
+	var image string
+	switch dialect {
+	case "postgres":
+		image = fmt.Sprintf("row_to_json(%s)::text", row)
+	case "oracle":
+		pairs := make([]string, len(columns))
+		for i, column := range columns {
+			pairs[i] = fmt.Sprintf("'%s' VALUE %s.%s", column, row, column)
+		}
+		image = "JSON_OBJECT(" + strings.Join(pairs, ", ") + ")"
+	default:
+		pairs := make([]string, len(columns))
+		for i, column := range columns {
+			pairs[i] = fmt.Sprintf("'%s', %s.%s", column, row, column)
+		}
+		function := "json_object"
+		if dialect == "mysql" {
+			function = "JSON_OBJECT"
+		}
+		image = function + "(" + strings.Join(pairs, ", ") + ")"
+	}
+	if !keyChanged {
+		return fmt.Sprintf("%s VALUES (%s, '%s', %s.%s, %s)", insert, table, operation, row, key, image)
+	}
+
+	var condition string
+	switch dialect {
+	case "sqlite":
+		condition = fmt.Sprintf(" WHERE OLD.%[1]s IS NOT NEW.%[1]s", key)
+	case "postgres":
+		condition = fmt.Sprintf(" WHERE OLD.%[1]s IS DISTINCT FROM NEW.%[1]s", key)
+	case "mysql":
+		condition = fmt.Sprintf(" FROM DUAL WHERE NOT (OLD.%[1]s <=> NEW.%[1]s)", key)
+	case "oracle":
+		condition = fmt.Sprintf(" FROM DUAL WHERE :OLD.%[1]s <> :NEW.%[1]s", key)
+	}
+	return fmt.Sprintf("%s SELECT %s, '%s', %s.%s, %s%s", insert, table, operation, row, key, image, condition)
+}
+
+// ChangeCaptureKey retorna o campo de destino que corresponde a PrimaryKey, usado para localizar
+// a linha alterada no destino. Com transformações, é o destinationField da que lê PrimaryKey.
+func ChangeCaptureKey(config Config) (string, error) {
+	if config.PrimaryKey == "" {
+		return "", fmt.Errorf("captura de alterações exige primaryKey")
+	}
+	if len(config.Transformations) == 0 {
+		return config.PrimaryKey, nil
+	}
+	for _, t := range config.Transformations {
+		if strings.EqualFold(t.SourceField, config.PrimaryKey) {
+			return t.DestinationField, nil
+		}
+	}
+	return "", fmt.Errorf("nenhuma transformação lê a chave primária %s; ela é necessária para localizar as linhas no destino", config.PrimaryKey)
+}
+
+// ChangeKeyValue calcula o valor da chave de destino a partir de uma imagem da linha de origem,
+// aplicando só a transformação da chave primária. Basta a imagem ter a chave, como nas exclusões
+// capturadas por replicação lógica, que trazem apenas a identidade da linha.
+func ChangeKeyValue(config Config, row Data) (interface{}, error) {
+	key, keyErr := ChangeCaptureKey(config)
+	if keyErr != nil {
+		return nil, keyErr
+	}
+	var transformations []Transformation
+	for _, t := range config.Transformations {
+		if strings.EqualFold(t.SourceField, config.PrimaryKey) {
+			transformations = append(transformations, t)
+			break
+		}
+	}
+	transformed, transformErr := ApplyTransformations([]Data{row}, transformations)
+	if transformErr != nil {
+		return nil, transformErr
+	}
+	value := transformed[0][key]
+	if value == nil {
+		return nil, fmt.Errorf("chave %s ausente na imagem da linha", config.PrimaryKey)
+	}
+	return value, nil
+}
+
+// DecodeRowImage converte a imagem JSON gravada pelo trigger em uma linha. Números inteiros
+// voltam como int64 e os demais como float64, como os drivers retornam na extração.
+func DecodeRowImage(image string) (Data, error) {
+	decoder := json.NewDecoder(strings.NewReader(image))
+	decoder.UseNumber()
+	var row Data
+	if err := decoder.Decode(&row); err != nil {
+		return nil, fmt.Errorf("imagem da linha inválida: %w", err)
+	}
+	if row == nil {
+		return nil, fmt.Errorf("imagem da linha vazia")
+	}
+	normalizeJSONNumbers(row)
+	return row, nil
+}
+
+// normalizeJSONNumbers converte os json.Number da linha em int64, quando inteiros, ou float64.
+func normalizeJSONNumbers(row Data) {
+	for column, value := range row {
+		number, ok := value.(json.Number)
+		if !ok {
+			continue
+		}
+		if i, err := number.Int64(); err == nil {
+			row[column] = i
+		} else if f, err := number.Float64(); err == nil {
+			row[column] = f
+		} else {
+			row[column] = number.String()
+		}
+	}
+}
+
+// containsFold verifica se values contém value, sem diferenciar maiúsculas.
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
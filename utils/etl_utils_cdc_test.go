@@ -0,0 +1,126 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/faelmori/getl/etypes"
+)
+
+// TestChangeCaptureTriggers verifica os triggers de captura e a imagem da linha gravada por cada dialeto.
+func TestChangeCaptureTriggers(t *testing.T) {
+	config := Config{SourceType: "sqlite3", SourceTable: "sales.items", PrimaryKey: "id"}
+	columns := []string{"id", "name"}
+
+	triggers, err := ChangeCaptureTriggers(config, columns)
+	if err != nil {
+		t.Fatalf("ChangeCaptureTriggers() falhou: %v", err)
+	}
+	var names, events []string
+	for _, trigger := range triggers {
+		names = append(names, trigger.Name)
+		events = append(events, trigger.Event)
+		if trigger.Table != "sales.items" {
+			t.Errorf("trigger %s na tabela %s", trigger.Name, trigger.Table)
+		}
+	}
+	if want := []string{"getl_cdc_sales_items_ins", "getl_cdc_sales_items_upd", "getl_cdc_sales_items_del"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("nomes = %v, esperado %v", names, want)
+	}
+	if want := []string{"AFTER INSERT", "AFTER UPDATE", "AFTER DELETE"}; !reflect.DeepEqual(events, want) {
+		t.Errorf("eventos = %v, esperado %v", events, want)
+	}
+	if want := "INSERT INTO getl_changelog (table_name, operation, pk, row_image) VALUES ('sales.items', 'D', OLD.id, json_object('id', OLD.id, 'name', OLD.name))"; triggers[2].Statement != want {
+		t.Errorf("statement sqlite = %q, esperado %q", triggers[2].Statement, want)
+	}
+	// Uma atualização que muda a chave grava antes a exclusão da chave antiga.
+	if want := "INSERT INTO getl_changelog (table_name, operation, pk, row_image) SELECT 'sales.items', 'D', OLD.id, json_object('id', OLD.id, 'name', OLD.name) WHERE OLD.id IS NOT NEW.id;\n" +
+		"INSERT INTO getl_changelog (table_name, operation, pk, row_image) VALUES ('sales.items', 'U', NEW.id, json_object('id', NEW.id, 'name', NEW.name))"; triggers[1].Statement != want {
+		t.Errorf("statement sqlite do update = %q, esperado %q", triggers[1].Statement, want)
+	}
+
+	statements := map[string]string{
+		"postgres":  "row_to_json(NEW)::text",
+		"mysql":     "JSON_OBJECT('id', NEW.id, 'name', NEW.name)",
+		"godror":    "JSON_OBJECT('id' VALUE :NEW.id, 'name' VALUE :NEW.name)",
+		"sqlserver": "(SELECT r.id, r.name FOR JSON PATH, WITHOUT_ARRAY_WRAPPER, INCLUDE_NULL_VALUES) FROM inserted r",
+	}
+	for driver, want := range statements {
+		config.SourceType = driver
+		triggers, err := ChangeCaptureTriggers(config, columns)
+		if err != nil {
+			t.Fatalf("ChangeCaptureTriggers(%s) falhou: %v", driver, err)
+		}
+		if !strings.Contains(triggers[0].Statement, want) {
+			t.Errorf("statement %s = %q, esperado conter %q", driver, triggers[0].Statement, want)
+		}
+	}
+
+	config.SourceType = "sqlite3"
+	if _, err := ChangeCaptureTriggers(config, []string{"name"}); err == nil {
+		t.Error("ChangeCaptureTriggers() sem a chave primária nas colunas deveria falhar")
+	}
+}
+
+// TestChangeCaptureKey verifica o campo de destino usado para localizar as linhas alteradas.
+func TestChangeCaptureKey(t *testing.T) {
+	config := Config{PrimaryKey: "id"}
+	if key, err := ChangeCaptureKey(config); err != nil || key != "id" {
+		t.Errorf("ChangeCaptureKey() sem transformações = %q, %v", key, err)
+	}
+
+	config.Transformations = []Transformation{{SourceField: "ID", DestinationField: "item_id", Operation: "copy"}}
+	if key, err := ChangeCaptureKey(config); err != nil || key != "item_id" {
+		t.Errorf("ChangeCaptureKey() com transformação = %q, %v", key, err)
+	}
+
+	config.Transformations = []Transformation{{SourceField: "name", DestinationField: "name", Operation: "copy"}}
+	if _, err := ChangeCaptureKey(config); err == nil {
+		t.Error("ChangeCaptureKey() sem transformação da chave deveria falhar")
+	}
+}
+
+// TestDecodeRowImage verifica a conversão dos números da imagem JSON.
+func TestDecodeRowImage(t *testing.T) {
+	row, err := DecodeRowImage(`{"id": 42, "price": 9.5, "name": "x", "note": null}`)
+	if err != nil {
+		t.Fatalf("DecodeRowImage() falhou: %v", err)
+	}
+	want := Data{"id": int64(42), "price": 9.5, "name": "x", "note": nil}
+	if !reflect.DeepEqual(row, want) {
+		t.Errorf("DecodeRowImage() = %#v, esperado %#v", row, want)
+	}
+
+	for _, image := range []string{"", "null", "[1]"} {
+		if _, err := DecodeRowImage(image); err == nil {
+			t.Errorf("DecodeRowImage(%q) deveria falhar", image)
+		}
+	}
+}
+
+// TestValidateChangeCapture verifica as combinações inválidas de cdc.
+func TestValidateChangeCapture(t *testing.T) {
+	base := `sourceType: sqlite3
+sourceConnectionString: a.db
+destinationType: sqlite3
+destinationConnectionString: b.db
+`
+	tests := map[string]string{
+		"sourceTable: items\ncdc:\n  mode: triggers\n":                                                 "cdc.mode",
+		"sqlQuery: SELECT * FROM items\nprimaryKey: id\ncdc:\n  mode: triggers\n":                      "cdc.mode",
+		"sourceTable: items\nprimaryKey: id\ncdc:\n  mode: binlog\n":                                   "cdc.mode",
+		"sourceTable: items\nprimaryKey: id\ncdc:\n  mode: triggers\n  batchSize: -1\n":                "cdc.batchSize",
+		"sourceTable: items\nprimaryKey: id\njoins: [{table: b, condition: x}]\ncdc: {mode: triggers}": "cdc.mode",
+	}
+	for document, path := range tests {
+		errs := ValidateConfigData([]byte(base+document), "yaml")
+		if len(errs) != 1 || errs[0].Path != path {
+			t.Errorf("ValidateConfigData(%q) = %v, esperado erro em %s", document, errs, path)
+		}
+	}
+
+	if errs := ValidateConfigData([]byte(base+"sourceTable: items\nprimaryKey: id\ncdc:\n  mode: triggers\n"), "yaml"); len(errs) > 0 {
+		t.Errorf("ValidateConfigData() com cdc válido = %v", errs)
+	}
+}
@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"cmp"
+	"fmt"
+	. "github.com/faelmori/getl/etypes"
+	"slices"
+	"time"
+)
+
+// CheckpointKey retorna a coluna de origem que ordena a extração com checkpoint: Checkpoint.Key ou,
+// sem ela, PrimaryKey.
+func CheckpointKey(config Config) string {
+	return cmp.Or(config.Checkpoint.Key, config.PrimaryKey)
+}
+
+// BuildCheckpointQuery gera a consulta de extração ordenada pela chave de checkpoint (CheckpointKey).
+// Com lastKey, só as linhas com chave maior que a última confirmada são lidas.
+// Com sqlQuery, a consulta original é usada como subconsulta e a chave deve ser uma de suas colunas.
+func BuildCheckpointQuery(config Config, fields []string, lastKey interface{}) (string, []interface{}, error) {
+	key := CheckpointKey(config)
+	if key == "" {
+		return "", nil, fmt.Errorf("chave de checkpoint não informada")
+	}
+	if config.SQLQuery != "" {
+		fields = []string{"*"}
+	} else if len(fields) > 0 && !slices.Contains(fields, key) {
+		// A chave é lida mesmo sem transformação, para registrar a última linha de cada lote.
+		fields = append(slices.Clone(fields), key)
+	}
+
+	config.OrderBy = ""
+	query, err := extractBaseQuery(config, fields)
+	if err != nil {
+		return "", nil, err
+	}
+	if lastKey != nil {
+		query = query.Where(key+" > ?", lastKey)
+	}
+
+	sqlQuery, args, err := query.OrderBy(key).PlaceholderFormat(PlaceholderFormatFor(config.SourceType)).ToSql()
+	if err != nil {
+		return "", nil, fmt.Errorf("falha ao gerar a consulta de checkpoint: %w", err)
+	}
+	return sqlQuery, args, nil
+}
+
+// CheckpointKeyValue converte o valor da chave de checkpoint no texto gravado em etl_checkpoints.
+// Datas usam RFC 3339, que CheckpointKeyParam converte de volta em time.Time na retomada.
+func CheckpointKeyValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(value)
+}
+
+// CheckpointKeyParam converte o texto gravado por CheckpointKeyValue no parâmetro da consulta de
+// retomada. Uma data em RFC 3339 volta a ser time.Time, para que o banco a compare como um
+// instante, e não como texto em outro formato; os demais valores seguem como texto.
+func CheckpointKeyParam(lastKey string) interface{} {
+	if t, err := time.Parse(time.RFC3339Nano, lastKey); err == nil {
+		return t
+	}
+	return lastKey
+}
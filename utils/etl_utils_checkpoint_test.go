@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	. "github.com/faelmori/getl/etypes"
+)
+
+// TestBuildCheckpointQuery verifica a ordenação pela chave e a retomada após a última chave confirmada.
+func TestBuildCheckpointQuery(t *testing.T) {
+	config := Config{
+		SourceType:  "postgres",
+		SourceTable: "orders",
+		Where:       "status = 'open'",
+		OrderBy:     "created_at",
+		Checkpoint:  Checkpointing{BatchSize: 100, Key: "id"},
+	}
+
+	query, args, err := BuildCheckpointQuery(config, []string{"total"}, "42")
+	if err != nil {
+		t.Fatalf("BuildCheckpointQuery() falhou: %v", err)
+	}
+	if want := "SELECT total, id FROM orders WHERE status = 'open' AND id > $1 ORDER BY id"; query != want {
+		t.Errorf("consulta = %q, esperado %q", query, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"42"}) {
+		t.Errorf("argumentos = %v", args)
+	}
+
+	config.SQLQuery = "SELECT id, total FROM orders"
+	query, args, err = BuildCheckpointQuery(config, nil, nil)
+	if err != nil {
+		t.Fatalf("BuildCheckpointQuery() falhou: %v", err)
+	}
+	if want := "SELECT * FROM (SELECT id, total FROM orders) getl_partition ORDER BY id"; query != want || len(args) != 0 {
+		t.Errorf("consulta = %q %v, esperado %q", query, args, want)
+	}
+}
+
+// TestCheckpointKeyValue verifica o texto gravado para os tipos de chave mais comuns.
+func TestCheckpointKeyValue(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{nil, ""},
+		{int64(42), "42"},
+		{[]byte("A-01"), "A-01"},
+		{time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), "2024-03-01T12:00:00Z"},
+	}
+	for _, tt := range tests {
+		if got := CheckpointKeyValue(tt.value); got != tt.want {
+			t.Errorf("CheckpointKeyValue(%v) = %q, esperado %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+// TestCheckpointKeyParam verifica que as datas gravadas voltam a ser time.Time e os demais valores, texto.
+func TestCheckpointKeyParam(t *testing.T) {
+	at := time.Date(2024, 3, 1, 9, 0, 0, 500, time.FixedZone("BRT", -3*3600))
+	if got, ok := CheckpointKeyParam(CheckpointKeyValue(at)).(time.Time); !ok || !got.Equal(at) {
+		t.Errorf("CheckpointKeyParam(%q) = %v, esperado %v", CheckpointKeyValue(at), got, at)
+	}
+	for _, value := range []string{"42", "A-01", "2024-03-01"} {
+		if got := CheckpointKeyParam(value); got != value {
+			t.Errorf("CheckpointKeyParam(%q) = %v, esperado o texto", value, got)
+		}
+	}
+}
+
+// TestValidateCheckpoint verifica as combinações inválidas de checkpoint.
+func TestValidateCheckpoint(t *testing.T) {
+	base := `sourceType: sqlite3
+sourceConnectionString: a.db
+destinationType: sqlite3
+destinationConnectionString: b.db
+`
+	tests := map[string]string{
+		"checkpoint:\n  batchSize: -1\n":                                                      "checkpoint.batchSize",
+		"checkpoint:\n  key: id\n":                                                            "checkpoint.batchSize",
+		"orderBy: name\ncheckpoint:\n  batchSize: 10\n  key: id\n":                            "checkpoint.key",
+		"partitioning: {column: id, count: 2}\ncheckpoint: {batchSize: 10, key: id}\n":        "checkpoint",
+		"checkpoint:\n  batchSize: 10\n":                                                      "checkpoint.key",
+		"primaryKey: id\npartitioning: {column: id, count: 2}\ncheckpoint: {batchSize: 10}\n": "checkpoint",
+	}
+	for document, path := range tests {
+		errs := ValidateConfigData([]byte(base+document), "yaml")
+		if len(errs) != 1 || errs[0].Path != path {
+			t.Errorf("ValidateConfigData(%q) = %v, esperado erro em %s", document, errs, path)
+		}
+	}
+
+	if errs := ValidateConfigData([]byte(base+"orderBy: id\ncheckpoint:\n  batchSize: 10\n  key: id\n"), "yaml"); len(errs) > 0 {
+		t.Errorf("ValidateConfigData() com checkpoint válido = %v", errs)
+	}
+	// Sem key, a extração é ordenada por primaryKey.
+	if errs := ValidateConfigData([]byte(base+"primaryKey: id\ncheckpoint:\n  batchSize: 10\n"), "yaml"); len(errs) > 0 {
+		t.Errorf("ValidateConfigData() com checkpoint pela primaryKey = %v", errs)
+	}
+}
@@ -0,0 +1,170 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	. "github.com/faelmori/getl/etypes"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	toDebeziumOperation   = map[string]string{ChangeInsert: "c", ChangeUpdate: "u", ChangeDelete: "d"}
+	fromDebeziumOperation = map[string]string{"c": ChangeInsert, "r": ChangeInsert, "u": ChangeUpdate, "d": ChangeDelete}
+	databaseParamPattern  = regexp.MustCompile(`(?i)(?:^|[\s;?&])(dbname|database|initial catalog)=([^;&\s]+)`)
+	mysqlDatabasePattern  = regexp.MustCompile(`\)/([^?]*)`)
+)
+
+// DebeziumConnector retorna o nome do conector do Debezium equivalente ao driver de origem.
+func DebeziumConnector(driver string) string {
+	switch driver {
+	case "postgres":
+		return "postgresql"
+	case "sqlserver", "mssql":
+		return "sqlserver"
+	case "godror", "oracle":
+		return "oracle"
+	case "sqlite3":
+		return "sqlite"
+	}
+	return driver
+}
+
+// SourceDatabase retorna o nome do banco de origem, lido da connection string: o parâmetro dbname
+// ou database, o caminho da URL, o banco do DSN do MySQL, o serviço do Oracle ou o arquivo do SQLite.
+func SourceDatabase(config Config) string {
+	connectionString := config.SourceConnectionString
+	if match := databaseParamPattern.FindStringSubmatch(connectionString); match != nil {
+		return match[2]
+	}
+	switch config.SourceType {
+	case "sqlite3":
+		path := strings.TrimPrefix(strings.SplitN(connectionString, "?", 2)[0], "file:")
+		return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	case "mysql":
+		if match := mysqlDatabasePattern.FindStringSubmatch(connectionString); match != nil {
+			return match[1]
+		}
+	}
+	if u, err := url.Parse(connectionString); err == nil && u.Scheme != "" {
+		return strings.Trim(u.Path, "/")
+	}
+	if i := strings.LastIndex(connectionString, "/"); i >= 0 && i < len(connectionString)-1 {
+		return connectionString[i+1:]
+	}
+	return ""
+}
+
+// NewDebeziumEnvelope converte uma alteração capturada para o envelope do Debezium, produzido em now.
+func NewDebeziumEnvelope(config Config, event ChangeEvent, now time.Time) DebeziumEnvelope {
+	table := event.Table
+	if table == "" {
+		table = config.SourceTable
+	}
+	envelope := DebeziumEnvelope{
+		Before: event.Before,
+		After:  event.After,
+		Op:     toDebeziumOperation[event.Operation],
+		TsMs:   now.UnixMilli(),
+		Source: DebeziumSource{
+			Connector: DebeziumConnector(config.SourceType),
+			TsMs:      now.UnixMilli(),
+			Snapshot:  "false",
+			DB:        SourceDatabase(config),
+			Table:     table,
+			Position:  event.Position,
+		},
+	}
+	if !event.Timestamp.IsZero() {
+		envelope.Source.TsMs = event.Timestamp.UnixMilli()
+	}
+	return envelope
+}
+
+// NewDebeziumSnapshot envolve uma linha lida da origem pela carga como uma leitura ("r") da carga inicial.
+func NewDebeziumSnapshot(config Config, row Data, now time.Time) DebeziumEnvelope {
+	envelope := NewDebeziumEnvelope(config, ChangeEvent{Table: config.SourceTable, After: row}, now)
+	envelope.Op = "r"
+	envelope.Source.Snapshot = "true"
+	return envelope
+}
+
+// DecodeChangeMessage decodifica uma mensagem consumida como alteração. Aceita o envelope do
+// Debezium, com ou sem o schema (payload), a ChangeEvent publicada por cdc.sink kafka e a linha
+// publicada pela carga, que é uma inclusão ou atualização. As leituras ("r") do Debezium são
+// inclusões. Retorna false nas mensagens sem valor (tombstones), que não alteram o destino.
+func DecodeChangeMessage(value []byte) (ChangeEvent, bool, error) {
+	trimmed := bytes.TrimSpace(value)
+	if len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		return ChangeEvent{}, false, nil
+	}
+	message, decodeErr := DecodeMessageRow(trimmed)
+	if decodeErr != nil {
+		return ChangeEvent{}, false, decodeErr
+	}
+	if payload, ok := message["payload"].(map[string]interface{}); ok {
+		if _, hasSchema := message["schema"]; hasSchema {
+			normalizeJSONNumbers(payload)
+			message = payload
+		}
+	}
+
+	op, _ := message["op"].(string)
+	_, hasBefore := message["before"]
+	_, hasAfter := message["after"]
+	if op == "" || (!hasBefore && !hasAfter) {
+		return ChangeEvent{Operation: ChangeUpdate, After: message}, true, nil
+	}
+
+	event := ChangeEvent{Operation: op}
+	var imageErr error
+	if event.Before, imageErr = messageImage(message["before"]); imageErr == nil {
+		event.After, imageErr = messageImage(message["after"])
+	}
+	if imageErr != nil {
+		return ChangeEvent{}, false, imageErr
+	}
+	if operation, ok := fromDebeziumOperation[op]; ok {
+		event.Operation = operation
+		source, _ := message["source"].(map[string]interface{})
+		event.Table, _ = source["table"].(string)
+		event.Position, _ = source["position"].(string)
+		if ts, ok := source["ts_ms"].(json.Number); ok {
+			if ms, tsErr := ts.Int64(); tsErr == nil {
+				event.Timestamp = time.UnixMilli(ms).UTC()
+			}
+		}
+	} else if op == ChangeInsert || op == ChangeUpdate || op == ChangeDelete {
+		event.Table, _ = message["table"].(string)
+		event.Position, _ = message["position"].(string)
+		if ts, ok := message["ts"].(string); ok {
+			event.Timestamp, _ = time.Parse(time.RFC3339Nano, ts)
+		}
+	} else {
+		return ChangeEvent{}, false, fmt.Errorf("operação desconhecida na mensagem: %s", op)
+	}
+
+	if event.Operation == ChangeDelete && event.Before == nil {
+		return ChangeEvent{}, false, fmt.Errorf("exclusão sem a imagem anterior (before) da linha")
+	}
+	if event.Operation != ChangeDelete && event.After == nil {
+		return ChangeEvent{}, false, fmt.Errorf("alteração %s sem a nova imagem (after) da linha", op)
+	}
+	return event, true, nil
+}
+
+// messageImage converte a imagem before ou after de uma alteração.
+func messageImage(value interface{}) (Data, error) {
+	switch image := value.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		normalizeJSONNumbers(image)
+		return image, nil
+	}
+	return nil, fmt.Errorf("imagem da linha não é um objeto JSON: %v", value)
+}
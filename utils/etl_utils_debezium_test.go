@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	. "github.com/faelmori/getl/etypes"
+)
+
+// TestSourceDatabase verifica o nome do banco lido de cada formato de connection string.
+func TestSourceDatabase(t *testing.T) {
+	tests := map[string]Config{
+		"shop":   {SourceType: "postgres", SourceConnectionString: "postgres://user:pw@localhost:5432/shop?sslmode=disable"},
+		"sales":  {SourceType: "postgres", SourceConnectionString: "host=localhost dbname=sales user=app"},
+		"testdb": {SourceType: "mysql", SourceConnectionString: "user:password@tcp(localhost:3306)/testdb?parseTime=true"},
+		"erp":    {SourceType: "sqlserver", SourceConnectionString: "sqlserver://sa:pw@localhost:1433?database=erp"},
+		"ORCL":   {SourceType: "godror", SourceConnectionString: "user/pw@localhost:1521/ORCL"},
+		"items":  {SourceType: "sqlite3", SourceConnectionString: "file:/tmp/items.db?cache=shared"},
+	}
+	for want, config := range tests {
+		if got := SourceDatabase(config); got != want {
+			t.Errorf("SourceDatabase(%s) = %q, esperado %q", config.SourceConnectionString, got, want)
+		}
+	}
+}
+
+// TestNewDebeziumEnvelope verifica o envelope das alterações e das linhas da carga.
+func TestNewDebeziumEnvelope(t *testing.T) {
+	config := Config{SourceType: "mysql", SourceConnectionString: "u:p@tcp(db:3306)/shop", SourceTable: "items"}
+	now := time.UnixMilli(2000)
+	event := ChangeEvent{Operation: ChangeUpdate, Table: "items", Position: "binlog.000003:1200", Timestamp: time.UnixMilli(1000),
+		Before: Data{"id": 1, "name": "a"}, After: Data{"id": 1, "name": "b"}}
+	envelope := NewDebeziumEnvelope(config, event, now)
+	want := DebeziumEnvelope{Before: event.Before, After: event.After, Op: "u", TsMs: 2000,
+		Source: DebeziumSource{Connector: "mysql", TsMs: 1000, Snapshot: "false", DB: "shop", Table: "items", Position: "binlog.000003:1200"}}
+	if !reflect.DeepEqual(envelope, want) {
+		t.Errorf("NewDebeziumEnvelope() = %+v", envelope)
+	}
+	snapshot := NewDebeziumSnapshot(config, Data{"id": 2}, now)
+	if snapshot.Op != "r" || snapshot.Source.Snapshot != "true" || snapshot.Before != nil || snapshot.Source.TsMs != 2000 {
+		t.Errorf("NewDebeziumSnapshot() = %+v", snapshot)
+	}
+}
+
+// TestDecodeChangeMessage verifica os formatos aceitos pelo consumo.
+func TestDecodeChangeMessage(t *testing.T) {
+	config := Config{SourceType: "postgres", SourceConnectionString: "dbname=shop", SourceTable: "items"}
+	deleted, _ := json.Marshal(NewDebeziumEnvelope(config, ChangeEvent{Operation: ChangeDelete, Position: "0/16B3748", Timestamp: time.UnixMilli(1000), Before: Data{"id": 7}}, time.Now()))
+	getlEvent, _ := json.Marshal(ChangeEvent{Operation: ChangeInsert, Table: "items", After: Data{"id": 8}})
+	tests := map[string]ChangeEvent{
+		string(deleted): {Operation: ChangeDelete, Table: "items", Position: "0/16B3748", Timestamp: time.UnixMilli(1000).UTC(), Before: Data{"id": int64(7)}},
+		`{"schema": {}, "payload": {"before": null, "after": {"id": 3, "price": 1.5}, "op": "r", "source": {"table": "items"}}}`: {Operation: ChangeInsert, Table: "items", After: Data{"id": int64(3), "price": 1.5}},
+		`{"before": {"id": 4}, "after": {"id": 4}, "op": "u", "source": {}}`:                                                     {Operation: ChangeUpdate, Before: Data{"id": int64(4)}, After: Data{"id": int64(4)}},
+		string(getlEvent):            {Operation: ChangeInsert, Table: "items", After: Data{"id": int64(8)}},
+		`{"id": 5, "op": "c"}`:       {Operation: ChangeUpdate, After: Data{"id": int64(5), "op": "c"}},
+		`{"id": 6, "name": "linha"}`: {Operation: ChangeUpdate, After: Data{"id": int64(6), "name": "linha"}},
+	}
+	for message, want := range tests {
+		event, ok, err := DecodeChangeMessage([]byte(message))
+		if err != nil || !ok || !reflect.DeepEqual(event, want) {
+			t.Errorf("DecodeChangeMessage(%s) = %+v, %v, %v", message, event, ok, err)
+		}
+	}
+
+	for _, tombstone := range []string{"", " null "} {
+		if _, ok, err := DecodeChangeMessage([]byte(tombstone)); ok || err != nil {
+			t.Errorf("DecodeChangeMessage(%q) = %v, %v; esperado tombstone", tombstone, ok, err)
+		}
+	}
+	for _, invalid := range []string{`{"before": null, "after": null, "op": "d"}`, `{"before": null, "after": null, "op": "c"}`, `{"after": {"id": 1}, "op": "x"}`, `{"after": [1], "op": "c"}`, `[1]`} {
+		if _, _, err := DecodeChangeMessage([]byte(invalid)); err == nil {
+			t.Errorf("DecodeChangeMessage(%s) sem erro", invalid)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"cmp"
+	"fmt"
+	. "github.com/faelmori/getl/etypes"
+	"strconv"
+	"time"
+)
+
+// DefaultDeleteColumn é a coluna marcada na exclusão lógica quando DeletePropagation.Column não é informada.
+const DefaultDeleteColumn = "deleted_at"
+
+const defaultDeleteMaxPercent = 10
+
+// DeleteColumn retorna a coluna de destino marcada na exclusão lógica.
+func DeleteColumn(config Config) string {
+	return cmp.Or(config.Deletes.Column, DefaultDeleteColumn)
+}
+
+// DeleteColumnType retorna o tipo genérico da coluna de exclusão lógica, convertido pelo mapeamento do destino.
+func DeleteColumnType(config Config) string {
+	if config.Deletes.Flag {
+		return "BOOLEAN"
+	}
+	return "TIMESTAMP"
+}
+
+// DeletedValue retorna o valor gravado na coluna de exclusão lógica das linhas excluídas em now.
+func DeletedValue(config Config, now time.Time) interface{} {
+	if config.Deletes.Flag {
+		return true
+	}
+	return now.UTC()
+}
+
+// ActiveValue retorna o valor da coluna de exclusão lógica nas linhas presentes na origem.
+func ActiveValue(config Config) interface{} {
+	if config.Deletes.Flag {
+		return false
+	}
+	return nil
+}
+
+// DeleteMaxPercent retorna o percentual máximo de linhas do destino que a comparação de chaves pode excluir.
+func DeleteMaxPercent(config Config) int {
+	if config.Deletes.MaxPercent > 0 {
+		return config.Deletes.MaxPercent
+	}
+	return defaultDeleteMaxPercent
+}
+
+// DeleteKeyString normaliza o valor de uma chave para comparar as lidas da origem e do destino, que
+// os drivers podem retornar em tipos diferentes (e.g. int64 e float64 ou string e []byte).
+func DeleteKeyString(value interface{}) string {
+	switch v := value.(type) {
+	case []byte:
+		return string(v)
+	case float32:
+		return DeleteKeyString(float64(v))
+	case float64:
+		if v == float64(int64(v)) {
+			return strconv.FormatInt(int64(v), 10)
+		}
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return CheckpointKeyValue(value)
+}
+
+// MissingKeys retorna, na ordem de destination, as chaves do destino ausentes das linhas extraídas,
+// que têm a chave de destino em key.
+func MissingKeys(destination []interface{}, rows []Data, key string) []interface{} {
+	present := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		if value, ok := row[key]; ok && value != nil {
+			present[DeleteKeyString(value)] = true
+		}
+	}
+	var missing []interface{}
+	for _, value := range destination {
+		if !present[DeleteKeyString(value)] {
+			missing = append(missing, value)
+		}
+	}
+	return missing
+}
+
+// CheckDeleteThreshold retorna um erro se excluir deleted das total linhas do destino passa do
+// percentual máximo: uma extração vazia ou incompleta não deve esvaziar o destino.
+func CheckDeleteThreshold(config Config, deleted, total int) error {
+	if deleted == 0 || total == 0 {
+		return nil
+	}
+	maxPercent := DeleteMaxPercent(config)
+	if deleted*100 > total*maxPercent {
+		return fmt.Errorf("a comparação de chaves excluiria %d de %d linha(s) de %s (%.1f%%), acima do limite de %d%% (deletes.maxPercent)",
+			deleted, total, config.DestinationTable, float64(deleted)*100/float64(total), maxPercent)
+	}
+	return nil
+}
+
+// AddColumnStatement monta o ALTER TABLE que acrescenta uma coluna à tabela, no dialeto do driver.
+func AddColumnStatement(driver, table, column, columnType string) string {
+	switch driver {
+	case "godror", "oracle":
+		return fmt.Sprintf("ALTER TABLE %s ADD (%s %s)", table, column, columnType)
+	case "sqlserver", "mssql":
+		return fmt.Sprintf("ALTER TABLE %s ADD %s %s", table, column, columnType)
+	}
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, columnType)
+}
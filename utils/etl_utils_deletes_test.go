@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	. "github.com/faelmori/getl/etypes"
+)
+
+// TestMissingKeys verifica a comparação das chaves mesmo quando os drivers as retornam em tipos diferentes.
+func TestMissingKeys(t *testing.T) {
+	destination := []interface{}{int64(1), float64(2), []byte("a"), "b", int64(5)}
+	rows := []Data{{"id": int64(2)}, {"id": "a"}, {"id": []byte("b")}, {"id": nil}, {"other": int64(5)}}
+	if missing := MissingKeys(destination, rows, "id"); !reflect.DeepEqual(missing, []interface{}{int64(1), int64(5)}) {
+		t.Errorf("MissingKeys() = %v", missing)
+	}
+	if missing := MissingKeys(nil, rows, "id"); missing != nil {
+		t.Errorf("MissingKeys() sem destino = %v", missing)
+	}
+	if key := DeleteKeyString(2.5); key != "2.5" {
+		t.Errorf("DeleteKeyString(2.5) = %s", key)
+	}
+}
+
+// TestCheckDeleteThreshold verifica o limite padrão e o configurado.
+func TestCheckDeleteThreshold(t *testing.T) {
+	config := Config{DestinationTable: "items"}
+	if err := CheckDeleteThreshold(config, 10, 100); err != nil {
+		t.Errorf("CheckDeleteThreshold(10 de 100) = %v", err)
+	}
+	if err := CheckDeleteThreshold(config, 11, 100); err == nil {
+		t.Errorf("CheckDeleteThreshold(11 de 100) sem erro com o limite padrão")
+	}
+	if err := CheckDeleteThreshold(config, 0, 0); err != nil {
+		t.Errorf("CheckDeleteThreshold(0 de 0) = %v", err)
+	}
+	config.Deletes.MaxPercent = 100
+	if err := CheckDeleteThreshold(config, 100, 100); err != nil {
+		t.Errorf("CheckDeleteThreshold(100 de 100) com maxPercent 100 = %v", err)
+	}
+}
+
+// TestSoftDeleteValues verifica a coluna e os valores da exclusão lógica por horário e por flag.
+func TestSoftDeleteValues(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.FixedZone("BRT", -3*3600))
+	config := Config{Deletes: DeletePropagation{Detect: "keys", Mode: "soft"}}
+	if DeleteColumn(config) != "deleted_at" || DeleteColumnType(config) != "TIMESTAMP" || ActiveValue(config) != nil {
+		t.Errorf("exclusão lógica por horário: %s %s %v", DeleteColumn(config), DeleteColumnType(config), ActiveValue(config))
+	}
+	if value := DeletedValue(config, now); value != now.UTC() {
+		t.Errorf("DeletedValue() = %v", value)
+	}
+	config.Deletes.Column, config.Deletes.Flag = "removed", true
+	if DeleteColumn(config) != "removed" || DeleteColumnType(config) != "BOOLEAN" || ActiveValue(config) != false || DeletedValue(config, now) != true {
+		t.Errorf("exclusão lógica por flag: %s %s %v", DeleteColumn(config), DeleteColumnType(config), ActiveValue(config))
+	}
+}
+
+// TestAddColumnStatement verifica o ALTER TABLE de cada dialeto.
+func TestAddColumnStatement(t *testing.T) {
+	tests := map[string]string{
+		"sqlite3":   "ALTER TABLE items ADD COLUMN deleted_at TEXT",
+		"godror":    "ALTER TABLE items ADD (deleted_at TEXT)",
+		"sqlserver": "ALTER TABLE items ADD deleted_at TEXT",
+	}
+	for driver, want := range tests {
+		if got := AddColumnStatement(driver, "items", "deleted_at", "TEXT"); got != want {
+			t.Errorf("AddColumnStatement(%s) = %s", driver, got)
+		}
+	}
+}
+
+// TestValidateDeletes verifica as combinações inválidas de deletes.
+func TestValidateDeletes(t *testing.T) {
+	base := `sourceType: sqlite3
+sourceConnectionString: a.db
+destinationType: sqlite3
+destinationConnectionString: b.db
+sourceTable: items
+`
+	tests := map[string]string{
+		"deletes:\n  detect: keys\n":                                          "deletes.detect",
+		"primaryKey: id\ndeletes:\n  mode: soft\n":                            "deletes.detect",
+		"primaryKey: id\ndeletes:\n  detect: rows\n":                          "deletes.detect",
+		"primaryKey: id\ndeletes:\n  detect: keys\n  maxPercent: 150\n":       "deletes.maxPercent",
+		"primaryKey: id\ndeletes:\n  detect: keys\n  column: removed_at\n":    "deletes.column",
+		"primaryKey: id\ndeletes:\n  detect: cdc\n  mode: soft\n":             "deletes.detect",
+		"primaryKey: id\ndeletes:\n  detect: keys\n  mode: soft\n  flag: 1\n": "deletes.flag",
+	}
+	for document, path := range tests {
+		errs := ValidateConfigData([]byte(base+document), "yaml")
+		if len(errs) != 1 || errs[0].Path != path {
+			t.Errorf("ValidateConfigData(%q) = %v, esperado erro em %s", document, errs, path)
+		}
+	}
+
+	valid := []string{
+		"primaryKey: id\ndeletes:\n  detect: keys\n",
+		"primaryKey: id\ndeletes:\n  detect: keys\n  mode: soft\n  column: removed\n  flag: true\n  maxPercent: 100\n",
+		"primaryKey: id\ncdc:\n  mode: triggers\ndeletes:\n  detect: cdc\n  mode: soft\n",
+	}
+	for _, document := range valid {
+		if errs := ValidateConfigData([]byte(base+document), "yaml"); len(errs) > 0 {
+			t.Errorf("ValidateConfigData(%q) = %v", document, errs)
+		}
+	}
+}
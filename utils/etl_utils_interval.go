@@ -0,0 +1,202 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronField descreve um campo de uma expressão cron: seu intervalo válido e a duração de uma unidade.
+type cronField struct {
+	name     string
+	min, max int
+	unit     time.Duration
+}
+
+var cronFieldsWithSeconds = []cronField{
+	{"segundo", 0, 59, time.Second},
+	{"minuto", 0, 59, time.Minute},
+	{"hora", 0, 23, time.Hour},
+	{"dia do mês", 1, 31, 24 * time.Hour},
+	{"mês", 1, 12, 30 * 24 * time.Hour},
+	{"dia da semana", 0, 7, 7 * 24 * time.Hour},
+}
+
+// ParseSyncInterval interpreta o valor de syncInterval e retorna o intervalo entre execuções.
+// Aceita durações Go ("30s", "5m"), um número inteiro de segundos, "@every <duração>",
+// "@hourly", "@daily" e expressões cron de 5 ou 6 campos (com segundos) que se repetem em um
+// intervalo fixo: "*/15 * * * *" a cada 15 minutos, "0 3 * * *" a cada dia e "0 0 * * 1" a cada
+// semana. Expressões mensais, listas e faixas de valores são rejeitadas.
+func ParseSyncInterval(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, fmt.Errorf("intervalo de sincronização vazio")
+	}
+
+	switch value {
+	case "@hourly":
+		return time.Hour, nil
+	case "@daily", "@midnight":
+		return 24 * time.Hour, nil
+	case "@weekly":
+		return 7 * 24 * time.Hour, nil
+	}
+
+	if every, ok := strings.CutPrefix(value, "@every "); ok {
+		value = strings.TrimSpace(every)
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds <= 0 {
+			return 0, fmt.Errorf("intervalo de sincronização deve ser positivo: %s", value)
+		}
+		return time.Duration(seconds) * time.Second, nil
+	}
+
+	if duration, err := time.ParseDuration(value); err == nil {
+		if duration <= 0 {
+			return 0, fmt.Errorf("intervalo de sincronização deve ser positivo: %s", value)
+		}
+		return duration, nil
+	}
+
+	return parseCronInterval(value)
+}
+
+// parseCronInterval valida a expressão cron campo a campo e calcula o intervalo entre execuções.
+// O campo mais amplo restrito define o intervalo: com "*/n", n unidades do campo; com um valor
+// fixo, a unidade do campo seguinte. Os campos mais finos precisam ter um único valor e os dias e
+// meses, sob o dia da semana, precisam ser "*".
+func parseCronInterval(expression string) (time.Duration, error) {
+	parts := strings.Fields(expression)
+	var fields []cronField
+	switch len(parts) {
+	case 6:
+		fields = cronFieldsWithSeconds
+	case 5:
+		fields = cronFieldsWithSeconds[1:]
+	default:
+		return 0, fmt.Errorf("intervalo de sincronização inválido: %q não é uma duração nem uma expressão cron", expression)
+	}
+
+	steps := make([]int, len(parts))
+	restricted := make([]bool, len(parts))
+	widest := -1
+	for i, part := range parts {
+		step, fixed, err := parseCronField(part, fields[i])
+		if err != nil {
+			return 0, fmt.Errorf("intervalo de sincronização inválido: %w", err)
+		}
+		steps[i], restricted[i] = step, step > 0 || fixed
+		if restricted[i] {
+			widest = i
+		}
+	}
+	if widest < 0 {
+		return fields[0].unit, nil
+	}
+
+	notFixed := fmt.Errorf("intervalo de sincronização inválido: %q não se repete em um intervalo fixo; use uma duração (e.g. 24h)", expression)
+	for i := range widest {
+		if fields[i].unit >= 24*time.Hour {
+			if restricted[i] {
+				return 0, notFixed
+			}
+		} else if !singleCronValue(parts[i]) {
+			return 0, notFixed
+		}
+	}
+	field := fields[widest]
+	switch {
+	case field.unit == 24*time.Hour || field.unit == 30*24*time.Hour:
+		// O dia do mês e o mês se repetem a cada mês ou ano, que não têm duração fixa.
+		return 0, notFixed
+	case steps[widest] > 0 && field.unit < 24*time.Hour:
+		if (field.max-field.min+1)%steps[widest] != 0 {
+			return 0, notFixed
+		}
+		return time.Duration(steps[widest]) * field.unit, nil
+	case singleCronValue(parts[widest]) && widest+1 < len(fields):
+		return fields[widest+1].unit, nil
+	case singleCronValue(parts[widest]):
+		return field.unit, nil
+	}
+	return 0, notFixed
+}
+
+// singleCronValue verifica se o campo cron tem um único valor.
+func singleCronValue(part string) bool {
+	_, err := strconv.Atoi(part)
+	return err == nil
+}
+
+// parseCronField valida um campo cron ("*", "*/n", "a", "a-b", "a-b/n" ou listas separadas por vírgula).
+// Retorna o passo, quando o campo é da forma "*/n", e se o campo restringe valores.
+func parseCronField(part string, field cronField) (int, bool, error) {
+	if part == "*" || part == "?" {
+		return 0, false, nil
+	}
+
+	if stepValue, ok := strings.CutPrefix(part, "*/"); ok {
+		step, err := strconv.Atoi(stepValue)
+		if err != nil || step <= 0 || step > field.max {
+			return 0, false, fmt.Errorf("passo inválido no campo %s: %s", field.name, part)
+		}
+		return step, false, nil
+	}
+
+	for _, item := range strings.Split(part, ",") {
+		rangeValue, stepValue, hasStep := strings.Cut(item, "/")
+		if hasStep {
+			if step, err := strconv.Atoi(stepValue); err != nil || step <= 0 {
+				return 0, false, fmt.Errorf("passo inválido no campo %s: %s", field.name, item)
+			}
+		}
+		low, high, isRange := strings.Cut(rangeValue, "-")
+		bounds := []string{low}
+		if isRange {
+			bounds = append(bounds, high)
+		}
+		for _, bound := range bounds {
+			number, err := strconv.Atoi(bound)
+			if err != nil || number < field.min || number > field.max {
+				return 0, false, fmt.Errorf("valor fora do intervalo %d-%d no campo %s: %s", field.min, field.max, field.name, item)
+			}
+		}
+	}
+
+	return 0, true, nil
+}
+
+// ParseStageTimeout interpreta o timeout de uma etapa (Config.Timeouts). Vazio significa sem limite.
+func ParseStageTimeout(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("timeout inválido: %q não é uma duração (e.g. 30s, 5m)", value)
+	}
+	if timeout <= 0 {
+		return 0, fmt.Errorf("timeout deve ser positivo: %s", value)
+	}
+	return timeout, nil
+}
+
+// WithStageTimeout deriva de ctx um contexto limitado pelo timeout da etapa.
+// Sem timeout, o contexto retornado só é cancelado junto com ctx ou pela CancelFunc.
+func WithStageTimeout(ctx context.Context, timeout string) (context.Context, context.CancelFunc, error) {
+	duration, err := ParseStageTimeout(timeout)
+	if err != nil {
+		return ctx, func() {}, err
+	}
+	if duration == 0 {
+		stageCtx, cancel := context.WithCancel(ctx)
+		return stageCtx, cancel, nil
+	}
+	stageCtx, cancel := context.WithTimeout(ctx, duration)
+	return stageCtx, cancel, nil
+}
@@ -0,0 +1,463 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	. "github.com/faelmori/getl/etypes"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultConsumerBatchSize     = 100
+	defaultConsumerFlushInterval = time.Second
+	defaultProducerBatchSize     = 100
+	defaultRetryAttempts         = 3
+	defaultRetryBackoff          = 5 * time.Second
+	defaultTransactionTimeout    = time.Minute
+)
+
+// Cabeçalhos que identificam a origem das mensagens publicadas.
+const (
+	SourceTableHeader = "getl-source-table"
+	RunIDHeader       = "getl-run-id"
+)
+
+// Cabeçalhos das mensagens publicadas nos tópicos de retentativa e de dead-letter: a origem da
+// mensagem, a tentativa, quando ela deve ser feita e o último erro.
+const (
+	OriginalTopicHeader     = "getl-original-topic"
+	OriginalPartitionHeader = "getl-original-partition"
+	OriginalOffsetHeader    = "getl-original-offset"
+	RetryAttemptHeader      = "getl-retry-attempt"
+	RetryAtHeader           = "getl-retry-at"
+	ErrorHeader             = "getl-error"
+	ErrorStageHeader        = "getl-error-stage"
+	FailedAtHeader          = "getl-failed-at"
+)
+
+// FailureHeaders lista os cabeçalhos acrescentados às mensagens que falharam no consumo, removidos
+// quando elas são publicadas de novo no tópico de origem.
+var FailureHeaders = []string{OriginalTopicHeader, OriginalPartitionHeader, OriginalOffsetHeader, RetryAttemptHeader, RetryAtHeader, ErrorHeader, ErrorStageHeader, FailedAtHeader}
+
+// KafkaBrokers retorna os brokers de kafka.brokers ou, sem eles, os de kafkaURL, separados por vírgulas.
+func KafkaBrokers(config Config) []string {
+	if len(config.Kafka.Brokers) > 0 {
+		return config.Kafka.Brokers
+	}
+	return SplitKafkaBrokers(config.KafkaURL)
+}
+
+// SplitKafkaBrokers separa uma lista de brokers separados por vírgulas, ignorando os espaços e os itens vazios.
+func SplitKafkaBrokers(brokers string) []string {
+	var result []string
+	for _, broker := range strings.Split(brokers, ",") {
+		if broker = strings.TrimSpace(broker); broker != "" {
+			result = append(result, broker)
+		}
+	}
+	return result
+}
+
+// KafkaTLSConfig retorna a configuração TLS de kafka.tls, ou nil sem TLS. Os certificados são
+// lidos dos arquivos a cada chamada.
+func KafkaTLSConfig(config Config) (*tls.Config, error) {
+	options := config.Kafka.TLS
+	if !options.Enabled && options.CAFile == "" && options.CertFile == "" && options.KeyFile == "" {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         options.ServerName,
+		InsecureSkipVerify: options.InsecureSkipVerify,
+	}
+	if options.CAFile != "" {
+		ca, readErr := os.ReadFile(options.CAFile)
+		if readErr != nil {
+			return nil, fmt.Errorf("kafka.tls.caFile: %w", readErr)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("kafka.tls.caFile: nenhum certificado PEM em %s", options.CAFile)
+		}
+	}
+	if options.CertFile != "" || options.KeyFile != "" {
+		certificate, loadErr := tls.LoadX509KeyPair(options.CertFile, options.KeyFile)
+		if loadErr != nil {
+			return nil, fmt.Errorf("kafka.tls.certFile e keyFile: %w", loadErr)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+	return tlsConfig, nil
+}
+
+// ReaderCommitInterval retorna o intervalo de kafka.reader.commitInterval, ou zero para confirmar
+// os offsets a cada lote.
+func ReaderCommitInterval(config Config) (time.Duration, error) {
+	if config.Kafka.Reader.CommitInterval == "" {
+		return 0, nil
+	}
+	interval, err := ParseStageTimeout(config.Kafka.Reader.CommitInterval)
+	if err != nil {
+		return 0, fmt.Errorf("kafka.reader.commitInterval: %w", err)
+	}
+	return interval, nil
+}
+
+// StartPosition é a posição para a qual os offsets de um grupo são redefinidos: o início ou o fim de
+// cada partição, a primeira mensagem a partir de Time ou os Offsets informados por partição.
+type StartPosition struct {
+	Earliest bool
+	Latest   bool
+	Time     time.Time
+	// Offsets mapeia as partições aos seus offsets; as partições ausentes mantêm o offset confirmado.
+	Offsets map[int]int64
+}
+
+// ParseStartPosition lê uma posição de início: "earliest", "latest", um instante RFC 3339 (ou uma
+// data, 2006-01-02, em UTC) ou uma lista partição:offset separada por vírgulas, e.g. "0:120,1:98".
+func ParseStartPosition(value string) (StartPosition, error) {
+	value = strings.TrimSpace(value)
+	switch value {
+	case "earliest":
+		return StartPosition{Earliest: true}, nil
+	case "latest":
+		return StartPosition{Latest: true}, nil
+	case "":
+		return StartPosition{}, fmt.Errorf("posição de início vazia")
+	}
+	for _, layout := range []string{time.RFC3339Nano, time.DateOnly} {
+		if at, err := time.Parse(layout, value); err == nil {
+			return StartPosition{Time: at}, nil
+		}
+	}
+	position := StartPosition{Offsets: map[int]int64{}}
+	for _, item := range strings.Split(value, ",") {
+		partitionText, offsetText, found := strings.Cut(strings.TrimSpace(item), ":")
+		partition, partitionErr := strconv.Atoi(partitionText)
+		offset, offsetErr := strconv.ParseInt(offsetText, 10, 64)
+		if !found || partitionErr != nil || offsetErr != nil || partition < 0 || offset < 0 {
+			return StartPosition{}, fmt.Errorf("posição de início inválida: %q (use earliest, latest, um instante RFC 3339 ou partição:offset,...)", value)
+		}
+		if _, repeated := position.Offsets[partition]; repeated {
+			return StartPosition{}, fmt.Errorf("partição %d repetida em %q", partition, value)
+		}
+		position.Offsets[partition] = offset
+	}
+	return position, nil
+}
+
+// String retorna a posição no formato lido por ParseStartPosition.
+func (p StartPosition) String() string {
+	switch {
+	case p.Earliest:
+		return "earliest"
+	case p.Latest:
+		return "latest"
+	case !p.Time.IsZero():
+		return p.Time.Format(time.RFC3339Nano)
+	}
+	items := make([]string, 0, len(p.Offsets))
+	for _, partition := range slices.Sorted(maps.Keys(p.Offsets)) {
+		items = append(items, fmt.Sprintf("%d:%d", partition, p.Offsets[partition]))
+	}
+	return strings.Join(items, ",")
+}
+
+// ConsumerBatchSize retorna o máximo de mensagens gravadas em cada transação do consumo.
+func ConsumerBatchSize(config Config) int {
+	if config.Kafka.Consumer.BatchSize > 0 {
+		return config.Kafka.Consumer.BatchSize
+	}
+	return defaultConsumerBatchSize
+}
+
+// ConsumerFlushInterval retorna quanto um lote incompleto espera por mais mensagens.
+func ConsumerFlushInterval(config Config) (time.Duration, error) {
+	if config.Kafka.Consumer.FlushInterval == "" {
+		return defaultConsumerFlushInterval, nil
+	}
+	interval, err := ParseStageTimeout(config.Kafka.Consumer.FlushInterval)
+	if err != nil {
+		return 0, fmt.Errorf("kafka.consumer.flushInterval: %w", err)
+	}
+	return interval, nil
+}
+
+// RetryTopic retorna o tópico das novas tentativas do consumo: kafka.consumer.retry.topic ou "<kafkaTopic>-retry".
+func RetryTopic(config Config) string {
+	if config.Kafka.Consumer.Retry.Topic != "" {
+		return config.Kafka.Consumer.Retry.Topic
+	}
+	return config.KafkaTopic + "-retry"
+}
+
+// RetryAttempts retorna o máximo de novas tentativas de cada mensagem antes do dead-letter.
+func RetryAttempts(config Config) int {
+	if config.Kafka.Consumer.Retry.Attempts > 0 {
+		return config.Kafka.Consumer.Retry.Attempts
+	}
+	return defaultRetryAttempts
+}
+
+// RetryBackoff retorna a espera antes da nova tentativa attempt (a partir de 1): o backoff
+// configurado, dobrado a cada tentativa.
+func RetryBackoff(config Config, attempt int) (time.Duration, error) {
+	backoff := defaultRetryBackoff
+	if config.Kafka.Consumer.Retry.Backoff != "" {
+		var err error
+		if backoff, err = ParseStageTimeout(config.Kafka.Consumer.Retry.Backoff); err != nil {
+			return 0, fmt.Errorf("kafka.consumer.retry.backoff: %w", err)
+		}
+	}
+	for ; attempt > 1 && backoff < 24*time.Hour; attempt-- {
+		backoff *= 2
+	}
+	return backoff, nil
+}
+
+// ConsumerTopics retorna os tópicos consumidos no kafkaGroupID: kafkaTopic e os das rotas de
+// kafka.consumer.routes, sem repetições.
+func ConsumerTopics(config Config) []string {
+	var topics []string
+	if config.KafkaTopic != "" {
+		topics = append(topics, config.KafkaTopic)
+	}
+	for _, route := range config.Kafka.Consumer.Routes {
+		if route.Topic != "" && !slices.Contains(topics, route.Topic) {
+			topics = append(topics, route.Topic)
+		}
+	}
+	return topics
+}
+
+// MatchKafkaRoute retorna o índice da primeira rota que atende a uma mensagem de topic, com os
+// cabeçalhos headers e a linha decodificada row, ou -1 se nenhuma a atender.
+func MatchKafkaRoute(routes []KafkaRoute, topic string, headers map[string]string, row Data) int {
+	for i, route := range routes {
+		if route.Topic != "" && route.Topic != topic {
+			continue
+		}
+		matches := true
+		for name, value := range route.Headers {
+			if header, ok := headers[name]; !ok || header != value {
+				matches = false
+				break
+			}
+		}
+		for name, value := range route.Fields {
+			if field, ok := row[name]; !ok || field == nil || fmt.Sprint(field) != value {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return i
+		}
+	}
+	return -1
+}
+
+// RouteConfig retorna a Config da gravação das mensagens da rota: a da pipeline com a tabela, a
+// chave e as transformações da rota.
+func RouteConfig(config Config, route KafkaRoute) Config {
+	routeConfig := config
+	routeConfig.DestinationTable = route.Table
+	routeConfig.Transformations = route.Transformations
+	routeConfig.UpdateKey = ""
+	if route.PrimaryKey != "" {
+		routeConfig.PrimaryKey = route.PrimaryKey
+	}
+	return routeConfig
+}
+
+// StreamTransactionalID retorna o id do produtor transacional de getl stream:
+// kafka.stream.transactionalID ou "getl-stream-<kafkaGroupID>".
+func StreamTransactionalID(config Config) string {
+	if config.Kafka.Stream.TransactionalID != "" {
+		return config.Kafka.Stream.TransactionalID
+	}
+	return "getl-stream-" + config.KafkaGroupID
+}
+
+// StreamTransactionTimeout retorna o tempo máximo de cada transação de getl stream.
+func StreamTransactionTimeout(config Config) (time.Duration, error) {
+	if config.Kafka.Stream.TransactionTimeout == "" {
+		return defaultTransactionTimeout, nil
+	}
+	timeout, err := ParseStageTimeout(config.Kafka.Stream.TransactionTimeout)
+	if err != nil {
+		return 0, fmt.Errorf("kafka.stream.transactionTimeout: %w", err)
+	}
+	return timeout, nil
+}
+
+// DedupPath retorna o arquivo do estado da deduplicação: kafka.stream.dedup.path ou
+// "getl-stream-<kafkaGroupID>.db".
+func DedupPath(config Config) string {
+	if config.Kafka.Stream.Dedup.Path != "" {
+		return config.Kafka.Stream.Dedup.Path
+	}
+	return "getl-stream-" + config.KafkaGroupID + ".db"
+}
+
+// DedupTTL retorna por quanto tempo cada chave da deduplicação é lembrada; zero sem expiração.
+func DedupTTL(config Config) (time.Duration, error) {
+	if config.Kafka.Stream.Dedup.TTL == "" {
+		return 0, nil
+	}
+	ttl, err := ParseStageTimeout(config.Kafka.Stream.Dedup.TTL)
+	if err != nil {
+		return 0, fmt.Errorf("kafka.stream.dedup.ttl: %w", err)
+	}
+	return ttl, nil
+}
+
+// DecodeMessageRow decodifica uma mensagem JSON com a linha publicada por RunETL. Os números
+// inteiros são mantidos como int64, e não convertidos para float64, para não mudar o valor das
+// chaves e colunas inteiras gravadas no destino.
+func DecodeMessageRow(value []byte) (Data, error) {
+	decoder := json.NewDecoder(bytes.NewReader(value))
+	decoder.UseNumber()
+	var row Data
+	if err := decoder.Decode(&row); err != nil {
+		return nil, fmt.Errorf("mensagem não é um objeto JSON: %w", err)
+	}
+	if row == nil {
+		return nil, fmt.Errorf("mensagem vazia (null)")
+	}
+	normalizeJSONNumbers(row)
+	return row, nil
+}
+
+// MessageFieldTypes deduz o tipo genérico de cada coluna dos valores decodificados das mensagens,
+// para criar a tabela de destino quando a transformação não informa type. Colunas com inteiros e
+// números decimais são DECIMAL; só com nulos, com objetos ou com tipos misturados, TEXT.
+func MessageFieldTypes(rows []Data) map[string]string {
+	fields := make(map[string]string)
+	for _, row := range rows {
+		for column, value := range row {
+			if value == nil {
+				continue
+			}
+			fieldType := "TEXT"
+			switch value.(type) {
+			case int64:
+				fieldType = "INT"
+			case float64:
+				fieldType = "DECIMAL"
+			case bool:
+				fieldType = "BOOLEAN"
+			}
+			switch current := fields[column]; {
+			case current == "" || current == fieldType:
+				fields[column] = fieldType
+			case (current == "INT" || current == "DECIMAL") && (fieldType == "INT" || fieldType == "DECIMAL"):
+				fields[column] = "DECIMAL"
+			default:
+				fields[column] = "TEXT"
+			}
+		}
+	}
+	for _, row := range rows {
+		for column := range row {
+			if _, ok := fields[column]; !ok {
+				fields[column] = "TEXT"
+			}
+		}
+	}
+	return fields
+}
+
+// keyPlaceholder encontra as colunas de um modelo de chave, e.g. {id} em "{region}-{id}".
+var keyPlaceholder = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// ProducerKey retorna a chave das mensagens publicadas: kafka.producer.key ou, sem ela, primaryKey.
+func ProducerKey(config Config) string {
+	if config.Kafka.Producer.Key != "" {
+		return config.Kafka.Producer.Key
+	}
+	return config.PrimaryKey
+}
+
+// ProducerBatchSize retorna o máximo de mensagens de cada escrita no tópico.
+func ProducerBatchSize(config Config) int {
+	if config.Kafka.Producer.BatchSize > 0 {
+		return config.Kafka.Producer.BatchSize
+	}
+	return defaultProducerBatchSize
+}
+
+// MessageKey calcula a chave da mensagem de uma linha. key é o nome de uma coluna ou um modelo com
+// colunas entre chaves; as colunas são procuradas pelo nome exato e, na falta dele, sem diferenciar
+// maiúsculas (o Oracle, por exemplo, retorna os nomes em maiúsculas). Sem key, ou com a coluna da
+// chave nula ou vazia, a mensagem não tem chave.
+func MessageKey(key string, row Data) ([]byte, error) {
+	if key == "" {
+		return nil, nil
+	}
+	if !strings.Contains(key, "{") {
+		value, err := keyColumnValue(key, row)
+		if err != nil || value == "" {
+			return nil, err
+		}
+		return []byte(value), nil
+	}
+	var keyErr error
+	result := keyPlaceholder.ReplaceAllStringFunc(key, func(placeholder string) string {
+		value, err := keyColumnValue(placeholder[1:len(placeholder)-1], row)
+		if err != nil && keyErr == nil {
+			keyErr = err
+		}
+		return value
+	})
+	if keyErr != nil {
+		return nil, keyErr
+	}
+	return []byte(result), nil
+}
+
+func keyColumnValue(column string, row Data) (string, error) {
+	value, ok := row[column]
+	if !ok {
+		for name, candidate := range row {
+			if strings.EqualFold(name, column) {
+				value, ok = candidate, true
+				break
+			}
+		}
+	}
+	if !ok {
+		return "", fmt.Errorf("coluna %s da chave da mensagem ausente na linha", column)
+	}
+	if value == nil {
+		return "", nil
+	}
+	return DeleteKeyString(value), nil
+}
+
+// ProducerHeaders retorna os cabeçalhos das mensagens publicadas, em ordem alfabética: a tabela de
+// origem, a execução (runID, se houver) e os de kafka.producer.headers, que prevalecem.
+func ProducerHeaders(config Config, runID string) [][2]string {
+	headers := map[string]string{}
+	if config.SourceTable != "" {
+		headers[SourceTableHeader] = config.SourceTable
+	}
+	if runID != "" {
+		headers[RunIDHeader] = runID
+	}
+	maps.Copy(headers, config.Kafka.Producer.Headers)
+	result := make([][2]string, 0, len(headers))
+	for _, name := range slices.Sorted(maps.Keys(headers)) {
+		result = append(result, [2]string{name, headers[name]})
+	}
+	return result
+}
@@ -0,0 +1,404 @@
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	. "github.com/faelmori/getl/etypes"
+)
+
+// TestDecodeMessageRow verifica que os inteiros da mensagem não viram float64.
+func TestDecodeMessageRow(t *testing.T) {
+	row, err := DecodeMessageRow([]byte(`{"id": 9007199254740993, "price": 10.5, "name": "a", "active": true, "note": null}`))
+	if err != nil {
+		t.Fatalf("DecodeMessageRow() = %v", err)
+	}
+	want := Data{"id": int64(9007199254740993), "price": 10.5, "name": "a", "active": true, "note": nil}
+	if !reflect.DeepEqual(row, want) {
+		t.Errorf("DecodeMessageRow() = %#v", row)
+	}
+	for _, value := range []string{`[1, 2]`, `null`, `{"id": 1`, ``} {
+		if _, err := DecodeMessageRow([]byte(value)); err == nil {
+			t.Errorf("DecodeMessageRow(%q) sem erro", value)
+		}
+	}
+}
+
+// TestMessageFieldTypes verifica os tipos deduzidos dos valores das mensagens.
+func TestMessageFieldTypes(t *testing.T) {
+	rows := []Data{
+		{"id": int64(1), "price": int64(10), "name": "a", "active": true, "note": nil, "mixed": int64(1)},
+		{"id": int64(2), "price": 10.5, "name": nil, "active": false, "note": nil, "mixed": "x"},
+	}
+	want := map[string]string{"id": "INT", "price": "DECIMAL", "name": "TEXT", "active": "BOOLEAN", "note": "TEXT", "mixed": "TEXT"}
+	if fields := MessageFieldTypes(rows); !reflect.DeepEqual(fields, want) {
+		t.Errorf("MessageFieldTypes() = %v", fields)
+	}
+}
+
+// TestConsumerSettings verifica os padrões e a validação dos lotes do consumo.
+func TestConsumerSettings(t *testing.T) {
+	config := Config{}
+	if interval, err := ConsumerFlushInterval(config); err != nil || interval != time.Second || ConsumerBatchSize(config) != 100 {
+		t.Errorf("padrões do consumo: %v %v %d", interval, err, ConsumerBatchSize(config))
+	}
+	config.Kafka.Consumer = KafkaConsumer{BatchSize: 10, FlushInterval: "250ms"}
+	if interval, err := ConsumerFlushInterval(config); err != nil || interval != 250*time.Millisecond || ConsumerBatchSize(config) != 10 {
+		t.Errorf("consumo configurado: %v %v %d", interval, err, ConsumerBatchSize(config))
+	}
+
+	base := "sourceType: sqlite3\nsourceConnectionString: a.db\ndestinationType: sqlite3\ndestinationConnectionString: b.db\nsourceTable: items\n"
+	tests := map[string]string{
+		"kafka:\n  consumer:\n    batchSize: -1\n":       "kafka.consumer.batchSize",
+		"kafka:\n  consumer:\n    flushInterval: soon\n": "kafka.consumer.flushInterval",
+	}
+	for document, path := range tests {
+		errs := ValidateConfigData([]byte(base+document), "yaml")
+		if len(errs) != 1 || errs[0].Path != path {
+			t.Errorf("ValidateConfigData(%q) = %v, esperado erro em %s", document, errs, path)
+		}
+	}
+}
+
+// TestMessageKey verifica a chave por coluna e por modelo.
+func TestMessageKey(t *testing.T) {
+	row := Data{"ID": int64(7), "region": []byte("sul"), "price": 2.0, "note": nil}
+	tests := map[string]string{
+		"":               "",
+		"id":             "7",
+		"note":           "",
+		"{region}-{id}":  "sul-7",
+		"p{price}/{ID}":  "p2/7",
+		"{note}:{price}": ":2",
+	}
+	for key, want := range tests {
+		got, err := MessageKey(key, row)
+		if err != nil || string(got) != want {
+			t.Errorf("MessageKey(%q) = %q, %v; esperado %q", key, got, err, want)
+		}
+	}
+	if got, _ := MessageKey("note", row); got != nil {
+		t.Errorf("MessageKey() com a coluna nula = %q, esperado sem chave", got)
+	}
+	for _, key := range []string{"missing", "{region}-{missing}"} {
+		if _, err := MessageKey(key, row); err == nil {
+			t.Errorf("MessageKey(%q) sem erro com a coluna ausente", key)
+		}
+	}
+	if ProducerKey(Config{PrimaryKey: "id"}) != "id" || ProducerKey(Config{PrimaryKey: "id", Kafka: KafkaOptions{Producer: KafkaProducer{Key: "{a}"}}}) != "{a}" {
+		t.Errorf("ProducerKey() não usa kafka.producer.key ou primaryKey")
+	}
+}
+
+// TestProducerHeaders verifica os cabeçalhos padrão e os configurados.
+func TestProducerHeaders(t *testing.T) {
+	config := Config{SourceTable: "items", Kafka: KafkaOptions{Producer: KafkaProducer{Headers: map[string]string{"team": "data", RunIDHeader: "fixo"}}}}
+	want := [][2]string{{RunIDHeader, "fixo"}, {SourceTableHeader, "items"}, {"team", "data"}}
+	if headers := ProducerHeaders(config, "run-1"); !reflect.DeepEqual(headers, want) {
+		t.Errorf("ProducerHeaders() = %v", headers)
+	}
+	if headers := ProducerHeaders(Config{}, ""); len(headers) != 0 {
+		t.Errorf("ProducerHeaders() sem origem = %v", headers)
+	}
+}
+
+// TestValidateKafkaProducer verifica os valores inválidos de kafka.producer.
+func TestValidateKafkaProducer(t *testing.T) {
+	base := "sourceType: sqlite3\nsourceConnectionString: a.db\ndestinationType: sqlite3\ndestinationConnectionString: b.db\nsourceTable: items\n"
+	tests := map[string]string{
+		"kafka:\n  producer:\n    compression: brotli\n": "kafka.producer.compression",
+		"kafka:\n  producer:\n    partitioner: random\n": "kafka.producer.partitioner",
+		"kafka:\n  producer:\n    requiredAcks: two\n":   "kafka.producer.requiredAcks",
+		"kafka:\n  producer:\n    linger: 0s\n":          "kafka.producer.linger",
+		"kafka:\n  producer:\n    key: \"{id\"\n":        "kafka.producer.key",
+	}
+	for document, path := range tests {
+		errs := ValidateConfigData([]byte(base+document), "yaml")
+		if len(errs) != 1 || errs[0].Path != path {
+			t.Errorf("ValidateConfigData(%q) = %v, esperado erro em %s", document, errs, path)
+		}
+	}
+	valid := "kafka:\n  producer:\n    key: \"{region}-{id}\"\n    partitioner: murmur2\n    compression: zstd\n    requiredAcks: all\n    linger: 50ms\n    batchSize: 500\n    headers:\n      team: data\n"
+	if errs := ValidateConfigData([]byte(base+valid), "yaml"); len(errs) > 0 {
+		t.Errorf("ValidateConfigData(%q) = %v", valid, errs)
+	}
+}
+
+// TestRetrySettings verifica os padrões das novas tentativas, o backoff dobrado e a validação de
+// kafka.consumer.retry.
+func TestRetrySettings(t *testing.T) {
+	config := Config{KafkaTopic: "items"}
+	if RetryTopic(config) != "items-retry" || RetryAttempts(config) != 3 {
+		t.Errorf("padrões das novas tentativas: %s %d", RetryTopic(config), RetryAttempts(config))
+	}
+	config.Kafka.Consumer.Retry = KafkaRetry{Topic: "items-again", Attempts: 5, Backoff: "100ms"}
+	if RetryTopic(config) != "items-again" || RetryAttempts(config) != 5 {
+		t.Errorf("novas tentativas configuradas: %s %d", RetryTopic(config), RetryAttempts(config))
+	}
+	for attempt, want := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 4: 800 * time.Millisecond} {
+		if backoff, err := RetryBackoff(config, attempt); err != nil || backoff != want {
+			t.Errorf("RetryBackoff(%d) = %v, %v, esperado %v", attempt, backoff, err, want)
+		}
+	}
+
+	base := "sourceType: sqlite3\nsourceConnectionString: a.db\ndestinationType: sqlite3\ndestinationConnectionString: b.db\nsourceTable: items\nkafkaTopic: items\n"
+	tests := map[string]string{
+		"kafka:\n  consumer:\n    deadLetterTopic: dlq\n    retry:\n      attempts: -1\n":  "kafka.consumer.retry.attempts",
+		"kafka:\n  consumer:\n    deadLetterTopic: dlq\n    retry:\n      backoff: soon\n": "kafka.consumer.retry.backoff",
+		"kafka:\n  consumer:\n    retry:\n      attempts: 2\n":                             "kafka.consumer.retry",
+		"kafka:\n  consumer:\n    deadLetterTopic: items\n":                                "kafka.consumer.deadLetterTopic",
+	}
+	for document, path := range tests {
+		errs := ValidateConfigData([]byte(base+document), "yaml")
+		if len(errs) != 1 || errs[0].Path != path {
+			t.Errorf("ValidateConfigData(%q) = %v, esperado erro em %s", document, errs, path)
+		}
+	}
+	valid := "kafka:\n  consumer:\n    deadLetterTopic: items-dlq\n    retry:\n      topic: items-again\n      attempts: 0\n      backoff: 1m\n"
+	if errs := ValidateConfigData([]byte(base+valid), "yaml"); len(errs) > 0 {
+		t.Errorf("ValidateConfigData(%q) = %v", valid, errs)
+	}
+}
+
+// TestKafkaBrokers verifica a lista de brokers de kafka.brokers e de kafkaURL.
+func TestKafkaBrokers(t *testing.T) {
+	if brokers := KafkaBrokers(Config{KafkaURL: " a:9092, b:9092,,"}); !reflect.DeepEqual(brokers, []string{"a:9092", "b:9092"}) {
+		t.Errorf("KafkaBrokers() com kafkaURL = %v", brokers)
+	}
+	config := Config{KafkaURL: "a:9092", Kafka: KafkaOptions{Brokers: []string{"c:9093"}}}
+	if brokers := KafkaBrokers(config); !reflect.DeepEqual(brokers, []string{"c:9093"}) {
+		t.Errorf("KafkaBrokers() com kafka.brokers = %v", brokers)
+	}
+	if brokers := KafkaBrokers(Config{}); len(brokers) != 0 {
+		t.Errorf("KafkaBrokers() sem brokers = %v", brokers)
+	}
+}
+
+// TestKafkaTLSConfig verifica a leitura da autoridade e do certificado do cliente.
+func TestKafkaTLSConfig(t *testing.T) {
+	if tlsConfig, err := KafkaTLSConfig(Config{}); tlsConfig != nil || err != nil {
+		t.Errorf("KafkaTLSConfig() sem TLS = %v, %v", tlsConfig, err)
+	}
+	certFile, keyFile := writeTestCertificate(t)
+	config := Config{Kafka: KafkaOptions{TLS: KafkaTLS{CAFile: certFile, CertFile: certFile, KeyFile: keyFile, ServerName: "kafka"}}}
+	tlsConfig, err := KafkaTLSConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tlsConfig.RootCAs == nil || len(tlsConfig.Certificates) != 1 || tlsConfig.ServerName != "kafka" || tlsConfig.MinVersion != tls.VersionTLS12 {
+		t.Errorf("KafkaTLSConfig() = %+v", tlsConfig)
+	}
+	config.Kafka.TLS.CAFile = keyFile
+	if _, err := KafkaTLSConfig(config); err == nil {
+		t.Errorf("KafkaTLSConfig() aceitou uma autoridade sem certificado")
+	}
+}
+
+// writeTestCertificate grava um certificado autoassinado e a sua chave em arquivos PEM.
+func writeTestCertificate(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "kafka"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "client.pem"), filepath.Join(dir, "client.key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+// TestValidateKafkaClient verifica os valores inválidos das opções de conexão e de leitura.
+func TestValidateKafkaClient(t *testing.T) {
+	base := "sourceType: sqlite3\nsourceConnectionString: a.db\ndestinationType: sqlite3\ndestinationConnectionString: b.db\nsourceTable: items\n"
+	tests := map[string]string{
+		"kafka:\n  tls:\n    certFile: client.pem\n":                   "kafka.tls",
+		"kafka:\n  sasl:\n    mechanism: gssapi\n    username: getl\n": "kafka.sasl.mechanism",
+		"kafka:\n  sasl:\n    mechanism: scram-sha-512\n":              "kafka.sasl.username",
+		"kafka:\n  reader:\n    startOffset: middle\n":                 "kafka.reader.startOffset",
+		"kafka:\n  reader:\n    isolationLevel: serializable\n":        "kafka.reader.isolationLevel",
+		"kafka:\n  reader:\n    maxBytes: -1\n":                        "kafka.reader.maxBytes",
+		"kafka:\n  reader:\n    minBytes: 2048\n    maxBytes: 1024\n":  "kafka.reader.minBytes",
+		"kafka:\n  reader:\n    commitInterval: often\n":               "kafka.reader.commitInterval",
+	}
+	for document, path := range tests {
+		errs := ValidateConfigData([]byte(base+document), "yaml")
+		if len(errs) != 1 || errs[0].Path != path {
+			t.Errorf("ValidateConfigData(%q) = %v, esperado erro em %s", document, errs, path)
+		}
+	}
+	valid := "kafka:\n  brokers: [a:9093, b:9093]\n  clientID: getl\n  tls:\n    caFile: ca.pem\n    certFile: client.pem\n    keyFile: client.key\n  sasl:\n    mechanism: plain\n    username: getl\n    password: secret\n  reader:\n    startOffset: latest\n    minBytes: 1\n    maxBytes: 1048576\n    commitInterval: 1s\n    isolationLevel: read_committed\n"
+	if errs := ValidateConfigData([]byte(base+valid), "yaml"); len(errs) > 0 {
+		t.Errorf("ValidateConfigData(%q) = %v", valid, errs)
+	}
+	if masked := MaskConfigSecrets(Config{Kafka: KafkaOptions{SASL: KafkaSASL{Password: "secret"}}}); masked.Kafka.SASL.Password != "****" {
+		t.Errorf("MaskConfigSecrets() manteve a senha SASL")
+	}
+}
+
+// TestParseStartPosition verifica as posições de início aceitas por getl consume --from.
+func TestParseStartPosition(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	tests := map[string]StartPosition{
+		"earliest":             {Earliest: true},
+		"latest":               {Latest: true},
+		"2024-05-01T12:30:00Z": {Time: at},
+		"2024-05-01":           {Time: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+		"0:120, 2:98":          {Offsets: map[int]int64{0: 120, 2: 98}},
+	}
+	for value, want := range tests {
+		position, err := ParseStartPosition(value)
+		if err != nil || !reflect.DeepEqual(position, want) {
+			t.Errorf("ParseStartPosition(%q) = %+v, %v, esperado %+v", value, position, err, want)
+		}
+		if again, err := ParseStartPosition(position.String()); err != nil || !reflect.DeepEqual(again, want) {
+			t.Errorf("ParseStartPosition(%q) = %+v, %v", position.String(), again, err)
+		}
+	}
+	for _, value := range []string{"", "first", "0:-1", "a:1", "0:1,0:2", "0:1,"} {
+		if _, err := ParseStartPosition(value); err == nil {
+			t.Errorf("ParseStartPosition(%q) sem erro", value)
+		}
+	}
+}
+
+// TestKafkaRoutes verifica os tópicos consumidos, a escolha da rota de cada mensagem e a validação das rotas.
+func TestKafkaRoutes(t *testing.T) {
+	routes := []KafkaRoute{
+		{Topic: "orders", Fields: map[string]string{"kind": "refund"}, Table: "refunds"},
+		{Topic: "orders", Table: "orders"},
+		{Headers: map[string]string{"table": "customers"}, Table: "customers", PrimaryKey: "customer_id"},
+		{Topic: "items", Table: "items_archive"},
+	}
+	config := Config{KafkaTopic: "events", PrimaryKey: "id", UpdateKey: "id", DestinationTable: "events",
+		Transformations: []Transformation{{SourceField: "id", DestinationField: "event_id", Operation: "copy"}},
+		Kafka:           KafkaOptions{Consumer: KafkaConsumer{Routes: routes}}}
+	if topics := ConsumerTopics(config); !reflect.DeepEqual(topics, []string{"events", "orders", "items"}) {
+		t.Errorf("ConsumerTopics() = %v", topics)
+	}
+
+	tests := []struct {
+		topic   string
+		headers map[string]string
+		row     Data
+		want    int
+	}{
+		{"orders", nil, Data{"kind": "refund"}, 0},
+		{"orders", nil, Data{"kind": "sale"}, 1},
+		{"orders", nil, Data{"kind": nil}, 1},
+		{"events", map[string]string{"table": "customers"}, Data{}, 2},
+		{"events", map[string]string{"table": "products"}, Data{}, -1},
+		{"items", nil, nil, 3},
+	}
+	for _, test := range tests {
+		if got := MatchKafkaRoute(routes, test.topic, test.headers, test.row); got != test.want {
+			t.Errorf("MatchKafkaRoute(%s, %v, %v) = %d, esperado %d", test.topic, test.headers, test.row, got, test.want)
+		}
+	}
+	if got := MatchKafkaRoute([]KafkaRoute{{Fields: map[string]string{"id": "7"}, Table: "seven"}}, "events", nil, Data{"id": int64(7)}); got != 0 {
+		t.Errorf("MatchKafkaRoute() não comparou o campo inteiro como texto")
+	}
+
+	routeConfig := RouteConfig(config, routes[2])
+	if routeConfig.DestinationTable != "customers" || routeConfig.PrimaryKey != "customer_id" || routeConfig.UpdateKey != "" || routeConfig.Transformations != nil {
+		t.Errorf("RouteConfig() = %+v", routeConfig)
+	}
+	if routeConfig = RouteConfig(config, routes[0]); routeConfig.PrimaryKey != "id" {
+		t.Errorf("RouteConfig() sem primaryKey = %s, esperado id", routeConfig.PrimaryKey)
+	}
+
+	base := "sourceType: sqlite3\nsourceConnectionString: a.db\ndestinationType: sqlite3\ndestinationConnectionString: b.db\nsourceTable: items\nkafkaTopic: items\n"
+	invalid := map[string]string{
+		"kafka:\n  consumer:\n    routes:\n      - topic: orders\n":                                               "kafka.consumer.routes[0].table",
+		"kafka:\n  consumer:\n    routes:\n      - table: orders\n":                                               "kafka.consumer.routes[0]",
+		"kafka:\n  consumer:\n    deadLetterTopic: dlq\n    routes:\n      - topic: dlq\n        table: orders\n": "kafka.consumer.routes[0].topic",
+		"kafka:\n  consumer:\n    routes:\n      - topic: orders\n        table: orders\n        transformations:\n          - sourceField: id\n            destinationField: id\n            operation: explode\n": "kafka.consumer.routes[0].transformations[0].operation",
+	}
+	for document, path := range invalid {
+		errs := ValidateConfigData([]byte(base+document), "yaml")
+		if len(errs) != 1 || errs[0].Path != path {
+			t.Errorf("ValidateConfigData(%q) = %v, esperado erro em %s", document, errs, path)
+		}
+	}
+	valid := "kafka:\n  consumer:\n    routes:\n      - topic: orders\n        fields: {kind: refund}\n        table: refunds\n      - headers: {table: customers}\n        table: customers\n        primaryKey: customer_id\n"
+	if errs := ValidateConfigData([]byte(base+valid), "yaml"); len(errs) > 0 {
+		t.Errorf("ValidateConfigData(%q) = %v", valid, errs)
+	}
+}
+
+func TestStreamSettings(t *testing.T) {
+	config := Config{KafkaGroupID: "group"}
+	if id := StreamTransactionalID(config); id != "getl-stream-group" {
+		t.Errorf("StreamTransactionalID() = %s", id)
+	}
+	if path := DedupPath(config); path != "getl-stream-group.db" {
+		t.Errorf("DedupPath() = %s", path)
+	}
+	if timeout, err := StreamTransactionTimeout(config); err != nil || timeout != time.Minute {
+		t.Errorf("StreamTransactionTimeout() = %v, %v", timeout, err)
+	}
+	if ttl, err := DedupTTL(config); err != nil || ttl != 0 {
+		t.Errorf("DedupTTL() = %v, %v", ttl, err)
+	}
+	config.Kafka.Stream = KafkaStream{TransactionalID: "orders", TransactionTimeout: "30s", Dedup: KafkaDedup{Path: "dedup.db", TTL: "24h"}}
+	if id := StreamTransactionalID(config); id != "orders" {
+		t.Errorf("StreamTransactionalID() = %s", id)
+	}
+	if path := DedupPath(config); path != "dedup.db" {
+		t.Errorf("DedupPath() = %s", path)
+	}
+	if timeout, err := StreamTransactionTimeout(config); err != nil || timeout != 30*time.Second {
+		t.Errorf("StreamTransactionTimeout() = %v, %v", timeout, err)
+	}
+	if ttl, err := DedupTTL(config); err != nil || ttl != 24*time.Hour {
+		t.Errorf("DedupTTL() = %v, %v", ttl, err)
+	}
+
+	base := "sourceType: sqlite3\nsourceConnectionString: a.db\ndestinationType: sqlite3\ndestinationConnectionString: b.db\nsourceTable: items\nkafkaTopic: items\n"
+	invalid := map[string]string{
+		"kafka:\n  stream:\n    outputTopic: items\n":                  "kafka.stream.outputTopic",
+		"kafka:\n  stream:\n    transactionTimeout: soon\n":            "kafka.stream.transactionTimeout",
+		"kafka:\n  stream:\n    dedup:\n      ttl: forever\n":          "kafka.stream.dedup.ttl",
+		"kafka:\n  stream:\n    dedup:\n      key: \"{id}-{region\"\n": "kafka.stream.dedup.key",
+	}
+	for document, path := range invalid {
+		errs := ValidateConfigData([]byte(base+document), "yaml")
+		if len(errs) != 1 || errs[0].Path != path {
+			t.Errorf("ValidateConfigData(%q) = %v, esperado erro em %s", document, errs, path)
+		}
+	}
+	valid := "kafka:\n  stream:\n    outputTopic: items-clean\n    transactionTimeout: 30s\n    dedup:\n      key: \"{id}-{region}\"\n      ttl: 24h\n"
+	if errs := ValidateConfigData([]byte(base+valid), "yaml"); len(errs) > 0 {
+		t.Errorf("ValidateConfigData(%q) = %v", valid, errs)
+	}
+}
@@ -0,0 +1,223 @@
+package utils
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	. "github.com/faelmori/getl/etypes"
+	"github.com/goccy/go-json"
+	"github.com/jackc/pglogrepl"
+	"github.com/jackc/pgx/v5/pgtype"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReplicationSlot retorna o slot de replicação lógica da Config.
+func ReplicationSlot(config Config) string {
+	return cmp.Or(config.CDC.Slot, "getl_"+strings.Trim(nonIdentifierChars.ReplaceAllString(strings.ToLower(config.SourceTable), "_"), "_"))
+}
+
+// ReplicationPublication retorna a publicação lida pelo pgoutput.
+func ReplicationPublication(config Config) string {
+	return cmp.Or(config.CDC.Publication, ReplicationSlot(config))
+}
+
+// LogicalDecoder interpreta as mensagens de um plugin de replicação lógica, acumulando as alterações
+// da transação corrente da tabela capturada.
+type LogicalDecoder interface {
+	// Decode interpreta uma mensagem do WAL. No commit, retorna as alterações da transação e true.
+	Decode(walData []byte) ([]ChangeEvent, bool, error)
+}
+
+// NewLogicalDecoder cria o decodificador do plugin ("pgoutput" ou "wal2json") para as alterações de table,
+// no formato "tabela" ou "esquema.tabela".
+func NewLogicalDecoder(plugin, table string) (LogicalDecoder, error) {
+	switch plugin {
+	case "pgoutput":
+		return &pgoutputDecoder{table: table, relations: map[uint32]*pglogrepl.RelationMessage{}}, nil
+	case "wal2json":
+		return &wal2jsonDecoder{table: table}, nil
+	}
+	return nil, fmt.Errorf("plugin de replicação lógica não suportado: %s", plugin)
+}
+
+// matchesTable verifica se a relação é a tabela capturada. Sem esquema em table, qualquer esquema serve.
+func matchesTable(table, schema, name string) bool {
+	if strings.Contains(table, ".") {
+		return strings.EqualFold(table, schema+"."+name)
+	}
+	return strings.EqualFold(table, name)
+}
+
+// pgoutputDecoder converte as mensagens da versão 1 do pgoutput, lidas por pglogrepl.Parse, em
+// alterações. As mensagens de relação precedem as alterações da tabela e descrevem suas colunas.
+type pgoutputDecoder struct {
+	table     string
+	relations map[uint32]*pglogrepl.RelationMessage
+	events    []ChangeEvent
+	commitAt  time.Time
+}
+
+func (d *pgoutputDecoder) Decode(walData []byte) ([]ChangeEvent, bool, error) {
+	if len(walData) == 0 {
+		return nil, false, fmt.Errorf("mensagem do pgoutput vazia")
+	}
+	message, parseErr := pglogrepl.Parse(walData)
+	if parseErr != nil {
+		return nil, false, fmt.Errorf("mensagem do pgoutput inválida: %w", parseErr)
+	}
+
+	var event ChangeEvent
+	var relationID uint32
+	var before, after *pglogrepl.TupleData
+	switch message := message.(type) {
+	case *pglogrepl.BeginMessage:
+		d.commitAt = message.CommitTime
+		d.events = nil
+		return nil, false, nil
+	case *pglogrepl.CommitMessage:
+		events := d.events
+		d.events = nil
+		return events, true, nil
+	case *pglogrepl.RelationMessage:
+		d.relations[message.RelationID] = message
+		return nil, false, nil
+	case *pglogrepl.InsertMessage:
+		event.Operation, relationID, after = ChangeInsert, message.RelationID, message.Tuple
+	case *pglogrepl.UpdateMessage:
+		event.Operation, relationID, before, after = ChangeUpdate, message.RelationID, message.OldTuple, message.NewTuple
+	case *pglogrepl.DeleteMessage:
+		event.Operation, relationID, before = ChangeDelete, message.RelationID, message.OldTuple
+	default:
+		// Truncate, tipos, origem e mensagens não alteram linhas da tabela capturada.
+		return nil, false, nil
+	}
+
+	relation, ok := d.relations[relationID]
+	if !ok {
+		return nil, false, fmt.Errorf("alteração de uma relação não descrita pelo pgoutput")
+	}
+	if !matchesTable(d.table, relation.Namespace, relation.RelationName) {
+		return nil, false, nil
+	}
+	event.Table = relation.Namespace + "." + relation.RelationName
+	event.Timestamp = d.commitAt
+	event.Before = pgoutputRow(relation, before)
+	event.After = pgoutputRow(relation, after)
+	// Colunas TOAST não alteradas não vêm na nova imagem, mas vêm na anterior, pois a instalação exige
+	// REPLICA IDENTITY FULL.
+	for column, value := range event.Before {
+		if _, present := event.After[column]; !present && event.After != nil {
+			event.After[column] = value
+		}
+	}
+	d.events = append(d.events, event)
+	return nil, false, nil
+}
+
+// pgoutputRow converte uma tupla do pgoutput em linha. Valores nulos viram nil e colunas TOAST não
+// alteradas são omitidas. Sem tupla, retorna nil.
+func pgoutputRow(relation *pglogrepl.RelationMessage, tuple *pglogrepl.TupleData) Data {
+	if tuple == nil {
+		return nil
+	}
+	row := Data{}
+	for i, column := range tuple.Columns {
+		if i >= len(relation.Columns) {
+			break
+		}
+		name := relation.Columns[i].Name
+		switch column.DataType {
+		case pglogrepl.TupleDataTypeNull:
+			row[name] = nil
+		case pglogrepl.TupleDataTypeText:
+			row[name] = postgresTextValue(relation.Columns[i].DataType, string(column.Data))
+		case pglogrepl.TupleDataTypeBinary:
+			row[name] = column.Data
+		}
+	}
+	return row
+}
+
+// postgresTextValue converte o texto de uma coluna nos tipos retornados pelos drivers: inteiros,
+// decimais de ponto flutuante e booleanos; numeric e os demais tipos permanecem texto.
+func postgresTextValue(typeOID uint32, value string) interface{} {
+	switch typeOID {
+	case pgtype.Int8OID, pgtype.Int2OID, pgtype.Int4OID, pgtype.OIDOID:
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return i
+		}
+	case pgtype.Float4OID, pgtype.Float8OID:
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	case pgtype.BoolOID:
+		return value == "t"
+	}
+	return value
+}
+
+// wal2jsonDecoder interpreta o formato 2 do wal2json, uma mensagem JSON por alteração.
+type wal2jsonDecoder struct {
+	table  string
+	events []ChangeEvent
+}
+
+type wal2jsonColumn struct {
+	Name  string      `json:"name"`
+	Value interface{} `json:"value"`
+}
+
+type wal2jsonMessage struct {
+	Action    string           `json:"action"`
+	Schema    string           `json:"schema"`
+	Table     string           `json:"table"`
+	Timestamp string           `json:"timestamp"`
+	Columns   []wal2jsonColumn `json:"columns"`
+	Identity  []wal2jsonColumn `json:"identity"`
+}
+
+func (d *wal2jsonDecoder) Decode(walData []byte) ([]ChangeEvent, bool, error) {
+	decoder := json.NewDecoder(bytes.NewReader(walData))
+	decoder.UseNumber()
+	var message wal2jsonMessage
+	if err := decoder.Decode(&message); err != nil {
+		return nil, false, fmt.Errorf("mensagem do wal2json inválida: %w", err)
+	}
+
+	switch message.Action {
+	case "B":
+		d.events = nil
+	case "C":
+		events := d.events
+		d.events = nil
+		return events, true, nil
+	case "I", "U", "D":
+		if !matchesTable(d.table, message.Schema, message.Table) {
+			break
+		}
+		event := ChangeEvent{Operation: message.Action, Table: message.Schema + "." + message.Table}
+		if timestamp, err := time.Parse("2006-01-02 15:04:05.999999-07", message.Timestamp); err == nil {
+			event.Timestamp = timestamp
+		}
+		if len(message.Identity) > 0 {
+			event.Before = wal2jsonRow(message.Identity)
+		}
+		if message.Action != ChangeDelete {
+			event.After = wal2jsonRow(message.Columns)
+		}
+		d.events = append(d.events, event)
+	}
+	return nil, false, nil
+}
+
+// wal2jsonRow converte as colunas do wal2json em uma linha, com os números como em DecodeRowImage.
+func wal2jsonRow(columns []wal2jsonColumn) Data {
+	row := Data{}
+	for _, column := range columns {
+		row[column.Name] = column.Value
+	}
+	normalizeJSONNumbers(row)
+	return row
+}
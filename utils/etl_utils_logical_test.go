@@ -0,0 +1,172 @@
+package utils
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"time"
+
+	. "github.com/faelmori/getl/etypes"
+)
+
+// pgoutputMessage monta uma mensagem do pgoutput a partir dos campos, em ordem.
+func pgoutputMessage(kind byte, fields ...interface{}) []byte {
+	message := []byte{kind}
+	for _, field := range fields {
+		switch v := field.(type) {
+		case byte:
+			message = append(message, v)
+		case uint16:
+			message = binary.BigEndian.AppendUint16(message, v)
+		case uint32:
+			message = binary.BigEndian.AppendUint32(message, v)
+		case uint64:
+			message = binary.BigEndian.AppendUint64(message, v)
+		case string:
+			message = append(append(message, v...), 0)
+		case []byte:
+			message = binary.BigEndian.AppendUint32(message, uint32(len(v)))
+			message = append(message, v...)
+		}
+	}
+	return message
+}
+
+// TestPgoutputDecoder verifica a decodificação de uma transação do pgoutput com inclusão,
+// atualização com coluna TOAST não alterada e exclusão, ignorando as alterações de outras tabelas.
+func TestPgoutputDecoder(t *testing.T) {
+	decoder, err := NewLogicalDecoder("pgoutput", "items")
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := [][]byte{
+		pgoutputMessage('R', uint32(1), "public", "items", byte('f'), uint16(3),
+			byte(1), "id", uint32(23), uint32(0), byte(0), "name", uint32(25), uint32(0), byte(0), "active", uint32(16), uint32(0)),
+		pgoutputMessage('R', uint32(2), "public", "others", byte('d'), uint16(1), byte(1), "id", uint32(23), uint32(0)),
+		pgoutputMessage('B', uint64(0x20), uint64(0), uint32(7)),
+		pgoutputMessage('I', uint32(1), byte('N'), uint16(3), byte('t'), []byte("1"), byte('t'), []byte("a"), byte('t'), []byte("t")),
+		pgoutputMessage('I', uint32(2), byte('N'), uint16(1), byte('t'), []byte("9")),
+		pgoutputMessage('U', uint32(1), byte('O'), uint16(3), byte('t'), []byte("1"), byte('t'), []byte("a"), byte('t'), []byte("t"),
+			byte('N'), uint16(3), byte('t'), []byte("1"), byte('u'), byte('n')),
+		pgoutputMessage('D', uint32(1), byte('K'), uint16(3), byte('t'), []byte("1"), byte('n'), byte('n')),
+	}
+	for _, message := range messages {
+		if events, commit, err := decoder.Decode(message); err != nil || commit || events != nil {
+			t.Fatalf("Decode(%q) = %v, %v, %v", message[0], events, commit, err)
+		}
+	}
+
+	events, commit, err := decoder.Decode(pgoutputMessage('C', byte(0), uint64(0x10), uint64(0x20), uint64(0)))
+	if err != nil || !commit {
+		t.Fatalf("Decode(C) = %v, %v", commit, err)
+	}
+	want := []ChangeEvent{
+		{Operation: ChangeInsert, Table: "public.items", After: Data{"id": int64(1), "name": "a", "active": true}},
+		{Operation: ChangeUpdate, Table: "public.items", Before: Data{"id": int64(1), "name": "a", "active": true}, After: Data{"id": int64(1), "name": "a", "active": nil}},
+		{Operation: ChangeDelete, Table: "public.items", Before: Data{"id": int64(1), "name": nil, "active": nil}},
+	}
+	for i := range events {
+		events[i].Timestamp = time.Time{}
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("Decode(C) = %#v, esperado %#v", events, want)
+	}
+
+	if _, _, err := decoder.Decode(pgoutputMessage('I', uint32(5), byte('N'), uint16(0))); err == nil {
+		t.Errorf("Decode() de relação não descrita sem erro")
+	}
+	if _, _, err := decoder.Decode([]byte{'I', 0, 0}); err == nil {
+		t.Errorf("Decode() de mensagem truncada sem erro")
+	}
+}
+
+// TestWal2jsonDecoder verifica a decodificação do formato 2 do wal2json.
+func TestWal2jsonDecoder(t *testing.T) {
+	decoder, err := NewLogicalDecoder("wal2json", "public.items")
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := []string{
+		`{"action":"B"}`,
+		`{"action":"I","schema":"public","table":"items","timestamp":"2024-05-01 10:00:00.5+00","columns":[{"name":"id","value":1},{"name":"price","value":2.5}]}`,
+		`{"action":"I","schema":"other","table":"items","columns":[{"name":"id","value":9}]}`,
+		`{"action":"U","schema":"public","table":"items","columns":[{"name":"id","value":1},{"name":"price","value":3}],"identity":[{"name":"id","value":1}]}`,
+		`{"action":"D","schema":"public","table":"items","identity":[{"name":"id","value":1}]}`,
+	}
+	for _, message := range messages {
+		if _, commit, err := decoder.Decode([]byte(message)); err != nil || commit {
+			t.Fatalf("Decode(%s) = %v, %v", message, commit, err)
+		}
+	}
+
+	events, commit, err := decoder.Decode([]byte(`{"action":"C"}`))
+	if err != nil || !commit {
+		t.Fatalf("Decode(C) = %v, %v", commit, err)
+	}
+	want := []ChangeEvent{
+		{Operation: ChangeInsert, Table: "public.items", Timestamp: time.Date(2024, 5, 1, 10, 0, 0, 500000000, time.UTC), After: Data{"id": int64(1), "price": 2.5}},
+		{Operation: ChangeUpdate, Table: "public.items", Before: Data{"id": int64(1)}, After: Data{"id": int64(1), "price": int64(3)}},
+		{Operation: ChangeDelete, Table: "public.items", Before: Data{"id": int64(1)}},
+	}
+	if len(events) == 3 && events[0].Timestamp.Equal(want[0].Timestamp) {
+		events[0].Timestamp = want[0].Timestamp
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("Decode(C) = %#v, esperado %#v", events, want)
+	}
+
+	if _, _, err := decoder.Decode([]byte(`{`)); err == nil {
+		t.Errorf("Decode() de JSON inválido sem erro")
+	}
+	if _, err := NewLogicalDecoder("test_decoding", "items"); err == nil {
+		t.Errorf("NewLogicalDecoder() de plugin não suportado sem erro")
+	}
+}
+
+// TestReplicationNames verifica os nomes padrão do slot e da publicação.
+func TestReplicationNames(t *testing.T) {
+	config := Config{SourceTable: "public.Items"}
+	if slot := ReplicationSlot(config); slot != "getl_public_items" {
+		t.Errorf("ReplicationSlot() = %s", slot)
+	}
+	config.CDC.Slot = "orders"
+	if publication := ReplicationPublication(config); publication != "orders" {
+		t.Errorf("ReplicationPublication() = %s", publication)
+	}
+}
+
+// TestValidateLogicalChangeCapture verifica as regras da replicação lógica e do sink kafka.
+func TestValidateLogicalChangeCapture(t *testing.T) {
+	base := `sourceType: postgres
+sourceConnectionString: postgres://localhost/db
+destinationType: sqlite3
+destinationConnectionString: b.db
+sourceTable: items
+`
+	tests := map[string]string{
+		"cdc:\n  mode: pgoutput\n":                                                          "cdc.mode",
+		"primaryKey: id\ncdc:\n  mode: pgoutput\n  sink: queue\n":                           "cdc.sink",
+		"primaryKey: id\nkafkaURL: localhost:9092\ncdc:\n  mode: wal2json\n  sink: kafka\n": "cdc.sink",
+	}
+	for document, path := range tests {
+		errs := ValidateConfigData([]byte(base+document), "yaml")
+		if len(errs) != 1 || errs[0].Path != path {
+			t.Errorf("ValidateConfigData(%q) = %v, esperado erro em %s", document, errs, path)
+		}
+	}
+
+	valid := []string{
+		"primaryKey: id\ncdc:\n  mode: pgoutput\n  slot: items_slot\n",
+		"kafkaURL: localhost:9092\nkafkaTopic: items\ncdc:\n  mode: wal2json\n  sink: kafka\n",
+	}
+	for _, document := range valid {
+		if errs := ValidateConfigData([]byte(base+document), "yaml"); len(errs) > 0 {
+			t.Errorf("ValidateConfigData(%q) = %v", document, errs)
+		}
+	}
+
+	sqlite := "sourceType: sqlite3\nsourceConnectionString: a.db\ndestinationType: sqlite3\ndestinationConnectionString: b.db\nsourceTable: items\nprimaryKey: id\ncdc:\n  mode: pgoutput\n"
+	if errs := ValidateConfigData([]byte(sqlite), "yaml"); len(errs) != 1 || errs[0].Path != "cdc.mode" {
+		t.Errorf("ValidateConfigData() de pgoutput com sqlite3 = %v", errs)
+	}
+}
@@ -0,0 +1,282 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/elgris/sqrl"
+	. "github.com/faelmori/getl/etypes"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PartitionQuery é a consulta de extração de uma partição.
+type PartitionQuery struct {
+	Index int
+	Label string
+	Query string
+	Args  []interface{}
+}
+
+// PartitionBoundsQuery retorna a consulta de MIN e MAX da coluna de partição, com os mesmos
+// joins e filtros da extração, usada para calcular as faixas quando Partitioning.Count é informado.
+func PartitionBoundsQuery(config Config) (string, []interface{}, error) {
+	column := config.Partitioning.Column
+	config.OrderBy = ""
+	query, err := extractBaseQuery(config, []string{fmt.Sprintf("MIN(%s)", column), fmt.Sprintf("MAX(%s)", column)})
+	if err != nil {
+		return "", nil, err
+	}
+	return query.ToSql()
+}
+
+// BuildPartitionQueries gera uma consulta limitada por partição a partir da consulta de extração.
+// Com sqlQuery, a consulta original é usada como subconsulta e Column deve ser uma de suas colunas.
+// Com includeNulls, uma partição extra traz as linhas em que a coluna é nula.
+func BuildPartitionQueries(config Config, fields []string, ranges []PartitionRange, includeNulls bool) ([]PartitionQuery, error) {
+	column := config.Partitioning.Column
+	if column == "" {
+		return nil, fmt.Errorf("coluna de partição não informada")
+	}
+	if config.SQLQuery != "" {
+		fields = []string{"*"}
+	}
+
+	var queries []PartitionQuery
+	addQuery := func(label string, bound func(*sqrl.SelectBuilder) *sqrl.SelectBuilder) error {
+		query, err := extractBaseQuery(config, fields)
+		if err != nil {
+			return err
+		}
+		sqlQuery, args, err := bound(query).PlaceholderFormat(PlaceholderFormatFor(config.SourceType)).ToSql()
+		if err != nil {
+			return fmt.Errorf("falha ao gerar a consulta da partição %s: %w", label, err)
+		}
+		queries = append(queries, PartitionQuery{Index: len(queries), Label: label, Query: sqlQuery, Args: args})
+		return nil
+	}
+
+	for _, partition := range ranges {
+		from, to := partitionArg(partition.From), partitionArg(partition.To)
+		var conditions []string
+		if from != nil {
+			conditions = append(conditions, fmt.Sprintf("%s >= %v", column, from))
+		}
+		if to != nil {
+			conditions = append(conditions, fmt.Sprintf("%s < %v", column, to))
+		}
+		if len(conditions) == 0 && includeNulls {
+			conditions = append(conditions, column+" IS NOT NULL")
+		}
+		if len(conditions) == 0 {
+			conditions = append(conditions, "todas as linhas")
+		}
+
+		err := addQuery(strings.Join(conditions, " AND "), func(query *sqrl.SelectBuilder) *sqrl.SelectBuilder {
+			if from != nil {
+				query = query.Where(column+" >= ?", from)
+			}
+			if to != nil {
+				query = query.Where(column+" < ?", to)
+			}
+			if from == nil && to == nil && includeNulls {
+				query = query.Where(column + " IS NOT NULL")
+			}
+			return query
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if includeNulls {
+		err := addQuery(column+" IS NULL", func(query *sqrl.SelectBuilder) *sqrl.SelectBuilder {
+			return query.Where(column + " IS NULL")
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return queries, nil
+}
+
+// SplitPartitionRange divide o intervalo [minValue, maxValue] em até count faixas contíguas de mesmo tamanho.
+// Aceita números, datas (time.Time) e textos que representem um deles, como os drivers retornam MIN e MAX.
+// A primeira e a última faixa ficam abertas, para não perder linhas inseridas durante a extração.
+func SplitPartitionRange(minValue, maxValue interface{}, count int) ([]PartitionRange, error) {
+	if count < 1 {
+		return nil, fmt.Errorf("número de partições deve ser positivo: %d", count)
+	}
+	minValue, maxValue = normalizeBound(minValue), normalizeBound(maxValue)
+	if minValue == nil || maxValue == nil || count == 1 {
+		return []PartitionRange{{}}, nil
+	}
+
+	// Limites inteiro e decimal misturados são tratados como decimais.
+	if lowInt, ok := minValue.(int64); ok {
+		if _, highIsFloat := maxValue.(float64); highIsFloat {
+			minValue = float64(lowInt)
+		}
+	}
+	if highInt, ok := maxValue.(int64); ok {
+		if _, lowIsFloat := minValue.(float64); lowIsFloat {
+			maxValue = float64(highInt)
+		}
+	}
+
+	var bounds []interface{}
+	switch low := minValue.(type) {
+	case int64:
+		high, ok := maxValue.(int64)
+		if !ok {
+			return nil, fmt.Errorf("limites de partição de tipos diferentes: %v e %v", minValue, maxValue)
+		}
+		span := high - low + 1
+		count = int(min(int64(count), max(span, 1)))
+		step := (span + int64(count) - 1) / int64(count)
+		for k := 1; k < count; k++ {
+			bounds = append(bounds, low+int64(k)*step)
+		}
+	case float64:
+		high, ok := maxValue.(float64)
+		if !ok {
+			return nil, fmt.Errorf("limites de partição de tipos diferentes: %v e %v", minValue, maxValue)
+		}
+		step := (high - low) / float64(count)
+		for k := 1; k < count && step > 0; k++ {
+			bounds = append(bounds, low+float64(k)*step)
+		}
+	case time.Time:
+		high, ok := maxValue.(time.Time)
+		if !ok {
+			return nil, fmt.Errorf("limites de partição de tipos diferentes: %v e %v", minValue, maxValue)
+		}
+		step := high.Sub(low) / time.Duration(count)
+		for k := 1; k < count && step > 0; k++ {
+			bounds = append(bounds, low.Add(time.Duration(k)*step))
+		}
+	default:
+		return nil, fmt.Errorf("coluna de partição deve ser numérica ou data, recebido %T", minValue)
+	}
+
+	ranges := make([]PartitionRange, 0, len(bounds)+1)
+	var from interface{}
+	for _, bound := range bounds {
+		ranges = append(ranges, PartitionRange{From: from, To: bound})
+		from = bound
+	}
+	return append(ranges, PartitionRange{From: from}), nil
+}
+
+// extractBaseQuery retorna a consulta de extração sobre a qual os limites da partição ou do checkpoint são aplicados.
+func extractBaseQuery(config Config, fields []string) (*sqrl.SelectBuilder, error) {
+	if config.SQLQuery != "" {
+		// Sem AS: o Oracle não aceita AS no alias de tabela.
+		return sqrl.Select(fields...).From("(" + config.SQLQuery + ") getl_partition"), nil
+	}
+	return newExtractQueryBuilder(config, fields)
+}
+
+// partitionArg normaliza um limite vindo da configuração: textos vazios são limites abertos
+// e números inteiros decodificados como float64 voltam a ser inteiros.
+func partitionArg(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		if v == "" {
+			return nil
+		}
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return int64(v)
+		}
+	case time.Time:
+		return v
+	case fmt.Stringer:
+		// Datas locais do TOML (e.g. toml.LocalDate) não são valores aceitos pelos drivers.
+		return v.String()
+	}
+	return value
+}
+
+// normalizeBound converte MIN e MAX retornados pelo driver em int64, float64 ou time.Time.
+func normalizeBound(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return normalizeBound(string(v))
+	case string:
+		if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return i
+		}
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02"} {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t
+			}
+		}
+		return v
+	case int:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case float32:
+		return float64(v)
+	}
+	return value
+}
+
+// numberedPlaceholder gera placeholders numerados com um prefixo, como :1 (Oracle) e @p1 (SQL Server).
+type numberedPlaceholder string
+
+func (p numberedPlaceholder) ReplacePlaceholders(sql string) (string, error) {
+	var buf bytes.Buffer
+	n := 0
+	for {
+		i := strings.Index(sql, "?")
+		if i < 0 {
+			buf.WriteString(sql)
+			return buf.String(), nil
+		}
+		buf.WriteString(sql[:i])
+		if strings.HasPrefix(sql[i:], "??") {
+			buf.WriteString("?")
+			sql = sql[i+2:]
+			continue
+		}
+		n++
+		buf.WriteString(string(p) + strconv.Itoa(n))
+		sql = sql[i+1:]
+	}
+}
+
+// PlaceholderFormatFor retorna o formato de placeholder esperado pelo driver.
+func PlaceholderFormatFor(driver string) sqrl.PlaceholderFormat {
+	switch driver {
+	case "postgres":
+		return sqrl.Dollar
+	case "godror", "oracle":
+		return numberedPlaceholder(":")
+	case "sqlserver", "mssql":
+		return numberedPlaceholder("@p")
+	default:
+		return sqrl.Question
+	}
+}
+
+// LimitRows limita a consulta às primeiras limit linhas, no dialeto do driver: TOP no SQL Server,
+// FETCH FIRST no Oracle e LIMIT nos demais.
+func LimitRows(query *sqrl.SelectBuilder, driver string, limit int) *sqrl.SelectBuilder {
+	switch driver {
+	case "sqlserver", "mssql":
+		return query.Options(fmt.Sprintf("TOP %d", limit))
+	case "godror", "oracle":
+		return query.Suffix(fmt.Sprintf("FETCH FIRST %d ROWS ONLY", limit))
+	default:
+		return query.Limit(uint64(limit))
+	}
+}
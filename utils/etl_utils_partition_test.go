@@ -0,0 +1,125 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/elgris/sqrl"
+	. "github.com/faelmori/getl/etypes"
+)
+
+// TestSplitPartitionRange verifica se as faixas calculadas cobrem o intervalo sem sobreposição.
+func TestSplitPartitionRange(t *testing.T) {
+	ranges, err := SplitPartitionRange(int64(1), []byte("10"), 3)
+	if err != nil {
+		t.Fatalf("SplitPartitionRange() falhou: %v", err)
+	}
+	want := []PartitionRange{{To: int64(5)}, {From: int64(5), To: int64(9)}, {From: int64(9)}}
+	if !reflect.DeepEqual(ranges, want) {
+		t.Errorf("SplitPartitionRange() = %v, esperado %v", ranges, want)
+	}
+
+	if ranges, _ := SplitPartitionRange(int64(1), int64(2), 8); len(ranges) != 2 {
+		t.Errorf("SplitPartitionRange() deveria limitar as partições ao número de valores, retornou %v", ranges)
+	}
+	if ranges, _ := SplitPartitionRange(nil, nil, 4); len(ranges) != 1 {
+		t.Errorf("SplitPartitionRange() de tabela vazia = %v, esperado uma faixa aberta", ranges)
+	}
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ranges, err = SplitPartitionRange(start, "2024-01-05", 2)
+	if err != nil || len(ranges) != 2 || !ranges[0].To.(time.Time).Equal(start.Add(48*time.Hour)) {
+		t.Errorf("SplitPartitionRange() com datas = %v, %v", ranges, err)
+	}
+
+	if _, err := SplitPartitionRange("a", "z", 2); err == nil {
+		t.Errorf("SplitPartitionRange() deveria rejeitar colunas de texto")
+	}
+}
+
+// TestBuildPartitionQueries verifica os limites e os placeholders gerados para cada driver.
+func TestBuildPartitionQueries(t *testing.T) {
+	config := Config{
+		SourceType:   "postgres",
+		SourceTable:  "orders",
+		Where:        "status = 'open'",
+		Partitioning: Partitioning{Column: "id"},
+	}
+	ranges := []PartitionRange{{To: float64(100)}, {From: float64(100)}}
+
+	queries, err := BuildPartitionQueries(config, []string{"id", "total"}, ranges, true)
+	if err != nil {
+		t.Fatalf("BuildPartitionQueries() falhou: %v", err)
+	}
+	want := []string{
+		"SELECT id, total FROM orders WHERE status = 'open' AND id < $1",
+		"SELECT id, total FROM orders WHERE status = 'open' AND id >= $1",
+		"SELECT id, total FROM orders WHERE status = 'open' AND id IS NULL",
+	}
+	if len(queries) != len(want) {
+		t.Fatalf("BuildPartitionQueries() retornou %d consultas, esperado %d", len(queries), len(want))
+	}
+	for i, query := range queries {
+		if query.Query != want[i] {
+			t.Errorf("consulta %d = %q, esperado %q", i, query.Query, want[i])
+		}
+	}
+	if !reflect.DeepEqual(queries[0].Args, []interface{}{int64(100)}) {
+		t.Errorf("argumentos = %v", queries[0].Args)
+	}
+
+	config.SourceType = "godror"
+	config.SQLQuery = "SELECT * FROM orders"
+	queries, err = BuildPartitionQueries(config, nil, []PartitionRange{{From: "2024-01-01", To: "2024-02-01"}}, false)
+	if err != nil {
+		t.Fatalf("BuildPartitionQueries() falhou: %v", err)
+	}
+	if want := "SELECT * FROM (SELECT * FROM orders) getl_partition WHERE id >= :1 AND id < :2"; queries[0].Query != want {
+		t.Errorf("consulta = %q, esperado %q", queries[0].Query, want)
+	}
+}
+
+// TestValidatePartitioning verifica as combinações inválidas de partitioning.
+func TestValidatePartitioning(t *testing.T) {
+	base := `sourceType: sqlite3
+sourceConnectionString: a.db
+destinationType: sqlite3
+destinationConnectionString: b.db
+`
+	tests := map[string]string{
+		"partitioning:\n  count: 4\n":                                     "partitioning.column",
+		"partitioning:\n  column: id\n":                                   "partitioning",
+		"partitioning:\n  column: id\n  count: 2\n  ranges: [{to: 10}]\n": "partitioning.ranges",
+	}
+	for document, path := range tests {
+		errs := ValidateConfigData([]byte(base+document), "yaml")
+		if len(errs) != 1 || errs[0].Path != path {
+			t.Errorf("ValidateConfigData(%q) = %v, esperado erro em %s", document, errs, path)
+		}
+	}
+
+	if errs := ValidateConfigData([]byte(base+"partitioning:\n  column: id\n  ranges: [{to: 10}, {from: 10}]\n"), "yaml"); len(errs) > 0 {
+		t.Errorf("ValidateConfigData() com ranges válidas = %v", errs)
+	}
+}
+
+// TestLimitRows verifica o limite de linhas no dialeto de cada driver.
+func TestLimitRows(t *testing.T) {
+	tests := map[string]string{
+		"sqlite3":   "SELECT id FROM changes ORDER BY id LIMIT 5",
+		"postgres":  "SELECT id FROM changes ORDER BY id LIMIT 5",
+		"mysql":     "SELECT id FROM changes ORDER BY id LIMIT 5",
+		"oracle":    "SELECT id FROM changes ORDER BY id FETCH FIRST 5 ROWS ONLY",
+		"sqlserver": "SELECT TOP 5 id FROM changes ORDER BY id",
+	}
+	for driver, want := range tests {
+		query, _, err := LimitRows(sqrl.Select("id").From("changes").OrderBy("id"), driver, 5).ToSql()
+		if err != nil {
+			t.Fatalf("LimitRows(%s) falhou: %v", driver, err)
+		}
+		if query != want {
+			t.Errorf("LimitRows(%s) = %q, esperado %q", driver, query, want)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package utils
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	. "github.com/faelmori/getl/etypes"
+	"os"
+	"slices"
+	"strings"
+)
+
+// LoadPipelinesFile lê o arquivo de configuração e retorna todas as pipelines que ele define,
+// com as conexões nomeadas já resolvidas.
+func LoadPipelinesFile(fileConfigPath string) ([]Pipeline, error) {
+	fileData, err := os.ReadFile(fileConfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao ler o arquivo de configuração: %w", err)
+	}
+
+	pipelines, parseErr := ParsePipelinesData(fileData, ConfigFormatFromPath(fileConfigPath))
+	if parseErr != nil {
+		return nil, fmt.Errorf("configuração inválida em %s:\n%w", fileConfigPath, parseErr)
+	}
+
+	return pipelines, nil
+}
+
+// ParsePipelinesData decodifica o conteúdo do arquivo e retorna as pipelines definidas nele.
+// São aceitos três formatos: uma Config única, uma lista de Configs, ou um ConfigFile com
+// "connections" e "pipelines". Nos dois primeiros o nome da pipeline é a tabela de destino.
+func ParsePipelinesData(fileData []byte, format string) ([]Pipeline, error) {
+	pipelines, errs := parsePipelinesDocument(fileData, format)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return pipelines, nil
+}
+
+// SelectPipelines filtra as pipelines pelos nomes informados, na ordem pedida.
+// Sem nomes, retorna todas.
+func SelectPipelines(pipelines []Pipeline, names []string) ([]Pipeline, error) {
+	if len(names) == 0 {
+		return pipelines, nil
+	}
+
+	selected := make([]Pipeline, 0, len(names))
+	for _, name := range names {
+		index := slices.IndexFunc(pipelines, func(p Pipeline) bool { return p.Name == name })
+		if index < 0 {
+			return nil, fmt.Errorf("pipeline não encontrada: %s (disponíveis: %s)", name, strings.Join(PipelineNames(pipelines), ", "))
+		}
+		selected = append(selected, pipelines[index])
+	}
+	return selected, nil
+}
+
+// PipelineNames retorna os nomes das pipelines, na ordem do arquivo.
+func PipelineNames(pipelines []Pipeline) []string {
+	names := make([]string, 0, len(pipelines))
+	for _, pipeline := range pipelines {
+		names = append(names, pipeline.Name)
+	}
+	return names
+}
+
+// parsePipelinesDocument identifica o formato do documento, valida e converte para pipelines.
+func parsePipelinesDocument(fileData []byte, format string) ([]Pipeline, ConfigErrors) {
+	document, positions, errs := decodeConfigDocument(fileData, format)
+	if document == nil {
+		return nil, locateConfigErrors(errs, positions)
+	}
+
+	var pipelines []Pipeline
+	root, isObject := document.(map[string]interface{})
+	switch {
+	case isObject && isConfigFileDocument(root):
+		validateAgainstSchema(root, GenerateConfigFileSchema(), "", &errs)
+		connections, _ := root["connections"].(map[string]interface{})
+		if items, ok := root["pipelines"].([]interface{}); ok {
+			for i, item := range items {
+				if pipeline, ok := item.(map[string]interface{}); ok {
+					validateConfigSemantics(withConnectionTypes(pipeline, connections), fmt.Sprintf("pipelines[%d]", i), &errs)
+				}
+			}
+		}
+		if len(errs) == 0 {
+			var file ConfigFile
+			if err := convertConfigDocument(root, &file); err != nil {
+				return nil, ConfigErrors{{Message: err.Error()}}
+			}
+			pipelines, errs = resolvePipelines(file)
+		}
+	case isObject:
+		validateAgainstSchema(root, GenerateConfigSchema(), "", &errs)
+		validateConfigSemantics(root, "", &errs)
+		if len(errs) == 0 {
+			var config Config
+			if err := convertConfigDocument(root, &config); err != nil {
+				return nil, ConfigErrors{{Message: err.Error()}}
+			}
+			pipelines = []Pipeline{{Name: defaultPipelineName(config, 0), Config: config}}
+		}
+	default:
+		items, ok := document.([]interface{})
+		if !ok {
+			return nil, ConfigErrors{{Message: "a configuração deve ser um objeto ou uma lista de objetos"}}
+		}
+		schema := GenerateConfigSchema()
+		for i, item := range items {
+			path := fmt.Sprintf("[%d]", i)
+			validateAgainstSchema(item, schema, path, &errs)
+			if config, ok := item.(map[string]interface{}); ok {
+				validateConfigSemantics(config, path, &errs)
+			}
+		}
+		if len(errs) == 0 {
+			var configs []Config
+			if err := convertConfigDocument(items, &configs); err != nil {
+				return nil, ConfigErrors{{Message: err.Error()}}
+			}
+			seen := map[string]bool{}
+			for i, config := range configs {
+				name := defaultPipelineName(config, i)
+				if seen[name] {
+					errs = append(errs, ConfigError{Path: fmt.Sprintf("[%d].destinationTable", i), Message: "nome de pipeline duplicado: " + name})
+				}
+				seen[name] = true
+				pipelines = append(pipelines, Pipeline{Name: name, Config: config})
+			}
+		}
+	}
+
+	return pipelines, locateConfigErrors(errs, positions)
+}
+
+// withConnectionTypes retorna uma cópia da pipeline com sourceType e destinationType herdados das
+// conexões nomeadas em source e destination, como em resolvePipelines, para as regras semânticas
+// que dependem dos drivers.
+func withConnectionTypes(pipeline map[string]interface{}, connections map[string]interface{}) map[string]interface{} {
+	resolved := make(map[string]interface{}, len(pipeline)+2)
+	for key, value := range pipeline {
+		resolved[key] = value
+	}
+	for reference, field := range map[string]string{"source": "sourceType", "destination": "destinationType"} {
+		if current, _ := resolved[field].(string); current != "" {
+			continue
+		}
+		name, _ := pipeline[reference].(string)
+		if connection, ok := connections[name].(map[string]interface{}); ok {
+			if connectionType, ok := connection["type"].(string); ok {
+				resolved[field] = connectionType
+			}
+		}
+	}
+	return resolved
+}
+
+// resolvePipelines preenche origem e destino das pipelines a partir das conexões nomeadas
+// e verifica nomes duplicados e campos obrigatórios. Campos informados na própria pipeline
+// têm precedência sobre os da conexão.
+func resolvePipelines(file ConfigFile) ([]Pipeline, ConfigErrors) {
+	var errs ConfigErrors
+	seen := map[string]bool{}
+	pipelines := make([]Pipeline, 0, len(file.Pipelines))
+	// Pipelines com o mesmo driver e DSN compartilham um pool, criado com as opções da primeira.
+	pools := map[string]Pipeline{}
+
+	for i, pipeline := range file.Pipelines {
+		path := fmt.Sprintf("pipelines[%d]", i)
+		if seen[pipeline.Name] {
+			errs = append(errs, ConfigError{Path: path + ".name", Message: "nome de pipeline duplicado: " + pipeline.Name})
+		}
+		seen[pipeline.Name] = true
+
+		if pipeline.Source != "" {
+			if connection, ok := file.Connections[pipeline.Source]; ok {
+				pipeline.SourceType = cmp.Or(pipeline.SourceType, connection.Type)
+				pipeline.SourceConnectionString = cmp.Or(pipeline.SourceConnectionString, connection.ConnectionString)
+			} else {
+				errs = append(errs, ConfigError{Path: path + ".source", Message: "conexão não encontrada: " + pipeline.Source})
+			}
+		}
+		if pipeline.Destination != "" {
+			if connection, ok := file.Connections[pipeline.Destination]; ok {
+				pipeline.DestinationType = cmp.Or(pipeline.DestinationType, connection.Type)
+				pipeline.DestinationConnectionString = cmp.Or(pipeline.DestinationConnectionString, connection.ConnectionString)
+			} else {
+				errs = append(errs, ConfigError{Path: path + ".destination", Message: "conexão não encontrada: " + pipeline.Destination})
+			}
+		}
+
+		required := []struct{ name, value string }{
+			{"sourceType", pipeline.SourceType},
+			{"sourceConnectionString", pipeline.SourceConnectionString},
+			{"destinationType", pipeline.DestinationType},
+			{"destinationConnectionString", pipeline.DestinationConnectionString},
+		}
+		for _, field := range required {
+			if field.value == "" {
+				errs = append(errs, ConfigError{Path: path + "." + field.name, Message: "campo obrigatório ausente (informe-o ou use source/destination)"})
+			}
+		}
+
+		for _, endpoint := range [][2]string{{pipeline.SourceType, pipeline.SourceConnectionString}, {pipeline.DestinationType, pipeline.DestinationConnectionString}} {
+			if !IsSupportedDriver(endpoint[0]) || endpoint[1] == "" {
+				continue
+			}
+			key := endpoint[0] + "\x00" + endpoint[1]
+			first, shared := pools[key]
+			if !shared {
+				pools[key] = pipeline
+			} else if first.Name != pipeline.Name && !SamePoolOptions(first.Pool, pipeline.Pool) {
+				errs = append(errs, ConfigError{Path: path + ".pool", Message: fmt.Sprintf("opções de pool diferentes das da pipeline %s, que usa a mesma conexão %s", first.Name, endpoint[0])})
+				break
+			}
+		}
+
+		pipelines = append(pipelines, pipeline)
+	}
+
+	return pipelines, errs
+}
+
+// isConfigFileDocument verifica se o documento usa o formato com connections e pipelines.
+func isConfigFileDocument(document map[string]interface{}) bool {
+	_, hasPipelines := document["pipelines"]
+	_, hasConnections := document["connections"]
+	return hasPipelines || hasConnections
+}
+
+// convertConfigDocument converte o documento genérico, já validado, na struct de destino.
+// A conversão passa por JSON, que é a fonte das tags usadas pelo schema.
+func convertConfigDocument(document interface{}, target interface{}) error {
+	normalized, err := json.Marshal(document)
+	if err != nil {
+		return fmt.Errorf("falha ao normalizar a configuração: %w", err)
+	}
+	if err := json.Unmarshal(normalized, target); err != nil {
+		return fmt.Errorf("falha ao processar a configuração: %w", err)
+	}
+	return nil
+}
+
+// defaultPipelineName nomeia as pipelines de arquivos sem nomes explícitos.
+func defaultPipelineName(config Config, index int) string {
+	if config.DestinationTable != "" {
+		return config.DestinationTable
+	}
+	return fmt.Sprintf("pipeline-%d", index+1)
+}
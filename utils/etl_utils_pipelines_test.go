@@ -0,0 +1,178 @@
+package utils
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestParsePipelinesData verifica se as pipelines herdam as conexões nomeadas e se os campos
+// informados na própria pipeline têm precedência.
+func TestParsePipelinesData(t *testing.T) {
+	data := []byte(`connections:
+  erp:
+    type: sqlite3
+    connectionString: erp.db
+  warehouse:
+    type: postgres
+    connectionString: postgres://localhost/warehouse
+pipelines:
+  - name: products
+    source: erp
+    destination: warehouse
+    sourceTable: products
+    destinationTable: erp_products
+  - name: partners
+    source: erp
+    sourceConnectionString: erp_replica.db
+    destinationType: sqlite3
+    destinationConnectionString: partners.db
+    destinationTable: partners
+`)
+
+	pipelines, err := ParsePipelinesData(data, "yaml")
+	if err != nil {
+		t.Fatalf("ParsePipelinesData() falhou: %v", err)
+	}
+	if len(pipelines) != 2 {
+		t.Fatalf("ParsePipelinesData() retornou %d pipelines, esperado 2", len(pipelines))
+	}
+	if p := pipelines[0]; p.SourceType != "sqlite3" || p.SourceConnectionString != "erp.db" || p.DestinationType != "postgres" {
+		t.Errorf("products = %+v", p.Config)
+	}
+	if p := pipelines[1]; p.SourceConnectionString != "erp_replica.db" || p.DestinationConnectionString != "partners.db" {
+		t.Errorf("partners = %+v", p.Config)
+	}
+
+	selected, err := SelectPipelines(pipelines, []string{"partners"})
+	if err != nil || len(selected) != 1 || selected[0].Name != "partners" {
+		t.Errorf("SelectPipelines() = %v, %v", PipelineNames(selected), err)
+	}
+	if _, err := SelectPipelines(pipelines, []string{"orders"}); err == nil {
+		t.Errorf("SelectPipelines() deveria falhar para pipeline inexistente")
+	}
+	if _, err := ParseConfigData(data, "yaml"); err == nil {
+		t.Errorf("ParseConfigData() deveria exigir a seleção de uma pipeline")
+	}
+}
+
+// TestValidatePipelinesData verifica os erros de referência a conexões e de nomes duplicados.
+func TestValidatePipelinesData(t *testing.T) {
+	data := []byte(`{
+  "connections": {"erp": {"type": "sqlite3", "connectionString": "erp.db"}},
+  "pipelines": [
+    {"name": "a", "source": "erp", "destination": "dw"},
+    {"name": "a", "source": "erp", "destinationType": "sqlite3", "destinationConnectionString": "a.db"}
+  ]
+}`)
+
+	want := []ConfigError{
+		{Path: "pipelines[0].destinationType", Line: 4, Column: 5},
+		{Path: "pipelines[0].destinationConnectionString", Line: 4, Column: 5},
+		{Path: "pipelines[0].destination", Line: 4, Column: 36},
+		{Path: "pipelines[1].name", Line: 5, Column: 6},
+	}
+
+	got := ValidateConfigData(data, "json")
+	if len(got) != len(want) {
+		t.Fatalf("ValidateConfigData() retornou %d erros, esperado %d: %v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		if got[i].Path != w.Path || got[i].Line != w.Line || got[i].Column != w.Column {
+			t.Errorf("erro %d = %s (%d:%d), esperado %s (%d:%d)", i, got[i].Path, got[i].Line, got[i].Column, w.Path, w.Line, w.Column)
+		}
+	}
+}
+
+// TestParsePipelinesDataConnectionTypes verifica se as regras que dependem dos drivers usam os tipos
+// das conexões nomeadas.
+func TestParsePipelinesDataConnectionTypes(t *testing.T) {
+	data := []byte(`connections:
+  shop:
+    type: mysql
+    connectionString: root:getl@tcp(localhost:3306)/shop
+  cache:
+    type: redis
+    connectionString: redis://localhost:6379/0
+pipelines:
+  - name: orders
+    source: shop
+    destination: cache
+    sourceTable: orders
+    primaryKey: id
+    cdc:
+      mode: binlog
+    redis:
+      sink:
+        key: "order:{id}"
+`)
+
+	pipelines, err := ParsePipelinesData(data, "yaml")
+	if err != nil {
+		t.Fatalf("ParsePipelinesData() falhou: %v", err)
+	}
+	if p := pipelines[0]; p.SourceType != "mysql" || p.DestinationType != "redis" {
+		t.Errorf("orders = %+v", p.Config)
+	}
+
+	// Com outro driver na conexão, as mesmas regras rejeitam a pipeline.
+	invalid := []byte(strings.Replace(string(data), "type: mysql", "type: postgres", 1))
+	if errs := ValidateConfigData(invalid, "yaml"); len(errs) != 1 || errs[0].Path != "pipelines[0].cdc.mode" {
+		t.Errorf("ValidateConfigData() = %v, esperado erro só em pipelines[0].cdc.mode", errs)
+	}
+}
+
+// TestParsePipelinesDataList verifica se uma lista de Configs vira pipelines nomeadas pela tabela de destino.
+func TestParsePipelinesDataList(t *testing.T) {
+	data, err := os.ReadFile("../examples/configFiles/exp_config_a.json")
+	if err != nil {
+		t.Fatalf("falha ao ler o exemplo: %v", err)
+	}
+	list := append(append([]byte("["), data...), []byte(",\n"+string(data)+"]")...)
+
+	pipelines, parseErr := ParsePipelinesData(list, "json")
+	if parseErr == nil {
+		t.Fatalf("ParsePipelinesData() deveria rejeitar nomes duplicados, retornou %v", PipelineNames(pipelines))
+	}
+
+	single, parseErr := ParsePipelinesData(data, "json")
+	if parseErr != nil || len(single) != 1 || single[0].Name != "erp_products" {
+		t.Errorf("ParsePipelinesData() = %v, %v", PipelineNames(single), parseErr)
+	}
+}
+
+// TestValidatePipelinesPool verifica se pipelines que compartilham uma conexão exigem as mesmas opções de pool.
+func TestValidatePipelinesPool(t *testing.T) {
+	data := []byte(`connections:
+  erp:
+    type: sqlite3
+    connectionString: erp.db
+pipelines:
+  - name: products
+    source: erp
+    destinationType: sqlite3
+    destinationConnectionString: products.db
+    pool:
+      maxOpenConns: 4
+      connMaxLifetime: 30m
+  - name: partners
+    source: erp
+    destinationType: sqlite3
+    destinationConnectionString: partners.db
+    pool:
+      maxOpenConns: 4
+      connMaxLifetime: 30m0s
+      retries: 5
+  - name: orders
+    source: erp
+    destinationType: sqlite3
+    destinationConnectionString: orders.db
+    pool:
+      maxOpenConns: 8
+`)
+
+	errs := ValidateConfigData(data, "yaml")
+	if len(errs) != 1 || errs[0].Path != "pipelines[2].pool" {
+		t.Errorf("ValidateConfigData() = %v, esperado erro só em pipelines[2].pool", errs)
+	}
+}
@@ -0,0 +1,330 @@
+package utils
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"github.com/bufbuild/protocompile"
+	. "github.com/faelmori/getl/etypes"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// protobufSchemaFile é o nome sob o qual o schema é compilado.
+const protobufSchemaFile = "getl-registry.proto"
+
+// protoFieldTypes convertem os tipos genéricos das colunas para os do Protobuf. Horários e datas são
+// texto em RFC 3339.
+var protoFieldTypes = map[string]string{
+	LongField:      "int64",
+	DoubleField:    "double",
+	BooleanField:   "bool",
+	StringField:    "string",
+	BytesField:     "bytes",
+	TimestampField: "string",
+	DateField:      "string",
+}
+
+// protobufCodec é o RowCodec dos schemas Protobuf. O payload começa pelos índices da mensagem no
+// schema, como no wire format do Confluent, seguidos da mensagem serializada.
+type protobufCodec struct {
+	schema string
+	file   protoreflect.FileDescriptor
+}
+
+// NewProtobufCodec cria um schema proto3 com a mensagem name, no pacote getl, com um campo optional
+// por coluna, numerados na ordem das colunas.
+func NewProtobufCodec(name string, fields []SchemaField) (RowCodec, error) {
+	var schema strings.Builder
+	schema.WriteString("syntax = \"proto3\";\npackage getl;\n\nmessage " + name + " {\n")
+	for i, field := range fields {
+		fmt.Fprintf(&schema, "  optional %s %s = %d;\n", protoFieldTypes[field.Kind], field.Name, i+1)
+	}
+	schema.WriteString("}\n")
+	return ParseProtobufCodec(schema.String())
+}
+
+// ParseProtobufCodec compila o schema Protobuf com o protocompile. O schema pode importar os tipos
+// padrão (google/protobuf/*.proto), mas não outros arquivos.
+func ParseProtobufCodec(schema string) (RowCodec, error) {
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(map[string]string{protobufSchemaFile: schema}),
+		}),
+	}
+	files, err := compiler.Compile(context.Background(), protobufSchemaFile)
+	if err != nil {
+		return nil, fmt.Errorf("schema Protobuf inválido: %w", err)
+	}
+	if files[0].Messages().Len() == 0 {
+		return nil, fmt.Errorf("o schema Protobuf não define mensagens")
+	}
+	return &protobufCodec{schema: schema, file: files[0]}, nil
+}
+
+func (c *protobufCodec) SchemaType() string { return ProtobufSchemaType }
+func (c *protobufCodec) Schema() string     { return c.schema }
+
+// Encode serializa a linha na primeira mensagem do schema; as colunas nulas ficam sem valor.
+func (c *protobufCodec) Encode(row Data) ([]byte, error) {
+	message := dynamicpb.NewMessage(c.file.Messages().Get(0))
+	if err := protoSetFields(message, row); err != nil {
+		return nil, err
+	}
+	data, err := proto.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+	// Os índices [0], da primeira mensagem, são abreviados como um único 0.
+	return append(binary.AppendVarint(nil, 0), data...), nil
+}
+
+// Decode lê os índices da mensagem, o caminho até ela entre as mensagens do schema e as aninhadas,
+// e a decodifica.
+func (c *protobufCodec) Decode(payload []byte) (Data, error) {
+	count, n := binary.Varint(payload)
+	if n <= 0 || count < 0 {
+		return nil, fmt.Errorf("mensagem Protobuf sem os índices da mensagem")
+	}
+	payload = payload[n:]
+	indexes := []int64{0}
+	if count > 0 {
+		indexes = make([]int64, count)
+		for i := range indexes {
+			if indexes[i], n = binary.Varint(payload); n <= 0 {
+				return nil, fmt.Errorf("mensagem Protobuf com índices truncados")
+			}
+			payload = payload[n:]
+		}
+	}
+	descriptor, err := protoMessageAt(c.file.Messages(), indexes)
+	if err != nil {
+		return nil, err
+	}
+
+	message := dynamicpb.NewMessage(descriptor)
+	if err := proto.Unmarshal(payload, message); err != nil {
+		return nil, fmt.Errorf("mensagem Protobuf inválida: %w", err)
+	}
+	return protoRow(message), nil
+}
+
+// protoMessageAt segue os índices: o primeiro escolhe a mensagem do arquivo e os seguintes, as
+// mensagens aninhadas.
+func protoMessageAt(messages protoreflect.MessageDescriptors, indexes []int64) (protoreflect.MessageDescriptor, error) {
+	var descriptor protoreflect.MessageDescriptor
+	for _, index := range indexes {
+		if index < 0 || index >= int64(messages.Len()) {
+			return nil, fmt.Errorf("mensagem %v ausente do schema Protobuf", indexes)
+		}
+		descriptor = messages.Get(int(index))
+		messages = descriptor.Messages()
+	}
+	return descriptor, nil
+}
+
+// protoSetFields preenche os campos da mensagem com os valores da linha; os valores nulos ficam
+// sem valor.
+func protoSetFields(message protoreflect.Message, row map[string]interface{}) error {
+	fields := message.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		value := row[string(field.Name())]
+		if value == nil {
+			continue
+		}
+		if err := protoSetField(message, field, value); err != nil {
+			return fmt.Errorf("campo %s: %w", field.Name(), err)
+		}
+	}
+	return nil
+}
+
+func protoSetField(message protoreflect.Message, field protoreflect.FieldDescriptor, value interface{}) error {
+	switch {
+	case field.IsList():
+		items, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("o valor %v não é uma lista", value)
+		}
+		list := message.Mutable(field).List()
+		for _, item := range items {
+			if item == nil {
+				return fmt.Errorf("listas não aceitam valores nulos")
+			}
+			protoValue, err := protoFieldValue(field, item, list.NewElement)
+			if err != nil {
+				return err
+			}
+			list.Append(protoValue)
+		}
+	case field.IsMap():
+		entries, ok := protoMapValue(value)
+		if !ok {
+			return fmt.Errorf("o valor %v não é um mapa", value)
+		}
+		mapValue := message.Mutable(field).Map()
+		for key, item := range entries {
+			if item == nil {
+				return fmt.Errorf("mapas não aceitam valores nulos")
+			}
+			mapKey, err := protoFieldValue(field.MapKey(), key, nil)
+			if err != nil {
+				return err
+			}
+			protoValue, err := protoFieldValue(field.MapValue(), item, mapValue.NewValue)
+			if err != nil {
+				return err
+			}
+			mapValue.Set(mapKey.MapKey(), protoValue)
+		}
+	default:
+		protoValue, err := protoFieldValue(field, value, func() protoreflect.Value { return message.NewField(field) })
+		if err != nil {
+			return err
+		}
+		message.Set(field, protoValue)
+	}
+	return nil
+}
+
+// protoMapValue aceita Data e map[string]interface{} como mensagens e mapas.
+func protoMapValue(value interface{}) (map[string]interface{}, bool) {
+	switch v := value.(type) {
+	case Data:
+		return v, true
+	case map[string]interface{}:
+		return v, true
+	}
+	return nil, false
+}
+
+// protoFieldValue converte o valor de uma coluna para o tipo do campo. As mensagens são criadas por
+// newMessage; os enums aceitam o nome ou o número do valor.
+func protoFieldValue(field protoreflect.FieldDescriptor, value interface{}, newMessage func() protoreflect.Value) (protoreflect.Value, error) {
+	kind := field.Kind()
+	switch kind {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		row, ok := protoMapValue(value)
+		if !ok {
+			return protoreflect.Value{}, fmt.Errorf("o valor %v não é uma mensagem", value)
+		}
+		message := newMessage()
+		return message, protoSetFields(message.Message(), row)
+	case protoreflect.EnumKind:
+		if name, ok := value.(string); ok {
+			enum := field.Enum().Values().ByName(protoreflect.Name(name))
+			if enum == nil {
+				return protoreflect.Value{}, fmt.Errorf("%s não é um valor de %s", name, field.Enum().FullName())
+			}
+			return protoreflect.ValueOfEnum(enum.Number()), nil
+		}
+		i, err := schemaInt64(value)
+		if err == nil && (i < math.MinInt32 || i > math.MaxInt32) {
+			err = fmt.Errorf("%d excede um inteiro de 32 bits", i)
+		}
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(i)), err
+	case protoreflect.BoolKind:
+		b, err := schemaBool(value)
+		return protoreflect.ValueOfBool(b), err
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(schemaString(value)), nil
+	case protoreflect.BytesKind:
+		return protoreflect.ValueOfBytes(schemaBytes(value)), nil
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		f, err := schemaFloat64(value)
+		if kind == protoreflect.FloatKind {
+			return protoreflect.ValueOfFloat32(float32(f)), err
+		}
+		return protoreflect.ValueOfFloat64(f), err
+	}
+	i, err := schemaInt64(value)
+	if err != nil {
+		return protoreflect.Value{}, err
+	}
+	switch kind {
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		if i < math.MinInt32 || i > math.MaxInt32 {
+			return protoreflect.Value{}, fmt.Errorf("%d excede um inteiro de 32 bits", i)
+		}
+		return protoreflect.ValueOfInt32(int32(i)), nil
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		if i < 0 || i > math.MaxUint32 {
+			return protoreflect.Value{}, fmt.Errorf("%d excede um inteiro sem sinal de 32 bits", i)
+		}
+		return protoreflect.ValueOfUint32(uint32(i)), nil
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		if i < 0 {
+			return protoreflect.Value{}, fmt.Errorf("%d é negativo", i)
+		}
+		return protoreflect.ValueOfUint64(uint64(i)), nil
+	}
+	return protoreflect.ValueOfInt64(i), nil
+}
+
+// protoRow converte a mensagem em uma linha. Os campos sem valor, com presença registrada, são
+// nulos; as mensagens e os mapas são Data, os repeated são []interface{} e os enums, o nome do valor.
+func protoRow(message protoreflect.Message) Data {
+	fields := message.Descriptor().Fields()
+	row := make(Data, fields.Len())
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		name := string(field.Name())
+		switch {
+		case field.IsList():
+			list := message.Get(field).List()
+			values := make([]interface{}, list.Len())
+			for j := range values {
+				values[j] = protoRowValue(field, list.Get(j))
+			}
+			row[name] = values
+		case field.IsMap():
+			entries := make(Data)
+			message.Get(field).Map().Range(func(key protoreflect.MapKey, value protoreflect.Value) bool {
+				entries[key.String()] = protoRowValue(field.MapValue(), value)
+				return true
+			})
+			row[name] = entries
+		case field.HasPresence() && !message.Has(field):
+			row[name] = nil
+		default:
+			row[name] = protoRowValue(field, message.Get(field))
+		}
+	}
+	return row
+}
+
+// protoRowValue converte o valor de um campo para os tipos das linhas decodificadas do JSON: os
+// inteiros são int64 e os números de ponto flutuante, float64.
+func protoRowValue(field protoreflect.FieldDescriptor, value protoreflect.Value) interface{} {
+	switch field.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return protoRow(value.Message())
+	case protoreflect.EnumKind:
+		if enum := field.Enum().Values().ByNumber(value.Enum()); enum != nil {
+			return string(enum.Name())
+		}
+		return int64(value.Enum())
+	}
+	switch v := value.Interface().(type) {
+	case int32:
+		return int64(v)
+	case uint32:
+		return int64(v)
+	case uint64:
+		if v > math.MaxInt64 {
+			return strconv.FormatUint(v, 10)
+		}
+		return int64(v)
+	case float32:
+		return float64(v)
+	case []byte:
+		return append([]byte(nil), v...)
+	default:
+		return v
+	}
+}
@@ -0,0 +1,109 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	. "github.com/faelmori/getl/etypes"
+	"time"
+)
+
+const (
+	defaultRedisBatchSize = 100
+	defaultRedisGroup     = "getl"
+)
+
+// RedisBatchSize retorna o número de chaves ou entradas de cada leitura e de linhas de cada
+// transação no destino Redis.
+func RedisBatchSize(config Config) int {
+	if config.Redis.BatchSize > 0 {
+		return config.Redis.BatchSize
+	}
+	return defaultRedisBatchSize
+}
+
+// RedisGroup retorna o grupo e o consumidor com que o stream de origem é lido.
+func RedisGroup(config Config) (string, string) {
+	group, consumer := config.Redis.Source.Group, config.Redis.Source.Consumer
+	if group == "" {
+		group = defaultRedisGroup
+	}
+	if consumer == "" {
+		consumer = defaultRedisGroup
+	}
+	return group, consumer
+}
+
+// RedisSinkType retorna como as linhas são gravadas no destino Redis: hash, stream ou list.
+func RedisSinkType(config Config) string {
+	if config.Redis.Sink.Type != "" {
+		return config.Redis.Sink.Type
+	}
+	return "hash"
+}
+
+// RedisSinkKey retorna o modelo da chave das linhas gravadas no destino Redis: redis.sink.key ou,
+// com hash, "<destinationTable>:{<primaryKey>}" e, com stream e list, destinationTable.
+func RedisSinkKey(config Config) string {
+	if config.Redis.Sink.Key != "" {
+		return config.Redis.Sink.Key
+	}
+	if RedisSinkType(config) != "hash" {
+		return config.DestinationTable
+	}
+	if config.PrimaryKey == "" {
+		return ""
+	}
+	if config.DestinationTable == "" {
+		return "{" + config.PrimaryKey + "}"
+	}
+	return config.DestinationTable + ":{" + config.PrimaryKey + "}"
+}
+
+// RedisRowKey calcula a chave de uma linha no destino Redis com o modelo de RedisSinkKey. Um modelo
+// sem colunas entre chaves é o próprio nome da chave; uma coluna do modelo nula ou vazia é um erro,
+// para que linhas diferentes não sejam gravadas na mesma chave.
+func RedisRowKey(config Config, row Data) (string, error) {
+	template := RedisSinkKey(config)
+	var keyErr error
+	key := keyPlaceholder.ReplaceAllStringFunc(template, func(placeholder string) string {
+		column := placeholder[1 : len(placeholder)-1]
+		value, err := keyColumnValue(column, row)
+		if err == nil && value == "" {
+			err = fmt.Errorf("coluna %s da chave %s do destino Redis nula ou vazia", column, template)
+		}
+		if err != nil && keyErr == nil {
+			keyErr = err
+		}
+		return value
+	})
+	if keyErr != nil {
+		return "", keyErr
+	}
+	return key, nil
+}
+
+// RedisSinkTTL retorna a expiração dos hashes gravados; zero sem expiração.
+func RedisSinkTTL(config Config) (time.Duration, error) {
+	if config.Redis.Sink.TTL == "" {
+		return 0, nil
+	}
+	ttl, err := ParseStageTimeout(config.Redis.Sink.TTL)
+	if err != nil {
+		return 0, fmt.Errorf("redis.sink.ttl: %w", err)
+	}
+	return ttl, nil
+}
+
+// RedisFieldValue converte o valor de uma coluna no texto gravado no hash ou na entrada do stream.
+// Objetos e listas são gravados em JSON; colunas nulas não são gravadas.
+func RedisFieldValue(value interface{}) (string, bool) {
+	switch value.(type) {
+	case nil:
+		return "", false
+	case map[string]interface{}, Data, []interface{}:
+		if encoded, err := json.Marshal(value); err == nil {
+			return string(encoded), true
+		}
+	}
+	return DeleteKeyString(value), true
+}
@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/faelmori/getl/etypes"
+)
+
+func TestRedisSinkKey(t *testing.T) {
+	tests := []struct {
+		sink RedisSink
+		row  Data
+		want string
+	}{
+		{RedisSink{}, Data{"id": int64(7)}, "items:7"},
+		{RedisSink{Key: "item:{region}:{id}"}, Data{"id": 7.0, "region": "sul"}, "item:sul:7"},
+		{RedisSink{Type: "stream"}, Data{"id": int64(7)}, "items"},
+		{RedisSink{Type: "list", Key: "queue"}, Data{}, "queue"},
+	}
+	for _, test := range tests {
+		config := Config{DestinationTable: "items", PrimaryKey: "id", Redis: RedisOptions{Sink: test.sink}}
+		if key, err := RedisRowKey(config, test.row); err != nil || key != test.want {
+			t.Errorf("RedisRowKey(%+v, %v) = %s, %v, esperado %s", test.sink, test.row, key, err, test.want)
+		}
+	}
+	config := Config{DestinationTable: "items", PrimaryKey: "id"}
+	if _, err := RedisRowKey(config, Data{"id": nil}); err == nil {
+		t.Error("RedisRowKey() com a chave nula não falhou")
+	}
+
+	if ttl, err := RedisSinkTTL(Config{Redis: RedisOptions{Sink: RedisSink{TTL: "2h"}}}); err != nil || ttl != 2*time.Hour {
+		t.Errorf("RedisSinkTTL() = %v, %v", ttl, err)
+	}
+	if group, consumer := RedisGroup(Config{}); group != "getl" || consumer != "getl" || RedisBatchSize(Config{}) != 100 {
+		t.Errorf("padrões = %s, %s, %d", group, consumer, RedisBatchSize(Config{}))
+	}
+	if value, ok := RedisFieldValue(map[string]interface{}{"a": 1}); !ok || value != `{"a":1}` {
+		t.Errorf("RedisFieldValue() = %s, %v", value, ok)
+	}
+	if _, ok := RedisFieldValue(nil); ok {
+		t.Error("RedisFieldValue(nil) gravaria o campo")
+	}
+}
+
+func TestValidateRedisOptions(t *testing.T) {
+	source := "sourceType: redis\nsourceConnectionString: redis://localhost:6379/0\ndestinationType: sqlite3\ndestinationConnectionString: b.db\ndestinationTable: items\nprimaryKey: id\n"
+	sink := "sourceType: sqlite3\nsourceConnectionString: a.db\nsourceTable: items\ndestinationType: redis\ndestinationConnectionString: redis://localhost:6379/0\n"
+	invalid := map[string]string{
+		source: "redis.source",
+		source + "redis:\n  source:\n    pattern: a*\n    stream: s\n":              "redis.source",
+		source + "redis:\n  source:\n    stream: s\ncheckpoint:\n  batchSize: 10\n": "checkpoint",
+		sink: "redis.sink.key",
+		sink + "primaryKey: id\nredis:\n  sink:\n    key: items\n":                       "redis.sink.key",
+		sink + "redis:\n  sink:\n    type: stream\n":                                     "redis.sink.key",
+		sink + "primaryKey: id\nredis:\n  sink:\n    type: set\n":                        "redis.sink.type",
+		sink + "primaryKey: id\nredis:\n  sink:\n    ttl: 0s\n":                          "redis.sink.ttl",
+		sink + "destinationTable: items\nredis:\n  sink:\n    type: list\n    ttl: 1h\n": "redis.sink.ttl",
+		sink + "primaryKey: id\nredis:\n  sink:\n    maxLen: 10\n":                       "redis.sink.maxLen",
+		sink + "primaryKey: id\nloadMode: scd2\n":                                        "loadMode",
+		sink + "primaryKey: id\nredis:\n  source:\n    pattern: a*\n":                    "redis.source",
+	}
+	for document, path := range invalid {
+		errs := ValidateConfigData([]byte(document), "yaml")
+		if len(errs) != 1 || errs[0].Path != path {
+			t.Errorf("ValidateConfigData(%q) = %v, esperado erro em %s", document, errs, path)
+		}
+	}
+	valid := []string{
+		source + "redis:\n  source:\n    pattern: items:*\n    keyField: key\n",
+		source + "redis:\n  source:\n    stream: events\n    group: loader\n  batchSize: 500\n",
+		sink + "primaryKey: id\nredis:\n  sink:\n    ttl: 24h\n",
+		sink + "redis:\n  sink:\n    type: stream\n    key: \"events:{region}\"\n    maxLen: 10000\n",
+	}
+	for _, document := range valid {
+		if errs := ValidateConfigData([]byte(document), "yaml"); len(errs) > 0 {
+			t.Errorf("ValidateConfigData(%q) = %v", document, errs)
+		}
+	}
+}
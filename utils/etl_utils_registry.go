@@ -0,0 +1,290 @@
+package utils
+
+import (
+	"encoding/binary"
+	"fmt"
+	. "github.com/faelmori/getl/etypes"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Tipos de schema da API do schema registry.
+const (
+	AvroSchemaType     = "AVRO"
+	ProtobufSchemaType = "PROTOBUF"
+)
+
+// Tipos genéricos das colunas nos schemas das mensagens, deduzidos do tipo da coluna de origem.
+const (
+	LongField      = "long"
+	DoubleField    = "double"
+	BooleanField   = "boolean"
+	StringField    = "string"
+	BytesField     = "bytes"
+	TimestampField = "timestamp"
+	DateField      = "date"
+)
+
+// schemaMagicByte inicia as mensagens serializadas com um schema registrado (wire format do Confluent).
+const schemaMagicByte = 0
+
+var schemaNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// SchemaField é uma coluna do schema das mensagens.
+type SchemaField struct {
+	Name string
+	Kind string
+}
+
+// RowCodec serializa e desserializa linhas com um schema Avro ou Protobuf.
+type RowCodec interface {
+	// SchemaType é o tipo do schema no registry: AVRO ou PROTOBUF.
+	SchemaType() string
+	// Schema é o texto do schema, como registrado.
+	Schema() string
+	Encode(row Data) ([]byte, error)
+	Decode(payload []byte) (Data, error)
+}
+
+// NewRowCodec cria o codec de kafka.format (avro ou protobuf) para linhas com as colunas de fields.
+// name é o nome do registro ou da mensagem no schema.
+func NewRowCodec(format, name string, fields []SchemaField) (RowCodec, error) {
+	for _, field := range fields {
+		if !schemaNamePattern.MatchString(field.Name) {
+			return nil, fmt.Errorf("a coluna %q não é um nome válido em um schema %s; renomeie-a na consulta (AS)", field.Name, format)
+		}
+	}
+	switch format {
+	case "avro":
+		return NewAvroCodec(name, fields)
+	case "protobuf":
+		return NewProtobufCodec(name, fields)
+	}
+	return nil, fmt.Errorf("kafka.format %s não usa schema registry", format)
+}
+
+// ParseRowCodec cria o codec de um schema lido do registry. schemaType vazio é AVRO, como na API.
+func ParseRowCodec(schemaType, schema string) (RowCodec, error) {
+	switch schemaType {
+	case "", AvroSchemaType:
+		return ParseAvroCodec(schema)
+	case ProtobufSchemaType:
+		return ParseProtobufCodec(schema)
+	}
+	return nil, fmt.Errorf("tipo de schema não suportado: %s", schemaType)
+}
+
+// SchemaSubject retorna o subject do schema das mensagens: kafka.schemaRegistry.subject ou, sem
+// ele, "<kafkaTopic>-value", a estratégia padrão dos clientes do Confluent.
+func SchemaSubject(config Config) string {
+	if config.Kafka.SchemaRegistry.Subject != "" {
+		return config.Kafka.SchemaRegistry.Subject
+	}
+	return config.KafkaTopic + "-value"
+}
+
+// SchemaRecordName retorna o nome do registro Avro ou da mensagem Protobuf: sourceTable, com os
+// caracteres inválidos trocados por _, ou "row" sem ela.
+func SchemaRecordName(config Config) string {
+	table := config.SourceTable
+	if i := strings.LastIndex(table, "."); i >= 0 {
+		table = table[i+1:]
+	}
+	name := strings.Map(func(r rune) rune {
+		if r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, table)
+	if name == "" {
+		return "row"
+	}
+	if name[0] >= '0' && name[0] <= '9' {
+		return "_" + name
+	}
+	return name
+}
+
+// MessageSchemaFields deduz os campos do schema das colunas da consulta e de seus tipos no banco
+// (sql.ColumnType.DatabaseTypeName). Os DECIMAL e NUMERIC são texto, para manter a precisão.
+func MessageSchemaFields(columns, databaseTypes []string) []SchemaField {
+	fields := make([]SchemaField, len(columns))
+	for i, column := range columns {
+		databaseType := ""
+		if i < len(databaseTypes) {
+			databaseType = databaseTypes[i]
+		}
+		fields[i] = SchemaField{Name: column, Kind: SchemaFieldKind(databaseType)}
+	}
+	return fields
+}
+
+// SchemaFieldKind converte o tipo de uma coluna no banco para o tipo genérico do schema.
+func SchemaFieldKind(databaseType string) string {
+	base := strings.ToUpper(strings.TrimSpace(databaseType))
+	if i := strings.Index(base, "("); i >= 0 {
+		base = strings.TrimSpace(base[:i])
+	}
+	base = strings.TrimPrefix(base, "UNSIGNED ")
+	switch {
+	case base == "BOOL" || base == "BOOLEAN" || base == "BIT":
+		return BooleanField
+	case base == "DATE":
+		return DateField
+	case strings.Contains(base, "TIMESTAMP") || strings.Contains(base, "DATETIME"):
+		return TimestampField
+	case strings.Contains(base, "INT") && !strings.Contains(base, "INTERVAL") || strings.HasSuffix(base, "SERIAL"):
+		return LongField
+	case strings.Contains(base, "FLOAT") || strings.Contains(base, "DOUBLE") || base == "REAL":
+		return DoubleField
+	case strings.Contains(base, "BLOB") || strings.Contains(base, "BINARY") || base == "BYTEA" || base == "RAW" || base == "IMAGE":
+		return BytesField
+	}
+	return StringField
+}
+
+// EncodeSchemaMessage monta o valor da mensagem no wire format do Confluent: o byte 0, o id do
+// schema em 4 bytes big-endian e o payload.
+func EncodeSchemaMessage(schemaID int, payload []byte) []byte {
+	message := make([]byte, 5, 5+len(payload))
+	message[0] = schemaMagicByte
+	binary.BigEndian.PutUint32(message[1:], uint32(schemaID))
+	return append(message, payload...)
+}
+
+// DecodeSchemaMessage separa o id do schema e o payload de uma mensagem no wire format do
+// Confluent. Retorna false se a mensagem não está nesse formato, como as mensagens JSON.
+func DecodeSchemaMessage(value []byte) (int, []byte, bool) {
+	if len(value) < 5 || value[0] != schemaMagicByte {
+		return 0, nil, false
+	}
+	return int(binary.BigEndian.Uint32(value[1:5])), value[5:], true
+}
+
+// schemaInt64 converte o valor de uma coluna para os inteiros do schema.
+func schemaInt64(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case int64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case int16:
+		return int64(v), nil
+	case int8:
+		return int64(v), nil
+	case uint64:
+		if v > math.MaxInt64 {
+			return 0, fmt.Errorf("%d excede um inteiro de 64 bits", v)
+		}
+		return int64(v), nil
+	case uint32:
+		return int64(v), nil
+	case uint16:
+		return int64(v), nil
+	case uint8:
+		return int64(v), nil
+	case float64:
+		if v != math.Trunc(v) {
+			return 0, fmt.Errorf("%v não é um inteiro", v)
+		}
+		return int64(v), nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case []byte:
+		return strconv.ParseInt(strings.TrimSpace(string(v)), 10, 64)
+	case string:
+		return strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+	}
+	return 0, fmt.Errorf("%v (%T) não é um inteiro", value, value)
+}
+
+// schemaFloat64 converte o valor de uma coluna para os números de ponto flutuante do schema.
+func schemaFloat64(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case []byte:
+		return strconv.ParseFloat(strings.TrimSpace(string(v)), 64)
+	case string:
+		return strconv.ParseFloat(strings.TrimSpace(v), 64)
+	}
+	i, err := schemaInt64(value)
+	if err != nil {
+		return 0, fmt.Errorf("%v (%T) não é um número", value, value)
+	}
+	return float64(i), nil
+}
+
+// schemaBool converte o valor de uma coluna para os booleanos do schema.
+func schemaBool(value interface{}) (bool, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case []byte:
+		return strconv.ParseBool(strings.TrimSpace(string(v)))
+	case string:
+		return strconv.ParseBool(strings.TrimSpace(v))
+	}
+	i, err := schemaInt64(value)
+	if err != nil {
+		return false, fmt.Errorf("%v (%T) não é um booleano", value, value)
+	}
+	return i != 0, nil
+}
+
+// schemaString converte o valor de uma coluna para os textos do schema; os horários ficam em RFC 3339.
+func schemaString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(value)
+}
+
+// schemaBytes converte o valor de uma coluna para os bytes do schema.
+func schemaBytes(value interface{}) []byte {
+	if v, ok := value.([]byte); ok {
+		return v
+	}
+	return []byte(schemaString(value))
+}
+
+// schemaTime converte o valor de uma coluna para os horários do schema. Os textos são lidos em
+// RFC 3339 ou nos formatos de data e hora dos bancos; os números, como milissegundos desde 1970.
+func schemaTime(value interface{}) (time.Time, error) {
+	var text string
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case []byte:
+		text = string(v)
+	case string:
+		text = v
+	default:
+		ms, err := schemaInt64(value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%v (%T) não é um horário", value, value)
+		}
+		return time.UnixMilli(ms).UTC(), nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999Z07:00", "2006-01-02 15:04:05.999999999", "2006-01-02"} {
+		if t, err := time.Parse(layout, strings.TrimSpace(text)); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q não é um horário", text)
+}
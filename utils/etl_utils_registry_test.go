@@ -0,0 +1,242 @@
+package utils
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	. "github.com/faelmori/getl/etypes"
+)
+
+// TestSchemaFieldKind verifica o tipo do schema deduzido dos tipos das colunas de cada banco.
+func TestSchemaFieldKind(t *testing.T) {
+	tests := map[string]string{
+		"INTEGER": LongField, "INT8": LongField, "bigint unsigned": LongField, "BIGSERIAL": LongField,
+		"FLOAT8": DoubleField, "REAL": DoubleField, "DOUBLE PRECISION": DoubleField,
+		"NUMERIC(10,2)": StringField, "DECIMAL": StringField, "VARCHAR(20)": StringField, "INTERVAL": StringField, "": StringField,
+		"BOOL": BooleanField, "TIMESTAMPTZ": TimestampField, "DATETIME2": TimestampField, "DATE": DateField,
+		"BYTEA": BytesField, "LONGBLOB": BytesField, "VARBINARY": BytesField,
+	}
+	for databaseType, want := range tests {
+		if got := SchemaFieldKind(databaseType); got != want {
+			t.Errorf("SchemaFieldKind(%q) = %s, esperado %s", databaseType, got, want)
+		}
+	}
+	if name := SchemaRecordName(Config{SourceTable: "public.order-items"}); name != "order_items" {
+		t.Errorf("SchemaRecordName() = %s", name)
+	}
+	if subject := SchemaSubject(Config{KafkaTopic: "items"}); subject != "items-value" {
+		t.Errorf("SchemaSubject() = %s", subject)
+	}
+}
+
+// TestSchemaMessage verifica o wire format do Confluent.
+func TestSchemaMessage(t *testing.T) {
+	message := EncodeSchemaMessage(258, []byte("payload"))
+	if !bytes.Equal(message[:5], []byte{0, 0, 0, 1, 2}) {
+		t.Fatalf("EncodeSchemaMessage() = %v", message)
+	}
+	id, payload, ok := DecodeSchemaMessage(message)
+	if !ok || id != 258 || string(payload) != "payload" {
+		t.Errorf("DecodeSchemaMessage() = %d, %q, %v", id, payload, ok)
+	}
+	if _, _, ok := DecodeSchemaMessage([]byte(`{"id": 1}`)); ok {
+		t.Errorf("DecodeSchemaMessage() aceitou uma mensagem JSON")
+	}
+}
+
+// TestRowCodecRoundTrip verifica que as linhas serializadas com o schema deduzido das colunas são
+// decodificadas com o schema registrado, em Avro e em Protobuf.
+func TestRowCodecRoundTrip(t *testing.T) {
+	fields := MessageSchemaFields(
+		[]string{"id", "name", "price", "active", "created_at", "birthday", "photo", "amount"},
+		[]string{"INTEGER", "TEXT", "REAL", "BOOLEAN", "DATETIME", "DATE", "BLOB", "DECIMAL(10,2)"})
+	created := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	row := Data{"id": int64(7), "name": []byte("caneta"), "price": 2.5, "active": int64(1), "created_at": created,
+		"birthday": "2000-02-29", "photo": []byte{0, 1, 2}, "amount": []byte("10.50")}
+
+	wants := map[string]Data{
+		"avro": {"id": int64(7), "name": "caneta", "price": 2.5, "active": true, "created_at": created,
+			"birthday": time.Date(2000, 2, 29, 0, 0, 0, 0, time.UTC), "photo": []byte{0, 1, 2}, "amount": "10.50"},
+		"protobuf": {"id": int64(7), "name": "caneta", "price": 2.5, "active": true, "created_at": "2024-05-01T12:30:00Z",
+			"birthday": "2000-02-29", "photo": []byte{0, 1, 2}, "amount": "10.50"},
+	}
+	for format, want := range wants {
+		codec, err := NewRowCodec(format, "items", fields)
+		if err != nil {
+			t.Fatalf("NewRowCodec(%s) = %v", format, err)
+		}
+		parsed, err := ParseRowCodec(codec.SchemaType(), codec.Schema())
+		if err != nil {
+			t.Fatalf("ParseRowCodec(%s) = %v\n%s", format, err, codec.Schema())
+		}
+		for _, input := range []Data{row, {"id": int64(8)}} {
+			payload, err := codec.Encode(input)
+			if err != nil {
+				t.Fatalf("%s: Encode() = %v", format, err)
+			}
+			got, err := parsed.Decode(payload)
+			if err != nil {
+				t.Fatalf("%s: Decode() = %v", format, err)
+			}
+			expected := want
+			if len(input) == 1 {
+				expected = Data{"id": int64(8), "name": nil, "price": nil, "active": nil, "created_at": nil, "birthday": nil, "photo": nil, "amount": nil}
+			}
+			if !reflect.DeepEqual(got, expected) {
+				t.Errorf("%s: Decode() = %v, esperado %v", format, got, expected)
+			}
+		}
+	}
+
+	if _, err := NewRowCodec("avro", "items", []SchemaField{{Name: "unit price", Kind: DoubleField}}); err == nil {
+		t.Errorf("NewRowCodec() aceitou uma coluna com espaço no nome")
+	}
+}
+
+// TestParseAvroCodec verifica a decodificação de schemas Avro que não foram criados pelo getl.
+func TestParseAvroCodec(t *testing.T) {
+	codec, err := ParseAvroCodec(`{"type": "record", "name": "Order", "namespace": "shop", "fields": [
+		{"name": "id", "type": "int"},
+		{"name": "status", "type": {"type": "enum", "name": "Status", "symbols": ["NEW", "PAID"]}},
+		{"name": "total", "type": {"type": "bytes", "logicalType": "decimal", "precision": 6, "scale": 2}},
+		{"name": "tags", "type": {"type": "array", "items": "string"}},
+		{"name": "previous", "type": ["null", "Status"]}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// id 3, PAID, -12.34 (-1234 em complemento de dois), ["a"] e o Status NEW.
+	payload := []byte{6, 2, 4, 0xfb, 0x2e, 2, 2, 'a', 0, 2, 0}
+	got, err := codec.Decode(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Data{"id": int64(3), "status": "PAID", "total": "-12.34", "tags": []interface{}{"a"}, "previous": "NEW"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode() = %v, esperado %v", got, want)
+	}
+	if _, err := codec.Decode(payload[:3]); err == nil {
+		t.Errorf("Decode() aceitou uma mensagem truncada")
+	}
+
+	nested, err := ParseAvroCodec(`{"type": "record", "name": "Cart", "fields": [
+		{"name": "totals", "type": ["null", {"type": "map", "values": "long"}]},
+		{"name": "customer", "type": ["null", {"type": "record", "name": "Customer", "fields": [{"name": "name", "type": "string"}]}]},
+		{"name": "items", "type": {"type": "array", "items": "int"}}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cart := Data{"totals": Data{"BRL": int64(150)}, "customer": Data{"name": "Ana"}, "items": []interface{}{int64(1), int64(2)}}
+	if payload, err = nested.Encode(cart); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = nested.Decode(payload); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, cart) {
+		t.Errorf("Decode() = %v, esperado %v", got, cart)
+	}
+	if _, err := ParseAvroCodec(`"string"`); err == nil {
+		t.Errorf("ParseAvroCodec() aceitou um schema que não é um record")
+	}
+}
+
+// TestParseProtobufCodec verifica os schemas Protobuf aceitos e a escolha da mensagem pelos índices.
+func TestParseProtobufCodec(t *testing.T) {
+	codec, err := ParseProtobufCodec(`syntax = "proto3";
+// pedidos
+message Key { string id = 1; }
+message Order {
+  int32 id = 1;
+  repeated string tags = 2 [packed = false];
+  optional uint64 total = 3;
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Índices [1] (a mensagem Order), id 5, tags ["a"] e total ausente.
+	payload := []byte{2, 2, 0x08, 5, 0x12, 1, 'a'}
+	got, err := codec.Decode(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Data{"id": int64(5), "tags": []interface{}{"a"}, "total": nil}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode() = %v, esperado %v", got, want)
+	}
+
+	nested, err := ParseProtobufCodec(`syntax = "proto3";
+package shop;
+import "google/protobuf/wrappers.proto";
+enum Status { NEW = 0; PAID = 1; }
+message Order {
+  message Line { string sku = 1; int32 quantity = 2; }
+  Status status = 1;
+  repeated Line lines = 2;
+  map<string, int64> totals = 3;
+  google.protobuf.StringValue note = 4;
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	order := Data{
+		"status": "PAID",
+		"lines":  []interface{}{Data{"sku": "a", "quantity": int64(2)}},
+		"totals": Data{"BRL": int64(150)},
+		"note":   Data{"value": "urgente"},
+	}
+	payload, err = nested.Encode(order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err = nested.Decode(payload); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, order) {
+		t.Errorf("Decode() = %v, esperado %v", got, order)
+	}
+	// Índices [0, 0]: a mensagem Line, aninhada em Order.
+	if got, err = nested.Decode([]byte{4, 0, 0, 0x0a, 1, 'b'}); err != nil {
+		t.Fatal(err)
+	} else if want := (Data{"sku": "b", "quantity": int64(0)}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode() = %v, esperado %v", got, want)
+	}
+	if _, err := nested.Decode([]byte{4, 0, 10}); err == nil {
+		t.Error("Decode() de uma mensagem ausente sem erro")
+	}
+	if _, err := nested.Encode(Data{"status": "CANCELED"}); err == nil {
+		t.Error("Encode() de um valor de enum desconhecido sem erro")
+	}
+
+	for _, schema := range []string{
+		`syntax = "proto3"; message Order { Status status = 1; }`,
+		`syntax = "proto3"; import "other.proto"; message Order { int32 id = 1; }`,
+		`syntax = "proto3"; enum Status { NEW = 0; }`,
+		`syntax = "proto3"; message Order { int32 id = 1 }`,
+	} {
+		if _, err := ParseProtobufCodec(schema); err == nil {
+			t.Errorf("ParseProtobufCodec(%q) sem erro", schema)
+		}
+	}
+}
+
+// TestValidateSchemaRegistry verifica as exigências dos formatos avro e protobuf.
+func TestValidateSchemaRegistry(t *testing.T) {
+	base := "sourceType: sqlite3\nsourceConnectionString: a.db\ndestinationType: sqlite3\ndestinationConnectionString: b.db\nsourceTable: items\nprimaryKey: id\n"
+	tests := map[string]string{
+		"kafka:\n  format: avro\n":   "kafka.schemaRegistry.url",
+		"kafka:\n  format: thrift\n": "kafka.format",
+		"kafka:\n  format: protobuf\n  schemaRegistry:\n    url: http://localhost:8081\nkafkaURL: localhost:9092\nkafkaTopic: items\ncdc:\n  mode: triggers\n  sink: kafka\n": "kafka.format",
+	}
+	for document, path := range tests {
+		errs := ValidateConfigData([]byte(base+document), "yaml")
+		if len(errs) != 1 || errs[0].Path != path {
+			t.Errorf("ValidateConfigData(%q) = %v, esperado erro em %s", document, errs, path)
+		}
+	}
+	valid := "kafka:\n  format: protobuf\n  schemaRegistry:\n    url: http://localhost:8081\n    subject: items-proto\n"
+	if errs := ValidateConfigData([]byte(base+valid), "yaml"); len(errs) > 0 {
+		t.Errorf("ValidateConfigData(%q) = %v", valid, errs)
+	}
+}
@@ -0,0 +1,122 @@
+package utils
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	. "github.com/faelmori/getl/etypes"
+	"github.com/lib/pq"
+	"io"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// maxRetryBackoff limita a espera entre duas tentativas, qualquer que seja o número da tentativa.
+const maxRetryBackoff = 30 * time.Second
+
+// transientErrorMessages são trechos de mensagens de erro de drivers que indicam uma falha
+// passageira de conexão, para os drivers que não expõem um tipo de erro verificável.
+var transientErrorMessages = []string{
+	"too many connections",
+	"connection refused",
+	"connection reset",
+	"broken pipe",
+	"i/o timeout",
+	"database is locked",
+	"ORA-12541", // sem listener
+	"ORA-12170", // timeout de conexão
+	"ORA-12537", // conexão encerrada
+	"ORA-03113", // fim de arquivo no canal de comunicação
+	"ORA-03114", // não conectado
+}
+
+// ParseDuration interpreta uma duração Go positiva das opções de configuração. Vazio retorna zero.
+func ParseDuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("duração inválida: %q (e.g. 500ms, 30s, 5m)", value)
+	}
+	if duration <= 0 {
+		return 0, fmt.Errorf("duração deve ser positiva: %s", value)
+	}
+	return duration, nil
+}
+
+// SamePoolOptions indica se a e b configuram o *sql.DB da mesma forma: mesmos limites de conexões e
+// mesmas durações, ainda que escritas de outro jeito ("30m" e "30m0s"). Retries e RetryBackoff
+// valem por chamada e não são comparados.
+func SamePoolOptions(a, b Pool) bool {
+	if a.MaxOpenConns != b.MaxOpenConns || a.MaxIdleConns != b.MaxIdleConns {
+		return false
+	}
+	for _, durations := range [][2]string{{a.ConnMaxLifetime, b.ConnMaxLifetime}, {a.ConnMaxIdleTime, b.ConnMaxIdleTime}} {
+		first, firstErr := ParseDuration(durations[0])
+		second, secondErr := ParseDuration(durations[1])
+		if firstErr != nil || secondErr != nil {
+			if strings.TrimSpace(durations[0]) != strings.TrimSpace(durations[1]) {
+				return false
+			}
+			continue
+		}
+		if first != second {
+			return false
+		}
+	}
+	return true
+}
+
+// BackoffWithJitter retorna a espera antes da tentativa attempt (a partir de 1): base dobrada a cada
+// tentativa, limitada a 30s, com metade do valor sorteada para que clientes não reconectem juntos.
+func BackoffWithJitter(base time.Duration, attempt int) time.Duration {
+	backoff := maxRetryBackoff
+	if attempt < 32 {
+		backoff = min(base<<(attempt-1), maxRetryBackoff)
+	}
+	if backoff <= 0 {
+		return 0
+	}
+	half := backoff / 2
+	return half + rand.N(half+1)
+}
+
+// IsTransientError indica se o erro de conexão é passageiro e vale uma nova tentativa, como
+// recusa de conexão, timeout de rede ou excesso de conexões. Erros de autenticação e de DSN não são.
+func IsTransientError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ETIMEDOUT) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+
+	// Postgres: classe 08 (connection exception), too_many_connections e cannot_connect_now.
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code.Class() == "08" || pqErr.Code == "53300" || pqErr.Code == "57P03"
+	}
+
+	message := strings.ToLower(err.Error())
+	for _, transient := range transientErrorMessages {
+		if strings.Contains(message, strings.ToLower(transient)) {
+			return true
+		}
+	}
+	return false
+}
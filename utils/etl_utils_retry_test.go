@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// TestBackoffWithJitter verifica se a espera dobra a cada tentativa, com jitter e limite de 30s.
+func TestBackoffWithJitter(t *testing.T) {
+	base := 100 * time.Millisecond
+	for attempt := 1; attempt <= 40; attempt++ {
+		limit := min(base<<min(attempt-1, 31), maxRetryBackoff)
+		for range 20 {
+			wait := BackoffWithJitter(base, attempt)
+			if wait < limit/2 || wait > limit {
+				t.Fatalf("BackoffWithJitter(%s, %d) = %s, esperado entre %s e %s", base, attempt, wait, limit/2, limit)
+			}
+		}
+	}
+}
+
+// TestIsTransientError verifica a classificação dos erros de conexão.
+func TestIsTransientError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{driver.ErrBadConn, true},
+		{fmt.Errorf("ping: %w", syscall.ECONNREFUSED), true},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("no route to host")}, true},
+		{&pq.Error{Code: "53300", Message: "sorry, too many clients already"}, true},
+		{&pq.Error{Code: "08006", Message: "connection failure"}, true},
+		{&pq.Error{Code: "28P01", Message: "password authentication failed"}, false},
+		{errors.New("Error 1040 (08004): Too many connections"), true},
+		{errors.New("ORA-12541: TNS:no listener"), true},
+		{errors.New("ORA-01017: invalid username/password"), false},
+		{errors.New("unknown driver \"foo\""), false},
+	}
+	for _, tt := range tests {
+		if got := IsTransientError(tt.err); got != tt.want {
+			t.Errorf("IsTransientError(%v) = %v, esperado %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+// TestValidatePool verifica as durações das opções de pool.
+func TestValidatePool(t *testing.T) {
+	data := []byte(`sourceType: sqlite3
+sourceConnectionString: a.db
+destinationType: sqlite3
+destinationConnectionString: b.db
+pool:
+  maxOpenConns: 8
+  connMaxLifetime: 30m
+  retryBackoff: rápido
+`)
+	errs := ValidateConfigData(data, "yaml")
+	if len(errs) != 1 || errs[0].Path != "pool.retryBackoff" || errs[0].Line != 8 {
+		t.Errorf("ValidateConfigData() = %v, esperado erro em pool.retryBackoff", errs)
+	}
+}
@@ -0,0 +1,134 @@
+package utils
+
+import (
+	"cmp"
+	"fmt"
+	. "github.com/faelmori/getl/etypes"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SCD2Settings retorna a configuração do SCD2 com os nomes padrão das colunas de versão.
+func SCD2Settings(config Config) SlowlyChangingDimension {
+	settings := config.SCD2
+	settings.SurrogateKey = cmp.Or(settings.SurrogateKey, "sk")
+	settings.ValidFrom = cmp.Or(settings.ValidFrom, "valid_from")
+	settings.ValidTo = cmp.Or(settings.ValidTo, "valid_to")
+	settings.CurrentFlag = cmp.Or(settings.CurrentFlag, "is_current")
+	return settings
+}
+
+// SurrogateKeyDefinition retorna a definição da coluna de chave gerada pelo banco, no dialeto do driver.
+func SurrogateKeyDefinition(driver, column string) string {
+	switch driver {
+	case "postgres":
+		return column + " BIGSERIAL PRIMARY KEY"
+	case "mysql":
+		return column + " BIGINT AUTO_INCREMENT PRIMARY KEY"
+	case "sqlserver", "mssql":
+		return column + " BIGINT IDENTITY(1,1) PRIMARY KEY"
+	case "godror", "oracle":
+		return column + " NUMBER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY"
+	}
+	return column + " INTEGER PRIMARY KEY AUTOINCREMENT"
+}
+
+// SCD2TrackedColumns retorna as colunas comparadas entre a versão corrente e a linha extraída: as
+// configuradas ou, sem elas, todas as colunas da linha exceto a chave, em ordem alfabética.
+func SCD2TrackedColumns(config Config, key string, row Data) []string {
+	if len(config.SCD2.TrackedColumns) > 0 {
+		return config.SCD2.TrackedColumns
+	}
+	columns := make([]string, 0, len(row))
+	for column := range row {
+		if column != key {
+			columns = append(columns, column)
+		}
+	}
+	slices.Sort(columns)
+	return columns
+}
+
+// SCD2Changed indica se alguma das colunas rastreadas difere entre a versão corrente e a linha extraída.
+func SCD2Changed(current, row Data, columns []string) bool {
+	for _, column := range columns {
+		if !SameValue(current[column], row[column]) {
+			return true
+		}
+	}
+	return false
+}
+
+// SameValue compara um valor lido do destino com o extraído da origem, que os drivers podem
+// retornar em tipos diferentes: texto e []byte, inteiros e números de ponto flutuante, números em
+// texto (DECIMAL), booleanos gravados como 0 e 1 e horários em texto, comparados como instantes.
+func SameValue(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if t, ok := a.(time.Time); ok {
+		return sameTime(t, b)
+	}
+	if t, ok := b.(time.Time); ok {
+		return sameTime(t, a)
+	}
+	left, right := comparableValue(a), comparableValue(b)
+	if left == right {
+		return true
+	}
+	leftNumber, leftErr := strconv.ParseFloat(left, 64)
+	rightNumber, rightErr := strconv.ParseFloat(right, 64)
+	return leftErr == nil && rightErr == nil && leftNumber == rightNumber
+}
+
+// sameTime compara t com um horário, ou o seu texto em um dos formatos dos bancos. Os textos sem
+// fuso estão no fuso de t.
+func sameTime(t time.Time, value interface{}) bool {
+	var text string
+	switch v := value.(type) {
+	case time.Time:
+		return t.Equal(v)
+	case []byte:
+		text = strings.TrimSpace(string(v))
+	case string:
+		text = strings.TrimSpace(v)
+	default:
+		return false
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999Z07:00", "2006-01-02 15:04:05.999999999-07"} {
+		if parsed, err := time.Parse(layout, text); err == nil {
+			return t.Equal(parsed)
+		}
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05.999999999", "2006-01-02 15:04:05.999999999", "2006-01-02"} {
+		if parsed, err := time.ParseInLocation(layout, text, t.Location()); err == nil {
+			return t.Equal(parsed)
+		}
+	}
+	return false
+}
+
+func comparableValue(value interface{}) string {
+	if v, ok := value.(bool); ok {
+		if v {
+			return "1"
+		}
+		return "0"
+	}
+	return strings.TrimSpace(DeleteKeyString(value))
+}
+
+// ValidateSCD2Row verifica se a linha transformada tem a chave e as colunas rastreadas.
+func ValidateSCD2Row(config Config, key string, row Data) error {
+	if value, ok := row[key]; !ok || value == nil {
+		return fmt.Errorf("chave %s ausente na linha", key)
+	}
+	for _, column := range config.SCD2.TrackedColumns {
+		if _, ok := row[column]; !ok {
+			return fmt.Errorf("coluna rastreada %s ausente na linha transformada", column)
+		}
+	}
+	return nil
+}
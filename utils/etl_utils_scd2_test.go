@@ -0,0 +1,119 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	. "github.com/faelmori/getl/etypes"
+)
+
+// TestSameValue verifica a comparação de valores que os drivers retornam em tipos diferentes.
+func TestSameValue(t *testing.T) {
+	at := time.Date(2024, 3, 1, 9, 30, 15, 250000000, time.FixedZone("BRT", -3*3600))
+	same := [][2]interface{}{
+		{nil, nil},
+		{"a", []byte("a")},
+		{int64(2), float64(2)},
+		{"10.50", 10.5},
+		{true, int64(1)},
+		{false, "0"},
+		{at, at.UTC()},
+		{at, "2024-03-01 09:30:15.25-03:00"},
+		{"2024-03-01T12:30:15.25Z", at},
+		{at, []byte("2024-03-01 09:30:15.25")},
+		{at, "2024-03-01 09:30:15.25-03"},
+	}
+	for _, pair := range same {
+		if !SameValue(pair[0], pair[1]) {
+			t.Errorf("SameValue(%v, %v) = false", pair[0], pair[1])
+		}
+	}
+	different := [][2]interface{}{
+		{nil, ""},
+		{"a", "b"},
+		{int64(2), 2.5},
+		{true, int64(0)},
+		{at, "2024-03-01 09:30:15"},
+		{at, at.Add(time.Second)},
+		{at, "amanhã"},
+	}
+	for _, pair := range different {
+		if SameValue(pair[0], pair[1]) {
+			t.Errorf("SameValue(%v, %v) = true", pair[0], pair[1])
+		}
+	}
+}
+
+// TestSCD2TrackedColumns verifica as colunas rastreadas configuradas e as padrão.
+func TestSCD2TrackedColumns(t *testing.T) {
+	row := Data{"id": 1, "price": 10.0, "name": "a"}
+	if columns := SCD2TrackedColumns(Config{}, "id", row); !reflect.DeepEqual(columns, []string{"name", "price"}) {
+		t.Errorf("SCD2TrackedColumns() = %v", columns)
+	}
+	config := Config{SCD2: SlowlyChangingDimension{TrackedColumns: []string{"price"}}}
+	columns := SCD2TrackedColumns(config, "id", row)
+	if !reflect.DeepEqual(columns, []string{"price"}) {
+		t.Errorf("SCD2TrackedColumns() configuradas = %v", columns)
+	}
+	current := Data{"id": int64(1), "price": "10", "name": "b"}
+	if SCD2Changed(current, row, columns) {
+		t.Errorf("SCD2Changed() com alteração só em coluna não rastreada")
+	}
+	if !SCD2Changed(current, row, SCD2TrackedColumns(Config{}, "id", row)) {
+		t.Errorf("SCD2Changed() sem detectar a alteração em name")
+	}
+	if err := ValidateSCD2Row(config, "id", Data{"id": 1}); err == nil {
+		t.Errorf("ValidateSCD2Row() sem erro com a coluna rastreada ausente")
+	}
+	if err := ValidateSCD2Row(config, "id", Data{"price": 1}); err == nil {
+		t.Errorf("ValidateSCD2Row() sem erro com a chave ausente")
+	}
+}
+
+// TestSCD2Settings verifica os nomes padrão das colunas de versão e a chave gerada por dialeto.
+func TestSCD2Settings(t *testing.T) {
+	settings := SCD2Settings(Config{SCD2: SlowlyChangingDimension{ValidTo: "ends_at"}})
+	if settings.SurrogateKey != "sk" || settings.ValidFrom != "valid_from" || settings.ValidTo != "ends_at" || settings.CurrentFlag != "is_current" {
+		t.Errorf("SCD2Settings() = %+v", settings)
+	}
+	tests := map[string]string{
+		"sqlite3":  "sk INTEGER PRIMARY KEY AUTOINCREMENT",
+		"postgres": "sk BIGSERIAL PRIMARY KEY",
+		"mysql":    "sk BIGINT AUTO_INCREMENT PRIMARY KEY",
+	}
+	for driver, want := range tests {
+		if got := SurrogateKeyDefinition(driver, "sk"); got != want {
+			t.Errorf("SurrogateKeyDefinition(%s) = %s", driver, got)
+		}
+	}
+}
+
+// TestValidateSCD2 verifica as combinações inválidas do loadMode scd2.
+func TestValidateSCD2(t *testing.T) {
+	base := `sourceType: sqlite3
+sourceConnectionString: a.db
+destinationType: sqlite3
+destinationConnectionString: b.db
+sourceTable: items
+`
+	tests := map[string]string{
+		"loadMode: scd2\n":                                                "loadMode",
+		"loadMode: merge\nprimaryKey: id\n":                               "loadMode",
+		"loadMode: scd2\nprimaryKey: id\nupdateKey: id\n":                 "updateKey",
+		"loadMode: scd2\nprimaryKey: id\ncheckpoint:\n  batchSize: 100\n": "checkpoint",
+		"primaryKey: id\nscd2:\n  trackedColumns: [price]\n":              "scd2",
+		"loadMode: scd2\nprimaryKey: id\ndeletes:\n  detect: keys\n":      "deletes",
+	}
+	for document, path := range tests {
+		errs := ValidateConfigData([]byte(base+document), "yaml")
+		if len(errs) != 1 || errs[0].Path != path {
+			t.Errorf("ValidateConfigData(%q) = %v, esperado erro em %s", document, errs, path)
+		}
+	}
+
+	valid := "loadMode: scd2\nprimaryKey: id\nscd2:\n  trackedColumns: [price]\n  currentFlag: current\n"
+	if errs := ValidateConfigData([]byte(base+valid), "yaml"); len(errs) > 0 {
+		t.Errorf("ValidateConfigData(%q) = %v", valid, errs)
+	}
+}
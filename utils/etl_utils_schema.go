@@ -0,0 +1,984 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	. "github.com/faelmori/getl/etypes"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ConfigSchemaID é o identificador publicado do schema gerado por GenerateConfigSchema.
+const ConfigSchemaID = "https://raw.githubusercontent.com/faelmori/getl/main/docs/getl-config.schema.json"
+
+// ConfigFileSchemaID é o identificador publicado do schema gerado por GenerateConfigFileSchema.
+const ConfigFileSchemaID = "https://raw.githubusercontent.com/faelmori/getl/main/docs/getl-pipelines.schema.json"
+
+// requiredConfigFields são os campos que toda configuração precisa informar.
+var requiredConfigFields = []string{"sourceType", "sourceConnectionString", "destinationType", "destinationConnectionString"}
+
+// JSONSchema é o subconjunto de JSON Schema usado para descrever e validar a Config.
+type JSONSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	ID                   string                 `json:"$id,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties interface{}            `json:"additionalProperties,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	Enum                 []string               `json:"enum,omitempty"`
+}
+
+// Property retorna o schema da propriedade no caminho informado, atravessando os itens de arrays.
+// Retorna nil se alguma parte do caminho não existir.
+func (s *JSONSchema) Property(path ...string) *JSONSchema {
+	current := s
+	for _, name := range path {
+		for current.Items != nil {
+			current = current.Items
+		}
+		if current.Properties[name] == nil {
+			return nil
+		}
+		current = current.Properties[name]
+	}
+	return current
+}
+
+// ConfigError descreve um erro de validação de um campo da configuração.
+// Line e Column são zero quando a posição não pôde ser determinada.
+type ConfigError struct {
+	Path    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e ConfigError) Error() string {
+	path := e.Path
+	if path == "" {
+		path = "(raiz)"
+	}
+	if e.Line > 0 {
+		return fmt.Sprintf("linha %d, coluna %d: %s: %s", e.Line, e.Column, path, e.Message)
+	}
+	return fmt.Sprintf("%s: %s", path, e.Message)
+}
+
+// ConfigErrors agrupa os erros de validação de um arquivo de configuração.
+type ConfigErrors []ConfigError
+
+func (e ConfigErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+// position é a linha e coluna (a partir de 1) de um campo no arquivo de configuração.
+type position struct {
+	line, column int
+}
+
+// GenerateConfigSchema gera o JSON Schema da Config a partir das tags json da struct,
+// acrescido das restrições que não podem ser deduzidas dos tipos.
+func GenerateConfigSchema() *JSONSchema {
+	schema := schemaForType(reflect.TypeOf(Config{}))
+	schema.Schema = "https://json-schema.org/draft/2020-12/schema"
+	schema.ID = ConfigSchemaID
+	schema.Title = "getl configuration"
+	schema.Required = requiredConfigFields
+	applyConfigConstraints(schema)
+
+	return schema
+}
+
+// GenerateConfigFileSchema gera o JSON Schema do arquivo com várias pipelines (ConfigFile).
+// Os campos obrigatórios de cada pipeline são verificados depois de resolvidas as conexões.
+func GenerateConfigFileSchema() *JSONSchema {
+	schema := schemaForType(reflect.TypeOf(ConfigFile{}))
+	schema.Schema = "https://json-schema.org/draft/2020-12/schema"
+	schema.ID = ConfigFileSchemaID
+	schema.Title = "getl pipelines"
+	schema.Required = []string{"pipelines"}
+
+	pipeline := schema.Property("pipelines").Items
+	pipeline.Required = []string{"name"}
+	pipeline.Property("source").Description = "Nome da conexão de origem em connections"
+	pipeline.Property("destination").Description = "Nome da conexão de destino em connections"
+	applyConfigConstraints(pipeline)
+
+	connection := schema.Property("connections").AdditionalProperties.(*JSONSchema)
+	connection.Required = []string{"type", "connectionString"}
+	connection.Property("type").Description = "Driver da conexão: " + strings.Join(SupportedDrivers, ", ")
+
+	return schema
+}
+
+// applyConfigConstraints acrescenta ao schema de um objeto com os campos da Config as
+// descrições e enums que não podem ser deduzidos dos tipos.
+func applyConfigConstraints(schema *JSONSchema) {
+	schema.Property("sourceType").Description = "Driver do banco de origem: " + strings.Join(SupportedDrivers, ", ") + "; ou redis, com as opções de redis.source"
+	schema.Property("destinationType").Description = "Driver do banco de destino: " + strings.Join(SupportedDrivers, ", ") + "; ou redis, com as opções de redis.sink"
+	schema.Property("redis").Description = "Origem e destino Redis, com connection strings redis://[usuário:senha@]host:porta[/db] (rediss:// com TLS); batchSize é o número de chaves ou entradas de cada leitura e de linhas de cada transação MULTI/EXEC (padrão: 100)"
+	schema.Property("redis", "source").Description = "Linhas lidas dos hashes cujas chaves atendem pattern (SCAN MATCH) ou das entradas de stream, consumido pelo grupo group (padrão: getl) como consumer (padrão: getl) e confirmadas com XACK após a carga; keyField recebe a chave do hash ou o id da entrada"
+	schema.Property("redis", "sink").Description = "Linhas gravadas como hashes (type hash, padrão), entradas de um stream (stream) ou elementos JSON de uma lista (list); key é o modelo da chave, com colunas entre chaves (padrão: <destinationTable>:{<primaryKey>} com hash, destinationTable com stream e list)"
+	schema.Property("redis", "sink", "type").Enum = SupportedRedisSinkTypes
+	schema.Property("syncInterval").Description = "Duração Go (30s, 5m), segundos, @every <duração> ou expressão cron de 5 ou 6 campos"
+	schema.Property("partitioning").Description = "Extração paralela por faixas de uma coluna: column com count (faixas calculadas de MIN/MAX) ou ranges explícitas"
+	schema.Property("checkpoint").Description = "Carga confirmada em lotes de batchSize linhas, com checkpoint para getl sync --resume; key (padrão: primaryKey) ordena a extração e retoma após a última chave"
+	schema.Property("pool").Description = "Pool de conexões por driver e DSN (maxOpenConns, maxIdleConns, connMaxLifetime, connMaxIdleTime) e novas tentativas com backoff ao conectar, extrair e carregar; pipelines com o mesmo driver e DSN usam as mesmas opções"
+	schema.Property("cdc").Description = "Captura de alterações da tabela de origem, aplicadas por getl cdc run; mode triggers grava as alterações em changelog (padrão: getl_changelog) na origem, pgoutput ou wal2json as recebem do slot de replicação lógica do PostgreSQL e binlog as lê do log binário do MySQL ou MariaDB"
+	schema.Property("cdc", "mode").Enum = SupportedCaptureModes
+	schema.Property("cdc", "slot").Description = "Slot de replicação lógica ou, no binlog, nome da posição registrada no destino (padrão: getl_<sourceTable>)"
+	schema.Property("cdc", "serverId").Description = "server_id com que o binlog é lido, único entre as réplicas (padrão: derivado do slot)"
+	schema.Property("cdc", "gtid").Description = "No binlog, registra e retoma a posição pelo conjunto de GTIDs (gtid_mode=ON, só MySQL) em vez do arquivo e posição"
+	schema.Property("cdc", "publication").Description = "Publicação lida pelo pgoutput (padrão: o nome do slot)"
+	schema.Property("cdc", "sink").Description = "Para onde vão as alterações: destination aplica no destino da pipeline e kafka publica em kafkaTopic"
+	schema.Property("cdc", "sink").Enum = SupportedChangeSinks
+	schema.Property("deletes").Description = "Propagação das exclusões da origem: detect keys compara as chaves a cada sync e cdc usa as exclusões capturadas; mode hard remove a linha e soft a marca em column"
+	schema.Property("deletes").Required = []string{"detect"}
+	schema.Property("deletes", "detect").Enum = SupportedDeleteDetections
+	schema.Property("deletes", "mode").Enum = SupportedDeleteModes
+	schema.Property("deletes", "column").Description = "Coluna marcada na exclusão lógica, criada se preciso (padrão: " + DefaultDeleteColumn + ")"
+	schema.Property("deletes", "flag").Description = "Marca column como booleano em vez do horário da exclusão"
+	schema.Property("deletes", "maxPercent").Description = "Interrompe a pipeline se a comparação de chaves excluiria mais que esse percentual das linhas do destino (padrão: 10; 100 desativa)"
+	schema.Property("loadMode").Description = "Como as linhas são gravadas: insert (padrão) insere ou, com updateKey, atualiza; scd2 mantém o histórico das versões de cada primaryKey"
+	schema.Property("loadMode").Enum = SupportedLoadModes
+	schema.Property("scd2").Description = "Colunas da carga scd2, criadas com a tabela de destino: surrogateKey (padrão: sk), validFrom (valid_from), validTo (valid_to) e currentFlag (is_current)"
+	schema.Property("scd2", "trackedColumns").Description = "Colunas cuja alteração fecha a versão corrente e insere uma nova (padrão: todas, exceto primaryKey)"
+	schema.Property("kafka").Description = "Opções do cliente Kafka de kafkaURL e kafkaTopic"
+	schema.Property("kafka", "format").Description = "Valor das mensagens publicadas: json (padrão), a linha ou a alteração do getl, debezium, o envelope before/after/op/ts_ms/source do Debezium, ou avro e protobuf, a linha serializada com o schema deduzido das colunas e registrado em schemaRegistry; o consumo aceita todos"
+	schema.Property("kafka", "format").Enum = SupportedKafkaFormats
+	schema.Property("kafka", "schemaRegistry").Description = "Schema registry compatível com a API do Confluent dos formatos avro e protobuf: url, username e password (autenticação básica) e subject (padrão: <kafkaTopic>-value); o schema é verificado contra a última versão do subject antes de publicar"
+	schema.Property("kafka", "brokers").Description = "Brokers do cluster (host:porta); sem ele, os de kafkaURL, que aceita uma lista separada por vírgulas"
+	schema.Property("kafka", "tls").Description = "Conexão TLS com os brokers, usada com enabled ou com qualquer arquivo: caFile (padrão: as autoridades do sistema), certFile e keyFile do cliente, serverName e insecureSkipVerify"
+	schema.Property("kafka", "sasl").Description = "Autenticação SASL com os brokers: mechanism, username e password"
+	schema.Property("kafka", "sasl", "mechanism").Enum = SupportedSASLMechanisms
+	schema.Property("kafka", "reader").Description = "Leitura dos tópicos consumidos: startOffset de um grupo sem offsets confirmados (padrão: earliest), minBytes e maxBytes de cada busca, commitInterval das confirmações (padrão: a cada lote) e isolationLevel"
+	schema.Property("kafka", "reader", "startOffset").Enum = SupportedStartOffsets
+	schema.Property("kafka", "reader", "isolationLevel").Enum = SupportedIsolationLevels
+	schema.Property("kafka", "consumer").Description = "Carga das mensagens de kafkaTopic no destino por getl consume -f: lotes de batchSize mensagens (padrão: 100), ou as que chegarem em flushInterval (padrão: 1s), gravados em uma transação antes de confirmar os offsets"
+	schema.Property("kafka", "consumer", "deadLetterTopic").Description = "Tópico das mensagens que não puderam ser decodificadas ou gravadas após as novas tentativas de retry, com o erro nos cabeçalhos getl-error e getl-error-stage; ativa o tratamento de erros por mensagem, sem interromper o consumo"
+	schema.Property("kafka", "stream").Description = "getl stream: consome kafkaTopic, aplica as transformations e publica as linhas em outputTopic em transações do Kafka, que também confirmam os offsets de kafkaGroupID; com dedup.key, descarta as linhas iguais à última publicada com a mesma chave"
+	schema.Property("kafka", "stream", "dedup").Description = "Deduplicação por chave, com o estado em um arquivo SQLite local (path, padrão: getl-stream-<kafkaGroupID>.db) e chaves lembradas por ttl (padrão: sem expiração)"
+	schema.Property("kafka", "consumer", "routes").Description = "Rotas das mensagens para várias tabelas: cada mensagem vai para a table da primeira rota cujo topic, headers e fields a atendem, com a primaryKey e as transformations da rota, e, sem nenhuma, para destinationTable; os tópicos das rotas são consumidos com kafkaTopic no mesmo grupo"
+	schema.Property("kafka", "consumer", "routes", "transformations", "operation").Enum = SupportedOperations
+	schema.Property("kafka", "consumer", "retry").Description = "Novas tentativas das mensagens que falharam, publicadas em topic (padrão: <kafkaTopic>-retry) e consumidas após backoff (padrão: 5s, dobrado a cada tentativa), até attempts vezes (padrão: 3)"
+	schema.Property("kafka", "producer").Description = "Publicação das linhas em kafkaTopic: key (coluna ou modelo como {region}-{id}; padrão: primaryKey), partitioner, lotes de batchSize mensagens enviados após linger, compression, requiredAcks e headers acrescentados a getl-source-table e getl-run-id"
+	schema.Property("kafka", "producer", "partitioner").Enum = SupportedPartitioners
+	schema.Property("kafka", "producer", "compression").Enum = SupportedCompressions
+	schema.Property("kafka", "producer", "requiredAcks").Enum = SupportedRequiredAcks
+	schema.Property("timeouts").Description = "Tempo máximo de cada etapa (extract, load, consume), como duração Go"
+	schema.Property("transformations", "operation").Enum = SupportedOperations
+	schema.Property("triggers").Items.Required = []string{"name", "event", "statement"}
+	schema.Property("triggers", "event").Description = "Momento e operações, e.g. AFTER INSERT ou BEFORE INSERT OR UPDATE"
+	schema.Property("triggers", "table").Description = "Tabela do trigger no destino; padrão: destinationTable"
+	joinTypes := slices.Clone(SupportedJoinTypes)
+	for _, joinType := range SupportedJoinTypes {
+		joinTypes = append(joinTypes, strings.ToLower(joinType))
+	}
+	schema.Property("joins", "joinType").Enum = joinTypes
+}
+
+// MarshalConfigSchema serializa o schema da Config com indentação, como publicado em docs/.
+func MarshalConfigSchema() ([]byte, error) {
+	return marshalSchema(GenerateConfigSchema())
+}
+
+// MarshalConfigFileSchema serializa o schema do arquivo com várias pipelines, como publicado em docs/.
+func MarshalConfigFileSchema() ([]byte, error) {
+	return marshalSchema(GenerateConfigFileSchema())
+}
+
+func marshalSchema(schema *JSONSchema) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(schema); err != nil {
+		return nil, fmt.Errorf("falha ao codificar o schema: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// schemaForType converte um tipo Go no schema equivalente.
+func schemaForType(t reflect.Type) *JSONSchema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		schema := &JSONSchema{Type: "object", Properties: map[string]*JSONSchema{}, AdditionalProperties: false}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if field.Anonymous && name == "" {
+				// Structs embutidas sem tag são achatadas, como faz o encoding/json.
+				for embeddedName, embedded := range schemaForType(field.Type).Properties {
+					schema.Properties[embeddedName] = embedded
+				}
+				continue
+			}
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			schema.Properties[name] = schemaForType(field.Type)
+		}
+		return schema
+	case reflect.Map:
+		return &JSONSchema{Type: "object", AdditionalProperties: schemaForType(t.Elem())}
+	case reflect.Slice, reflect.Array:
+		return &JSONSchema{Type: "array", Items: schemaForType(t.Elem())}
+	case reflect.Bool:
+		return &JSONSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &JSONSchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &JSONSchema{Type: "number"}
+	case reflect.String:
+		return &JSONSchema{Type: "string"}
+	default:
+		return &JSONSchema{}
+	}
+}
+
+// ValidateConfigData valida o conteúdo de um arquivo de configuração contra o schema da Config
+// (ou do arquivo com várias pipelines) e contra as regras que o schema não expressa (e.g. syncInterval).
+// As referências ${VAR} e ${file:...} são resolvidas antes da validação.
+// Retorna todos os erros encontrados, com linha e coluna quando possível.
+func ValidateConfigData(fileData []byte, format string) ConfigErrors {
+	_, errs := parsePipelinesDocument(fileData, format)
+	return errs
+}
+
+// ParseConfigData decodifica o conteúdo do arquivo (json, yaml ou toml), resolve as
+// referências a variáveis de ambiente e arquivos, valida e retorna a Config.
+// Arquivos com mais de uma pipeline devem ser lidos com ParsePipelinesData.
+func ParseConfigData(fileData []byte, format string) (Config, error) {
+	pipelines, err := ParsePipelinesData(fileData, format)
+	if err != nil {
+		return Config{}, err
+	}
+	if len(pipelines) != 1 {
+		return Config{}, fmt.Errorf("o arquivo contém %d pipelines (%s), selecione uma pelo nome", len(pipelines), strings.Join(PipelineNames(pipelines), ", "))
+	}
+	return pipelines[0].Config, nil
+}
+
+// decodeConfigDocument decodifica o arquivo para tipos genéricos, registra a posição de cada
+// campo e resolve as referências ${VAR} e ${file:...}.
+func decodeConfigDocument(fileData []byte, format string) (interface{}, map[string]position, ConfigErrors) {
+	var document interface{}
+	var positions map[string]position
+
+	switch strings.ToLower(format) {
+	case "", "json":
+		decoder := json.NewDecoder(bytes.NewReader(fileData))
+		decoder.UseNumber()
+		if err := decoder.Decode(&document); err != nil {
+			return nil, nil, ConfigErrors{jsonSyntaxError(fileData, err)}
+		}
+		positions = jsonPositions(fileData)
+	case "yaml", "yml":
+		var root yaml.Node
+		if err := yaml.Unmarshal(fileData, &root); err != nil {
+			return nil, nil, ConfigErrors{{Message: "YAML inválido: " + err.Error()}}
+		}
+		if err := root.Decode(&document); err != nil {
+			return nil, nil, ConfigErrors{{Message: "YAML inválido: " + err.Error()}}
+		}
+		positions = map[string]position{}
+		yamlPositions(&root, "", positions)
+	case "toml":
+		if err := toml.Unmarshal(fileData, &document); err != nil {
+			configErr := ConfigError{Message: "TOML inválido: " + err.Error()}
+			var decodeErr *toml.DecodeError
+			if errors.As(err, &decodeErr) {
+				configErr.Line, configErr.Column = decodeErr.Position()
+			}
+			return nil, nil, ConfigErrors{configErr}
+		}
+	default:
+		return nil, nil, ConfigErrors{{Message: "formato de configuração inválido: " + format}}
+	}
+
+	var errs ConfigErrors
+	document = interpolateConfigValue(document, "", &errs)
+	return document, positions, errs
+}
+
+// locateConfigErrors preenche linha e coluna dos erros e os ordena pela posição no arquivo.
+func locateConfigErrors(errs ConfigErrors, positions map[string]position) ConfigErrors {
+	for i := range errs {
+		if pos, ok := lookupPosition(positions, errs[i].Path); ok {
+			errs[i].Line, errs[i].Column = pos.line, pos.column
+		}
+	}
+	sort.SliceStable(errs, func(i, j int) bool {
+		if errs[i].Line != errs[j].Line {
+			return errs[i].Line < errs[j].Line
+		}
+		return errs[i].Column < errs[j].Column
+	})
+	return errs
+}
+
+// validateAgainstSchema verifica tipo, enum, campos obrigatórios e chaves desconhecidas.
+func validateAgainstSchema(value interface{}, schema *JSONSchema, path string, errs *ConfigErrors) {
+	if value == nil || schema == nil || schema.Type == "" {
+		return
+	}
+
+	if !matchesSchemaType(value, schema.Type) {
+		*errs = append(*errs, ConfigError{Path: path, Message: fmt.Sprintf("tipo inválido: esperado %s", schema.Type)})
+		return
+	}
+
+	if len(schema.Enum) > 0 {
+		if str, ok := value.(string); ok && !slices.Contains(schema.Enum, str) {
+			*errs = append(*errs, ConfigError{Path: path, Message: fmt.Sprintf("valor %q não suportado, use um de: %s", str, strings.Join(schema.Enum, ", "))})
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, name := range schema.Required {
+			if field, ok := v[name]; !ok || field == nil || field == "" {
+				*errs = append(*errs, ConfigError{Path: joinConfigPath(path, name), Message: "campo obrigatório ausente"})
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			childPath := joinConfigPath(path, key)
+			if property, ok := schema.Properties[key]; ok {
+				validateAgainstSchema(v[key], property, childPath, errs)
+				continue
+			}
+			switch additional := schema.AdditionalProperties.(type) {
+			case *JSONSchema:
+				validateAgainstSchema(v[key], additional, childPath, errs)
+			case bool:
+				if !additional {
+					*errs = append(*errs, ConfigError{Path: childPath, Message: "chave desconhecida"})
+				}
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			validateAgainstSchema(item, schema.Items, fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	}
+}
+
+// validateConfigSemantics aplica as regras que o schema não consegue expressar.
+func validateConfigSemantics(config map[string]interface{}, path string, errs *ConfigErrors) {
+	if interval, ok := config["syncInterval"].(string); ok && interval != "" {
+		if _, err := ParseSyncInterval(interval); err != nil {
+			*errs = append(*errs, ConfigError{Path: joinConfigPath(path, "syncInterval"), Message: err.Error()})
+		}
+	}
+	if partitioning, ok := config["partitioning"].(map[string]interface{}); ok {
+		validatePartitioning(partitioning, joinConfigPath(path, "partitioning"), errs)
+	}
+	if checkpoint, ok := config["checkpoint"].(map[string]interface{}); ok {
+		validateCheckpoint(config, checkpoint, joinConfigPath(path, "checkpoint"), errs)
+	}
+	if cdc, ok := config["cdc"].(map[string]interface{}); ok {
+		validateChangeCapture(config, cdc, joinConfigPath(path, "cdc"), errs)
+	}
+	if deletes, ok := config["deletes"].(map[string]interface{}); ok {
+		validateDeletes(config, deletes, joinConfigPath(path, "deletes"), errs)
+	}
+	if kafkaOptions, ok := config["kafka"].(map[string]interface{}); ok {
+		validateKafkaOptions(config, kafkaOptions, joinConfigPath(path, "kafka"), errs)
+	}
+	redisOptions, _ := config["redis"].(map[string]interface{})
+	validateRedisOptions(config, redisOptions, path, errs)
+	if loadMode, _ := config["loadMode"].(string); loadMode == "scd2" {
+		validateSCD2(config, path, errs)
+	} else if _, ok := config["scd2"]; ok {
+		*errs = append(*errs, ConfigError{Path: joinConfigPath(path, "scd2"), Message: "só se aplica ao loadMode scd2"})
+	}
+	if triggers, ok := config["triggers"].([]interface{}); ok {
+		destinationType, _ := config["destinationType"].(string)
+		for i, item := range triggers {
+			if trigger, ok := item.(map[string]interface{}); ok {
+				if event, ok := trigger["event"].(string); ok {
+					if _, err := ValidateTriggerEvent(destinationType, event); err != nil {
+						*errs = append(*errs, ConfigError{Path: fmt.Sprintf("%s[%d].event", joinConfigPath(path, "triggers"), i), Message: err.Error()})
+					}
+				}
+			}
+		}
+	}
+	if pool, ok := config["pool"].(map[string]interface{}); ok {
+		for _, option := range []string{"connMaxLifetime", "connMaxIdleTime", "retryBackoff"} {
+			if value, ok := pool[option].(string); ok {
+				if _, err := ParseDuration(value); err != nil {
+					*errs = append(*errs, ConfigError{Path: joinConfigPath(joinConfigPath(path, "pool"), option), Message: err.Error()})
+				}
+			}
+		}
+	}
+	if timeouts, ok := config["timeouts"].(map[string]interface{}); ok {
+		for stage, value := range timeouts {
+			if timeout, ok := value.(string); ok {
+				if _, err := ParseStageTimeout(timeout); err != nil {
+					*errs = append(*errs, ConfigError{Path: joinConfigPath(joinConfigPath(path, "timeouts"), stage), Message: err.Error()})
+				}
+			}
+		}
+	}
+}
+
+// validatePartitioning verifica a combinação de coluna, número de partições e faixas explícitas.
+func validatePartitioning(partitioning map[string]interface{}, path string, errs *ConfigErrors) {
+	column, _ := partitioning["column"].(string)
+	count, hasCount := configInt(partitioning["count"])
+	ranges, _ := partitioning["ranges"].([]interface{})
+	hasCount = hasCount && count != 0
+
+	switch {
+	case column == "" && (hasCount || len(ranges) > 0):
+		*errs = append(*errs, ConfigError{Path: joinConfigPath(path, "column"), Message: "campo obrigatório ao particionar a extração"})
+	case column != "" && !hasCount && len(ranges) == 0:
+		*errs = append(*errs, ConfigError{Path: path, Message: "informe count ou ranges para particionar a extração"})
+	case hasCount && len(ranges) > 0:
+		*errs = append(*errs, ConfigError{Path: joinConfigPath(path, "ranges"), Message: "use count ou ranges, não ambos"})
+	}
+	if hasCount && count < 0 {
+		*errs = append(*errs, ConfigError{Path: joinConfigPath(path, "count"), Message: "deve ser positivo"})
+	}
+	if workers, ok := configInt(partitioning["workers"]); ok && workers < 0 {
+		*errs = append(*errs, ConfigError{Path: joinConfigPath(path, "workers"), Message: "deve ser positivo"})
+	}
+	for i, item := range ranges {
+		if partition, ok := item.(map[string]interface{}); ok {
+			for _, bound := range []string{"from", "to"} {
+				switch partition[bound].(type) {
+				case map[string]interface{}, []interface{}, bool:
+					*errs = append(*errs, ConfigError{Path: fmt.Sprintf("%s.ranges[%d].%s", path, i, bound), Message: "limite deve ser um número, data ou texto"})
+				}
+			}
+		}
+	}
+}
+
+// validateCheckpoint verifica o tamanho do lote e se a chave de checkpoint (key ou, sem ela,
+// primaryKey) pode ordenar a extração.
+func validateCheckpoint(config, checkpoint map[string]interface{}, path string, errs *ConfigErrors) {
+	batchSize, _ := configInt(checkpoint["batchSize"])
+	key, _ := checkpoint["key"].(string)
+
+	if batchSize < 0 {
+		*errs = append(*errs, ConfigError{Path: joinConfigPath(path, "batchSize"), Message: "deve ser positivo"})
+	}
+	if key == "" && batchSize > 0 {
+		if key, _ = config["primaryKey"].(string); key == "" {
+			*errs = append(*errs, ConfigError{Path: joinConfigPath(path, "key"), Message: "campo obrigatório sem primaryKey: a retomada lê só as linhas após a última chave confirmada"})
+			return
+		}
+	}
+	if key == "" {
+		return
+	}
+	if batchSize == 0 {
+		*errs = append(*errs, ConfigError{Path: joinConfigPath(path, "batchSize"), Message: "campo obrigatório ao usar key"})
+	}
+	if orderBy, _ := config["orderBy"].(string); orderBy != "" && orderBy != key {
+		*errs = append(*errs, ConfigError{Path: joinConfigPath(path, "key"), Message: "a extração é ordenada pela chave de checkpoint; remova orderBy"})
+	}
+	if partitioning, ok := config["partitioning"].(map[string]interface{}); ok && partitioning["column"] != nil && partitioning["column"] != "" {
+		*errs = append(*errs, ConfigError{Path: path, Message: "não pode ser usado com partitioning: a extração particionada não é ordenada pela chave"})
+	}
+}
+
+// validateChangeCapture verifica se a captura de alterações tem uma tabela e uma chave para aplicar as alterações.
+func validateChangeCapture(config, cdc map[string]interface{}, path string, errs *ConfigErrors) {
+	if batchSize, _ := configInt(cdc["batchSize"]); batchSize < 0 {
+		*errs = append(*errs, ConfigError{Path: joinConfigPath(path, "batchSize"), Message: "deve ser positivo"})
+	}
+	mode, _ := cdc["mode"].(string)
+	if gtid, _ := cdc["gtid"].(bool); gtid && mode != "binlog" {
+		*errs = append(*errs, ConfigError{Path: joinConfigPath(path, "gtid"), Message: "só se aplica ao mode binlog"})
+	}
+	if mode == "" {
+		return
+	}
+	sink, _ := cdc["sink"].(string)
+	if sink == "kafka" {
+		kafkaOptions, _ := config["kafka"].(map[string]interface{})
+		if kafkaURL, _ := config["kafkaURL"].(string); kafkaURL == "" && kafkaOptions["brokers"] == nil {
+			*errs = append(*errs, ConfigError{Path: joinConfigPath(path, "sink"), Message: "sink kafka exige kafkaURL ou kafka.brokers"})
+		}
+		if kafkaTopic, _ := config["kafkaTopic"].(string); kafkaTopic == "" {
+			*errs = append(*errs, ConfigError{Path: joinConfigPath(path, "sink"), Message: "sink kafka exige kafkaTopic"})
+		}
+	}
+	if mode == "pgoutput" || mode == "wal2json" {
+		if sourceType, _ := config["sourceType"].(string); sourceType != "postgres" {
+			*errs = append(*errs, ConfigError{Path: joinConfigPath(path, "mode"), Message: fmt.Sprintf("%s usa a replicação lógica e exige sourceType postgres", mode)})
+		}
+	}
+	if sourceType, _ := config["sourceType"].(string); mode == "binlog" && sourceType != "mysql" {
+		*errs = append(*errs, ConfigError{Path: joinConfigPath(path, "mode"), Message: "binlog lê o log binário do MySQL ou MariaDB e exige sourceType mysql"})
+	}
+	// Sem o destino, a chave só é necessária aos triggers, que a gravam no changelog.
+	if primaryKey, _ := config["primaryKey"].(string); primaryKey == "" && (sink != "kafka" || mode == "triggers") {
+		*errs = append(*errs, ConfigError{Path: joinConfigPath(path, "mode"), Message: "a captura de alterações exige primaryKey para localizar as linhas no destino"})
+	}
+	if sqlQuery, _ := config["sqlQuery"].(string); sqlQuery != "" {
+		*errs = append(*errs, ConfigError{Path: joinConfigPath(path, "mode"), Message: "a captura de alterações lê sourceTable e não pode ser usada com sqlQuery"})
+	} else if sourceTable, _ := config["sourceTable"].(string); sourceTable == "" {
+		*errs = append(*errs, ConfigError{Path: joinConfigPath(path, "mode"), Message: "a captura de alterações exige sourceTable"})
+	}
+	if joins, _ := config["joins"].([]interface{}); len(joins) > 0 {
+		*errs = append(*errs, ConfigError{Path: joinConfigPath(path, "mode"), Message: "a captura de alterações registra só as colunas de sourceTable e não pode ser usada com joins"})
+	}
+}
+
+// validateDeletes verifica se a propagação de exclusões tem como detectá-las e localizar as linhas no destino.
+func validateDeletes(config, deletes map[string]interface{}, path string, errs *ConfigErrors) {
+	if maxPercent, _ := configInt(deletes["maxPercent"]); maxPercent < 0 || maxPercent > 100 {
+		*errs = append(*errs, ConfigError{Path: joinConfigPath(path, "maxPercent"), Message: "deve estar entre 0 e 100"})
+	}
+	if mode, _ := deletes["mode"].(string); mode != "soft" {
+		for _, field := range []string{"column", "flag"} {
+			if _, ok := deletes[field]; ok {
+				*errs = append(*errs, ConfigError{Path: joinConfigPath(path, field), Message: "só se aplica ao mode soft"})
+			}
+		}
+	}
+	if primaryKey, _ := config["primaryKey"].(string); primaryKey == "" {
+		*errs = append(*errs, ConfigError{Path: joinConfigPath(path, "detect"), Message: "a propagação de exclusões exige primaryKey para localizar as linhas no destino"})
+	}
+	if detect, _ := deletes["detect"].(string); detect == "cdc" {
+		cdc, _ := config["cdc"].(map[string]interface{})
+		if mode, _ := cdc["mode"].(string); mode == "" {
+			*errs = append(*errs, ConfigError{Path: joinConfigPath(path, "detect"), Message: "detect cdc exige cdc.mode"})
+		} else if sink, _ := cdc["sink"].(string); sink == "kafka" {
+			*errs = append(*errs, ConfigError{Path: joinConfigPath(path, "detect"), Message: "com cdc.sink kafka as exclusões são publicadas no tópico, não aplicadas no destino"})
+		}
+	}
+}
+
+// validateKafkaOptions verifica os valores das opções do cliente Kafka que o schema não restringe.
+func validateKafkaOptions(config, kafkaOptions map[string]interface{}, path string, errs *ConfigErrors) {
+	if format, _ := kafkaOptions["format"].(string); format == "avro" || format == "protobuf" {
+		registry, _ := kafkaOptions["schemaRegistry"].(map[string]interface{})
+		if registryURL, _ := registry["url"].(string); registryURL == "" {
+			*errs = append(*errs, ConfigError{Path: joinConfigPath(joinConfigPath(path, "schemaRegistry"), "url"), Message: fmt.Sprintf("kafka.format %s exige o schema registry", format)})
+		}
+		if cdc, _ := config["cdc"].(map[string]interface{}); cdc != nil {
+			if sink, _ := cdc["sink"].(string); sink == "kafka" {
+				*errs = append(*errs, ConfigError{Path: joinConfigPath(path, "format"), Message: "as alterações de cdc.sink kafka são publicadas em json ou debezium"})
+			}
+		}
+	}
+
+	tlsOptions, _ := kafkaOptions["tls"].(map[string]interface{})
+	certFile, _ := tlsOptions["certFile"].(string)
+	keyFile, _ := tlsOptions["keyFile"].(string)
+	if (certFile == "") != (keyFile == "") {
+		*errs = append(*errs, ConfigError{Path: joinConfigPath(path, "tls"), Message: "certFile e keyFile devem ser informados juntos"})
+	}
+	sasl, _ := kafkaOptions["sasl"].(map[string]interface{})
+	if mechanism, _ := sasl["mechanism"].(string); mechanism != "" {
+		if username, _ := sasl["username"].(string); username == "" {
+			*errs = append(*errs, ConfigError{Path: joinConfigPath(joinConfigPath(path, "sasl"), "username"), Message: fmt.Sprintf("obrigatório com o mecanismo %s", mechanism)})
+		}
+	}
+	reader, _ := kafkaOptions["reader"].(map[string]interface{})
+	readerPath := joinConfigPath(path, "reader")
+	for _, name := range []string{"minBytes", "maxBytes"} {
+		if value, ok := configInt(reader[name]); ok && value < 0 {
+			*errs = append(*errs, ConfigError{Path: joinConfigPath(readerPath, name), Message: "não pode ser negativo"})
+		}
+	}
+	minBytes, hasMinBytes := configInt(reader["minBytes"])
+	maxBytes, hasMaxBytes := configInt(reader["maxBytes"])
+	if hasMinBytes && hasMaxBytes && maxBytes > 0 && minBytes > maxBytes {
+		*errs = append(*errs, ConfigError{Path: joinConfigPath(readerPath, "minBytes"), Message: "não pode ser maior que maxBytes"})
+	}
+	if interval, ok := reader["commitInterval"].(string); ok {
+		if _, err := ParseStageTimeout(interval); err != nil {
+			*errs = append(*errs, ConfigError{Path: joinConfigPath(readerPath, "commitInterval"), Message: err.Error()})
+		}
+	}
+
+	producer, _ := kafkaOptions["producer"].(map[string]interface{})
+	producerPath := joinConfigPath(path, "producer")
+	if batchSize, ok := configInt(producer["batchSize"]); ok && batchSize < 0 {
+		*errs = append(*errs, ConfigError{Path: joinConfigPath(producerPath, "batchSize"), Message: "não pode ser negativo"})
+	}
+	if linger, ok := producer["linger"].(string); ok {
+		if _, err := ParseStageTimeout(linger); err != nil {
+			*errs = append(*errs, ConfigError{Path: joinConfigPath(producerPath, "linger"), Message: err.Error()})
+		}
+	}
+	if key, ok := producer["key"].(string); ok && strings.Count(key, "{") != strings.Count(key, "}") {
+		*errs = append(*errs, ConfigError{Path: joinConfigPath(producerPath, "key"), Message: "modelo de chave com chaves desbalanceadas"})
+	}
+
+	consumer, _ := kafkaOptions["consumer"].(map[string]interface{})
+	consumerPath := joinConfigPath(path, "consumer")
+	if batchSize, ok := configInt(consumer["batchSize"]); ok && batchSize < 0 {
+		*errs = append(*errs, ConfigError{Path: joinConfigPath(consumerPath, "batchSize"), Message: "não pode ser negativo"})
+	}
+	if interval, ok := consumer["flushInterval"].(string); ok {
+		if _, err := ParseStageTimeout(interval); err != nil {
+			*errs = append(*errs, ConfigError{Path: joinConfigPath(consumerPath, "flushInterval"), Message: err.Error()})
+		}
+	}
+	retry, _ := consumer["retry"].(map[string]interface{})
+	retryPath := joinConfigPath(consumerPath, "retry")
+	if attempts, ok := configInt(retry["attempts"]); ok && attempts < 0 {
+		*errs = append(*errs, ConfigError{Path: joinConfigPath(retryPath, "attempts"), Message: "não pode ser negativo"})
+	}
+	if backoff, ok := retry["backoff"].(string); ok {
+		if _, err := ParseStageTimeout(backoff); err != nil {
+			*errs = append(*errs, ConfigError{Path: joinConfigPath(retryPath, "backoff"), Message: err.Error()})
+		}
+	}
+	deadLetterTopic, _ := consumer["deadLetterTopic"].(string)
+	if len(retry) > 0 && deadLetterTopic == "" {
+		*errs = append(*errs, ConfigError{Path: retryPath, Message: "só se aplica com kafka.consumer.deadLetterTopic"})
+	}
+	if kafkaTopic, _ := config["kafkaTopic"].(string); deadLetterTopic != "" && deadLetterTopic == kafkaTopic {
+		*errs = append(*errs, ConfigError{Path: joinConfigPath(consumerPath, "deadLetterTopic"), Message: "deve ser diferente de kafkaTopic"})
+	}
+	routes, _ := consumer["routes"].([]interface{})
+	for i, item := range routes {
+		route, _ := item.(map[string]interface{})
+		routePath := fmt.Sprintf("%s[%d]", joinConfigPath(consumerPath, "routes"), i)
+		if table, _ := route["table"].(string); table == "" {
+			*errs = append(*errs, ConfigError{Path: joinConfigPath(routePath, "table"), Message: "obrigatório"})
+		}
+		topic, _ := route["topic"].(string)
+		headers, _ := route["headers"].(map[string]interface{})
+		fields, _ := route["fields"].(map[string]interface{})
+		if topic == "" && len(headers) == 0 && len(fields) == 0 {
+			*errs = append(*errs, ConfigError{Path: routePath, Message: "informe topic, headers ou fields"})
+		}
+		if topic != "" && topic == deadLetterTopic {
+			*errs = append(*errs, ConfigError{Path: joinConfigPath(routePath, "topic"), Message: "deve ser diferente de kafka.consumer.deadLetterTopic"})
+		}
+	}
+
+	stream, _ := kafkaOptions["stream"].(map[string]interface{})
+	streamPath := joinConfigPath(path, "stream")
+	if outputTopic, _ := stream["outputTopic"].(string); outputTopic != "" {
+		if kafkaTopic, _ := config["kafkaTopic"].(string); outputTopic == kafkaTopic {
+			*errs = append(*errs, ConfigError{Path: joinConfigPath(streamPath, "outputTopic"), Message: "deve ser diferente de kafkaTopic"})
+		}
+	}
+	if timeout, ok := stream["transactionTimeout"].(string); ok {
+		if _, err := ParseStageTimeout(timeout); err != nil {
+			*errs = append(*errs, ConfigError{Path: joinConfigPath(streamPath, "transactionTimeout"), Message: err.Error()})
+		}
+	}
+	dedup, _ := stream["dedup"].(map[string]interface{})
+	dedupPath := joinConfigPath(streamPath, "dedup")
+	if ttl, ok := dedup["ttl"].(string); ok {
+		if _, err := ParseStageTimeout(ttl); err != nil {
+			*errs = append(*errs, ConfigError{Path: joinConfigPath(dedupPath, "ttl"), Message: err.Error()})
+		}
+	}
+	if key, ok := dedup["key"].(string); ok && strings.Count(key, "{") != strings.Count(key, "}") {
+		*errs = append(*errs, ConfigError{Path: joinConfigPath(dedupPath, "key"), Message: "modelo de chave com chaves desbalanceadas"})
+	}
+}
+
+// validateRedisOptions verifica a origem e o destino Redis e as opções da Config que não se aplicam
+// a eles, que leem e gravam linhas sem consultas SQL. path é o caminho da Config.
+func validateRedisOptions(config, redisOptions map[string]interface{}, path string, errs *ConfigErrors) {
+	source, _ := redisOptions["source"].(map[string]interface{})
+	sourcePath := joinConfigPath(path, "redis.source")
+	if sourceType, _ := config["sourceType"].(string); sourceType == RedisType {
+		pattern, _ := source["pattern"].(string)
+		stream, _ := source["stream"].(string)
+		if (pattern == "") == (stream == "") {
+			*errs = append(*errs, ConfigError{Path: sourcePath, Message: "a origem redis exige pattern ou stream, e não os dois"})
+		}
+		for _, name := range []string{"partitioning", "checkpoint", "cdc"} {
+			if _, ok := config[name]; ok {
+				*errs = append(*errs, ConfigError{Path: joinConfigPath(path, name), Message: "não se aplica à origem redis"})
+			}
+		}
+	} else if _, ok := redisOptions["source"]; ok {
+		*errs = append(*errs, ConfigError{Path: sourcePath, Message: "só se aplica ao sourceType redis"})
+	}
+
+	sink, _ := redisOptions["sink"].(map[string]interface{})
+	sinkPath := joinConfigPath(path, "redis.sink")
+	if destinationType, _ := config["destinationType"].(string); destinationType != RedisType {
+		if _, ok := redisOptions["sink"]; ok {
+			*errs = append(*errs, ConfigError{Path: sinkPath, Message: "só se aplica ao destinationType redis"})
+		}
+		return
+	}
+	sinkType, _ := sink["type"].(string)
+	if sinkType == "" {
+		sinkType = "hash"
+	} else if !slices.Contains(SupportedRedisSinkTypes, sinkType) {
+		// O valor é apontado pelo schema.
+		return
+	}
+	if key, _ := sink["key"].(string); key == "" {
+		primaryKey, _ := config["primaryKey"].(string)
+		destinationTable, _ := config["destinationTable"].(string)
+		if sinkType == "hash" && primaryKey == "" {
+			*errs = append(*errs, ConfigError{Path: joinConfigPath(sinkPath, "key"), Message: "os hashes exigem a chave ou primaryKey"})
+		} else if sinkType != "hash" && destinationTable == "" {
+			*errs = append(*errs, ConfigError{Path: joinConfigPath(sinkPath, "key"), Message: fmt.Sprintf("o %s exige a chave ou destinationTable", sinkType)})
+		}
+	} else if strings.Count(key, "{") != strings.Count(key, "}") {
+		*errs = append(*errs, ConfigError{Path: joinConfigPath(sinkPath, "key"), Message: "modelo de chave com chaves desbalanceadas"})
+	} else if sinkType == "hash" && !strings.Contains(key, "{") {
+		*errs = append(*errs, ConfigError{Path: joinConfigPath(sinkPath, "key"), Message: "o modelo da chave dos hashes exige uma coluna entre chaves, e.g. items:{id}"})
+	}
+	if ttl, ok := sink["ttl"].(string); ok {
+		if sinkType != "hash" {
+			*errs = append(*errs, ConfigError{Path: joinConfigPath(sinkPath, "ttl"), Message: "só se aplica ao type hash"})
+		} else if _, err := ParseStageTimeout(ttl); err != nil {
+			*errs = append(*errs, ConfigError{Path: joinConfigPath(sinkPath, "ttl"), Message: err.Error()})
+		}
+	}
+	if _, ok := sink["maxLen"]; ok && sinkType != "stream" {
+		*errs = append(*errs, ConfigError{Path: joinConfigPath(sinkPath, "maxLen"), Message: "só se aplica ao type stream"})
+	}
+	for _, name := range []string{"checkpoint", "deletes", "triggers", "scd2"} {
+		if _, ok := config[name]; ok {
+			*errs = append(*errs, ConfigError{Path: joinConfigPath(path, name), Message: "não se aplica ao destino redis"})
+		}
+	}
+	if loadMode, _ := config["loadMode"].(string); loadMode == "scd2" {
+		*errs = append(*errs, ConfigError{Path: joinConfigPath(path, "loadMode"), Message: "scd2 não se aplica ao destino redis"})
+	}
+}
+
+// validateSCD2 verifica se a carga scd2 tem a chave natural e não é combinada com cargas que
+// sobrescrevem ou removem as versões.
+func validateSCD2(config map[string]interface{}, path string, errs *ConfigErrors) {
+	if primaryKey, _ := config["primaryKey"].(string); primaryKey == "" {
+		*errs = append(*errs, ConfigError{Path: joinConfigPath(path, "loadMode"), Message: "loadMode scd2 exige primaryKey para identificar as versões de cada linha"})
+	}
+	for _, field := range []string{"updateKey", "checkpoint", "cdc", "deletes"} {
+		if value, ok := config[field]; ok && value != nil && value != "" {
+			*errs = append(*errs, ConfigError{Path: joinConfigPath(path, field), Message: "não pode ser combinado com loadMode scd2"})
+		}
+	}
+}
+
+// configInt lê um inteiro do documento decodificado, qualquer que seja o formato de origem.
+func configInt(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case json.Number:
+		i, err := v.Int64()
+		return i, err == nil
+	case int:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint64:
+		return int64(v), true
+	case float64:
+		return int64(v), v == float64(int64(v))
+	}
+	return 0, false
+}
+
+// matchesSchemaType verifica se o valor decodificado corresponde ao tipo do schema.
+func matchesSchemaType(value interface{}, schemaType string) bool {
+	switch schemaType {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "integer":
+		switch v := value.(type) {
+		case json.Number:
+			_, err := v.Int64()
+			return err == nil
+		case int, int64, uint64:
+			return true
+		}
+		return false
+	case "number":
+		switch value.(type) {
+		case json.Number, int, int64, uint64, float64:
+			return true
+		}
+		return false
+	}
+	return true
+}
+
+// joinConfigPath monta o caminho de um campo, e.g. "transformations[0].operation".
+func joinConfigPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// lookupPosition procura a posição do campo ou, se ele não existir no arquivo, do campo pai mais próximo.
+func lookupPosition(positions map[string]position, path string) (position, bool) {
+	for {
+		if pos, ok := positions[path]; ok {
+			return pos, true
+		}
+		if path == "" {
+			return position{}, false
+		}
+		cut := max(strings.LastIndex(path, "."), strings.LastIndex(path, "["))
+		if cut < 0 {
+			path = ""
+		} else {
+			path = path[:cut]
+		}
+	}
+}
+
+// jsonSyntaxError converte um erro de decodificação JSON em ConfigError com linha e coluna.
+func jsonSyntaxError(data []byte, err error) ConfigError {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	configErr := ConfigError{Message: "JSON inválido: " + err.Error()}
+	switch {
+	case errors.As(err, &syntaxErr):
+		configErr.Line, configErr.Column = offsetToPosition(data, int(syntaxErr.Offset))
+	case errors.As(err, &typeErr):
+		configErr.Line, configErr.Column = offsetToPosition(data, int(typeErr.Offset))
+	}
+	return configErr
+}
+
+// jsonPositions percorre os tokens do documento JSON e registra a posição de cada campo.
+// Para campos de objetos a posição registrada é a da chave.
+func jsonPositions(data []byte) map[string]position {
+	positions := map[string]position{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	_ = walkJSONPositions(decoder, data, "", positions)
+	return positions
+}
+
+func walkJSONPositions(decoder *json.Decoder, data []byte, path string, positions map[string]position) error {
+	offset := skipJSONSeparators(data, int(decoder.InputOffset()))
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if _, ok := positions[path]; !ok {
+		line, column := offsetToPosition(data, offset)
+		positions[path] = position{line, column}
+	}
+
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return nil
+	}
+
+	switch delim {
+	case '{':
+		for decoder.More() {
+			keyOffset := skipJSONSeparators(data, int(decoder.InputOffset()))
+			keyToken, keyErr := decoder.Token()
+			if keyErr != nil {
+				return keyErr
+			}
+			childPath := joinConfigPath(path, fmt.Sprint(keyToken))
+			line, column := offsetToPosition(data, keyOffset)
+			positions[childPath] = position{line, column}
+			if walkErr := walkJSONPositions(decoder, data, childPath, positions); walkErr != nil {
+				return walkErr
+			}
+		}
+	case '[':
+		for i := 0; decoder.More(); i++ {
+			if walkErr := walkJSONPositions(decoder, data, fmt.Sprintf("%s[%d]", path, i), positions); walkErr != nil {
+				return walkErr
+			}
+		}
+	}
+
+	_, err = decoder.Token()
+	return err
+}
+
+// yamlPositions percorre os nós do documento YAML e registra a posição de cada campo.
+func yamlPositions(node *yaml.Node, path string, positions map[string]position) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			yamlPositions(child, path, positions)
+		}
+		return
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			childPath := joinConfigPath(path, key.Value)
+			positions[childPath] = position{key.Line, key.Column}
+			yamlPositions(node.Content[i+1], childPath, positions)
+		}
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			yamlPositions(child, fmt.Sprintf("%s[%d]", path, i), positions)
+		}
+	}
+	if _, ok := positions[path]; !ok {
+		positions[path] = position{node.Line, node.Column}
+	}
+}
+
+// skipJSONSeparators avança o offset sobre espaços e separadores até o início do próximo token.
+func skipJSONSeparators(data []byte, offset int) int {
+	for offset < len(data) {
+		switch data[offset] {
+		case ' ', '\t', '\r', '\n', ',', ':':
+			offset++
+		default:
+			return offset
+		}
+	}
+	return offset
+}
+
+// offsetToPosition converte um offset em bytes para linha e coluna (a partir de 1).
+func offsetToPosition(data []byte, offset int) (int, int) {
+	offset = min(offset, len(data))
+	line := bytes.Count(data[:offset], []byte("\n")) + 1
+	column := offset - bytes.LastIndexByte(data[:offset], '\n')
+	return line, column
+}
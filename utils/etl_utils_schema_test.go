@@ -0,0 +1,147 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestValidateConfigData testa a validação do arquivo de configuração contra o schema.
+// Verifica se cada erro é reportado no campo e na posição corretos.
+func TestValidateConfigData(t *testing.T) {
+	data := []byte(`{
+  "sourceType": "sqlite3",
+  "sourceConnectionString": "source.db",
+  "destinationType": "sqlite3",
+  "destinationTablePrimaryKey": "id",
+  "syncInterval": "every now",
+  "transformations": [
+    {"sourceField": "a", "destinationField": "b", "operation": "lowercase"}
+  ],
+  "joins": [{"table": "t", "condition": "t.id = a.id", "joinType": "outer"}]
+}`)
+
+	want := []ConfigError{
+		{Path: "destinationConnectionString", Line: 1, Column: 1},
+		{Path: "destinationTablePrimaryKey", Line: 5, Column: 3},
+		{Path: "syncInterval", Line: 6, Column: 3},
+		{Path: "transformations[0].operation", Line: 8, Column: 51},
+		{Path: "joins[0].joinType", Line: 10, Column: 56},
+	}
+
+	got := ValidateConfigData(data, "json")
+	if len(got) != len(want) {
+		t.Fatalf("ValidateConfigData() retornou %d erros, esperado %d: %v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		if got[i].Path != w.Path || got[i].Line != w.Line || got[i].Column != w.Column {
+			t.Errorf("erro %d = %s (%d:%d), esperado %s (%d:%d)", i, got[i].Path, got[i].Line, got[i].Column, w.Path, w.Line, w.Column)
+		}
+	}
+}
+
+// TestValidateConfigDataValid verifica se os exemplos do repositório são configurações válidas.
+func TestValidateConfigDataValid(t *testing.T) {
+	for _, path := range []string{"../examples/configFiles/exp_config_a.json", "../examples/configFiles/exp_config_b.json"} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("falha ao ler %s: %v", path, err)
+		}
+		if errs := ValidateConfigData(data, "json"); len(errs) > 0 {
+			t.Errorf("%s: %v", path, errs)
+		}
+	}
+}
+
+// TestConfigSchemaIsPublished garante que os schemas em docs/ estão atualizados com as structs.
+// Para regenerar: make schema
+func TestConfigSchemaIsPublished(t *testing.T) {
+	schemas := map[string]func() ([]byte, error){
+		"../docs/getl-config.schema.json":    MarshalConfigSchema,
+		"../docs/getl-pipelines.schema.json": MarshalConfigFileSchema,
+	}
+	for path, marshal := range schemas {
+		published, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("falha ao ler o schema publicado: %v", err)
+		}
+		generated, err := marshal()
+		if err != nil {
+			t.Fatalf("falha ao gerar o schema de %s: %v", path, err)
+		}
+		if !bytes.Equal(published, generated) {
+			t.Errorf("%s está desatualizado, regenere com make schema", path)
+		}
+	}
+}
+
+// TestParseSyncInterval testa a interpretação dos formatos aceitos em syncInterval.
+func TestParseSyncInterval(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{value: "30s", want: 30 * time.Second},
+		{value: "45", want: 45 * time.Second},
+		{value: "@every 5m", want: 5 * time.Minute},
+		{value: "*/30 * * * * *", want: 30 * time.Second},
+		{value: "30 * * * * *", want: time.Minute},
+		{value: "*/15 * * * *", want: 15 * time.Minute},
+		{value: "0 3 * * *", want: 24 * time.Hour},
+		{value: "0 */6 * * *", want: 6 * time.Hour},
+		{value: "30 0 * * 1", want: 7 * 24 * time.Hour},
+		{value: "* 3 * * *", wantErr: true},
+		{value: "0 9-17 * * *", wantErr: true},
+		{value: "*/7 * * * *", wantErr: true},
+		{value: "0 0 1 * *", wantErr: true},
+		{value: "61 * * * * *", wantErr: true},
+		{value: "every now", wantErr: true},
+		{value: "-5s", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseSyncInterval(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSyncInterval(%q) erro = %v, esperado erro = %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseSyncInterval(%q) = %v, esperado %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestParseStageTimeout testa os timeouts por etapa e o contexto derivado deles.
+func TestParseStageTimeout(t *testing.T) {
+	if got, err := ParseStageTimeout(""); err != nil || got != 0 {
+		t.Errorf("ParseStageTimeout(\"\") = %v, %v; esperado sem limite", got, err)
+	}
+	if got, err := ParseStageTimeout("90s"); err != nil || got != 90*time.Second {
+		t.Errorf("ParseStageTimeout(\"90s\") = %v, %v", got, err)
+	}
+	for _, value := range []string{"0s", "-1m", "10"} {
+		if _, err := ParseStageTimeout(value); err == nil {
+			t.Errorf("ParseStageTimeout(%q) deveria falhar", value)
+		}
+	}
+
+	ctx, cancel, err := WithStageTimeout(context.Background(), "1ms")
+	if err != nil {
+		t.Fatalf("WithStageTimeout() falhou: %v", err)
+	}
+	defer cancel()
+	<-ctx.Done()
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		t.Errorf("ctx.Err() = %v, esperado DeadlineExceeded", ctx.Err())
+	}
+
+	errs := ValidateConfigData([]byte(`{"sourceType": "sqlite3", "sourceConnectionString": "a.db", "destinationType": "sqlite3",
+"destinationConnectionString": "b.db", "timeouts": {"extract": "5m", "load": "soon"}}`), "json")
+	if len(errs) != 1 || errs[0].Path != "timeouts.load" || errs[0].Line != 2 {
+		t.Errorf("ValidateConfigData() = %v, esperado erro em timeouts.load", errs)
+	}
+}
@@ -14,7 +14,6 @@ import (
 	"maps"
 	"os"
 	"path/filepath"
-	"reflect"
 	"strconv"
 	"strings"
 )
@@ -68,24 +67,14 @@ func ApplyTransformations(data []Data, transformations []Transformation) ([]Data
 	return transformedData, nil
 }
 func LoadFieldsFromTransformConfig(fileConfigPath string) (Fields, error) {
-	var config Config
-
 	logz.Info("Loading fields from file: "+fileConfigPath, map[string]interface{}{})
-	fileData, fileDataErr := os.ReadFile(fileConfigPath)
-	if fileDataErr != nil {
-		logz.Error("failed to load file: "+fileDataErr.Error(), map[string]interface{}{})
-		return nil, fileDataErr
+	config, loadErr := LoadConfigFile(fileConfigPath)
+	if loadErr != nil {
+		logz.Error("failed to load file: "+loadErr.Error(), map[string]interface{}{})
+		return nil, loadErr
 	}
 	logz.Info("File loaded successfully", map[string]interface{}{})
 
-	logz.Info("Unmarshalling file data", map[string]interface{}{})
-	unmarshalErr := json.Unmarshal(fileData, &config)
-	if unmarshalErr != nil {
-		logz.Error("334: "+unmarshalErr.Error(), map[string]interface{}{})
-		return nil, unmarshalErr
-	}
-	logz.Info("File data unmarshalled successfully", map[string]interface{}{})
-
 	logz.Info("Creating fields map", map[string]interface{}{})
 	var fields Fields
 
@@ -115,6 +104,16 @@ func LoadFieldsFromTransformConfig(fileConfigPath string) (Fields, error) {
 	return fields, nil
 }
 func BuilExtractdQuery(config Config, fields []string) (string, []interface{}, error) {
+	query, err := newExtractQueryBuilder(config, fields)
+	if err != nil {
+		return "", nil, err
+	}
+	return query.ToSql()
+}
+
+// newExtractQueryBuilder monta o SELECT de extração (campos, joins, where e order by) sem gerar o SQL,
+// para que as partições possam acrescentar seus limites.
+func newExtractQueryBuilder(config Config, fields []string) (*sqrl.SelectBuilder, error) {
 	query := sqrl.Select(fields...).From(config.SourceTable)
 
 	for _, join := range config.Joins {
@@ -126,7 +125,7 @@ func BuilExtractdQuery(config Config, fields []string) (string, []interface{}, e
 		case "RIGHT":
 			query = query.RightJoin(join.Table + " ON " + join.Condition)
 		default:
-			return "", nil, fmt.Errorf("tipo de join desconhecido: %s", join.JoinType)
+			return nil, fmt.Errorf("tipo de join desconhecido: %s", join.JoinType)
 		}
 	}
 
@@ -138,7 +137,7 @@ func BuilExtractdQuery(config Config, fields []string) (string, []interface{}, e
 		query = query.OrderBy(config.OrderBy)
 	}
 
-	return query.ToSql()
+	return query, nil
 }
 func LoadConfigFile(fileConfigPath string) (Config, error) {
 	fileData, err := os.ReadFile(fileConfigPath)
@@ -146,28 +145,16 @@ func LoadConfigFile(fileConfigPath string) (Config, error) {
 		return Config{}, fmt.Errorf("falha ao ler o arquivo de configuração: %w", err)
 	}
 
-	var config Config
-	if unmarshalErr := json.Unmarshal(fileData, &config); unmarshalErr != nil {
-		return Config{}, fmt.Errorf("falha ao processar JSON de configuração: %w", unmarshalErr)
-	}
-
-	// Verificação de campos obrigatórios
-	requiredFields := []string{"sourceType", "sourceConnectionString", "destinationType", "destinationConnectionString"}
-	for _, field := range requiredFields {
-		if reflect.ValueOf(config).FieldByName(strings.ToTitle(field)).String() == "" {
-			return Config{}, fmt.Errorf("campo obrigatório ausente na configuração: %s", field)
-		}
+	// O formato (JSON, YAML ou TOML) é deduzido pela extensão; ${VAR} e ${file:...} são
+	// resolvidos e o resultado é validado contra o schema da Config.
+	config, parseErr := ParseConfigData(fileData, ConfigFormatFromPath(fileConfigPath))
+	if parseErr != nil {
+		return Config{}, fmt.Errorf("configuração inválida em %s:\n%w", fileConfigPath, parseErr)
 	}
 
 	return config, nil
 }
-func GetDataTableHandlerFromQuery(sourceType, sourceConnectionString, sqlQuery string) (*TableHandler, error) {
-	db, err := sql.Open(sourceType, sourceConnectionString)
-	if err != nil {
-		return nil, fmt.Errorf("falha ao conectar ao banco de dados de origem: %w", err)
-	}
-	defer db.Close()
-
+func GetDataTableHandlerFromQuery(db *sql.DB, sqlQuery string) (*TableHandler, error) {
 	rows, err := db.Query(sqlQuery)
 	if err != nil {
 		return nil, fmt.Errorf("falha ao executar a consulta SQL: %w", err)
@@ -201,42 +188,7 @@ func GetDataTableHandlerFromQuery(sourceType, sourceConnectionString, sqlQuery s
 	return &TableHandler{Columns: columns, Data: data}, nil
 }
 func GenerateConfigTemplate(filePath string) error {
-	config := Config{
-		SourceType:                  "sqlite,postgres,mysql,oracle,sqlserver",
-		SourceConnectionString:      "connection_string_for_source_database",
-		SourceTable:                 "origin_table_name",
-		DestinationType:             "sqlite,postgres,mysql,oracle,sqlserver",
-		DestinationConnectionString: "connection_string_for_destination_database",
-		DestinationTable:            "destination_table_name",
-		SQLQuery:                    "SELECT * FROM your_table",
-		OutputPath:                  "output_file_path",
-		OutputFormat:                "json,csv,xml,parquet",
-		Transformations: []Transformation{
-			{
-				SourceField:      "campo_origem",
-				DestinationField: "campo_destino",
-				Operation:        "none",
-				SPath:            "caminho_origem",
-				DPath:            "caminho_destino",
-				Type:             "string",
-			},
-		},
-		Joins: []Join{
-			{
-				Table:     "nome_da_tabela_join",
-				Condition: "condicao_de_join",
-				JoinType:  "INNER",
-			},
-		},
-		Where:        "where_clause",
-		OrderBy:      "order_by_clause",
-		Triggers:     []Trigger{},
-		LogTable:     "log_table_name",
-		SyncInterval: "sync_interval",
-		KafkaURL:     "kafka_broker_url",
-		KafkaTopic:   "kafka_topic_name",
-		KafkaGroupID: "kafka_group_id",
-	}
+	config := NewConfigTemplate()
 
 	if filePath == "" {
 		homeFilePath, filePathErr := utils.GetWorkDir()
@@ -246,19 +198,7 @@ func GenerateConfigTemplate(filePath string) error {
 		filePath = homeFilePath + "/.kubex/example_config.json"
 	}
 
-	file, err := os.Create(filePath)
-	if err != nil {
-		return fmt.Errorf("falha ao criar o arquivo: %w", err)
-	}
-	defer file.Close()
-
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ")
-	if err := encoder.Encode(config); err != nil {
-		return fmt.Errorf("falha ao codificar o JSON: %w", err)
-	}
-
-	return nil
+	return WriteConfigFile(filePath, config, ConfigFormatFromPath(filePath))
 }
 func GetETLJobs() (JobList, error) {
 	cwd, cwdErr := utils.GetWorkDir()
@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"fmt"
+	. "github.com/faelmori/getl/etypes"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// TriggerEvent é o Trigger.Event interpretado: o momento (BEFORE, AFTER, INSTEAD OF) e as operações.
+type TriggerEvent struct {
+	Timing     string
+	Operations []string
+}
+
+var triggerEventSeparator = regexp.MustCompile(`\s*(?:,|\bOR\b)\s*`)
+
+// ParseTriggerEvent interpreta Trigger.Event no formato "<momento> <operação>[ OR <operação>...]".
+// As operações também podem ser separadas por vírgula, como no SQL Server.
+func ParseTriggerEvent(event string) (TriggerEvent, error) {
+	normalized := strings.ToUpper(strings.Join(strings.Fields(event), " "))
+	if normalized == "" {
+		return TriggerEvent{}, fmt.Errorf("evento do trigger não informado (e.g. AFTER INSERT)")
+	}
+
+	if slices.Contains(TriggerTimings, normalized) {
+		return TriggerEvent{}, fmt.Errorf("evento do trigger sem operação: %q (e.g. %s INSERT)", event, normalized)
+	}
+
+	var parsed TriggerEvent
+	for _, timing := range TriggerTimings {
+		if strings.HasPrefix(normalized, timing+" ") {
+			parsed.Timing = timing
+			break
+		}
+	}
+	if parsed.Timing == "" {
+		return TriggerEvent{}, fmt.Errorf("evento do trigger inválido: %q deve começar com %s", event, strings.Join(TriggerTimings, ", "))
+	}
+
+	for _, operation := range triggerEventSeparator.Split(strings.TrimPrefix(normalized, parsed.Timing+" "), -1) {
+		if !slices.Contains(TriggerOperations, operation) {
+			return TriggerEvent{}, fmt.Errorf("operação de trigger inválida em %q: %q (aceitas: %s)", event, operation, strings.Join(TriggerOperations, ", "))
+		}
+		if slices.Contains(parsed.Operations, operation) {
+			return TriggerEvent{}, fmt.Errorf("operação de trigger repetida em %q: %s", event, operation)
+		}
+		parsed.Operations = append(parsed.Operations, operation)
+	}
+	return parsed, nil
+}
+
+// ValidateTriggerEvent interpreta Trigger.Event e verifica se o banco de destino aceita a combinação:
+// SQLite e MySQL aceitam uma operação por trigger, o MySQL não tem INSTEAD OF e o SQL Server não tem BEFORE.
+func ValidateTriggerEvent(driver, event string) (TriggerEvent, error) {
+	parsed, err := ParseTriggerEvent(event)
+	if err != nil {
+		return parsed, err
+	}
+
+	switch TriggerDialect(driver) {
+	case "sqlite", "mysql":
+		if len(parsed.Operations) > 1 {
+			return parsed, fmt.Errorf("%s aceita uma operação por trigger, recebido %q", driver, event)
+		}
+		if parsed.Timing == "INSTEAD OF" && TriggerDialect(driver) == "mysql" {
+			return parsed, fmt.Errorf("mysql não suporta triggers INSTEAD OF")
+		}
+	case "sqlserver":
+		if parsed.Timing == "BEFORE" {
+			return parsed, fmt.Errorf("%s não suporta triggers BEFORE; use AFTER ou INSTEAD OF", driver)
+		}
+	}
+	return parsed, nil
+}
+
+// TriggerDialect retorna o dialeto de triggers do driver: sqlite, postgres, mysql, oracle ou sqlserver.
+func TriggerDialect(driver string) string {
+	switch driver {
+	case "sqlite3", "sqlite":
+		return "sqlite"
+	case "godror", "oracle":
+		return "oracle"
+	case "sqlserver", "mssql":
+		return "sqlserver"
+	}
+	return driver
+}
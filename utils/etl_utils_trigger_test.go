@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestParseTriggerEvent verifica os formatos aceitos em Trigger.Event.
+func TestParseTriggerEvent(t *testing.T) {
+	tests := []struct {
+		event   string
+		want    TriggerEvent
+		wantErr bool
+	}{
+		{event: "AFTER INSERT", want: TriggerEvent{Timing: "AFTER", Operations: []string{"INSERT"}}},
+		{event: "  before insert or  update ", want: TriggerEvent{Timing: "BEFORE", Operations: []string{"INSERT", "UPDATE"}}},
+		{event: "INSTEAD OF DELETE", want: TriggerEvent{Timing: "INSTEAD OF", Operations: []string{"DELETE"}}},
+		{event: "AFTER INSERT, DELETE", want: TriggerEvent{Timing: "AFTER", Operations: []string{"INSERT", "DELETE"}}},
+		{event: "", wantErr: true},
+		{event: "AFTER", wantErr: true},
+		{event: "ON INSERT", wantErr: true},
+		{event: "AFTER TRUNCATE", wantErr: true},
+		{event: "AFTER INSERT OR INSERT", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseTriggerEvent(tt.event)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseTriggerEvent(%q) erro = %v, esperado erro = %v", tt.event, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseTriggerEvent(%q) = %+v, esperado %+v", tt.event, got, tt.want)
+		}
+	}
+}
+
+// TestValidateTriggerEvent verifica as restrições de cada banco de destino.
+func TestValidateTriggerEvent(t *testing.T) {
+	tests := []struct {
+		driver, event string
+		wantErr       bool
+	}{
+		{"sqlite3", "AFTER INSERT", false},
+		{"sqlite3", "AFTER INSERT OR UPDATE", true},
+		{"mysql", "BEFORE UPDATE", false},
+		{"mysql", "INSTEAD OF INSERT", true},
+		{"postgres", "BEFORE INSERT OR UPDATE OR DELETE", false},
+		{"godror", "AFTER INSERT OR DELETE", false},
+		{"sqlserver", "AFTER INSERT, UPDATE", false},
+		{"mssql", "BEFORE INSERT", true},
+	}
+	for _, tt := range tests {
+		if _, err := ValidateTriggerEvent(tt.driver, tt.event); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateTriggerEvent(%q, %q) erro = %v, esperado erro = %v", tt.driver, tt.event, err, tt.wantErr)
+		}
+	}
+}